@@ -0,0 +1,54 @@
+package app
+
+import (
+	"database/sql"
+	"sync"
+
+	"github.com/gorilla/mux"
+	"github.com/rainbowmga/timetravel/conf"
+	"github.com/rainbowmga/timetravel/gateways"
+	"github.com/rainbowmga/timetravel/handler/grpcapi"
+	"github.com/rainbowmga/timetravel/observability"
+	"github.com/rainbowmga/timetravel/script"
+	"google.golang.org/grpc"
+)
+
+// openDatabase opens the shared SQLite handle described by cfg.Database
+func openDatabase(cfg *conf.Config) (*gateways.Database, error) {
+	return gateways.OpenDatabase(cfg.Database.Path, gateways.DatabaseOptions{
+		BusyTimeout: cfg.Database.BusyTimeout,
+		Synchronous: cfg.Database.Synchronous,
+		MaxReaders:  cfg.Database.MaxReaders,
+	})
+}
+
+// newMigrator runs the migrations embedded from script/migrations
+func newMigrator(db *sql.DB) (*gateways.Migrator, error) {
+	return gateways.NewMigrator(db, script.Migrations())
+}
+
+// App is the wired HTTP and gRPC APIs plus the health checker and background workers behind them
+type App struct {
+	Router *mux.Router
+	Health *observability.HealthChecker
+	// GRPC serves the record operations; the caller picks the listener (grpc.addr)
+	GRPC *grpc.Server
+
+	grpcRecords *grpcapi.Server
+
+	closeOnce sync.Once
+	closers   []func()
+}
+
+func (a *App) onClose(fn func()) {
+	a.closers = append(a.closers, fn)
+}
+
+// Close stops background workers and flushes pending metrics, in reverse start order
+func (a *App) Close() {
+	a.closeOnce.Do(func() {
+		for i := len(a.closers) - 1; i >= 0; i-- {
+			a.closers[i]()
+		}
+	})
+}
@@ -0,0 +1,112 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rainbowmga/timetravel/auth"
+	"github.com/rainbowmga/timetravel/conf"
+	"github.com/rainbowmga/timetravel/service"
+)
+
+// newAuthenticator chains the methods named by auth.methods
+func newAuthenticator(cfg *conf.Config, keys auth.APIKeyStore) (auth.Authenticator, error) {
+	authCfg := cfg.Auth
+	jwtKeys, err := loadJWTKeys(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	methods := authCfg.Methods
+	if len(methods) == 0 {
+		methods = []string{auth.MethodAPIKey}
+		if jwtKeys.Len() > 0 {
+			methods = append(methods, auth.MethodJWT)
+		}
+	}
+
+	var chain auth.Chain
+	seen := map[string]bool{}
+	for _, method := range methods {
+		if seen[method] {
+			return nil, fmt.Errorf("auth.methods lists %q twice", method)
+		}
+		seen[method] = true
+
+		switch method {
+		case auth.MethodAPIKey:
+			chain = append(chain, auth.NewAPIKeyAuthenticator(keys))
+		case auth.MethodJWT:
+			if jwtKeys.Len() == 0 {
+				return nil, fmt.Errorf("auth.methods has jwt but auth.jwt has no keys")
+			}
+			chain = append(chain, auth.NewJWTAuthenticator(auth.JWTOptions{
+				Keys:        jwtKeys,
+				Issuer:      authCfg.JWT.Issuer,
+				Audience:    authCfg.JWT.Audience,
+				Leeway:      authCfg.JWT.Leeway,
+				UserClaim:   authCfg.JWT.UserClaim,
+				TenantClaim: authCfg.JWT.TenantClaim,
+				RolesClaim:  authCfg.JWT.RolesClaim,
+			}))
+		case auth.MethodHeader:
+			// anyone can send any X-User-ID, so only an explicit development environment may trust it
+			if !service.IsDevelopment(cfg.Environment) {
+				return nil, fmt.Errorf("auth.methods: header (trusting X-User-ID) is only allowed with environment: development")
+			}
+			roles := authCfg.DevRoles
+			if roles == nil {
+				roles = []string{"admin"}
+			}
+			chain = append(chain, auth.HeaderAuthenticator{Roles: roles})
+		default:
+			return nil, fmt.Errorf("unknown auth method %q (want api_key, jwt or header)", method)
+		}
+	}
+	return chain, nil
+}
+
+// loadJWTKeys reads auth.jwt.keys and auth.jwt.jwks_file
+func loadJWTKeys(cfg *conf.Config) (*auth.KeySet, error) {
+	jwtCfg := cfg.Auth.JWT
+	keys := auth.NewKeySet()
+	for i, k := range jwtCfg.Keys {
+		if k.KID == "" {
+			return nil, fmt.Errorf("auth.jwt.keys[%d]: kid is required", i)
+		}
+		var err error
+		switch strings.ToUpper(k.Alg) {
+		case "HS256":
+			secret := []byte(k.Secret)
+			if k.SecretFile != "" {
+				if secret, err = os.ReadFile(k.SecretFile); err != nil {
+					return nil, fmt.Errorf("auth.jwt.keys[%d]: %w", i, err)
+				}
+				secret = []byte(strings.TrimSpace(string(secret)))
+			}
+			err = keys.AddHMAC(k.KID, secret)
+		case "RS256":
+			var pem []byte
+			if pem, err = os.ReadFile(k.PublicKeyFile); err != nil {
+				return nil, fmt.Errorf("auth.jwt.keys[%d]: %w", i, err)
+			}
+			err = keys.AddRSAPEM(k.KID, pem)
+		default:
+			err = fmt.Errorf("alg %q is not HS256 or RS256", k.Alg)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("auth.jwt.keys[%d]: %w", i, err)
+		}
+	}
+	if jwtCfg.JWKSFile != "" {
+		data, err := os.ReadFile(jwtCfg.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("auth.jwt.jwks_file: %w", err)
+		}
+		if err := keys.AddJWKS(data); err != nil {
+			return nil, fmt.Errorf("auth.jwt.jwks_file: %w", err)
+		}
+	}
+	return keys, nil
+}
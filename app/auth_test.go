@@ -0,0 +1,146 @@
+package app_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rainbowmga/timetravel/app"
+	"github.com/rainbowmga/timetravel/conf"
+)
+
+const testJWTSecret = "0123456789abcdef0123456789abcdef"
+
+func authConfig(t *testing.T, environment string, methods ...string) *conf.Config {
+	t.Helper()
+	cfg := &conf.Config{Environment: environment}
+	cfg.Database.Path = filepath.Join(t.TempDir(), "auth.db")
+	cfg.Database.Migrations.RunOnStartup = true
+	cfg.Auth.Methods = methods
+	return cfg
+}
+
+func TestBuildRouter_AuthConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     func(*conf.Config)
+		env     string
+		methods []string
+		wantErr string
+	}{
+		{"default", nil, "", nil, ""},
+		{"header in development", nil, "development", []string{"header"}, ""},
+		{"header in dev", nil, "dev", []string{"header"}, ""},
+		{"header in production", nil, "production", []string{"api_key", "header"}, "only allowed with environment: development"},
+		{"header in staging", nil, "staging", []string{"header"}, "only allowed with environment: development"},
+		{"header by default environment", nil, "", []string{"header"}, "only allowed with environment: development"},
+		{"unknown method", nil, "", []string{"basic"}, "unknown auth method"},
+		{"duplicate method", nil, "", []string{"api_key", "api_key"}, "twice"},
+		{"jwt without keys", nil, "", []string{"jwt"}, "no keys"},
+		{"short secret", func(c *conf.Config) {
+			c.Auth.JWT.Keys = []conf.JWTKey{{KID: "k", Alg: "HS256", Secret: "short"}}
+		}, "", nil, "at least 32"},
+		{"unsupported alg", func(c *conf.Config) {
+			c.Auth.JWT.Keys = []conf.JWTKey{{KID: "k", Alg: "ES256"}}
+		}, "", nil, "not HS256 or RS256"},
+		{"missing jwks file", func(c *conf.Config) {
+			c.Auth.JWT.JWKSFile = filepath.Join(t.TempDir(), "missing.json")
+		}, "", nil, "jwks_file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := authConfig(t, tt.env, tt.methods...)
+			if tt.cfg != nil {
+				tt.cfg(cfg)
+			}
+			built, err := app.BuildRouterWithConfig(cfg)
+			if err == nil {
+				t.Cleanup(built.Close)
+			}
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("BuildRouterWithConfig() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("BuildRouterWithConfig() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// hs256 signs claims with testJWTSecret
+func hs256(claims string) string {
+	enc := base64.RawURLEncoding
+	signed := enc.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT","kid":"local"}`)) + "." + enc.EncodeToString([]byte(claims))
+	mac := hmac.New(sha256.New, []byte(testJWTSecret))
+	mac.Write([]byte(signed))
+	return signed + "." + enc.EncodeToString(mac.Sum(nil))
+}
+
+func TestBuildRouter_APIKeysAndJWT(t *testing.T) {
+	cfg := authConfig(t, "development", "api_key", "jwt", "header")
+	cfg.Auth.JWT.Keys = []conf.JWTKey{{KID: "local", Alg: "HS256", Secret: testJWTSecret}}
+	built, err := app.BuildRouterWithConfig(cfg)
+	if err != nil {
+		t.Fatalf("failed to build router: %v", err)
+	}
+	t.Cleanup(built.Close)
+	router := built.Router
+
+	call := func(method, path, body string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// a dev-mode X-User-ID caller is an admin and bootstraps the first key
+	rec := call("POST", "/api/v2/admin/api-keys", `{"name":"ci","user_id":7,"roles":["underwriter"]}`, "X-User-ID", "1")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create key: %d %s", rec.Code, rec.Body)
+	}
+	var issued struct {
+		APIKey struct {
+			ID int64 `json:"id"`
+		} `json:"api_key"`
+		Key string `json:"key"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&issued); err != nil || issued.Key == "" {
+		t.Fatalf("decode issued key: %v", err)
+	}
+
+	if rec := call("POST", "/api/v2/health", "", "X-API-Key", issued.Key); rec.Code != http.StatusOK {
+		t.Errorf("health with API key: %d %s", rec.Code, rec.Body)
+	}
+	if rec := call("GET", "/api/v2/admin/api-keys", "", "X-API-Key", issued.Key); rec.Code != http.StatusForbidden {
+		t.Errorf("admin with an underwriter key: %d, want 403", rec.Code)
+	}
+	if rec := call("POST", "/api/v2/health", "", "X-API-Key", issued.Key+"x", "X-User-ID", "1"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("bad API key with X-User-ID: %d, want 401", rec.Code)
+	}
+
+	exp := time.Now().Add(time.Hour).Unix()
+	admin := "Bearer " + hs256(fmt.Sprintf(`{"sub":"2","roles":["admin"],"exp":%d}`, exp))
+	if rec := call("DELETE", fmt.Sprintf("/api/v2/admin/api-keys/%d", issued.APIKey.ID), "", "Authorization", admin); rec.Code != http.StatusOK {
+		t.Fatalf("revoke with admin JWT: %d %s", rec.Code, rec.Body)
+	}
+	rec = call("POST", "/api/v2/health", "", "X-API-Key", issued.Key)
+	if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), "revoked") || rec.Header().Get("WWW-Authenticate") == "" {
+		t.Errorf("revoked key: %d %s", rec.Code, rec.Body)
+	}
+
+	expired := "Bearer " + hs256(fmt.Sprintf(`{"sub":"2","exp":%d}`, time.Now().Add(-time.Hour).Unix()))
+	if rec := call("POST", "/api/v2/health", "", "Authorization", expired); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expired JWT: %d, want 401", rec.Code)
+	}
+}
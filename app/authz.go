@@ -0,0 +1,32 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/rainbowmga/timetravel/authz"
+	"github.com/rainbowmga/timetravel/conf"
+	"github.com/rainbowmga/timetravel/service"
+)
+
+// newAuthorizer builds the policy from the built-in roles and authz.roles,
+// with assignments and denials kept by svc
+func newAuthorizer(cfg *conf.Config, svc *service.AuthzService) (*authz.Authorizer, error) {
+	roles := authz.DefaultRoles()
+	for role, names := range cfg.Authz.Roles {
+		perms := make([]authz.Permission, len(names))
+		for i, name := range names {
+			perms[i] = authz.Permission(name)
+		}
+		roles[role] = perms
+	}
+	scoped := cfg.Authz.ScopedRoles
+	if scoped == nil {
+		scoped = authz.DefaultScopedRoles
+	}
+
+	policy, err := authz.NewPolicy(roles, scoped)
+	if err != nil {
+		return nil, fmt.Errorf("authz: %w", err)
+	}
+	return authz.New(policy, svc, svc), nil
+}
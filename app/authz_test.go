@@ -0,0 +1,121 @@
+package app_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rainbowmga/timetravel/app"
+	"github.com/rainbowmga/timetravel/conf"
+	"github.com/rainbowmga/timetravel/gateways"
+)
+
+func TestBuildRouter_AuthzConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		roles   map[string][]string
+		scoped  []string
+		wantErr string
+	}{
+		{"custom role", map[string][]string{"support": {"records:read", "metrics:read"}}, nil, ""},
+		{"unknown permission", map[string][]string{"support": {"records:delete"}}, nil, "unknown permission"},
+		{"undefined scoped role", nil, []string{"agent"}, "not defined"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := authConfig(t, "development", "header")
+			cfg.Authz.Roles = tt.roles
+			cfg.Authz.ScopedRoles = tt.scoped
+			built, err := app.BuildRouterWithConfig(cfg)
+			if err == nil {
+				t.Cleanup(built.Close)
+			}
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("BuildRouterWithConfig() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("BuildRouterWithConfig() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBuildRouter_Authorization(t *testing.T) {
+	cfg := authConfig(t, "development", "jwt", "header")
+	cfg.Auth.JWT.Keys = []conf.JWTKey{{KID: "local", Alg: "HS256", Secret: testJWTSecret}}
+	built, err := app.BuildRouterWithConfig(cfg)
+	if err != nil {
+		t.Fatalf("failed to build router: %v", err)
+	}
+	t.Cleanup(built.Close)
+	router := built.Router
+
+	call := func(method, path, body, header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(header, value)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	bearer := func(sub int, roles string) string {
+		return "Bearer " + hs256(fmt.Sprintf(`{"sub":"%d","roles":%s,"exp":%d}`, sub, roles, time.Now().Add(time.Hour).Unix()))
+	}
+	reader, broker := bearer(5, `["reader"]`), bearer(9, `["broker"]`)
+
+	// the dev-mode admin writes two records and assigns the first to the broker
+	for _, id := range []string{"1", "2"} {
+		if rec := call("POST", "/api/v2/records/"+id, `{"name":"ann"}`, "X-User-ID", "1"); rec.Code != http.StatusOK {
+			t.Fatalf("admin write %s: %d %s", id, rec.Code, rec.Body)
+		}
+	}
+	if rec := call("PUT", "/api/v2/admin/users/9/policyholders/1", "", "X-User-ID", "1"); rec.Code != http.StatusOK {
+		t.Fatalf("assign: %d %s", rec.Code, rec.Body)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		auth   string
+		want   int
+	}{
+		{"reader reads", "GET", "/api/v2/records/1", reader, http.StatusOK},
+		{"reader cannot write", "PATCH", "/api/v2/records/1", reader, http.StatusForbidden},
+		{"reader cannot query history", "POST", "/api/v2/graphql", reader, http.StatusForbidden},
+		{"broker writes an assigned policyholder", "PATCH", "/api/v2/records/1", broker, http.StatusOK},
+		{"broker cannot read another", "GET", "/api/v2/records/2", broker, http.StatusForbidden},
+		{"broker cannot assign", "PUT", "/api/v2/admin/users/9/policyholders/2", broker, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := call(tt.method, tt.path, `{"name":"bob"}`, "Authorization", tt.auth)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+
+	// every denial is kept with its reason
+	db, err := gateways.OpenDatabase(cfg.Database.Path, gateways.DatabaseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var denied int
+	var details string
+	if err := db.Reader().QueryRow(`SELECT COUNT(*) FROM event_logs WHERE action = 'access_denied'`).Scan(&denied); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Reader().QueryRow(`
+		SELECT details FROM event_logs
+		WHERE action = 'access_denied' AND details LIKE '%not assigned%'
+		LIMIT 1`).Scan(&details); err != nil {
+		t.Fatalf("broker denial not logged: %v", err)
+	}
+	if denied != 4 || !strings.Contains(details, `"policyholder_id":2`) || !strings.Contains(details, `"roles":["broker"]`) {
+		t.Errorf("denials = %d, details = %s", denied, details)
+	}
+}
@@ -0,0 +1,53 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/rainbowmga/timetravel/conf"
+	"github.com/rainbowmga/timetravel/observability"
+	"github.com/rainbowmga/timetravel/replay"
+)
+
+// newCapture opens the replay log named by capture.path; nil means capture is off
+func newCapture(cfg *conf.Config) (*replay.Capturer, error) {
+	captureCfg := cfg.Capture
+	if captureCfg.Path == "" {
+		return nil, nil
+	}
+	sampleRate := 1.0
+	if captureCfg.SampleRate != nil {
+		sampleRate = *captureCfg.SampleRate
+	}
+	if sampleRate < 0 || sampleRate > 1 {
+		return nil, fmt.Errorf("capture.sample_rate must be between 0 and 1, got %v", sampleRate)
+	}
+
+	maxSizeMB, maxFiles := captureCfg.MaxSizeMB, captureCfg.MaxFiles
+	if maxSizeMB <= 0 {
+		maxSizeMB = 100
+	}
+	if maxFiles <= 0 {
+		maxFiles = 5
+	}
+	out, err := replay.OpenRotatingFile(captureCfg.Path, int64(maxSizeMB)<<20, maxFiles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open capture file: %w", err)
+	}
+
+	paths := captureCfg.Paths
+	if len(paths) == 0 {
+		paths = []string{"/api/"}
+	}
+	return replay.NewCapturer(out, replay.CaptureOptions{
+		SampleRate: sampleRate,
+		Paths:      paths,
+		// issued API keys appear in these responses
+		SkipPaths:     []string{"/api/v2/admin/api-keys"},
+		RedactHeaders: captureCfg.RedactHeaders,
+		RedactKeys:    captureCfg.RedactKeys,
+		MaxBodyBytes:  captureCfg.MaxBodyBytes,
+		ErrorLog: func(err error) {
+			observability.DefaultLogger.Error("request_capture_failed", "error", err)
+		},
+	}), nil
+}
@@ -0,0 +1,104 @@
+package app_test
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rainbowmga/timetravel/app"
+	"github.com/rainbowmga/timetravel/conf"
+	"github.com/rainbowmga/timetravel/replay"
+)
+
+func TestBuildApp_CapturesRequests(t *testing.T) {
+	capturePath := filepath.Join(t.TempDir(), "capture.jsonl")
+	cfg := &conf.Config{Environment: "development"}
+	cfg.Auth.Methods = []string{"header"}
+	cfg.Database.Path = filepath.Join(t.TempDir(), "capture.db")
+	cfg.Database.Migrations.RunOnStartup = true
+	cfg.Capture.Path = capturePath
+	cfg.Capture.RedactKeys = []string{"email"}
+	a, err := app.BuildApp(cfg)
+	if err != nil {
+		t.Fatalf("failed to build app: %v", err)
+	}
+	a.Health.MarkReady()
+
+	req := httptest.NewRequest("POST", "/api/v2/records/5", strings.NewReader(`{"name":"ada","email":"ada@example.com"}`))
+	req.Header.Set("X-User-ID", "1")
+	req.Header.Set("X-Api-Key", "secret")
+	rec := httptest.NewRecorder()
+	a.Router.ServeHTTP(rec, req)
+	a.Router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/livez", nil))
+	a.Close()
+
+	f, err := os.Open(capturePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	entries, err := replay.ReadLog(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("captured %d entries, want only the /api/ request", len(entries))
+	}
+	e := entries[0]
+	if e.ID != rec.Header().Get("X-Request-ID") || e.Header["X-Api-Key"] != replay.Redacted {
+		t.Errorf("entry = %+v, want the request id and a redacted key", e)
+	}
+	if e.Expect.Status != rec.Code || !strings.Contains(string(e.Expect.Body), `"email":"[REDACTED]"`) {
+		t.Errorf("expectation = %d %s", e.Expect.Status, e.Expect.Body)
+	}
+}
+
+func TestBuildApp_CaptureConfig(t *testing.T) {
+	cfg := &conf.Config{}
+	cfg.Database.Path = filepath.Join(t.TempDir(), "capture.db")
+	cfg.Capture.Path = filepath.Join(t.TempDir(), "capture.jsonl")
+	rate := 1.5
+	cfg.Capture.SampleRate = &rate
+	if _, err := app.BuildApp(cfg); err == nil || !strings.Contains(err.Error(), "capture.sample_rate") {
+		t.Errorf("BuildApp() error = %v, want a sample_rate error", err)
+	}
+}
+
+// /api/records/... is re-dispatched to a versioned route; the call is logged once, unversioned
+func TestBuildApp_CapturesUnversionedRequestsOnce(t *testing.T) {
+	capturePath := filepath.Join(t.TempDir(), "capture.jsonl")
+	cfg := &conf.Config{Environment: "development"}
+	cfg.Auth.Methods = []string{"header"}
+	cfg.Database.Path = filepath.Join(t.TempDir(), "capture.db")
+	cfg.Database.Migrations.RunOnStartup = true
+	cfg.Capture.Path = capturePath
+	a, err := app.BuildApp(cfg)
+	if err != nil {
+		t.Fatalf("failed to build app: %v", err)
+	}
+	a.Health.MarkReady()
+
+	req := httptest.NewRequest("POST", "/api/records/5", strings.NewReader(`{"name":"ada"}`))
+	req.Header.Set("X-User-ID", "1")
+	rec := httptest.NewRecorder()
+	a.Router.ServeHTTP(rec, req)
+	a.Close()
+
+	f, err := os.Open(capturePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	entries, err := replay.ReadLog(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("captured %d entries, want 1", len(entries))
+	}
+	if e := entries[0]; e.Path != "/api/records/5" || e.Expect.Status != rec.Code {
+		t.Errorf("entry = %s %d, want /api/records/5 answered %d", e.Path, e.Expect.Status, rec.Code)
+	}
+}
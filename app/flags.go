@@ -0,0 +1,71 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/rainbowmga/timetravel/conf"
+	"github.com/rainbowmga/timetravel/gateways"
+	"github.com/rainbowmga/timetravel/service"
+)
+
+// newFlagProvider builds the flag source selected by feature_flags.provider;
+// a file watcher it starts is stopped when a closes
+func newFlagProvider(a *App, cfg *conf.Config, db *gateways.Database) (service.FlagProvider, error) {
+	flagCfg := cfg.FeatureFlags
+
+	switch flagCfg.Provider {
+	case "", "sqlite":
+		return service.NewSQLiteFlagProviderWithDB(db)
+
+	case "file":
+		if flagCfg.File == "" {
+			return nil, fmt.Errorf("feature_flags.file is required for the file provider")
+		}
+		return newFileFlagProvider(a, flagCfg.File, cfg)
+
+	case "static":
+		return service.NewStaticFlagProvider(staticFlags(cfg)), nil
+
+	case "layered":
+		layers := []service.FlagProvider{service.NewStaticFlagProvider(staticFlags(cfg))}
+		if flagCfg.File != "" {
+			fileProvider, err := newFileFlagProvider(a, flagCfg.File, cfg)
+			if err != nil {
+				return nil, err
+			}
+			layers = append(layers, fileProvider)
+		}
+		sqliteProvider, err := service.NewSQLiteFlagProviderWithDB(db)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, sqliteProvider)
+		return service.NewLayeredFlagProvider(layers...), nil
+
+	default:
+		return nil, fmt.Errorf("unknown feature_flags.provider %q", flagCfg.Provider)
+	}
+}
+
+func newFileFlagProvider(a *App, path string, cfg *conf.Config) (*service.FileFlagProvider, error) {
+	p, err := service.NewFileFlagProvider(path)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.FeatureFlags.WatchInterval > 0 {
+		a.onClose(p.Watch(cfg.FeatureFlags.WatchInterval))
+	}
+	return p, nil
+}
+
+func staticFlags(cfg *conf.Config) map[string]service.FeatureFlag {
+	flags := make(map[string]service.FeatureFlag, len(cfg.FeatureFlags.Static))
+	for key, o := range cfg.FeatureFlags.Static {
+		rollout := 100
+		if o.RolloutPercentage != nil {
+			rollout = *o.RolloutPercentage
+		}
+		flags[key] = service.FeatureFlag{Key: key, Enabled: o.Enabled, RolloutPercentage: rollout}
+	}
+	return flags
+}
@@ -0,0 +1,40 @@
+package app
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	"github.com/rainbowmga/timetravel/auth"
+	"github.com/rainbowmga/timetravel/conf"
+	"github.com/rainbowmga/timetravel/controller"
+	"github.com/rainbowmga/timetravel/handler/grpcapi"
+)
+
+// newGRPCServer serves the v2 record controller over gRPC with the same
+// authentication, enable_v2_api, rate limit and permission checks as the HTTP
+// v2 routes
+func newGRPCServer(cfg *conf.Config, records *controller.SQLiteRecordController, flags *controller.FeatureFlagController, authenticator auth.Authenticator, limiter grpcapi.RateLimiter, authorizer grpcapi.Authorizer) (*grpc.Server, *grpcapi.Server) {
+	srv := grpcapi.NewServer(records)
+	if cfg.GRPC.WatchInterval > 0 {
+		srv.WatchInterval = cfg.GRPC.WatchInterval
+	}
+	return grpcapi.NewGRPCServer(srv, authenticator, limiter, authorizer, flags), srv
+}
+
+// StopGRPC ends open change streams, then waits for in-flight calls until ctx
+// is done and closes the remaining connections
+func (a *App) StopGRPC(ctx context.Context) {
+	a.grpcRecords.Drain()
+
+	stopped := make(chan struct{})
+	go func() {
+		a.GRPC.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		a.GRPC.Stop()
+	}
+}
@@ -0,0 +1,32 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/rainbowmga/timetravel/gateways"
+	"github.com/rainbowmga/timetravel/observability"
+)
+
+// sqliteCheck verifies the database answers a trivial query
+func sqliteCheck(db *sql.DB) observability.HealthCheck {
+	return func(ctx context.Context) error {
+		var one int
+		return db.QueryRowContext(ctx, "SELECT 1").Scan(&one)
+	}
+}
+
+// migrationsCheck verifies every embedded migration is applied and unmodified,
+// reading through the reader pool so probes never queue behind writes
+func migrationsCheck(m *gateways.Migrator, reader *sql.DB) observability.HealthCheck {
+	return func(ctx context.Context) error {
+		return m.VerifyReadOnly(ctx, reader)
+	}
+}
+
+// workerCheck adapts a context-free status func such as MetricsWriter.Alive
+func workerCheck(status func() error) observability.HealthCheck {
+	return func(context.Context) error {
+		return status()
+	}
+}
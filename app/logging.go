@@ -0,0 +1,54 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rainbowmga/timetravel/conf"
+	"github.com/rainbowmga/timetravel/observability"
+)
+
+// ConfigureLogging replaces observability.DefaultLogger according to the logging config
+func ConfigureLogging(cfg *conf.Config) error {
+	logCfg := cfg.Logging
+
+	level, err := observability.ParseLogLevel(logCfg.Level)
+	if err != nil {
+		return err
+	}
+
+	var format observability.LogFormat
+	switch logCfg.Format {
+	case "", "text":
+		format = observability.LogFormatText
+	case "json":
+		format = observability.LogFormatJSON
+	default:
+		return fmt.Errorf("unknown logging.format %q", logCfg.Format)
+	}
+
+	var out io.Writer
+	switch logCfg.Output {
+	case "", "stdout":
+		out = os.Stdout
+	case "stderr":
+		out = os.Stderr
+	default:
+		f, err := os.OpenFile(logCfg.Output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open log output: %w", err)
+		}
+		out = f
+	}
+
+	observability.DefaultLogger = observability.NewLoggerWithOptions(observability.LoggerOptions{
+		Level:            level,
+		Format:           format,
+		Output:           out,
+		SampleInitial:    logCfg.Sampling.Initial,
+		SampleThereafter: logCfg.Sampling.Thereafter,
+		SampleWindow:     logCfg.Sampling.Window,
+	})
+	return nil
+}
@@ -0,0 +1,43 @@
+package app_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/rainbowmga/timetravel/app"
+	"github.com/rainbowmga/timetravel/conf"
+	"github.com/rainbowmga/timetravel/observability"
+)
+
+func TestConfigureLogging(t *testing.T) {
+	orig := observability.DefaultLogger
+	defer func() { observability.DefaultLogger = orig }()
+
+	tests := []struct {
+		name    string
+		level   string
+		format  string
+		output  string
+		wantErr bool
+	}{
+		{"defaults", "", "", "", false},
+		{"json to file", "debug", "json", filepath.Join(t.TempDir(), "app.log"), false},
+		{"bad level", "loud", "", "", true},
+		{"bad format", "", "xml", "", true},
+		{"bad output", "", "", filepath.Join(t.TempDir(), "missing", "app.log"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &conf.Config{}
+			cfg.Logging.Level = tt.level
+			cfg.Logging.Format = tt.format
+			cfg.Logging.Output = tt.output
+
+			err := app.ConfigureLogging(cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ConfigureLogging() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
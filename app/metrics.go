@@ -0,0 +1,99 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rainbowmga/timetravel/conf"
+	"github.com/rainbowmga/timetravel/gateways"
+	"github.com/rainbowmga/timetravel/observability"
+)
+
+// metricsRollupRepo is the part of gateways.MetricsRepository the rollup loop needs
+type metricsRollupRepo interface {
+	RollupMetrics(now time.Time) error
+	PruneMetrics(now time.Time, retention gateways.MetricsRetention) (int64, error)
+}
+
+// newMetricsWriter queues request metrics and writes them to SQLite in batches
+func newMetricsWriter(cfg *conf.Config, repo observability.MetricsBatchRepo) *observability.MetricsWriter {
+	return observability.NewMetricsWriter(repo, observability.MetricsWriterOptions{
+		BatchSize:     cfg.Metrics.Writer.BatchSize,
+		BufferSize:    cfg.Metrics.Writer.BufferSize,
+		FlushInterval: cfg.Metrics.Writer.FlushInterval,
+	})
+}
+
+// metricsRollupLoop rolls up and prunes metrics every rollup.interval
+type metricsRollupLoop struct {
+	interval time.Duration
+	lastTick atomic.Int64 // unix nanos of the last tick (or start)
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// startMetricsRollups starts the rollup loop; a zero interval returns nil (disabled)
+func startMetricsRollups(cfg *conf.Config, repo metricsRollupRepo) *metricsRollupLoop {
+	rollupCfg := cfg.Metrics.Rollup
+	if rollupCfg.Interval <= 0 {
+		return nil
+	}
+	retention := gateways.MetricsRetention{
+		Raw:    rollupCfg.RawRetention,
+		Minute: rollupCfg.MinuteRetention,
+		Hour:   rollupCfg.HourRetention,
+	}
+
+	l := &metricsRollupLoop{interval: rollupCfg.Interval, done: make(chan struct{})}
+	l.lastTick.Store(time.Now().UnixNano())
+	go func() {
+		ticker := time.NewTicker(l.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-l.done:
+				return
+			case now := <-ticker.C:
+				l.lastTick.Store(now.UnixNano())
+				runMetricsRollup(repo, now, retention)
+			}
+		}
+	}()
+	return l
+}
+
+// Stop ends the loop; safe to call more than once
+func (l *metricsRollupLoop) Stop() {
+	l.stopOnce.Do(func() { close(l.done) })
+}
+
+// Alive fails when the loop was stopped or has missed several ticks
+func (l *metricsRollupLoop) Alive() error {
+	select {
+	case <-l.done:
+		return errors.New("metrics rollup loop stopped")
+	default:
+	}
+	if since := time.Since(time.Unix(0, l.lastTick.Load())); since > 3*l.interval {
+		return fmt.Errorf("metrics rollup loop stalled: last run %s ago", since.Round(time.Second))
+	}
+	return nil
+}
+
+func runMetricsRollup(repo metricsRollupRepo, now time.Time, retention gateways.MetricsRetention) {
+	if err := repo.RollupMetrics(now); err != nil {
+		observability.DefaultLogger.Error("metrics_rollup_failed", "error", err)
+		return
+	}
+	removed, err := repo.PruneMetrics(now, retention)
+	if err != nil {
+		observability.DefaultLogger.Error("metrics_prune_failed", "error", err)
+		return
+	}
+	if removed > 0 {
+		observability.DefaultLogger.Debug("metrics_pruned", "rows", removed)
+	}
+}
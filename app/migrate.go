@@ -0,0 +1,101 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/rainbowmga/timetravel/conf"
+	"github.com/rainbowmga/timetravel/gateways"
+)
+
+// ErrMigrateUsage is returned for an unknown or incomplete migrate command
+var ErrMigrateUsage = errors.New("usage: migrate up | down | status | to <version> | unlock")
+
+// RunMigrateCommand runs `migrate <args>` against cfg.Database.Path and writes progress to out
+func RunMigrateCommand(ctx context.Context, cfg *conf.Config, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return ErrMigrateUsage
+	}
+
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	m, err := newMigrator(db.Writer())
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := m.Up(ctx)
+		for _, v := range applied {
+			fmt.Fprintf(out, "applied %s\n", v)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Fprintln(out, "no pending migrations")
+		}
+		return err
+	case "down":
+		reverted, err := m.Down(ctx)
+		if err == nil {
+			if reverted == "" {
+				fmt.Fprintln(out, "no applied migrations")
+			} else {
+				fmt.Fprintf(out, "reverted %s\n", reverted)
+			}
+		}
+		return err
+	case "to":
+		if len(args) != 2 {
+			return ErrMigrateUsage
+		}
+		if err := m.To(ctx, args[1]); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "migrated to %s\n", args[1])
+		return nil
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		writeMigrationStatus(out, statuses)
+		return nil
+	case "unlock":
+		if err := m.Unlock(ctx); err != nil {
+			return err
+		}
+		fmt.Fprintln(out, "migration lock released")
+		return nil
+	default:
+		return ErrMigrateUsage
+	}
+}
+
+func writeMigrationStatus(out io.Writer, statuses []gateways.MigrationStatus) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, s := range statuses {
+		state := "pending"
+		switch {
+		case s.Missing:
+			state = "applied (file missing)"
+		case s.Modified:
+			state = "applied (modified)"
+		case s.Applied:
+			state = "applied"
+		}
+		appliedAt := ""
+		if !s.AppliedAt.IsZero() {
+			appliedAt = s.AppliedAt.UTC().Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+	}
+	w.Flush()
+}
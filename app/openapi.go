@@ -0,0 +1,39 @@
+package app
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/conf"
+	apiV1 "github.com/rainbowmga/timetravel/handler/v1"
+	"github.com/rainbowmga/timetravel/openapi"
+)
+
+// openAPIValidation builds the spec validation middleware for openapi.validation
+func openAPIValidation(cfg *conf.Config) (mux.MiddlewareFunc, error) {
+	mode, err := openapi.ParseMode(cfg.OpenAPI.Validation)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := openapi.Spec()
+	if err != nil {
+		return nil, err
+	}
+	return openapi.Validate(doc, openapi.Options{
+		Mode:         mode,
+		MaxBodyBytes: cfg.OpenAPI.MaxBodyBytes,
+		WriteError:   writeContractError,
+	}), nil
+}
+
+// writeContractError keeps v1's legacy error body; everything else gets problem+json
+func writeContractError(w http.ResponseWriter, r *http.Request, err error) {
+	if strings.HasPrefix(r.URL.Path, "/api/v1/") {
+		apiV1.RespondError(w, r, err)
+		return
+	}
+	apperr.WriteProblem(w, r, err)
+}
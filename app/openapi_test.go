@@ -0,0 +1,166 @@
+package app_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rainbowmga/timetravel/app"
+	"github.com/rainbowmga/timetravel/conf"
+	"github.com/rainbowmga/timetravel/openapi"
+)
+
+func buildStrictRouter(t *testing.T) *mux.Router {
+	t.Helper()
+	cfg := &conf.Config{Environment: "development"}
+	cfg.Auth.Methods = []string{"header"}
+	cfg.Database.Path = setupSharedInMemoryDB(t)
+	cfg.OpenAPI.Validation = string(openapi.ModeStrict)
+	built, err := app.BuildRouterWithConfig(cfg)
+	if err != nil {
+		t.Fatalf("failed to build router: %v", err)
+	}
+	t.Cleanup(built.Close)
+	router := built.Router
+	return router
+}
+
+// Every route registered in mux must be documented, and every documented
+// operation must be served
+func TestOpenAPI_RouteParity(t *testing.T) {
+	router := buildStrictRouter(t)
+
+	var registered []string
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		tmpl, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		// subrouter prefixes and the unversioned /api/records/ catch-all have no methods
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		for _, m := range methods {
+			registered = append(registered, m+" "+tmpl)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(registered)
+
+	doc, err := openapi.Spec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	documented := doc.Routes()
+
+	if missing := difference(registered, documented); len(missing) > 0 {
+		t.Errorf("routes registered but missing from openapi.yaml:\n  %s", strings.Join(missing, "\n  "))
+	}
+	if stale := difference(documented, registered); len(stale) > 0 {
+		t.Errorf("operations in openapi.yaml with no registered route:\n  %s", strings.Join(stale, "\n  "))
+	}
+}
+
+func difference(a, b []string) []string {
+	in := map[string]bool{}
+	for _, s := range b {
+		in[s] = true
+	}
+	var out []string
+	for _, s := range a {
+		if !in[s] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// Drives every endpoint in strict mode: a handler answering outside the spec
+// gets replaced by a 500, so any drift fails here
+func TestOpenAPI_StrictContract(t *testing.T) {
+	router := buildStrictRouter(t)
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"GET", "/openapi.json", "", http.StatusOK},
+		{"GET", "/metrics", "", http.StatusOK},
+		{"GET", "/livez", "", http.StatusOK},
+		{"GET", "/readyz", "", http.StatusOK},
+		{"POST", "/api/v1/health", "", http.StatusOK},
+		{"POST", "/api/v1/records/7001", `{"name":"ada","gone":null}`, http.StatusOK},
+		{"GET", "/api/v1/records/7001", "", http.StatusOK},
+		{"GET", "/api/v1/records/7002", "", http.StatusNotFound},
+		{"GET", "/api/v1/records/abc", "", http.StatusBadRequest},
+		{"POST", "/api/v2/health", "", http.StatusOK},
+		{"POST", "/api/v2/records/7001", `{"name":"ada"}`, http.StatusOK},
+		{"POST", "/api/v2/records/7001", `{"name":"ada lovelace"}`, http.StatusOK},
+		{"POST", "/api/v2/records/7001", `{"age":36}`, http.StatusBadRequest},
+		{"GET", "/api/v2/records/7001", "", http.StatusOK},
+		{"PATCH", "/api/v2/records/7001", `{"city":"london","name":null}`, http.StatusOK},
+		{"PATCH", "/api/v2/records/7002", `{"city":"london"}`, http.StatusNotFound},
+		{"GET", "/api/v2/records/7002", "", http.StatusNotFound},
+		{"GET", "/api/v2/records/0", "", http.StatusBadRequest},
+		{"GET", "/api/v2/records/7001/versions", "", http.StatusOK},
+		{"GET", "/api/v2/records/7002/versions", "", http.StatusOK},
+		{"GET", "/api/v2/records/7001/versions/2", "", http.StatusOK},
+		{"GET", "/api/v2/records/7001/versions/9", "", http.StatusNotFound},
+		{"POST", "/api/v2/admin/refresh-flags", "", http.StatusOK},
+		{"GET", "/api/v2/admin/api-versions", "", http.StatusOK},
+		{"PUT", "/api/v2/admin/api-versions/v2", `{"is_active":true,"rollout_percentage":0}`, http.StatusOK},
+		{"PUT", "/api/v2/admin/api-versions/v2", `{"is_active":true,"rollout_percentage":101}`, http.StatusBadRequest},
+		{"PUT", "/api/v2/admin/api-versions/v9", `{"is_active":true,"rollout_percentage":5}`, http.StatusNotFound},
+		{"GET", "/api/v2/admin/metrics?name=http_requests_total", "", http.StatusOK},
+		{"GET", "/api/v2/admin/metrics", "", http.StatusBadRequest},
+		{"GET", "/api/v2/admin/metrics?name=x&from=yesterday", "", http.StatusBadRequest},
+		{"POST", "/api/v2/graphql", `{"query":"{ record(policyholderId: 7001) { version versions(first: 1) { pageInfo { hasNextPage } } } }"}`, http.StatusOK},
+		{"POST", "/api/v2/graphql", `{"query":"{ record { nope } }"}`, http.StatusBadRequest},
+		{"POST", "/api/v2/graphql", `{"variables":{}}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("X-User-ID", "1")
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestOpenAPI_RequestErrorsKeepVersionFormat(t *testing.T) {
+	router := buildStrictRouter(t)
+
+	tests := []struct {
+		path        string
+		contentType string
+	}{
+		{"/api/v1/records/abc", "application/json; charset=utf-8"},
+		{"/api/v2/records/abc", "application/problem+json"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.Header.Set("X-User-ID", "1")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest || rec.Header().Get("Content-Type") != tt.contentType {
+			t.Errorf("%s: got %d %q, want 400 %q", tt.path, rec.Code, rec.Header().Get("Content-Type"), tt.contentType)
+		}
+	}
+}
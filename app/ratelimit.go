@@ -0,0 +1,75 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rainbowmga/timetravel/conf"
+	"github.com/rainbowmga/timetravel/controller"
+	"github.com/rainbowmga/timetravel/observability"
+	"github.com/rainbowmga/timetravel/ratelimit"
+)
+
+// defaultRateLimitReload is how often overrides are re-read when rate_limit.reload_interval is unset
+const defaultRateLimitReload = 30 * time.Second
+
+// newRateLimiter builds the limiter from the rate_limit defaults
+func newRateLimiter(cfg *conf.Config) (*ratelimit.Limiter, error) {
+	defaults := map[ratelimit.Class]ratelimit.Limit{}
+	for class, l := range map[ratelimit.Class]conf.RateLimit{
+		ratelimit.Read:  cfg.RateLimit.Read,
+		ratelimit.Write: cfg.RateLimit.Write,
+		ratelimit.Admin: cfg.RateLimit.Admin,
+	} {
+		if l.PerMinute < 0 || l.Burst < 0 {
+			return nil, fmt.Errorf("rate_limit.%s: per_minute and burst must not be negative", class)
+		}
+		defaults[class] = ratelimit.Limit{PerMinute: l.PerMinute, Burst: l.Burst}
+	}
+	return ratelimit.New(defaults), nil
+}
+
+// rateLimitReloader re-reads the stored overrides, so changes made through
+// another instance apply here too
+type rateLimitReloader struct {
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// startRateLimitReloads loads the overrides now, then every
+// rate_limit.reload_interval. Until a load succeeds (e.g. migrations are
+// pending, which /readyz reports) the configured defaults apply.
+func startRateLimitReloads(cfg *conf.Config, c *controller.RateLimitController) *rateLimitReloader {
+	reload := func() {
+		if err := c.Reload(context.Background()); err != nil {
+			observability.DefaultLogger.Warn("rate_limit_reload_failed", "error", err)
+		}
+	}
+	reload()
+	interval := cfg.RateLimit.ReloadInterval
+	if interval <= 0 {
+		interval = defaultRateLimitReload
+	}
+
+	r := &rateLimitReloader{done: make(chan struct{})}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.done:
+				return
+			case <-ticker.C:
+				reload()
+			}
+		}
+	}()
+	return r
+}
+
+// Stop ends the reloads; safe to call more than once
+func (r *rateLimitReloader) Stop() {
+	r.stopOnce.Do(func() { close(r.done) })
+}
@@ -0,0 +1,77 @@
+package app_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rainbowmga/timetravel/app"
+	"github.com/rainbowmga/timetravel/conf"
+)
+
+func TestBuildRouter_RateLimitConfig(t *testing.T) {
+	cfg := authConfig(t, "development", "header")
+	cfg.RateLimit.Write = conf.RateLimit{PerMinute: -1}
+	if built, err := app.BuildRouterWithConfig(cfg); err == nil || !strings.Contains(err.Error(), "rate_limit.write") {
+		if err == nil {
+			built.Close()
+		}
+		t.Fatalf("BuildRouterWithConfig() error = %v, want a rate_limit.write error", err)
+	}
+}
+
+func TestBuildRouter_RateLimit(t *testing.T) {
+	cfg := authConfig(t, "development", "header")
+	cfg.RateLimit.Write = conf.RateLimit{PerMinute: 60, Burst: 2}
+	built, err := app.BuildRouterWithConfig(cfg)
+	if err != nil {
+		t.Fatalf("failed to build router: %v", err)
+	}
+	t.Cleanup(built.Close)
+	router := built.Router
+
+	call := func(method, path, body, user string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-User-ID", user)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	write := func(user string) *httptest.ResponseRecorder {
+		return call("POST", "/api/v2/records/1", `{"name":"ann"}`, user)
+	}
+
+	for i := 0; i < 2; i++ {
+		if rec := write("2"); rec.Code != http.StatusOK {
+			t.Fatalf("write %d: %d %s", i+1, rec.Code, rec.Body)
+		}
+	}
+	rec := write("2")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("write over the burst: %d %s", rec.Code, rec.Body)
+	}
+	if rec.Header().Get("Retry-After") != "1" || rec.Header().Get("RateLimit-Policy") != "60;w=60;burst=2" {
+		t.Errorf("headers = %v", rec.Header())
+	}
+
+	// reads and other users keep their own buckets
+	if rec := call("GET", "/api/v2/records/1", "", "2"); rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Limit") != "" {
+		t.Errorf("read: %d with RateLimit-Limit %q", rec.Code, rec.Header().Get("RateLimit-Limit"))
+	}
+	if rec := write("3"); rec.Code != http.StatusOK {
+		t.Errorf("other user write: %d %s", rec.Code, rec.Body)
+	}
+
+	// an override raises the limit at once
+	if rec := call("PUT", "/api/v2/admin/users/2/rate-limits/write", `{"per_minute":600,"burst":10}`, "1"); rec.Code != http.StatusOK {
+		t.Fatalf("set override: %d %s", rec.Code, rec.Body)
+	}
+	if rec := write("2"); rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Limit") != "10" {
+		t.Errorf("write after override: %d with RateLimit-Limit %q", rec.Code, rec.Header().Get("RateLimit-Limit"))
+	}
+	rec = call("GET", "/api/v2/admin/rate-limits", "", "1")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"user_id":2`) {
+		t.Errorf("list: %d %s", rec.Code, rec.Body)
+	}
+}
@@ -1,78 +1,223 @@
 package app
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rainbowmga/timetravel/auth"
+	"github.com/rainbowmga/timetravel/conf"
 	"github.com/rainbowmga/timetravel/controller"
 	"github.com/rainbowmga/timetravel/gateways"
+	"github.com/rainbowmga/timetravel/handler/graphqlapi"
 	apiV1 "github.com/rainbowmga/timetravel/handler/v1"
 	apiV2 "github.com/rainbowmga/timetravel/handler/v2"
 	"github.com/rainbowmga/timetravel/observability"
+	"github.com/rainbowmga/timetravel/openapi"
+	"github.com/rainbowmga/timetravel/ratelimit"
+	"github.com/rainbowmga/timetravel/service"
 )
 
-func BuildRouter(dbPath string, runMigrations bool) (*mux.Router, error) {
-	
-	sqlPath := "script/create_v2_tables.sql"
-
-    // Skip SQL file if dbPath is in-memory (for tests)
-    if dbPath == ":memory:" || dbPath == "file:testdb?mode=memory&cache=shared" {
-        sqlPath = ""
-    }
-
-    db := gateways.ConnectDB(dbPath, sqlPath)
-    
-    if runMigrations && sqlPath != "" {
-        migrationsPath := "script/migrations"
-        if err := gateways.RunMigrations(db, migrationsPath); err != nil {
-            return nil, err
-        }
-    }
-
-	metricsRepo, err := gateways.NewMetricsRepository(dbPath)
+// BuildRouter wires the API with default settings for the given database, in
+// development mode: callers are identified by X-User-ID alone. The caller
+// serves App.Router and must Close the App.
+func BuildRouter(dbPath string, runMigrations bool) (*App, error) {
+	cfg := &conf.Config{Environment: "development"}
+	cfg.Auth.Methods = []string{auth.MethodHeader}
+	cfg.Database.Path = dbPath
+	cfg.Database.Migrations.RunOnStartup = runMigrations
+	return BuildRouterWithConfig(cfg)
+}
+
+// BuildRouterWithConfig wires the API from a loaded config and reports ready
+// immediately; the caller must Close the App. Servers that manage their own
+// lifecycle should use BuildApp instead.
+func BuildRouterWithConfig(cfg *conf.Config) (*App, error) {
+	a, err := BuildApp(cfg)
+	if err != nil {
+		return nil, err
+	}
+	a.Health.MarkReady()
+	return a, nil
+}
+
+// BuildApp wires the API, its health checks and background workers from a loaded config.
+// /readyz reports "starting" until the caller invokes Health.MarkReady.
+func BuildApp(cfg *conf.Config) (*App, error) {
+	runMigrations := cfg.Database.Migrations.RunOnStartup
+
+	// every service shares one database handle
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return nil, err
+	}
+	a := &App{Health: observability.NewHealthChecker()}
+	a.onClose(func() { db.Close() })
+
+	// file and in-memory databases get the same embedded migrations
+	migrator, err := newMigrator(db.Writer())
+	if err != nil {
+		a.Close()
+		return nil, err
+	}
+	if runMigrations {
+		if _, err := migrator.Up(context.Background()); err != nil {
+			a.Close()
+			return nil, err
+		}
+	}
+
+	a.Health.AddReadinessCheck("sqlite", sqliteCheck(db.Reader()))
+	a.Health.AddReadinessCheck("migrations", migrationsCheck(migrator, db.Reader()))
+
+	metricsRepo := gateways.NewMetricsRepositoryWithDB(db)
+	observability.InitMetricsRegion(cfg.Metrics.Region)
+	metricsWriter := newMetricsWriter(cfg, metricsRepo)
+	observability.InitMetricsRepository(metricsWriter)
+	a.onClose(metricsWriter.Close)
+	a.Health.AddLivenessCheck("metrics_writer", workerCheck(metricsWriter.Alive))
+	if rollups := startMetricsRollups(cfg, metricsRepo); rollups != nil {
+		a.onClose(rollups.Stop)
+		a.Health.AddLivenessCheck("metrics_rollup", workerCheck(rollups.Alive))
+	}
+
+	validation, err := openAPIValidation(cfg)
+	if err != nil {
+		a.Close()
+		return nil, err
+	}
+
+	apiKeys := service.NewAPIKeyServiceWithDB(db)
+	authenticator, err := newAuthenticator(cfg, apiKeys)
+	if err != nil {
+		a.Close()
+		return nil, err
+	}
+	authzService := service.NewAuthzServiceWithDB(db)
+	authorizer, err := newAuthorizer(cfg, authzService)
+	if err != nil {
+		a.Close()
+		return nil, err
+	}
+	limiter, err := newRateLimiter(cfg)
+	if err != nil {
+		a.Close()
+		return nil, err
+	}
+
+	capture, err := newCapture(cfg)
 	if err != nil {
-    	return nil, err
+		a.Close()
+		return nil, err
 	}
-	observability.InitMetricsRepository(metricsRepo)
 
 	router := mux.NewRouter()
-	router.Handle("/metrics", observability.MetricsHandler()).Methods("GET")
+	// request id + server span for every route
+	router.Use(observability.RequestTracing)
+	if capture != nil {
+		// after tracing so entries carry the request id
+		a.onClose(capture.Close)
+		router.Use(capture.Middleware)
+	}
+
+	// every route but the unversioned catch-all, which hands its requests
+	// straight to this chain so tracing and capture see them only once
+	routes := router.NewRoute().Subrouter()
+	routes.Use(RequestTimeouts(cfg))
+	routes.Use(validation)
+	routes.Handle("/openapi.json", openapi.Handler()).Methods("GET")
+	routes.Handle("/metrics", observability.MetricsHandler()).Methods("GET")
+	routes.Handle("/livez", a.Health.LivezHandler()).Methods("GET")
+	routes.Handle("/readyz", a.Health.ReadyzHandler()).Methods("GET")
 
 	// v1
-	v1Route := router.PathPrefix("/api/v1").Subrouter()
+	v1Route := routes.PathPrefix("/api/v1").Subrouter()
 	v1Route.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(map[string]bool{"ok": true})
 	}).Methods("POST")
 	
-	v1Service := controller.NewInMemoryRecordService()
-	v1Handler := apiV1.NewAPI(&v1Service)
-	v1Handler.CreateRoutes(v1Route)
 
 	// v2
-	v2Route := router.PathPrefix("/api/v2").Subrouter()
-
-	// 1️⃣ v2 health check and metrics(no user context required)
-	v2Route.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-				_ = json.NewEncoder(w).Encode(map[string]bool{"ok": true})
-	}).Methods("POST") 
-	// 2️⃣ middleware-protected routes
-	v2Route.Use(observability.RequireUserContext)
+	v2Route := routes.PathPrefix("/api/v2").Subrouter()
+
+	// authenticated routes; POST /health is served by the v2 handler (gated by enable_v2_api)
+	v2Route.Use(auth.Middleware(authenticator))
 	v2Route.Use(observability.LoggingAndMetrics)
+	// per user and route class, before authorization so refused floods do not
+	// fill event_logs with denials
+	v2Route.Use(ratelimit.Middleware(limiter))
 
-	// Metrics endpoint under v2
-	v2Controller, err := controller.NewSQLiteRecordController(dbPath)
+	flagProvider, err := newFlagProvider(a, cfg, db)
 	if err != nil {
+		a.Close()
 		return nil, err
 	}
+	flagEvaluator := service.NewFeatureFlagServiceWithProvider(flagProvider)
+	a.Health.AddReadinessCheck("feature_flags", workerCheck(flagEvaluator.Ready))
+	flagService := controller.NewFeatureFlagControllerWithService(flagEvaluator)
 
-	flagService, err := controller.NewFeatureFlagController(dbPath)
+	// enable_metrics / enable_audit_logging are evaluated at runtime
+	observability.InitMetricsFlags(flagEvaluator)
+
+	recordMetrics, err := observability.NewPrometheusRecordMetrics(prometheus.DefaultRegisterer)
 	if err != nil {
+		a.Close()
 		return nil, err
 	}
+	rateLimitMetrics, err := observability.NewPrometheusRateLimitMetrics(prometheus.DefaultRegisterer)
+	if err != nil {
+		a.Close()
+		return nil, err
+	}
+	limiter.SetMetrics(rateLimitMetrics)
+
+	v2Service := service.NewSQLiteRecordServiceWithDB(db, flagEvaluator, cfg.Environment)
+	v2Service.SetMetrics(recordMetrics)
+	v2Controller := controller.NewSQLiteRecordControllerWithService(v2Service)
 
-	v2Handler := apiV2.NewAPI(v2Controller, flagService)
+	// each route declares its permission; the authorizer checks the principal
+	v2Handler := apiV2.NewAPI(v2Controller, flagService, authorizer)
 	v2Handler.CreateRoutes(v2Route)
+	a.GRPC, a.grpcRecords = newGRPCServer(cfg, v2Controller, flagService, authenticator, limiter, authorizer)
+
+	// v1 mirrors to the v2 store when enable_v1_dual_write / enable_v1_shadow_read are on
+	v2Store := service.NewSQLiteRecordServiceWithDB(db, flagEvaluator, cfg.Environment)
+	v2Store.SetMetrics(recordMetrics)
+	v1Service := controller.NewInMemoryRecordService()
+	v1Handler := apiV1.NewAPI(controller.NewMigratingRecordService(&v1Service, v2Store, flagEvaluator))
+	v1Handler.CreateRoutes(v1Route)
+
+	// gradual v1 -> v2 rollout for unversioned /api/records/... requests
+	versionService, err := service.NewAPIVersionServiceWithDB(db)
+	if err != nil {
+		a.Close()
+		return nil, err
+	}
+	versionController := controller.NewAPIVersionControllerWithService(versionService)
+	apiV2.NewVersionAdminAPI(versionController, authorizer).CreateRoutes(v2Route)
+	apiV2.NewMetricsAdminAPI(controller.NewMetricsController(metricsRepo), authorizer).CreateRoutes(v2Route)
+	apiV2.NewAPIKeyAdminAPI(controller.NewAPIKeyControllerWithService(apiKeys), authorizer).CreateRoutes(v2Route)
+	apiV2.NewAssignmentAdminAPI(controller.NewAssignmentControllerWithService(authzService), authorizer).CreateRoutes(v2Route)
+	rateLimits := controller.NewRateLimitControllerWithService(service.NewRateLimitServiceWithDB(db), limiter)
+	apiV2.NewRateLimitAdminAPI(rateLimits, authorizer).CreateRoutes(v2Route)
+	a.onClose(startRateLimitReloads(cfg, rateLimits).Stop)
+
+	historyController := controller.NewHistoryController(service.NewHistoryServiceWithDB(db))
+	graphqlAPI, err := graphqlapi.NewAPI(historyController, flagService, authorizer, graphqlapi.Limits{
+		MaxCost:  cfg.GraphQL.MaxCost,
+		MaxDepth: cfg.GraphQL.MaxDepth,
+	})
+	if err != nil {
+		a.Close()
+		return nil, err
+	}
+	graphqlAPI.CreateRoutes(v2Route)
+
+	router.PathPrefix(unversionedPrefix + "/").Handler(versionRouting(routes, versionController, authenticator))
 
-	return router, nil
+	a.Router = router
+	return a, nil
 }
@@ -1,117 +1,39 @@
 package app_test
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/rainbowmga/timetravel/app"
+	"github.com/rainbowmga/timetravel/conf"
 	"github.com/rainbowmga/timetravel/gateways"
+	"github.com/rainbowmga/timetravel/observability"
+	"github.com/rainbowmga/timetravel/script"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// setupSharedInMemoryDB creates a shared in-memory DB and all required tables.
+// setupSharedInMemoryDB migrates a shared in-memory DB with the embedded migrations.
+// The connection stays open so the database outlives each test's handles.
 func setupSharedInMemoryDB(t *testing.T) string {
 	t.Helper()
 	dbPath := "file:testdb?mode=memory&cache=shared"
-	db := gateways.ConnectDB(dbPath, "") // no file
-	schema := `
-	PRAGMA foreign_keys = OFF;
-
---------------------------------------------------
--- FEATURE FLAGS
---------------------------------------------------
-CREATE TABLE IF NOT EXISTS feature_flags (
-    flag_key TEXT PRIMARY KEY,
-    enabled BOOLEAN NOT NULL,
-    description TEXT,
-    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-    rollout_percentage INTEGER DEFAULT 100
-);
-
-INSERT INTO feature_flags(flag_key, enabled, description, updated_at, rollout_percentage)
-VALUES
-('enable_v2_api', 1, 'Enable version 2 API', CURRENT_TIMESTAMP, 100),
-('enable_audit_logging', 1, 'Audit history logging', CURRENT_TIMESTAMP, 100),
-('enable_metrics', 1, 'Observability metrics', CURRENT_TIMESTAMP, 100)
-ON CONFLICT(flag_key) DO UPDATE SET
-    enabled = excluded.enabled,
-    description = excluded.description,
-    updated_at = CURRENT_TIMESTAMP,
-    rollout_percentage = excluded.rollout_percentage;
-
-CREATE TABLE IF NOT EXISTS policyholders (
-    policyholder_id INTEGER PRIMARY KEY AUTOINCREMENT,
-    name TEXT NOT NULL,
-    email TEXT NOT NULL,
-    country_code TEXT NOT NULL,
-    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-);
-
-
-CREATE TABLE IF NOT EXISTS policyholder_records (
-    record_id INTEGER PRIMARY KEY AUTOINCREMENT,
-    policyholder_id INTEGER NOT NULL,
-    data TEXT NOT NULL,
-    version INTEGER NOT NULL DEFAULT 1,
-    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY(policyholder_id) REFERENCES policyholders(policyholder_id) ON DELETE CASCADE
-);
-
-
-CREATE TABLE IF NOT EXISTS audit_history (
-    audit_id INTEGER PRIMARY KEY AUTOINCREMENT,
-    record_id INTEGER NOT NULL,
-    version INTEGER NOT NULL,
-    data TEXT NOT NULL,
-    event_type TEXT NOT NULL,
-    changed_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY(record_id) REFERENCES policyholder_records(record_id) ON DELETE CASCADE
-);
-CREATE TABLE IF NOT EXISTS event_logs (
-    event_id INTEGER PRIMARY KEY AUTOINCREMENT,
-    record_id INTEGER,
-    action TEXT NOT NULL,
-    details TEXT,
-    timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY(record_id) REFERENCES policyholder_records(record_id) ON DELETE CASCADE
-);
-
---------------------------------------------------
--- OBSERVABILITY METRICS
---------------------------------------------------
-CREATE TABLE IF NOT EXISTS observability_metrics (
-    metric_id INTEGER PRIMARY KEY AUTOINCREMENT,
-    metric_type TEXT NOT NULL,
-    metric_name TEXT NOT NULL,
-    value REAL,
-    region TEXT,
-    recorded_at DATETIME DEFAULT CURRENT_TIMESTAMP
-);
-
---------------------------------------------------
--- SCHEMA MIGRATION TRACKING
---------------------------------------------------
-CREATE TABLE IF NOT EXISTS schema_migrations (
-    version TEXT PRIMARY KEY,
-    applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
-);
-
-CREATE TABLE IF NOT EXISTS users(id INTEGER PRIMARY KEY);
-
-INSERT INTO schema_migrations(version)
-VALUES('001_create_users.sql')
-ON CONFLICT(version) DO NOTHING;
-
-
-PRAGMA foreign_keys = ON;
-`
-	if _, err := db.Exec(schema); err != nil {
-		t.Fatalf("failed to create schema: %v", err)
-	}
-	// leave db open if needed, or just return dbPath for BuildRouter
+	db := gateways.ConnectDB(dbPath)
+	m, err := gateways.NewMigrator(db, script.Migrations())
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
 	return dbPath
 }
 
@@ -138,10 +60,12 @@ func assertOkResponse(t *testing.T, rec *httptest.ResponseRecorder) {
 func TestBuildRouter_V1Health(t *testing.T) {
 	dbPath := setupSharedInMemoryDB(t)
 
-	router, err := app.BuildRouter(dbPath, false)
+	built, err := app.BuildRouter(dbPath, false)
 	if err != nil {
 		t.Fatalf("failed to build router: %v", err)
 	}
+	t.Cleanup(built.Close)
+	router := built.Router
 
 	req := httptest.NewRequest("POST", "/api/v1/health", nil)
 	rec := httptest.NewRecorder()
@@ -153,10 +77,12 @@ func TestBuildRouter_V1Health(t *testing.T) {
 func TestBuildRouter_V2Health(t *testing.T) {
 	dbPath := setupSharedInMemoryDB(t)
 
-	router, err := app.BuildRouter(dbPath, false)
+	built, err := app.BuildRouter(dbPath, false)
 	if err != nil {
 		t.Fatalf("failed to build router: %v", err)
 	}
+	t.Cleanup(built.Close)
+	router := built.Router
 
 	req := httptest.NewRequest("POST", "/api/v2/health", nil)
 	req.Header.Set("X-User-ID", "123")
@@ -169,10 +95,12 @@ func TestBuildRouter_V2Health(t *testing.T) {
 func TestBuildRouter_Metrics(t *testing.T) {
 	dbPath := setupSharedInMemoryDB(t)
 
-	router, err := app.BuildRouter(dbPath, false)
+	built, err := app.BuildRouter(dbPath, false)
 	if err != nil {
 		t.Fatalf("failed to build router: %v", err)
 	}
+	t.Cleanup(built.Close)
+	router := built.Router
 
 	req := httptest.NewRequest("GET", "/metrics", nil)
 	rec := httptest.NewRecorder()
@@ -186,10 +114,12 @@ func TestBuildRouter_Metrics(t *testing.T) {
 func TestBuildRouter_V2ProtectedRoute_NoUser(t *testing.T) {
 	dbPath := setupSharedInMemoryDB(t)
 
-	router, err := app.BuildRouter(dbPath, false)
+	built, err := app.BuildRouter(dbPath, false)
 	if err != nil {
 		t.Fatalf("failed to build router: %v", err)
 	}
+	t.Cleanup(built.Close)
+	router := built.Router
 
 	req := httptest.NewRequest("POST", "/api/v2/records", nil)
 	rec := httptest.NewRecorder()
@@ -203,10 +133,12 @@ func TestBuildRouter_V2ProtectedRoute_NoUser(t *testing.T) {
 func TestBuildRouter_V2ProtectedRoute_WithUser(t *testing.T) {
 	dbPath := setupSharedInMemoryDB(t)
 
-	router, err := app.BuildRouter(dbPath, false)
+	built, err := app.BuildRouter(dbPath, false)
 	if err != nil {
 		t.Fatalf("failed to build router: %v", err)
 	}
+	t.Cleanup(built.Close)
+	router := built.Router
 
 	req := httptest.NewRequest("POST", "/api/v2/records", nil)
 	req.Header.Set("X-User-ID", "123")
@@ -217,3 +149,183 @@ func TestBuildRouter_V2ProtectedRoute_WithUser(t *testing.T) {
 		t.Fatalf("expected 200 OK or 404 Not Found, got %d", rec.Code)
 	}
 }
+
+func TestBuildRouterWithConfig_FlagProviders(t *testing.T) {
+	dbPath := setupSharedInMemoryDB(t)
+
+	tests := []struct {
+		name     string
+		provider string
+		wantErr  bool
+	}{
+		{"default sqlite", "", false},
+		{"static", "static", false},
+		{"layered", "layered", false},
+		{"file without path", "file", true},
+		{"unknown", "bogus", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &conf.Config{}
+			cfg.Database.Path = dbPath
+			cfg.FeatureFlags.Provider = tt.provider
+
+			built, err := app.BuildRouterWithConfig(cfg)
+			if err == nil {
+				t.Cleanup(built.Close)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BuildRouterWithConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBuildRouter_UnversionedRecordsRouting(t *testing.T) {
+	dbPath := setupSharedInMemoryDB(t)
+
+	built, err := app.BuildRouter(dbPath, false)
+	if err != nil {
+		t.Fatalf("failed to build router: %v", err)
+	}
+	t.Cleanup(built.Close)
+	router := built.Router
+
+	// seeded rollout sends everyone to v1
+	req := httptest.NewRequest("GET", "/api/records/1", nil)
+	req.Header.Set("X-User-ID", "123")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if got := rec.Header().Get(app.APIVersionHeader); got != "v1" {
+		t.Fatalf("expected %s v1, got %q", app.APIVersionHeader, got)
+	}
+
+	// shift all traffic to v2 through the admin endpoint
+	shift := httptest.NewRequest("PUT", "/api/v2/admin/api-versions/v2", strings.NewReader(`{"is_active":true,"rollout_percentage":100}`))
+	shift.Header.Set("X-User-ID", "1")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, shift)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 from api-versions update, got %d", rec.Code)
+	}
+	defer func() {
+		restore := httptest.NewRequest("PUT", "/api/v2/admin/api-versions/v2", strings.NewReader(`{"is_active":true,"rollout_percentage":0}`))
+		restore.Header.Set("X-User-ID", "1")
+		router.ServeHTTP(httptest.NewRecorder(), restore)
+	}()
+
+	req = httptest.NewRequest("GET", "/api/records/1", nil)
+	req.Header.Set("X-User-ID", "123")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if got := rec.Header().Get(app.APIVersionHeader); got != "v2" {
+		t.Fatalf("expected %s v2, got %q", app.APIVersionHeader, got)
+	}
+	if rec.Code == http.StatusNotFound && strings.Contains(rec.Body.String(), "page not found") {
+		t.Fatalf("expected request to reach a v2 handler, got router 404")
+	}
+}
+
+// re-dispatch to the versioned chain must not run the root middleware twice:
+// one server span, and the versioned route's budget instead of the default
+func TestBuildRouter_UnversionedRecordsRunRootMiddlewareOnce(t *testing.T) {
+	exp := tracetest.NewInMemoryExporter()
+	orig := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp)))
+	t.Cleanup(func() { otel.SetTracerProvider(orig) })
+
+	cfg := &conf.Config{Environment: "development"}
+	cfg.Auth.Methods = []string{"header"}
+	cfg.Database.Path = filepath.Join(t.TempDir(), "routing.db")
+	cfg.Database.Migrations.RunOnStartup = true
+	cfg.Server.Timeouts.Default = time.Nanosecond
+	cfg.Server.Timeouts.Routes = map[string]time.Duration{
+		"PUT /api/v2/admin/api-versions/{version}": 0,
+		"POST /api/v2/records/{policyholder_id}":   0,
+	}
+	built, err := app.BuildRouterWithConfig(cfg)
+	if err != nil {
+		t.Fatalf("failed to build router: %v", err)
+	}
+	t.Cleanup(built.Close)
+	router := built.Router
+
+	shift := httptest.NewRequest("PUT", "/api/v2/admin/api-versions/v2", strings.NewReader(`{"is_active":true,"rollout_percentage":100}`))
+	shift.Header.Set("X-User-ID", "1")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, shift)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 from api-versions update, got %d: %s", rec.Code, rec.Body)
+	}
+	exp.Reset()
+
+	req := httptest.NewRequest("POST", "/api/records/5", strings.NewReader(`{"name":"ada"}`))
+	req.Header.Set("X-User-ID", "1")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 within the route budget, got %d: %s", rec.Code, rec.Body)
+	}
+
+	servers := 0
+	for _, span := range exp.GetSpans() {
+		if span.SpanKind == trace.SpanKindServer {
+			servers++
+		}
+	}
+	if servers != 1 {
+		t.Errorf("recorded %d server spans, want 1", servers)
+	}
+}
+
+func TestBuildApp_HealthEndpoints(t *testing.T) {
+	dbPath := setupSharedInMemoryDB(t)
+
+	cfg := &conf.Config{}
+	cfg.Database.Path = dbPath
+	a, err := app.BuildApp(cfg)
+	if err != nil {
+		t.Fatalf("failed to build app: %v", err)
+	}
+	defer a.Close()
+
+	get := func(path string) (int, observability.HealthReport) {
+		rec := httptest.NewRecorder()
+		a.Router.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		var report observability.HealthReport
+		if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+			t.Fatalf("invalid %s report: %v", path, err)
+		}
+		return rec.Code, report
+	}
+
+	if code, report := get("/readyz"); code != http.StatusServiceUnavailable || report.State != "starting" {
+		t.Errorf("expected 503 while starting, got %d %s", code, report.State)
+	}
+	if code, _ := get("/livez"); code != http.StatusOK {
+		t.Errorf("expected livez 200 while starting, got %d", code)
+	}
+
+	a.Health.MarkReady()
+	code, report := get("/readyz")
+	if code != http.StatusOK {
+		t.Fatalf("expected readyz 200, got %d: %+v", code, report)
+	}
+	for _, name := range []string{"sqlite", "migrations", "feature_flags", "metrics_writer"} {
+		if report.Checks[name].Status != "ok" {
+			t.Errorf("expected %s check ok, got %+v", name, report.Checks[name])
+		}
+	}
+
+	a.Health.MarkShuttingDown()
+	if code, _ := get("/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 while shutting down, got %d", code)
+	}
+
+	// stopped workers fail liveness
+	a.Close()
+	if code, report := get("/livez"); code != http.StatusServiceUnavailable || report.Checks["metrics_writer"].Status != "error" {
+		t.Errorf("expected livez 503 after workers stop, got %d %+v", code, report.Checks)
+	}
+}
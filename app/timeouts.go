@@ -0,0 +1,64 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rainbowmga/timetravel/conf"
+)
+
+// RequestTimeouts bounds every request's context by its route budget, so SQL
+// work stops once the budget is spent. Routes are keyed "METHOD /path/template"
+// (the server span name); unlisted routes get the default, and 0 means no limit.
+func RequestTimeouts(cfg *conf.Config) mux.MiddlewareFunc {
+	timeouts := cfg.Server.Timeouts
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if budget := routeTimeout(timeouts.Default, timeouts.Routes, r); budget > 0 {
+				ctx, cancel := context.WithTimeout(r.Context(), budget)
+				defer cancel()
+				r = r.WithContext(ctx)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// routeTimeout picks the per-route budget, falling back to the default
+func routeTimeout(def time.Duration, routes map[string]time.Duration, r *http.Request) time.Duration {
+	if current := mux.CurrentRoute(r); current != nil {
+		if tmpl, err := current.GetPathTemplate(); err == nil {
+			if budget, ok := routes[r.Method+" "+tmpl]; ok {
+				return budget
+			}
+		}
+	}
+	return def
+}
+
+// writeTimeoutHeadroom lets a request that spent its whole budget still send
+// its 504 before the server drops the connection
+const writeTimeoutHeadroom = 5 * time.Second
+
+// WriteTimeout is the http.Server WriteTimeout that never undercuts a route
+// budget: the largest budget plus headroom, or 0 (none) when some route is
+// unbounded
+func WriteTimeout(cfg *conf.Config) time.Duration {
+	timeouts := cfg.Server.Timeouts
+	longest := timeouts.Default
+	for _, budget := range timeouts.Routes {
+		if budget <= 0 {
+			return 0
+		}
+		if budget > longest {
+			longest = budget
+		}
+	}
+	if timeouts.Default <= 0 {
+		return 0
+	}
+	return longest + writeTimeoutHeadroom
+}
@@ -0,0 +1,86 @@
+package app_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rainbowmga/timetravel/app"
+	"github.com/rainbowmga/timetravel/conf"
+)
+
+func TestRequestTimeouts(t *testing.T) {
+	cfg := &conf.Config{}
+	cfg.Server.Timeouts.Default = 2 * time.Second
+	cfg.Server.Timeouts.Routes = map[string]time.Duration{
+		"GET /slow/{id}": time.Minute,
+		"GET /unbounded": 0,
+	}
+
+	var budget time.Duration
+	var bounded bool
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		var deadline time.Time
+		deadline, bounded = r.Context().Deadline()
+		budget = time.Until(deadline)
+	}
+
+	router := mux.NewRouter()
+	router.Use(app.RequestTimeouts(cfg))
+	router.HandleFunc("/fast", handler).Methods("GET")
+	router.HandleFunc("/slow/{id}", handler).Methods("GET", "POST")
+	router.HandleFunc("/unbounded", handler).Methods("GET")
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		wantBounded bool
+		wantMax     time.Duration
+	}{
+		{"default budget", "GET", "/fast", true, 2 * time.Second},
+		{"route override by template", "GET", "/slow/42", true, time.Minute},
+		{"override is per method", "POST", "/slow/42", true, 2 * time.Second},
+		{"zero disables the limit", "GET", "/unbounded", false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.path, nil))
+
+			if bounded != tt.wantBounded {
+				t.Fatalf("deadline set = %v, want %v", bounded, tt.wantBounded)
+			}
+			if bounded && (budget > tt.wantMax || budget < tt.wantMax-time.Second) {
+				t.Errorf("budget = %v, want about %v", budget, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestWriteTimeout(t *testing.T) {
+	tests := []struct {
+		name   string
+		def    time.Duration
+		routes map[string]time.Duration
+		want   time.Duration
+	}{
+		{"default only", 10 * time.Second, nil, 15 * time.Second},
+		{"longest route", 10 * time.Second, map[string]time.Duration{"GET /metrics": 30 * time.Second}, 35 * time.Second},
+		{"unbounded default", 0, map[string]time.Duration{"GET /metrics": 30 * time.Second}, 0},
+		{"unbounded route", 10 * time.Second, map[string]time.Duration{"GET /export": 0}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &conf.Config{}
+			cfg.Server.Timeouts.Default = tt.def
+			cfg.Server.Timeouts.Routes = tt.routes
+			if got := app.WriteTimeout(cfg); got != tt.want {
+				t.Errorf("WriteTimeout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+
+	"github.com/rainbowmga/timetravel/conf"
+)
+
+const defaultServiceName = "timetravel"
+
+// ConfigureTracing installs the global TracerProvider according to the tracing config.
+// The returned shutdown func flushes pending spans and is safe to call when tracing is off.
+func ConfigureTracing(cfg *conf.Config) (func(context.Context) error, error) {
+	noop := func(context.Context) error { return nil }
+	tracingCfg := cfg.Tracing
+
+	var exporter sdktrace.SpanExporter
+	switch tracingCfg.Exporter {
+	case "", "none":
+		return noop, nil
+	case "stdout":
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return noop, fmt.Errorf("failed to create stdout trace exporter: %w", err)
+		}
+		exporter = exp
+	default:
+		return noop, fmt.Errorf("unknown tracing.exporter %q", tracingCfg.Exporter)
+	}
+
+	if tracingCfg.SampleRatio < 0 || tracingCfg.SampleRatio > 1 {
+		return noop, fmt.Errorf("tracing.sample_ratio must be between 0 and 1, got %v", tracingCfg.SampleRatio)
+	}
+	sampler := sdktrace.AlwaysSample()
+	if tracingCfg.SampleRatio > 0 {
+		sampler = sdktrace.TraceIDRatioBased(tracingCfg.SampleRatio)
+	}
+
+	serviceName := tracingCfg.ServiceName
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sampler)),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
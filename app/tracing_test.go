@@ -0,0 +1,45 @@
+package app_test
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+
+	"github.com/rainbowmga/timetravel/app"
+	"github.com/rainbowmga/timetravel/conf"
+)
+
+func TestConfigureTracing(t *testing.T) {
+	orig := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(orig)
+
+	tests := []struct {
+		name     string
+		exporter string
+		ratio    float64
+		wantErr  bool
+	}{
+		{"disabled by default", "", 0, false},
+		{"none", "none", 0, false},
+		{"stdout sampled", "stdout", 0.5, false},
+		{"unknown exporter", "zipkin", 0, true},
+		{"bad ratio", "stdout", 2, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &conf.Config{}
+			cfg.Tracing.Exporter = tt.exporter
+			cfg.Tracing.SampleRatio = tt.ratio
+
+			shutdown, err := app.ConfigureTracing(cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ConfigureTracing() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err := shutdown(context.Background()); err != nil {
+				t.Errorf("shutdown failed: %v", err)
+			}
+		})
+	}
+}
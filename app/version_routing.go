@@ -0,0 +1,50 @@
+package app
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/rainbowmga/timetravel/auth"
+	"github.com/rainbowmga/timetravel/observability"
+)
+
+// APIVersionHeader reports which API version served a request
+const APIVersionHeader = "X-API-Version"
+
+// unversionedPrefix is the client-facing path that is routed to /api/v1 or /api/v2
+const unversionedPrefix = "/api/records"
+
+// versionResolver picks the API version for a caller
+type versionResolver interface {
+	Resolve(userID int64, hasUser bool) string
+}
+
+// versionRouting rewrites /api/records/... to /api/{version}/records/... and
+// dispatches to routes, the chain below tracing and capture, so the versioned
+// middleware runs while the root middleware, which already saw the request, does not.
+// Callers are identified first so rollouts bucket them by their authenticated
+// id; unauthenticated callers get the fallback version, and the v2 chain
+// answers their failure.
+func versionRouting(routes *mux.Router, resolver versionResolver, authenticator auth.Authenticator) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		var userID int64
+		hasUser := false
+		if p, err := authenticator.Authenticate(ctx, auth.CredentialsFromRequest(r)); err == nil {
+			ctx = auth.WithPrincipal(ctx, p)
+			userID, hasUser = p.UserID, true
+		}
+
+		version := resolver.Resolve(userID, hasUser)
+
+		routed := r.Clone(ctx)
+		routed.URL.Path = "/api/" + version + "/records" + strings.TrimPrefix(r.URL.Path, unversionedPrefix)
+		routed.URL.RawPath = ""
+		routed.RequestURI = routed.URL.RequestURI()
+
+		observability.DefaultLogger.DebugContext(r.Context(), "api_version_routed", "path", r.URL.Path, "version", version)
+		w.Header().Set(APIVersionHeader, version)
+		routes.ServeHTTP(w, routed)
+	})
+}
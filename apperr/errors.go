@@ -0,0 +1,113 @@
+// Package apperr is the shared error model. Every layer reports failures as one
+// of a small set of kinds; handlers map kinds to status codes in one place
+// (HTTPStatus) instead of comparing individual sentinels.
+package apperr
+
+import (
+	"errors"
+	"time"
+)
+
+// Kinds. Match them with errors.Is; the typed errors below unwrap to their kind.
+var (
+	ErrInternal           = errors.New("internal error")
+	ErrNotFound           = errors.New("not found")
+	ErrInvalidArgument    = errors.New("invalid argument")
+	ErrConflict           = errors.New("conflict")
+	ErrPreconditionFailed = errors.New("precondition failed")
+	ErrUnauthenticated    = errors.New("unauthenticated")
+	ErrPermissionDenied   = errors.New("permission denied")
+	ErrResourceExhausted  = errors.New("resource exhausted")
+	ErrUnavailable        = errors.New("unavailable")
+	ErrCanceled           = errors.New("canceled")
+	ErrTimeout            = errors.New("timeout")
+)
+
+// Error is a typed error: Kind classifies it (a kind above, or another *Error
+// used as a domain sentinel), Message is safe to show to clients and Err is the
+// underlying cause, which is logged but never returned in a response.
+type Error struct {
+	Kind    error
+	Message string
+	Err     error
+	// RetryAfter, when set, tells clients how long to back off (Unavailable,
+	// ResourceExhausted)
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() []error {
+	if e.Err != nil {
+		return []error{e.Kind, e.Err}
+	}
+	return []error{e.Kind}
+}
+
+// New declares a sentinel of the given kind, e.g.
+// var ErrRecordDoesNotExist = apperr.New(apperr.ErrNotFound, "record does not exist")
+func New(kind error, message string) *Error {
+	return &Error{Kind: kind, Message: message}
+}
+
+// Wrap attaches a cause to kind, keeping kind's client message.
+// errors.Is matches kind (and its own kind), and the cause.
+func Wrap(kind, cause error) *Error {
+	return &Error{Kind: kind, Message: Message(kind), Err: cause}
+}
+
+// NotFound, InvalidArgument, Conflict and PreconditionFailed build ad-hoc errors of their kind
+func NotFound(message string) *Error           { return New(ErrNotFound, message) }
+func InvalidArgument(message string) *Error    { return New(ErrInvalidArgument, message) }
+func Conflict(message string) *Error           { return New(ErrConflict, message) }
+func PreconditionFailed(message string) *Error { return New(ErrPreconditionFailed, message) }
+
+// Unavailable reports a dependency that may recover; clients should retry after retryAfter
+func Unavailable(message string, retryAfter time.Duration, cause error) *Error {
+	return &Error{Kind: ErrUnavailable, Message: message, Err: cause, RetryAfter: retryAfter}
+}
+
+// ResourceExhausted reports a caller over its quota; it may retry after retryAfter
+func ResourceExhausted(message string, retryAfter time.Duration) *Error {
+	return &Error{Kind: ErrResourceExhausted, Message: message, RetryAfter: retryAfter}
+}
+
+// Message returns the client-safe text of err: the outermost *Error's message,
+// or the generic internal error text for untyped errors (e.g. raw driver errors)
+func Message(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Message
+	}
+	for _, kind := range kinds {
+		if err == kind {
+			return kind.Error()
+		}
+	}
+	return ErrInternal.Error()
+}
+
+// RetryAfter returns the back-off hint carried anywhere in err's chain
+func RetryAfter(err error) (time.Duration, bool) {
+	var e *Error
+	for errors.As(err, &e) {
+		if e.RetryAfter > 0 {
+			return e.RetryAfter, true
+		}
+		if e.Err == nil {
+			break
+		}
+		err = e.Err
+	}
+	return 0, false
+}
+
+var kinds = []error{
+	ErrInternal, ErrNotFound, ErrInvalidArgument, ErrConflict, ErrPreconditionFailed,
+	ErrUnauthenticated, ErrPermissionDenied, ErrResourceExhausted, ErrUnavailable, ErrCanceled, ErrTimeout,
+}
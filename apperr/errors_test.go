@@ -0,0 +1,153 @@
+package apperr_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/common"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+)
+
+var errRecordMissing = apperr.NotFound("record does not exist")
+
+func TestHTTPStatusAndMessage(t *testing.T) {
+	cause := errors.New("sqlite: disk I/O error")
+
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantCode    string
+		wantMessage string
+	}{
+		{"domain sentinel", errRecordMissing, http.StatusNotFound, "not_found", "record does not exist"},
+		{"wrapped sentinel", apperr.Wrap(errRecordMissing, cause), http.StatusNotFound, "not_found", "record does not exist"},
+		{"invalid argument", apperr.InvalidArgument("bad id"), http.StatusBadRequest, "invalid_argument", "bad id"},
+		{"conflict", apperr.Conflict("exists"), http.StatusConflict, "conflict", "exists"},
+		{"precondition failed", apperr.PreconditionFailed("stale"), http.StatusPreconditionFailed, "precondition_failed", "stale"},
+		{"unauthenticated", apperr.New(apperr.ErrUnauthenticated, "who"), http.StatusUnauthorized, "unauthenticated", "who"},
+		{"permission denied", apperr.New(apperr.ErrPermissionDenied, "no"), http.StatusForbidden, "permission_denied", "no"},
+		{"unavailable", apperr.Unavailable("busy", time.Second, cause), http.StatusServiceUnavailable, "unavailable", "busy"},
+		{"canceled", apperr.Wrap(apperr.ErrCanceled, context.Canceled), apperr.StatusClientClosedRequest, "canceled", "canceled"},
+		{"timeout", apperr.Wrap(apperr.ErrTimeout, context.DeadlineExceeded), http.StatusGatewayTimeout, "timeout", "timeout"},
+		{"resource exhausted", apperr.ResourceExhausted("slow down", time.Second), http.StatusTooManyRequests, "resource_exhausted", "slow down"},
+		{"fmt-wrapped typed error", errors.Join(errors.New("context"), errRecordMissing), http.StatusNotFound, "not_found", "record does not exist"},
+		{"untyped error is internal and hidden", cause, http.StatusInternalServerError, "internal", "internal error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := apperr.HTTPStatus(tt.err); got != tt.wantStatus {
+				t.Errorf("HTTPStatus() = %d, want %d", got, tt.wantStatus)
+			}
+			if got := apperr.Code(tt.err); got != tt.wantCode {
+				t.Errorf("Code() = %q, want %q", got, tt.wantCode)
+			}
+			if got := apperr.Message(tt.err); got != tt.wantMessage {
+				t.Errorf("Message() = %q, want %q", got, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestWrapMatchesKindSentinelAndCause(t *testing.T) {
+	cause := errors.New("driver")
+	err := apperr.Wrap(errRecordMissing, cause)
+
+	for _, target := range []error{errRecordMissing, apperr.ErrNotFound, cause} {
+		if !errors.Is(err, target) {
+			t.Errorf("errors.Is(%v, %v) = false", err, target)
+		}
+	}
+	if err.Error() != "record does not exist: driver" {
+		t.Errorf("Error() = %q; logs should keep the cause", err.Error())
+	}
+}
+
+func TestWriteProblem(t *testing.T) {
+	req := httptest.NewRequest("POST", "/api/v2/records/1", nil)
+	req = req.WithContext(context.WithValue(req.Context(), common.RequestIDKey, "req-1"))
+	rec := httptest.NewRecorder()
+
+	apperr.WriteProblem(rec, req, apperr.Unavailable("busy", 1500*time.Millisecond, errors.New("database is locked")))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Type"); got != apperr.ProblemContentType {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After = %q, want 2", got)
+	}
+
+	var p apperr.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+		t.Fatal(err)
+	}
+	want := apperr.Problem{
+		Type:      "about:blank",
+		Title:     "Service Unavailable",
+		Status:    http.StatusServiceUnavailable,
+		Detail:    "busy",
+		Instance:  "/api/v2/records/1",
+		Code:      "unavailable",
+		RequestID: "req-1",
+	}
+	if p != want {
+		t.Errorf("problem = %+v, want %+v", p, want)
+	}
+}
+
+func TestGRPCStatus(t *testing.T) {
+	cause := errors.New("sqlite: disk I/O error")
+
+	tests := []struct {
+		name        string
+		err         error
+		wantCode    codes.Code
+		wantMessage string
+	}{
+		{"not found", errRecordMissing, codes.NotFound, "record does not exist"},
+		{"invalid argument", apperr.InvalidArgument("bad id"), codes.InvalidArgument, "bad id"},
+		{"conflict", apperr.Conflict("exists"), codes.AlreadyExists, "exists"},
+		{"precondition failed", apperr.PreconditionFailed("stale"), codes.FailedPrecondition, "stale"},
+		{"unauthenticated", apperr.New(apperr.ErrUnauthenticated, "who"), codes.Unauthenticated, "who"},
+		{"permission denied", apperr.New(apperr.ErrPermissionDenied, "no"), codes.PermissionDenied, "no"},
+		{"unavailable", apperr.Unavailable("busy", 0, cause), codes.Unavailable, "busy"},
+		{"canceled", apperr.Wrap(apperr.ErrCanceled, context.Canceled), codes.Canceled, "canceled"},
+		{"timeout", apperr.Wrap(apperr.ErrTimeout, context.DeadlineExceeded), codes.DeadlineExceeded, "timeout"},
+		{"resource exhausted", apperr.ResourceExhausted("slow down", time.Second), codes.ResourceExhausted, "slow down"},
+		{"untyped error is internal and hidden", cause, codes.Internal, "internal error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := apperr.GRPCStatus(tt.err)
+			if st.Code() != tt.wantCode || st.Message() != tt.wantMessage {
+				t.Errorf("GRPCStatus() = %v %q, want %v %q", st.Code(), st.Message(), tt.wantCode, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestGRPCStatusCarriesRetryInfo(t *testing.T) {
+	st := apperr.GRPCStatus(apperr.Unavailable("busy", 1500*time.Millisecond, errors.New("database is locked")))
+
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.RetryInfo); ok {
+			if got := info.GetRetryDelay().AsDuration(); got != 1500*time.Millisecond {
+				t.Errorf("retry delay = %v", got)
+			}
+			return
+		}
+	}
+	t.Errorf("no RetryInfo detail in %v", st.Details())
+}
@@ -0,0 +1,53 @@
+package apperr
+
+import (
+	"errors"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// grpcCodes mirrors statuses for the gRPC API, in the same order
+var grpcCodes = []struct {
+	kind error
+	code codes.Code
+}{
+	{ErrCanceled, codes.Canceled},
+	{ErrTimeout, codes.DeadlineExceeded},
+	{ErrUnavailable, codes.Unavailable},
+	{ErrNotFound, codes.NotFound},
+	{ErrInvalidArgument, codes.InvalidArgument},
+	{ErrConflict, codes.AlreadyExists},
+	{ErrPreconditionFailed, codes.FailedPrecondition},
+	{ErrUnauthenticated, codes.Unauthenticated},
+	{ErrPermissionDenied, codes.PermissionDenied},
+	{ErrResourceExhausted, codes.ResourceExhausted},
+}
+
+// GRPCCode maps an error's kind to a gRPC code; untyped errors are Internal
+func GRPCCode(err error) codes.Code {
+	for _, c := range grpcCodes {
+		if errors.Is(err, c.kind) {
+			return c.code
+		}
+	}
+	return codes.Internal
+}
+
+// GRPCStatus describes err as a gRPC status with the client-safe message. An
+// unavailable or exhausted error with a retry hint carries it as a RetryInfo detail, the
+// gRPC counterpart of Retry-After.
+func GRPCStatus(err error) *status.Status {
+	if err == nil {
+		return nil
+	}
+	st := status.New(GRPCCode(err), Message(err))
+	if after, ok := RetryAfter(err); ok {
+		if detailed, derr := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(after)}); derr == nil {
+			st = detailed
+		}
+	}
+	return st
+}
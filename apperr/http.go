@@ -0,0 +1,109 @@
+package apperr
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rainbowmga/timetravel/common"
+)
+
+// StatusClientClosedRequest is the nginx convention for a client that hung up
+// before the response was written; net/http has no constant for it
+const StatusClientClosedRequest = 499
+
+// ProblemContentType is the RFC 7807 media type used by v2 error responses
+const ProblemContentType = "application/problem+json"
+
+// statuses is checked in order, so the more specific outcome (the caller went
+// away) wins when an error carries several kinds
+var statuses = []struct {
+	kind   error
+	status int
+}{
+	{ErrCanceled, StatusClientClosedRequest},
+	{ErrTimeout, http.StatusGatewayTimeout},
+	{ErrUnavailable, http.StatusServiceUnavailable},
+	{ErrNotFound, http.StatusNotFound},
+	{ErrInvalidArgument, http.StatusBadRequest},
+	{ErrConflict, http.StatusConflict},
+	{ErrPreconditionFailed, http.StatusPreconditionFailed},
+	{ErrUnauthenticated, http.StatusUnauthorized},
+	{ErrPermissionDenied, http.StatusForbidden},
+	{ErrResourceExhausted, http.StatusTooManyRequests},
+}
+
+// HTTPStatus maps an error's kind to a status code; untyped errors are 500
+func HTTPStatus(err error) int {
+	for _, s := range statuses {
+		if errors.Is(err, s.kind) {
+			return s.status
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// Code is the snake_case kind name exposed to clients, e.g. "not_found"
+func Code(err error) string {
+	for _, kind := range kinds {
+		if kind != ErrInternal && errors.Is(err, kind) {
+			return strings.ReplaceAll(kind.Error(), " ", "_")
+		}
+	}
+	return "internal"
+}
+
+// Problem is an RFC 7807 problem details body. Code and RequestID are
+// extension members so clients can branch on the kind and quote the request.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// NewProblem describes err for the request r. The detail is the client-safe
+// message only; causes stay out of the response.
+func NewProblem(r *http.Request, err error) Problem {
+	status := HTTPStatus(err)
+	title := http.StatusText(status)
+	if status == StatusClientClosedRequest {
+		title = "Client Closed Request"
+	}
+	p := Problem{
+		// no per-kind documentation pages exist, so the type is about:blank
+		// and title is the status text, as RFC 7807 section 4.2 prescribes
+		Type:     "about:blank",
+		Title:    title,
+		Status:   status,
+		Detail:   Message(err),
+		Instance: r.URL.Path,
+		Code:     Code(err),
+	}
+	if id, ok := common.GetRequestID(r.Context()); ok {
+		p.RequestID = id
+	}
+	return p
+}
+
+// WriteProblem answers with an application/problem+json body for err, adding
+// Retry-After (whole seconds, at least 1) when err carries a back-off hint
+func WriteProblem(w http.ResponseWriter, r *http.Request, err error) {
+	p := NewProblem(r, err)
+	if after, ok := RetryAfter(err); ok {
+		seconds := int(math.Ceil(after.Seconds()))
+		if seconds < 1 {
+			seconds = 1
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	}
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
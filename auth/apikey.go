@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/entity"
+	"github.com/rainbowmga/timetravel/observability"
+)
+
+// API keys look like tt_<prefix>_<secret>: the prefix finds the stored key and
+// is safe to show, the secret is 256 random bits. Only the SHA-256 of the
+// whole key is stored; a slow hash buys nothing for secrets this long.
+const apiKeyScheme = "tt"
+
+var (
+	errInvalidAPIKey = apperr.New(apperr.ErrUnauthenticated, "invalid API key")
+	errAPIKeyRevoked = apperr.New(apperr.ErrUnauthenticated, "API key has been revoked")
+	errAPIKeyExpired = apperr.New(apperr.ErrUnauthenticated, "API key has expired")
+)
+
+// touchInterval bounds how often a key's last_used_at is written
+const touchInterval = time.Minute
+
+// NewAPIKey generates a key; store the prefix and hash, hand out the key once
+func NewAPIKey() (key, prefix, hash string, err error) {
+	b := make([]byte, 6+32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", "", err
+	}
+	prefix = hex.EncodeToString(b[:6])
+	key = apiKeyScheme + "_" + prefix + "_" + base64.RawURLEncoding.EncodeToString(b[6:])
+	return key, prefix, HashAPIKey(key), nil
+}
+
+// HashAPIKey is the stored form of a key
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// apiKeyPrefix returns the lookup prefix of a well-formed key
+func apiKeyPrefix(key string) (string, bool) {
+	parts := strings.SplitN(key, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyScheme || len(parts[1]) != 12 || parts[2] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// APIKeyStore finds stored keys (service.APIKeyService)
+type APIKeyStore interface {
+	// LookupAPIKey returns the key with prefix, or an apperr.ErrNotFound error
+	LookupAPIKey(ctx context.Context, prefix string) (entity.APIKey, error)
+	// TouchAPIKey records when a key was last used
+	TouchAPIKey(ctx context.Context, id int64, at time.Time) error
+}
+
+// APIKeyAuthenticator checks X-API-Key against the stored hashes
+type APIKeyAuthenticator struct {
+	store    APIKeyStore
+	now      func() time.Time
+	mu       sync.Mutex
+	lastSeen map[int64]time.Time // last_used_at writes, to throttle them
+}
+
+// NewAPIKeyAuthenticator looks keys up in store
+func NewAPIKeyAuthenticator(store APIKeyStore) *APIKeyAuthenticator {
+	return &APIKeyAuthenticator{store: store, now: time.Now, lastSeen: map[int64]time.Time{}}
+}
+
+func (a *APIKeyAuthenticator) Authenticate(ctx context.Context, c Credentials) (Principal, error) {
+	if c.APIKey == "" {
+		return Principal{}, ErrNoCredentials
+	}
+	prefix, ok := apiKeyPrefix(c.APIKey)
+	if !ok {
+		return Principal{}, errInvalidAPIKey
+	}
+	key, err := a.store.LookupAPIKey(ctx, prefix)
+	if errors.Is(err, apperr.ErrNotFound) {
+		return Principal{}, errInvalidAPIKey
+	}
+	if err != nil {
+		return Principal{}, err
+	}
+	if subtle.ConstantTimeCompare([]byte(HashAPIKey(c.APIKey)), []byte(key.Hash)) != 1 {
+		return Principal{}, errInvalidAPIKey
+	}
+
+	now := a.now().UTC()
+	if key.RevokedAt != nil && !key.RevokedAt.After(now) {
+		return Principal{}, errAPIKeyRevoked
+	}
+	if key.ExpiresAt != nil && !key.ExpiresAt.After(now) {
+		return Principal{}, errAPIKeyExpired
+	}
+	a.touch(ctx, key.ID, now)
+
+	return Principal{
+		UserID:       key.UserID,
+		Tenant:       key.Tenant,
+		Roles:        key.Roles,
+		Method:       MethodAPIKey,
+		CredentialID: key.Prefix,
+	}, nil
+}
+
+func (*APIKeyAuthenticator) Scheme() string { return "X-API-Key header" }
+
+// touch writes last_used_at at most once per touchInterval per key
+func (a *APIKeyAuthenticator) touch(ctx context.Context, id int64, now time.Time) {
+	a.mu.Lock()
+	if now.Sub(a.lastSeen[id]) < touchInterval {
+		a.mu.Unlock()
+		return
+	}
+	a.lastSeen[id] = now
+	a.mu.Unlock()
+
+	if err := a.store.TouchAPIKey(ctx, id, now); err != nil {
+		observability.DefaultLogger.WarnContext(ctx, "api_key_touch_failed", "api_key_id", id, "error", err)
+	}
+}
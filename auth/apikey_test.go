@@ -0,0 +1,121 @@
+package auth_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/auth"
+	"github.com/rainbowmga/timetravel/entity"
+)
+
+// memKeys is an in-memory auth.APIKeyStore
+type memKeys struct {
+	keys    map[string]entity.APIKey
+	touches int
+}
+
+func (m *memKeys) LookupAPIKey(_ context.Context, prefix string) (entity.APIKey, error) {
+	k, ok := m.keys[prefix]
+	if !ok {
+		return entity.APIKey{}, apperr.New(apperr.ErrNotFound, "api key does not exist")
+	}
+	return k, nil
+}
+
+func (m *memKeys) TouchAPIKey(context.Context, int64, time.Time) error {
+	m.touches++
+	return nil
+}
+
+// issue stores a new key, adjusted by edit, and returns its secret
+func (m *memKeys) issue(t *testing.T, edit func(*entity.APIKey)) string {
+	t.Helper()
+	secret, prefix, hash, err := auth.NewAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := entity.APIKey{ID: int64(len(m.keys) + 1), Prefix: prefix, Hash: hash, UserID: 7, Tenant: "acme", Roles: []string{"writer"}}
+	if edit != nil {
+		edit(&k)
+	}
+	m.keys[prefix] = k
+	return secret
+}
+
+func TestNewAPIKey(t *testing.T) {
+	key, prefix, hash, err := auth.NewAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(key, "tt_"+prefix+"_") || len(prefix) != 12 {
+		t.Errorf("key %q does not carry prefix %q", key, prefix)
+	}
+	if hash != auth.HashAPIKey(key) || strings.Contains(hash, key) {
+		t.Errorf("hash = %q", hash)
+	}
+	if other, _, _, _ := auth.NewAPIKey(); other == key {
+		t.Error("two keys are equal")
+	}
+}
+
+func TestAPIKeyAuthenticator(t *testing.T) {
+	store := &memKeys{keys: map[string]entity.APIKey{}}
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	valid := store.issue(t, nil)
+	expiring := store.issue(t, func(k *entity.APIKey) { k.ExpiresAt = &future })
+	expired := store.issue(t, func(k *entity.APIKey) { k.ExpiresAt = &past })
+	revoked := store.issue(t, func(k *entity.APIKey) { k.RevokedAt = &past })
+	_, unknownPrefix, _, _ := auth.NewAPIKey()
+
+	tests := []struct {
+		name    string
+		key     string
+		wantErr string
+	}{
+		{"valid", valid, ""},
+		{"expires later", expiring, ""},
+		{"expired", expired, "API key has expired"},
+		{"revoked", revoked, "API key has been revoked"},
+		{"wrong secret", valid[:len(valid)-4] + "AAAA", "invalid API key"},
+		{"unknown prefix", "tt_" + unknownPrefix + "_secret", "invalid API key"},
+		{"malformed", "not-a-key", "invalid API key"},
+	}
+	a := auth.NewAPIKeyAuthenticator(store)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := a.Authenticate(context.Background(), auth.Credentials{APIKey: tt.key})
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr || apperr.HTTPStatus(err) != http.StatusUnauthorized {
+					t.Errorf("error = %v, want 401 %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.UserID != 7 || p.Tenant != "acme" || !p.HasRole("writer") || p.Method != auth.MethodAPIKey || !strings.Contains(tt.key, p.CredentialID) {
+				t.Errorf("principal = %+v", p)
+			}
+		})
+	}
+}
+
+func TestAPIKeyAuthenticator_ThrottlesLastUsed(t *testing.T) {
+	store := &memKeys{keys: map[string]entity.APIKey{}}
+	key := store.issue(t, nil)
+	a := auth.NewAPIKeyAuthenticator(store)
+	for i := 0; i < 3; i++ {
+		if _, err := a.Authenticate(context.Background(), auth.Credentials{APIKey: key}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if store.touches != 1 {
+		t.Errorf("last_used_at written %d times, want once a minute", store.touches)
+	}
+}
@@ -0,0 +1,111 @@
+// Package auth identifies callers. Authenticators turn the credentials a
+// request carries (an API key, a signed JWT, or, in development only, a bare
+// X-User-ID) into a Principal, which the HTTP middleware and the gRPC
+// interceptors store in the request context.
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/common"
+)
+
+// Authentication methods, as named in auth.methods and Principal.Method
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+	MethodHeader = "header"
+)
+
+// Principal is the authenticated caller
+type Principal struct {
+	UserID int64    `json:"user_id"`
+	Tenant string   `json:"tenant,omitempty"`
+	Roles  []string `json:"roles,omitempty"`
+	// Method is how the caller authenticated: api_key, jwt or header
+	Method string `json:"method"`
+	// CredentialID names the credential used: the API key prefix or the
+	// token's kid, for logs and audits
+	CredentialID string `json:"credential_id,omitempty"`
+}
+
+// HasRole reports whether the principal holds role
+func (p Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// WithPrincipal stores p in ctx, along with its user id under
+// common.UserIDKey for flag rollouts and logs
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	ctx = context.WithValue(ctx, principalKey{}, p)
+	return context.WithValue(ctx, common.UserIDKey, p.UserID)
+}
+
+// FromContext returns the principal stored by WithPrincipal
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// Credentials are what a request presents, whatever the transport
+type Credentials struct {
+	APIKey string // X-API-Key header / x-api-key metadata
+	Bearer string // token from "Authorization: Bearer <token>"
+	UserID string // X-User-ID header / x-user-id metadata, trusted only in dev mode
+}
+
+// BearerToken extracts the token from an Authorization value
+func BearerToken(authorization string) string {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(authorization), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// ErrNoCredentials is returned by an Authenticator when the request carries
+// none of the credentials it reads, so the next one in a Chain gets a turn
+var ErrNoCredentials = errors.New("no credentials")
+
+// Authenticator checks one kind of credential
+type Authenticator interface {
+	// Authenticate returns ErrNoCredentials when c holds none of its kind and
+	// an apperr error when the credential is present but not valid
+	Authenticate(ctx context.Context, c Credentials) (Principal, error)
+	// Scheme names the credential for error messages, e.g. "X-API-Key header"
+	Scheme() string
+}
+
+// Chain tries authenticators in order; the first that finds its credential
+// decides, so a bad API key is not rescued by a later X-User-ID
+type Chain []Authenticator
+
+func (c Chain) Authenticate(ctx context.Context, creds Credentials) (Principal, error) {
+	for _, a := range c {
+		p, err := a.Authenticate(ctx, creds)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		return p, err
+	}
+	return Principal{}, apperr.New(apperr.ErrUnauthenticated, "missing "+c.Scheme())
+}
+
+// Scheme lists the accepted credentials, e.g. "X-API-Key header or Authorization bearer token"
+func (c Chain) Scheme() string {
+	schemes := make([]string, len(c))
+	for i, a := range c {
+		schemes[i] = a.Scheme()
+	}
+	return strings.Join(schemes, " or ")
+}
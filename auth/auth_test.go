@@ -0,0 +1,132 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/auth"
+	"github.com/rainbowmga/timetravel/common"
+)
+
+// stubAuth accepts one API key and reports ErrNoCredentials without one
+type stubAuth struct{}
+
+func (stubAuth) Authenticate(_ context.Context, c auth.Credentials) (auth.Principal, error) {
+	switch c.APIKey {
+	case "":
+		return auth.Principal{}, auth.ErrNoCredentials
+	case "good":
+		return auth.Principal{UserID: 9, Roles: []string{"reader"}, Method: auth.MethodAPIKey}, nil
+	}
+	return auth.Principal{}, apperr.New(apperr.ErrUnauthenticated, "invalid API key")
+}
+
+func (stubAuth) Scheme() string { return "X-API-Key header" }
+
+func TestChain(t *testing.T) {
+	chain := auth.Chain{stubAuth{}, auth.HeaderAuthenticator{Roles: []string{"admin"}}}
+	tests := []struct {
+		name       string
+		creds      auth.Credentials
+		wantUser   int64
+		wantStatus int // 0 when authentication succeeds
+		wantErr    string
+	}{
+		{"api key", auth.Credentials{APIKey: "good"}, 9, 0, ""},
+		{"header", auth.Credentials{UserID: "42"}, 42, 0, ""},
+		{"first credential decides", auth.Credentials{APIKey: "bad", UserID: "42"}, 0, http.StatusUnauthorized, "invalid API key"},
+		{"none", auth.Credentials{}, 0, http.StatusUnauthorized, "missing X-API-Key header or X-User-ID header"},
+		{"invalid user id", auth.Credentials{UserID: "abc"}, 0, http.StatusBadRequest, "invalid X-User-ID header"},
+		{"non-positive user id", auth.Credentials{UserID: "0"}, 0, http.StatusBadRequest, "invalid X-User-ID header"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := chain.Authenticate(context.Background(), tt.creds)
+			if tt.wantStatus == 0 {
+				if err != nil || p.UserID != tt.wantUser {
+					t.Fatalf("Authenticate() = %+v, %v; want user %d", p, err, tt.wantUser)
+				}
+				return
+			}
+			if got := apperr.HTTPStatus(err); got != tt.wantStatus || err.Error() != tt.wantErr {
+				t.Errorf("Authenticate() error = %v (%d), want %q (%d)", err, got, tt.wantErr, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestHeaderAuthenticator_GrantsRoles(t *testing.T) {
+	p, err := auth.HeaderAuthenticator{Roles: []string{"admin"}}.Authenticate(context.Background(), auth.Credentials{UserID: "3"})
+	if err != nil || !p.HasRole("admin") || p.Method != auth.MethodHeader {
+		t.Errorf("Authenticate() = %+v, %v", p, err)
+	}
+	if _, err := (auth.HeaderAuthenticator{}).Authenticate(context.Background(), auth.Credentials{}); !errors.Is(err, auth.ErrNoCredentials) {
+		t.Errorf("no header: error = %v, want ErrNoCredentials", err)
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	tests := map[string]string{
+		"Bearer abc.def.ghi": "abc.def.ghi",
+		"bearer  abc ":       "abc",
+		"Basic dXNlcjpwYXNz": "",
+		"Bearer":             "",
+		"":                   "",
+	}
+	for in, want := range tests {
+		if got := auth.BearerToken(in); got != want {
+			t.Errorf("BearerToken(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var seen auth.Principal
+	handler := auth.Middleware(auth.Chain{stubAuth{}, auth.HeaderAuthenticator{}})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = auth.FromContext(r.Context())
+		if id, _ := common.GetUserID(r.Context()); id != seen.UserID {
+			t.Errorf("common user id = %d, want %d", id, seen.UserID)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name       string
+		header     map[string]string
+		wantStatus int
+		wantUser   int64
+	}{
+		{"missing", nil, http.StatusUnauthorized, 0},
+		{"invalid user id", map[string]string{"X-User-ID": "abc"}, http.StatusBadRequest, 0},
+		{"user id", map[string]string{"X-User-ID": "42"}, http.StatusOK, 42},
+		{"api key", map[string]string{"X-API-Key": "good"}, http.StatusOK, 9},
+		{"bad api key", map[string]string{"X-API-Key": "bad", "X-User-ID": "42"}, http.StatusUnauthorized, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen = auth.Principal{}
+			req := httptest.NewRequest("GET", "/", nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus || seen.UserID != tt.wantUser {
+				t.Fatalf("status = %d, user = %d; want %d, %d", rr.Code, seen.UserID, tt.wantStatus, tt.wantUser)
+			}
+			if rr.Code != http.StatusOK {
+				if ct := rr.Header().Get("Content-Type"); ct != "application/problem+json" {
+					t.Errorf("Content-Type = %q, want problem+json", ct)
+				}
+			}
+			if got := rr.Header().Get("WWW-Authenticate"); (rr.Code == http.StatusUnauthorized) != (got != "") {
+				t.Errorf("WWW-Authenticate = %q with status %d", got, rr.Code)
+			}
+		})
+	}
+}
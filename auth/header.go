@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/rainbowmga/timetravel/apperr"
+)
+
+var errInvalidUserID = apperr.InvalidArgument("invalid X-User-ID header")
+
+// HeaderAuthenticator trusts the caller's X-User-ID. Anyone can claim any id,
+// so it is only for development and tests (auth.methods: [header]).
+type HeaderAuthenticator struct {
+	// Roles are granted to every caller
+	Roles []string
+}
+
+func (a HeaderAuthenticator) Authenticate(_ context.Context, c Credentials) (Principal, error) {
+	if c.UserID == "" {
+		return Principal{}, ErrNoCredentials
+	}
+	userID, err := strconv.ParseInt(c.UserID, 10, 64)
+	if err != nil || userID <= 0 {
+		return Principal{}, errInvalidUserID
+	}
+	return Principal{UserID: userID, Roles: a.Roles, Method: MethodHeader}, nil
+}
+
+func (HeaderAuthenticator) Scheme() string { return "X-User-ID header" }
@@ -0,0 +1,238 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rainbowmga/timetravel/apperr"
+)
+
+var (
+	errInvalidToken = apperr.New(apperr.ErrUnauthenticated, "invalid bearer token")
+	errTokenExpired = apperr.New(apperr.ErrUnauthenticated, "bearer token has expired")
+)
+
+// JWTOptions configure token checks; empty claim names use sub, tenant and roles
+type JWTOptions struct {
+	Keys *KeySet
+	// Issuer and Audience, when set, must match the iss and aud claims
+	Issuer   string
+	Audience string
+	// Leeway absorbs clock skew in exp and nbf
+	Leeway time.Duration
+
+	UserClaim   string // a positive integer, as a number or a string
+	TenantClaim string
+	RolesClaim  string // an array of strings, or one space-separated string
+}
+
+// JWTAuthenticator checks HS256 and RS256 bearer tokens against local keys
+type JWTAuthenticator struct {
+	opts JWTOptions
+	now  func() time.Time
+}
+
+// NewJWTAuthenticator checks tokens against opts.Keys
+func NewJWTAuthenticator(opts JWTOptions) *JWTAuthenticator {
+	if opts.Keys == nil {
+		opts.Keys = NewKeySet()
+	}
+	if opts.UserClaim == "" {
+		opts.UserClaim = "sub"
+	}
+	if opts.TenantClaim == "" {
+		opts.TenantClaim = "tenant"
+	}
+	if opts.RolesClaim == "" {
+		opts.RolesClaim = "roles"
+	}
+	return &JWTAuthenticator{opts: opts, now: time.Now}
+}
+
+func (a *JWTAuthenticator) Authenticate(_ context.Context, c Credentials) (Principal, error) {
+	if c.Bearer == "" {
+		return Principal{}, ErrNoCredentials
+	}
+	return a.Verify(c.Bearer)
+}
+
+func (*JWTAuthenticator) Scheme() string { return "Authorization bearer token" }
+
+type jwtHeader struct {
+	Alg  string   `json:"alg"`
+	Kid  string   `json:"kid"`
+	Crit []string `json:"crit"`
+}
+
+// Verify checks a compact JWS token's signature and claims and returns its principal
+func (a *JWTAuthenticator) Verify(token string) (Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Principal{}, apperr.Wrap(errInvalidToken, errors.New("not a compact JWS"))
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Principal{}, apperr.Wrap(errInvalidToken, fmt.Errorf("header: %w", err))
+	}
+	if len(header.Crit) > 0 {
+		return Principal{}, apperr.Wrap(errInvalidToken, fmt.Errorf("unsupported critical headers %v", header.Crit))
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Principal{}, apperr.Wrap(errInvalidToken, fmt.Errorf("signature: %w", err))
+	}
+	if err := a.verifySignature(header, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return Principal{}, apperr.Wrap(errInvalidToken, err)
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Principal{}, apperr.Wrap(errInvalidToken, fmt.Errorf("claims: %w", err))
+	}
+	if err := a.checkClaims(claims); err != nil {
+		return Principal{}, err
+	}
+	return a.principal(claims, header.Kid)
+}
+
+// verifySignature tries the key named by kid, or every key of the token's
+// algorithm when it has no kid
+func (a *JWTAuthenticator) verifySignature(h jwtHeader, signed, signature []byte) error {
+	switch h.Alg {
+	case "HS256":
+		for kid, secret := range a.opts.Keys.hmac {
+			if h.Kid != "" && kid != h.Kid {
+				continue
+			}
+			mac := hmac.New(sha256.New, secret)
+			mac.Write(signed)
+			if hmac.Equal(mac.Sum(nil), signature) {
+				return nil
+			}
+		}
+	case "RS256":
+		digest := sha256.Sum256(signed)
+		for kid, key := range a.opts.Keys.rsa {
+			if h.Kid != "" && kid != h.Kid {
+				continue
+			}
+			if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil {
+				return nil
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported alg %q", h.Alg)
+	}
+	return fmt.Errorf("no %s key with kid %q verifies the signature", h.Alg, h.Kid)
+}
+
+func (a *JWTAuthenticator) checkClaims(claims map[string]interface{}) error {
+	now := a.now()
+	exp, ok := numericDate(claims["exp"])
+	if !ok {
+		return apperr.Wrap(errInvalidToken, errors.New("exp claim is required"))
+	}
+	if !now.Before(exp.Add(a.opts.Leeway)) {
+		return errTokenExpired
+	}
+	if v, present := claims["nbf"]; present {
+		nbf, ok := numericDate(v)
+		if !ok {
+			return apperr.Wrap(errInvalidToken, errors.New("nbf claim is not a number"))
+		}
+		if now.Add(a.opts.Leeway).Before(nbf) {
+			return apperr.Wrap(errInvalidToken, errors.New("token is not valid yet"))
+		}
+	}
+	if a.opts.Issuer != "" && claims["iss"] != a.opts.Issuer {
+		return apperr.Wrap(errInvalidToken, fmt.Errorf("iss %v is not %q", claims["iss"], a.opts.Issuer))
+	}
+	if a.opts.Audience != "" && !hasAudience(claims["aud"], a.opts.Audience) {
+		return apperr.Wrap(errInvalidToken, fmt.Errorf("aud %v does not include %q", claims["aud"], a.opts.Audience))
+	}
+	return nil
+}
+
+func (a *JWTAuthenticator) principal(claims map[string]interface{}, kid string) (Principal, error) {
+	var userID int64
+	var err error
+	switch v := claims[a.opts.UserClaim].(type) {
+	case string:
+		userID, err = strconv.ParseInt(v, 10, 64)
+	case json.Number:
+		userID, err = v.Int64()
+	default:
+		err = errors.New("missing")
+	}
+	if err != nil || userID <= 0 {
+		return Principal{}, apperr.Wrap(errInvalidToken, fmt.Errorf("%s claim must be a positive integer user id", a.opts.UserClaim))
+	}
+
+	p := Principal{UserID: userID, Method: MethodJWT, CredentialID: kid}
+	if tenant, ok := claims[a.opts.TenantClaim].(string); ok {
+		p.Tenant = tenant
+	}
+	switch roles := claims[a.opts.RolesClaim].(type) {
+	case string:
+		p.Roles = strings.Fields(roles)
+	case []interface{}:
+		for _, r := range roles {
+			role, ok := r.(string)
+			if !ok {
+				return Principal{}, apperr.Wrap(errInvalidToken, fmt.Errorf("%s claim must hold strings", a.opts.RolesClaim))
+			}
+			p.Roles = append(p.Roles, role)
+		}
+	}
+	return p, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// numericDate reads an exp/nbf claim: seconds since the epoch, possibly fractional
+func numericDate(v interface{}) (time.Time, bool) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	f, err := n.Float64()
+	if err != nil || math.IsNaN(f) || math.Abs(f) > 1e15 {
+		return time.Time{}, false
+	}
+	sec, frac := math.Modf(f)
+	return time.Unix(int64(sec), int64(frac*float64(time.Second))), true
+}
+
+func hasAudience(aud interface{}, want string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == want
+	case []interface{}:
+		for _, a := range aud {
+			if a == want {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,195 @@
+package auth_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/auth"
+)
+
+var hsSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+// sign builds a compact JWS; alg HS256 uses hsSecret, RS256 uses key
+func sign(t *testing.T, header, claims map[string]interface{}, key *rsa.PrivateKey) string {
+	t.Helper()
+	h, _ := json.Marshal(header)
+	c, _ := json.Marshal(claims)
+	signed := b64(h) + "." + b64(c)
+	var sig []byte
+	switch header["alg"] {
+	case "HS256":
+		mac := hmac.New(sha256.New, hsSecret)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case "RS256":
+		digest := sha256.Sum256([]byte(signed))
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return signed + "." + b64(sig)
+}
+
+func TestJWTAuthenticator(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := auth.NewKeySet()
+	if err := keys.AddHMAC("hs", hsSecret); err != nil {
+		t.Fatal(err)
+	}
+	if err := keys.AddRSA("rs", &rsaKey.PublicKey); err != nil {
+		t.Fatal(err)
+	}
+	a := auth.NewJWTAuthenticator(auth.JWTOptions{Keys: keys, Issuer: "idp", Audience: "timetravel", Leeway: time.Second})
+
+	exp := time.Now().Add(time.Hour).Unix()
+	claims := func(edit func(map[string]interface{})) map[string]interface{} {
+		c := map[string]interface{}{"sub": "7", "iss": "idp", "aud": "timetravel", "exp": exp, "tenant": "acme", "roles": []string{"writer"}}
+		if edit != nil {
+			edit(c)
+		}
+		return c
+	}
+	hs := map[string]interface{}{"alg": "HS256", "kid": "hs"}
+	rs := map[string]interface{}{"alg": "RS256", "kid": "rs"}
+
+	tests := []struct {
+		name    string
+		token   string
+		want    auth.Principal
+		wantErr string
+	}{
+		{"HS256", sign(t, hs, claims(nil), nil),
+			auth.Principal{UserID: 7, Tenant: "acme", Roles: []string{"writer"}, Method: auth.MethodJWT, CredentialID: "hs"}, ""},
+		{"RS256", sign(t, rs, claims(nil), rsaKey),
+			auth.Principal{UserID: 7, Tenant: "acme", Roles: []string{"writer"}, Method: auth.MethodJWT, CredentialID: "rs"}, ""},
+		{"no kid tries every key of the alg", sign(t, map[string]interface{}{"alg": "RS256"}, claims(nil), rsaKey),
+			auth.Principal{UserID: 7, Tenant: "acme", Roles: []string{"writer"}, Method: auth.MethodJWT}, ""},
+		{"numeric sub and space-separated roles", sign(t, hs, claims(func(c map[string]interface{}) {
+			c["sub"], c["roles"], c["aud"] = 8, "reader admin", []string{"other", "timetravel"}
+		}), nil), auth.Principal{UserID: 8, Tenant: "acme", Roles: []string{"reader", "admin"}, Method: auth.MethodJWT, CredentialID: "hs"}, ""},
+
+		{"expired", sign(t, hs, claims(func(c map[string]interface{}) { c["exp"] = time.Now().Add(-time.Minute).Unix() }), nil), auth.Principal{}, "bearer token has expired"},
+		{"no exp", sign(t, hs, claims(func(c map[string]interface{}) { delete(c, "exp") }), nil), auth.Principal{}, "exp claim is required"},
+		{"not valid yet", sign(t, hs, claims(func(c map[string]interface{}) { c["nbf"] = time.Now().Add(time.Minute).Unix() }), nil), auth.Principal{}, "not valid yet"},
+		{"wrong issuer", sign(t, hs, claims(func(c map[string]interface{}) { c["iss"] = "evil" }), nil), auth.Principal{}, "iss"},
+		{"wrong audience", sign(t, hs, claims(func(c map[string]interface{}) { c["aud"] = "other" }), nil), auth.Principal{}, "aud"},
+		{"non-numeric sub", sign(t, hs, claims(func(c map[string]interface{}) { c["sub"] = "ada" }), nil), auth.Principal{}, "sub claim"},
+		{"unknown kid", sign(t, map[string]interface{}{"alg": "HS256", "kid": "nope"}, claims(nil), nil), auth.Principal{}, "no HS256 key"},
+		{"kid of the other alg", sign(t, map[string]interface{}{"alg": "HS256", "kid": "rs"}, claims(nil), nil), auth.Principal{}, "no HS256 key"},
+		{"other signer", sign(t, rs, claims(nil), otherKey), auth.Principal{}, "no RS256 key"},
+		{"alg none", b64([]byte(`{"alg":"none"}`)) + "." + b64([]byte(fmt.Sprintf(`{"sub":"7","exp":%d}`, exp))) + ".", auth.Principal{}, "unsupported alg"},
+		{"critical header", sign(t, map[string]interface{}{"alg": "HS256", "kid": "hs", "crit": []string{"b64"}}, claims(nil), nil), auth.Principal{}, "critical"},
+		{"not a JWS", "abc.def", auth.Principal{}, "compact JWS"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := a.Authenticate(context.Background(), auth.Credentials{Bearer: tt.token})
+			if tt.wantErr != "" {
+				if err == nil || apperr.HTTPStatus(err) != http.StatusUnauthorized || !strings.Contains(fmt.Sprintf("%v %v", err, unwrapAll(err)), tt.wantErr) {
+					t.Errorf("error = %v (%v), want 401 mentioning %q", err, unwrapAll(err), tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error = %v (%v)", err, unwrapAll(err))
+			}
+			if !reflect.DeepEqual(p, tt.want) {
+				t.Errorf("principal = %+v, want %+v", p, tt.want)
+			}
+		})
+	}
+}
+
+// unwrapAll joins the causes apperr.Wrap keeps out of the client-facing message
+func unwrapAll(err error) string {
+	var causes []string
+	for e := err; e != nil; {
+		causes = append(causes, e.Error())
+		switch u := e.(type) {
+		case interface{ Unwrap() error }:
+			e = u.Unwrap()
+		case interface{ Unwrap() []error }:
+			for _, inner := range u.Unwrap() {
+				causes = append(causes, unwrapAll(inner))
+			}
+			e = nil
+		default:
+			e = nil
+		}
+	}
+	return strings.Join(causes, ": ")
+}
+
+func TestKeySet(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	ks := auth.NewKeySet()
+	if err := ks.AddHMAC("short", []byte("too short")); err == nil {
+		t.Error("AddHMAC accepted a 9-byte secret")
+	}
+	if err := ks.AddRSAPEM("pem", pemKey); err != nil {
+		t.Errorf("AddRSAPEM() error = %v", err)
+	}
+	if err := ks.AddRSAPEM("pem", pemKey); err == nil {
+		t.Error("AddRSAPEM accepted a duplicate kid")
+	}
+	small, _ := rsa.GenerateKey(rand.Reader, 1024)
+	if err := ks.AddRSA("small", &small.PublicKey); err == nil {
+		t.Error("AddRSA accepted a 1024-bit key")
+	}
+
+	jwks := fmt.Sprintf(`{"keys":[
+		{"kty":"RSA","kid":"jwk-rs","use":"sig","alg":"RS256","n":%q,"e":%q},
+		{"kty":"oct","kid":"jwk-hs","k":%q},
+		{"kty":"RSA","kid":"jwk-enc","use":"enc","n":"AQAB","e":"AQAB"}
+	]}`, b64(rsaKey.N.Bytes()), b64(big.NewInt(int64(rsaKey.E)).Bytes()), b64(hsSecret))
+	if err := ks.AddJWKS([]byte(jwks)); err != nil {
+		t.Fatalf("AddJWKS() error = %v", err)
+	}
+	if ks.Len() != 3 {
+		t.Errorf("Len() = %d, want the PEM key and two signing JWKs", ks.Len())
+	}
+	if err := auth.NewKeySet().AddJWKS([]byte(`{"keys":[{"kty":"RSA","n":"AQAB","e":"AQAB"}]}`)); err == nil {
+		t.Error("AddJWKS accepted a key without kid")
+	}
+
+	a := auth.NewJWTAuthenticator(auth.JWTOptions{Keys: ks})
+	token := sign(t, map[string]interface{}{"alg": "RS256", "kid": "jwk-rs"}, map[string]interface{}{"sub": "5", "exp": time.Now().Add(time.Minute).Unix()}, rsaKey)
+	if p, err := a.Verify(token); err != nil || p.UserID != 5 {
+		t.Errorf("Verify() with a JWKS key = %+v, %v", p, err)
+	}
+}
@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// minHMACKeyBytes is the shortest HS256 secret accepted (RFC 7518 section 3.2)
+const minHMACKeyBytes = 32
+
+// KeySet holds the keys tokens may be signed with, by kid. HS256 and RS256
+// keys are kept apart so a token can never be checked with a key of the
+// other kind (the classic RSA-public-key-as-HMAC-secret confusion).
+type KeySet struct {
+	hmac map[string][]byte
+	rsa  map[string]*rsa.PublicKey
+}
+
+// NewKeySet returns an empty set
+func NewKeySet() *KeySet {
+	return &KeySet{hmac: map[string][]byte{}, rsa: map[string]*rsa.PublicKey{}}
+}
+
+// Len counts the keys
+func (ks *KeySet) Len() int {
+	return len(ks.hmac) + len(ks.rsa)
+}
+
+// AddHMAC adds an HS256 secret
+func (ks *KeySet) AddHMAC(kid string, secret []byte) error {
+	if len(secret) < minHMACKeyBytes {
+		return fmt.Errorf("HS256 key %q is %d bytes; at least %d are required", kid, len(secret), minHMACKeyBytes)
+	}
+	if err := ks.checkKID(kid); err != nil {
+		return err
+	}
+	ks.hmac[kid] = secret
+	return nil
+}
+
+// AddRSA adds an RS256 public key
+func (ks *KeySet) AddRSA(kid string, key *rsa.PublicKey) error {
+	if key.N.BitLen() < 2048 {
+		return fmt.Errorf("RS256 key %q is %d bits; at least 2048 are required", kid, key.N.BitLen())
+	}
+	if err := ks.checkKID(kid); err != nil {
+		return err
+	}
+	ks.rsa[kid] = key
+	return nil
+}
+
+func (ks *KeySet) checkKID(kid string) error {
+	_, inHMAC := ks.hmac[kid]
+	_, inRSA := ks.rsa[kid]
+	if inHMAC || inRSA {
+		return fmt.Errorf("duplicate key id %q", kid)
+	}
+	return nil
+}
+
+// AddRSAPEM adds an RS256 public key from a PEM "PUBLIC KEY", "RSA PUBLIC
+// KEY" or "CERTIFICATE" block
+func (ks *KeySet) AddRSAPEM(kid string, data []byte) error {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return fmt.Errorf("key %q: no PEM block found", kid)
+	}
+
+	var pub interface{}
+	var err error
+	switch block.Type {
+	case "PUBLIC KEY":
+		pub, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		pub, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+			pub = cert.PublicKey
+		}
+	default:
+		return fmt.Errorf("key %q: unsupported PEM block %q", kid, block.Type)
+	}
+	if err != nil {
+		return fmt.Errorf("key %q: %w", kid, err)
+	}
+	rsaKey, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("key %q is not an RSA public key", kid)
+	}
+	return ks.AddRSA(kid, rsaKey)
+}
+
+// jwk is the part of RFC 7517 a KeySet reads
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"` // RSA modulus
+	E   string `json:"e"` // RSA exponent
+	K   string `json:"k"` // symmetric key
+}
+
+// AddJWKS adds the signing keys of a JWK Set document: RSA keys for RS256
+// and oct keys for HS256. Encryption keys are skipped.
+func (ks *KeySet) AddJWKS(data []byte) error {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("invalid JWKS: %w", err)
+	}
+	for i, k := range doc.Keys {
+		if k.Use == "enc" {
+			continue
+		}
+		if k.Kid == "" {
+			return fmt.Errorf("JWKS key %d has no kid", i)
+		}
+		var err error
+		switch {
+		case k.Kty == "RSA" && (k.Alg == "" || k.Alg == "RS256"):
+			err = ks.addJWKRSA(k)
+		case k.Kty == "oct" && (k.Alg == "" || k.Alg == "HS256"):
+			var secret []byte
+			if secret, err = base64.RawURLEncoding.DecodeString(k.K); err == nil {
+				err = ks.AddHMAC(k.Kid, secret)
+			}
+		default:
+			err = fmt.Errorf("unsupported kty %q / alg %q", k.Kty, k.Alg)
+		}
+		if err != nil {
+			return fmt.Errorf("JWKS key %q: %w", k.Kid, err)
+		}
+	}
+	return nil
+}
+
+func (ks *KeySet) addJWKRSA(k jwk) error {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return fmt.Errorf("modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return fmt.Errorf("exponent: %w", err)
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+		return errors.New("exponent out of range")
+	}
+	return ks.AddRSA(k.Kid, &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())})
+}
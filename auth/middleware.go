@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/observability"
+)
+
+// Header names read by CredentialsFromRequest
+const (
+	APIKeyHeader = "X-API-Key"
+	UserIDHeader = "X-User-ID"
+)
+
+// CredentialsFromRequest collects the credentials an HTTP request carries
+func CredentialsFromRequest(r *http.Request) Credentials {
+	return Credentials{
+		APIKey: r.Header.Get(APIKeyHeader),
+		Bearer: BearerToken(r.Header.Get("Authorization")),
+		UserID: r.Header.Get(UserIDHeader),
+	}
+}
+
+// Middleware authenticates every request and stores the principal in its
+// context; failures are answered as problem+json. Requests that already carry
+// a principal (re-dispatched by the version router) are not checked twice.
+func Middleware(a Authenticator) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := FromContext(r.Context()); ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			p, err := a.Authenticate(r.Context(), CredentialsFromRequest(r))
+			if err != nil {
+				observability.DefaultLogger.WarnContext(r.Context(), "authentication_failed", "error", err)
+				if apperr.HTTPStatus(err) == http.StatusUnauthorized {
+					w.Header().Set("WWW-Authenticate", `Bearer realm="timetravel"`)
+				}
+				apperr.WriteProblem(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+		})
+	}
+}
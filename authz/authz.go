@@ -0,0 +1,129 @@
+// Package authz decides what an authenticated principal may do. Roles grant
+// permissions; routes declare the permission they need. Scoped roles (broker)
+// grant theirs only for the policyholders assigned to the caller.
+package authz
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/rainbowmga/timetravel/auth"
+)
+
+// Permission is an action on a kind of resource, declared per route
+type Permission string
+
+const (
+	RecordsRead       Permission = "records:read"        // current records and their versions
+	RecordsWrite      Permission = "records:write"       // create, replace and patch records
+	HistoryRead       Permission = "history:read"        // GraphQL history and event logs
+	MetricsRead       Permission = "metrics:read"        // persisted metric rollups
+	FlagsRefresh      Permission = "flags:refresh"       // reload feature flags
+	VersionsManage    Permission = "api_versions:manage" // v1/v2 rollout
+	APIKeysManage     Permission = "api_keys:manage"
+	AssignmentsManage Permission = "assignments:manage" // which policyholders scoped roles see
+	RateLimitsManage  Permission = "rate_limits:manage" // per-user rate limit overrides
+
+	// All grants every permission (admin)
+	All Permission = "*"
+)
+
+// Permissions lists every permission a role may be granted
+var Permissions = []Permission{
+	RecordsRead, RecordsWrite, HistoryRead, MetricsRead,
+	FlagsRefresh, VersionsManage, APIKeysManage, AssignmentsManage, RateLimitsManage,
+}
+
+// Built-in roles
+const (
+	RoleReader      = "reader"
+	RoleUnderwriter = "underwriter"
+	RoleAuditor     = "auditor"
+	RoleBroker      = "broker"
+	RoleAdmin       = "admin"
+)
+
+// DefaultRoles are the permissions of the built-in roles; config may replace
+// them or add roles
+func DefaultRoles() map[string][]Permission {
+	return map[string][]Permission{
+		RoleReader:      {RecordsRead},
+		RoleUnderwriter: {RecordsRead, RecordsWrite, HistoryRead},
+		RoleAuditor:     {RecordsRead, HistoryRead, MetricsRead},
+		RoleBroker:      {RecordsRead, RecordsWrite},
+		RoleAdmin:       {All},
+	}
+}
+
+// DefaultScopedRoles see only their assigned policyholders
+var DefaultScopedRoles = []string{RoleBroker}
+
+// Policy maps roles to permissions
+type Policy struct {
+	grants map[string]map[Permission]bool
+	scoped map[string]bool
+}
+
+// NewPolicy checks that every permission is known and every scoped role is defined
+func NewPolicy(roles map[string][]Permission, scoped []string) (*Policy, error) {
+	known := map[Permission]bool{All: true}
+	for _, perm := range Permissions {
+		known[perm] = true
+	}
+
+	p := &Policy{grants: map[string]map[Permission]bool{}, scoped: map[string]bool{}}
+	for role, perms := range roles {
+		p.grants[role] = map[Permission]bool{}
+		for _, perm := range perms {
+			if !known[perm] {
+				return nil, fmt.Errorf("role %q: unknown permission %q", role, perm)
+			}
+			p.grants[role][perm] = true
+		}
+	}
+	for _, role := range scoped {
+		if _, ok := p.grants[role]; !ok {
+			return nil, fmt.Errorf("scoped role %q is not defined", role)
+		}
+		if p.grants[role][All] {
+			return nil, fmt.Errorf("scoped role %q cannot grant every permission", role)
+		}
+		p.scoped[role] = true
+	}
+	return p, nil
+}
+
+// DefaultPolicy is the policy of the built-in roles
+func DefaultPolicy() *Policy {
+	p, err := NewPolicy(DefaultRoles(), DefaultScopedRoles)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Roles lists the defined roles
+func (p *Policy) Roles() []string {
+	roles := make([]string, 0, len(p.grants))
+	for role := range p.grants {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+// Grant reports whether the principal's roles grant perm, and whether only
+// scoped roles do, so the grant holds for assigned policyholders alone
+func (p *Policy) Grant(principal auth.Principal, perm Permission) (granted, scoped bool) {
+	for _, role := range principal.Roles {
+		perms := p.grants[role]
+		if !perms[perm] && !perms[All] {
+			continue
+		}
+		if !p.scoped[role] {
+			return true, false
+		}
+		granted, scoped = true, true
+	}
+	return granted, scoped
+}
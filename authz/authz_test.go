@@ -0,0 +1,176 @@
+package authz_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/auth"
+	"github.com/rainbowmga/timetravel/authz"
+)
+
+// stubAssignments assigns policyholder 3 to user 9
+type stubAssignments struct{ err error }
+
+func (s stubAssignments) IsAssigned(_ context.Context, userID, policyholderID int64) (bool, error) {
+	return userID == 9 && policyholderID == 3, s.err
+}
+
+type stubDenials struct{ got []authz.Denial }
+
+func (s *stubDenials) RecordDenial(_ context.Context, d authz.Denial) error {
+	s.got = append(s.got, d)
+	return nil
+}
+
+func TestNewPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		roles   map[string][]authz.Permission
+		scoped  []string
+		wantErr string
+	}{
+		{"defaults", authz.DefaultRoles(), authz.DefaultScopedRoles, ""},
+		{"unknown permission", map[string][]authz.Permission{"ops": {"records:delete"}}, nil, `unknown permission "records:delete"`},
+		{"undefined scoped role", authz.DefaultRoles(), []string{"agent"}, `scoped role "agent" is not defined`},
+		{"scoped wildcard", authz.DefaultRoles(), []string{authz.RoleAdmin}, "cannot grant every permission"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := authz.NewPolicy(tt.roles, tt.scoped)
+			if (err != nil) != (tt.wantErr != "") || (err != nil && !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPolicyGrant(t *testing.T) {
+	policy := authz.DefaultPolicy()
+	tests := []struct {
+		name        string
+		roles       []string
+		perm        authz.Permission
+		wantGranted bool
+		wantScoped  bool
+	}{
+		{"reader reads", []string{"reader"}, authz.RecordsRead, true, false},
+		{"reader cannot write", []string{"reader"}, authz.RecordsWrite, false, false},
+		{"auditor reads metrics", []string{"auditor"}, authz.MetricsRead, true, false},
+		{"admin holds everything", []string{"admin"}, authz.AssignmentsManage, true, false},
+		{"broker is scoped", []string{"broker"}, authz.RecordsWrite, true, true},
+		{"unscoped role wins", []string{"broker", "underwriter"}, authz.RecordsWrite, true, false},
+		{"unknown role", []string{"nobody"}, authz.RecordsRead, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			granted, scoped := policy.Grant(auth.Principal{UserID: 1, Roles: tt.roles}, tt.perm)
+			if granted != tt.wantGranted || scoped != tt.wantScoped {
+				t.Errorf("Grant = %v, %v; want %v, %v", granted, scoped, tt.wantGranted, tt.wantScoped)
+			}
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	ctxAs := func(userID int64, roles ...string) context.Context {
+		return auth.WithPrincipal(context.Background(), auth.Principal{UserID: userID, Roles: roles, Method: auth.MethodAPIKey})
+	}
+	tests := []struct {
+		name           string
+		ctx            context.Context
+		perm           authz.Permission
+		policyholderID int64
+		wantReason     string // empty when allowed
+	}{
+		{"no principal", context.Background(), authz.RecordsRead, 3, "no authenticated principal"},
+		{"granted", ctxAs(1, "underwriter"), authz.RecordsWrite, 3, ""},
+		{"not granted", ctxAs(1, "reader"), authz.RecordsWrite, 3, "do not grant records:write"},
+		{"assigned broker", ctxAs(9, "broker"), authz.RecordsWrite, 3, ""},
+		{"unassigned broker", ctxAs(8, "broker"), authz.RecordsWrite, 3, "policyholder 3 is not assigned to user 8"},
+		{"broker without a record", ctxAs(9, "broker"), authz.RecordsRead, 0, "limited to assigned policyholders"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			denials := &stubDenials{}
+			a := authz.New(authz.DefaultPolicy(), stubAssignments{}, denials)
+
+			err := a.Authorize(tt.ctx, "PATCH /api/v2/records/{policyholder_id}", tt.perm, tt.policyholderID)
+			if tt.wantReason == "" {
+				if err != nil || len(denials.got) != 0 {
+					t.Fatalf("err = %v, denials = %+v; want allowed", err, denials.got)
+				}
+				return
+			}
+			if apperr.HTTPStatus(err) != http.StatusForbidden {
+				t.Fatalf("err = %v, want 403", err)
+			}
+			if len(denials.got) != 1 || !strings.Contains(denials.got[0].Reason, tt.wantReason) {
+				t.Fatalf("denials = %+v, want reason %q", denials.got, tt.wantReason)
+			}
+			if d := denials.got[0]; d.Permission != tt.perm || d.Resource != "PATCH /api/v2/records/{policyholder_id}" || d.PolicyholderID != tt.policyholderID {
+				t.Errorf("denial = %+v", d)
+			}
+		})
+	}
+}
+
+func TestAuthorize_AssignmentLookupFails(t *testing.T) {
+	lookup := errors.New("database is locked")
+	denials := &stubDenials{}
+	a := authz.New(authz.DefaultPolicy(), stubAssignments{err: lookup}, denials)
+
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 9, Roles: []string{"broker"}})
+	if err := a.Authorize(ctx, "GET /api/v2/records/{policyholder_id}", authz.RecordsRead, 3); !errors.Is(err, lookup) {
+		t.Fatalf("err = %v, want the lookup error", err)
+	}
+	if len(denials.got) != 0 {
+		t.Errorf("a failed lookup was logged as a denial: %+v", denials.got)
+	}
+}
+
+func TestRequire(t *testing.T) {
+	denials := &stubDenials{}
+	a := authz.New(authz.DefaultPolicy(), stubAssignments{}, denials)
+
+	r := mux.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			ctx := auth.WithPrincipal(req.Context(), auth.Principal{UserID: 9, Roles: []string{"broker"}})
+			next.ServeHTTP(w, req.WithContext(ctx))
+		})
+	})
+	r.Handle("/records/{policyholder_id}", a.Require(authz.RecordsRead)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/records/3", http.StatusNoContent},
+		{"/records/4", http.StatusForbidden},
+		{"/records/abc", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+			if rec.Code == http.StatusForbidden && rec.Header().Get("Content-Type") != "application/problem+json" {
+				t.Errorf("Content-Type = %q, want problem+json", rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+	if len(denials.got) != 2 || denials.got[0].Resource != "GET /records/{policyholder_id}" {
+		t.Errorf("denials = %+v, want the route template as resource", denials.got)
+	}
+}
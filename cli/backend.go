@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"context"
+	"time"
+
+	"github.com/rainbowmga/timetravel/entity"
+)
+
+// Record is one version of a policyholder's record as the commands print it
+type Record struct {
+	PolicyholderID int64             `json:"policyholder_id"`
+	RecordID       int64             `json:"record_id"`
+	Version        int               `json:"version"`
+	Data           map[string]string `json:"data"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+}
+
+// Version is one entry of a record's history with its changes from the previous one
+type Version struct {
+	Version   int                  `json:"version"`
+	Action    string               `json:"action"`
+	ChangedAt time.Time            `json:"changed_at"`
+	Data      map[string]string    `json:"data"`
+	Changes   []entity.FieldChange `json:"changes"`
+}
+
+// Backend is where the commands read and write records: the HTTP API, or the
+// SQLite file itself with --db
+type Backend interface {
+	Get(ctx context.Context, policyholderID int64) (Record, error)
+	Put(ctx context.Context, policyholderID int64, data map[string]string) (Record, error)
+	// Patch sets the non-nil values and removes the keys mapped to nil
+	Patch(ctx context.Context, policyholderID int64, updates map[string]*string) (Record, error)
+	Version(ctx context.Context, policyholderID int64, version int) (map[string]string, error)
+	// History lists every version, oldest first
+	History(ctx context.Context, policyholderID int64) ([]Version, error)
+	// AsOf returns the version that was current at the given time
+	AsOf(ctx context.Context, policyholderID int64, at time.Time) (Record, error)
+	Close() error
+}
@@ -0,0 +1,527 @@
+// Package cli implements the client subcommands of the timetravel binary.
+// The record commands (get, put, patch, history, diff, as-of and revert)
+// call the HTTP API as the configured user or, with --db, read the SQLite
+// file directly; replay re-sends a recorded request log.
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rainbowmga/timetravel/conf"
+	"github.com/rainbowmga/timetravel/controller"
+)
+
+const (
+	defaultServer  = "http://localhost:8000"
+	defaultConfig  = "conf/config.yaml"
+	defaultTimeout = 30 * time.Second
+)
+
+// UsageError is a malformed command line; main exits 2 for it
+type UsageError struct {
+	msg string
+}
+
+func (e *UsageError) Error() string { return e.msg }
+
+func usageErrorf(format string, args ...interface{}) error {
+	return &UsageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// ExitCode is the process status for the error Run returned
+func ExitCode(err error) int {
+	var usage *UsageError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &usage):
+		return 2
+	default:
+		return 1
+	}
+}
+
+// call is what a command runs with: its positional arguments, its own flags
+// and where to read and print records
+type call struct {
+	backend Backend
+	print   printer
+	args    []string
+	stdin   io.Reader
+
+	data  string     // put, patch: --data
+	unset stringList // patch: --unset
+	now   func() time.Time
+}
+
+type command struct {
+	args    string // positional arguments, for usage
+	summary string
+	nargs   [2]int // min and max positional arguments
+	flags   func(fs *flag.FlagSet, c *call)
+	run     func(ctx context.Context, c *call) error
+}
+
+var commands = map[string]command{
+	"get": {
+		args:    "<policyholder-id> [version]",
+		summary: "Print the current record, or the data of one version",
+		nargs:   [2]int{1, 2},
+		run:     runGet,
+	},
+	"put": {
+		args:    "<policyholder-id> [key=value ...]",
+		summary: "Replace the record's data, creating it if needed",
+		nargs:   [2]int{1, -1},
+		flags: func(fs *flag.FlagSet, c *call) {
+			fs.StringVar(&c.data, "data", "", "JSON object instead of key=value; @file or - for stdin")
+		},
+		run: runPut,
+	},
+	"patch": {
+		args:    "<policyholder-id> [key=value ...]",
+		summary: "Set keys and remove --unset keys of an existing record",
+		nargs:   [2]int{1, -1},
+		flags: func(fs *flag.FlagSet, c *call) {
+			fs.StringVar(&c.data, "data", "", "JSON object instead of key=value, null removes a key; @file or - for stdin")
+			fs.Var(&c.unset, "unset", "key to remove (repeatable)")
+		},
+		run: runPatch,
+	},
+	"history": {
+		args:    "<policyholder-id>",
+		summary: "List every version with what changed",
+		nargs:   [2]int{1, 1},
+		run:     runHistory,
+	},
+	"diff": {
+		args:    "<policyholder-id> <from-version> [to-version]",
+		summary: "Compare two versions; to defaults to the current one",
+		nargs:   [2]int{2, 3},
+		run:     runDiff,
+	},
+	"as-of": {
+		args:    "<policyholder-id> <time>",
+		summary: "Print the record as it was at an RFC 3339 time, a date, or a duration ago (e.g. 36h)",
+		nargs:   [2]int{2, 2},
+		run:     runAsOf,
+	},
+	"revert": {
+		args:    "<policyholder-id> <version>",
+		summary: "Write an old version's data back as a new version",
+		nargs:   [2]int{2, 2},
+		run:     runRevert,
+	},
+}
+
+// IsCommand reports whether name is one of the record subcommands, replay or help
+func IsCommand(name string) bool {
+	_, ok := commands[name]
+	return ok || name == "replay" || name == "help"
+}
+
+// Run executes `timetravel <args>` and prints to stdout
+func Run(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		writeUsage(stdout)
+		return nil
+	}
+	name := args[0]
+	if name == "replay" {
+		return runReplay(ctx, args[1:], stdin, stdout)
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		return usageErrorf("unknown command %q; run timetravel help", name)
+	}
+
+	c := &call{stdin: stdin, now: time.Now}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	configPath := fs.String("config", defaultConfig, "config file with the cli section")
+	server := fs.String("server", "", "API base URL (default cli.server, else "+defaultServer+")")
+	userID := fs.Int64("user", 0, "X-User-ID to send (default cli.user_id); only dev-mode servers trust it")
+	apiKey := fs.String("api-key", "", "X-API-Key to send instead of a user id (default cli.api_key)")
+	dbPath := fs.String("db", "", "read this SQLite file directly instead of calling the API (read-only)")
+	output := fs.String("output", OutputTable, "table | json | yaml")
+	fs.StringVar(output, "o", OutputTable, "shorthand for --output")
+	timeout := fs.Duration("timeout", defaultTimeout, "limit for the whole command")
+	if cmd.flags != nil {
+		cmd.flags(fs, c)
+	}
+
+	positional, err := parseInterleaved(fs, args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		writeCommandUsage(stdout, name, cmd, fs)
+		return nil
+	}
+	if err != nil {
+		return usageErrorf("%s: %v", name, err)
+	}
+	if len(positional) < cmd.nargs[0] || (cmd.nargs[1] >= 0 && len(positional) > cmd.nargs[1]) {
+		return usageErrorf("usage: timetravel %s %s", name, cmd.args)
+	}
+	switch *output {
+	case OutputTable, OutputJSON, OutputYAML:
+	default:
+		return usageErrorf("--output must be table, json or yaml")
+	}
+	c.args = positional
+	c.print = printer{out: stdout, format: *output}
+
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	cfg, err := loadConfig(*configPath, set["config"])
+	if err != nil {
+		return err
+	}
+
+	if *dbPath != "" {
+		c.backend, err = newDBBackend(*dbPath, cfg.Environment)
+		if err != nil {
+			return err
+		}
+	} else {
+		if *server == "" {
+			*server = cfg.CLI.Server
+		}
+		if *server == "" {
+			*server = defaultServer
+		}
+		if !set["user"] {
+			*userID = cfg.CLI.UserID
+		}
+		if !set["api-key"] {
+			*apiKey = cfg.CLI.APIKey
+		}
+		if *userID <= 0 && *apiKey == "" {
+			return usageErrorf("an API key or user id is required: pass --api-key or --user, or set cli.api_key or cli.user_id in %s", *configPath)
+		}
+		c.backend = newHTTPBackend(*server, *userID, *apiKey, *timeout)
+	}
+	defer c.backend.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+	return cmd.run(ctx, c)
+}
+
+// loadConfig reads the config file; the default path may be missing, as when
+// the binary runs outside a checkout
+func loadConfig(path string, explicit bool) (*conf.Config, error) {
+	if _, err := os.Stat(path); err != nil {
+		if explicit || !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		return &conf.Config{}, nil
+	}
+	return conf.LoadConfig(path), nil
+}
+
+// parseInterleaved lets flags follow positional arguments (`get 7 -o json`),
+// which flag.Parse alone stops at; "--" ends flag parsing
+func parseInterleaved(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		if args[0] == "--" {
+			return append(positional, args[1:]...), nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func runGet(ctx context.Context, c *call) error {
+	id, err := policyholderArg(c.args[0])
+	if err != nil {
+		return err
+	}
+	if len(c.args) == 2 {
+		version, err := versionArg(c.args[1])
+		if err != nil {
+			return err
+		}
+		data, err := c.backend.Version(ctx, id, version)
+		if err != nil {
+			return err
+		}
+		return c.print.data(id, version, data)
+	}
+	rec, err := c.backend.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	return c.print.record(rec)
+}
+
+func runPut(ctx context.Context, c *call) error {
+	id, err := policyholderArg(c.args[0])
+	if err != nil {
+		return err
+	}
+	var data map[string]string
+	switch {
+	case c.data != "" && len(c.args) > 1:
+		return usageErrorf("put takes either --data or key=value arguments")
+	case c.data != "":
+		if err := c.decodeData(&data); err != nil {
+			return err
+		}
+	default:
+		if data, err = assignments(c.args[1:]); err != nil {
+			return err
+		}
+	}
+	if len(data) == 0 {
+		return usageErrorf("put needs the record's data: key=value arguments or --data")
+	}
+	rec, err := c.backend.Put(ctx, id, data)
+	if err != nil {
+		return err
+	}
+	return c.print.record(rec)
+}
+
+func runPatch(ctx context.Context, c *call) error {
+	id, err := policyholderArg(c.args[0])
+	if err != nil {
+		return err
+	}
+	updates := map[string]*string{}
+	if c.data != "" {
+		if len(c.args) > 1 || len(c.unset) > 0 {
+			return usageErrorf("patch takes either --data or key=value and --unset arguments")
+		}
+		if err := c.decodeData(&updates); err != nil {
+			return err
+		}
+	} else {
+		set, err := assignments(c.args[1:])
+		if err != nil {
+			return err
+		}
+		for k, v := range set {
+			updates[k] = &v
+		}
+		for _, k := range c.unset {
+			if _, ok := set[k]; ok {
+				return usageErrorf("%q is both set and unset", k)
+			}
+			updates[k] = nil
+		}
+	}
+	if len(updates) == 0 {
+		return usageErrorf("patch needs key=value arguments, --unset keys or --data")
+	}
+	rec, err := c.backend.Patch(ctx, id, updates)
+	if err != nil {
+		return err
+	}
+	return c.print.record(rec)
+}
+
+func runHistory(ctx context.Context, c *call) error {
+	id, err := policyholderArg(c.args[0])
+	if err != nil {
+		return err
+	}
+	versions, err := c.backend.History(ctx, id)
+	if err != nil {
+		return err
+	}
+	return c.print.history(versions)
+}
+
+func runDiff(ctx context.Context, c *call) error {
+	id, err := policyholderArg(c.args[0])
+	if err != nil {
+		return err
+	}
+	from, err := versionArg(c.args[1])
+	if err != nil {
+		return err
+	}
+	before, err := c.backend.Version(ctx, id, from)
+	if err != nil {
+		return err
+	}
+
+	var to int
+	var after map[string]string
+	if len(c.args) == 3 {
+		if to, err = versionArg(c.args[2]); err != nil {
+			return err
+		}
+		after, err = c.backend.Version(ctx, id, to)
+	} else {
+		var rec Record
+		rec, err = c.backend.Get(ctx, id)
+		to, after = rec.Version, rec.Data
+	}
+	if err != nil {
+		return err
+	}
+	return c.print.diff(id, from, to, controller.DiffData(before, after))
+}
+
+func runAsOf(ctx context.Context, c *call) error {
+	id, err := policyholderArg(c.args[0])
+	if err != nil {
+		return err
+	}
+	at, err := parseTime(c.args[1], c.now())
+	if err != nil {
+		return err
+	}
+	rec, err := c.backend.AsOf(ctx, id, at)
+	if err != nil {
+		return err
+	}
+	return c.print.record(rec)
+}
+
+// runRevert writes the old data as a new version, so history keeps both the
+// reverted change and the revert; the server records it as a revert
+func runRevert(ctx context.Context, c *call) error {
+	id, err := policyholderArg(c.args[0])
+	if err != nil {
+		return err
+	}
+	version, err := versionArg(c.args[1])
+	if err != nil {
+		return err
+	}
+	if _, ok := c.backend.(*dbBackend); ok {
+		return ErrReadOnly
+	}
+	data, err := c.backend.Version(ctx, id, version)
+	if err != nil {
+		return err
+	}
+	rec, err := c.backend.Put(withChangeSource(ctx, "revert"), id, data)
+	if err != nil {
+		return err
+	}
+	return c.print.record(rec)
+}
+
+// decodeData reads --data: inline JSON, @file, or - for stdin
+func (c *call) decodeData(v interface{}) error {
+	var raw []byte
+	var err error
+	switch {
+	case c.data == "-":
+		raw, err = io.ReadAll(c.stdin)
+	case strings.HasPrefix(c.data, "@"):
+		raw, err = os.ReadFile(c.data[1:])
+	default:
+		raw = []byte(c.data)
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return usageErrorf("--data must be a JSON object of strings: %v", err)
+	}
+	return nil
+}
+
+// assignments parses key=value arguments; values may contain '='
+func assignments(args []string) (map[string]string, error) {
+	data := make(map[string]string, len(args))
+	for _, a := range args {
+		k, v, ok := strings.Cut(a, "=")
+		if !ok || k == "" {
+			return nil, usageErrorf("expected key=value, got %q", a)
+		}
+		data[k] = v
+	}
+	return data, nil
+}
+
+func policyholderArg(s string) (int64, error) {
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, usageErrorf("policyholder id must be a positive integer, got %q", s)
+	}
+	return id, nil
+}
+
+func versionArg(s string) (int, error) {
+	v, err := strconv.Atoi(s)
+	if err != nil || v <= 0 {
+		return 0, usageErrorf("version must be a positive integer, got %q", s)
+	}
+	return v, nil
+}
+
+// timeLayouts are tried in order; times without a zone are UTC
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+
+// parseTime accepts an absolute time or a duration before now
+func parseTime(s string, now time.Time) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, usageErrorf("time must be RFC 3339, YYYY-MM-DD[ HH:MM:SS] or a duration ago such as 90m, got %q", s)
+}
+
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+func writeUsage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "usage: timetravel <command> [flags] [arguments]")
+	fmt.Fprintln(w, "       timetravel               run the server")
+	fmt.Fprintln(w, "       timetravel migrate ...   manage the schema")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Record commands:")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-8s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Common flags: --server URL, --user ID, --db PATH, -o table|json|yaml, --config PATH, --timeout")
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "  %-8s %s\n", "replay", replaySummary)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run timetravel <command> -h for its arguments.")
+}
+
+func writeCommandUsage(w io.Writer, name string, cmd command, fs *flag.FlagSet) {
+	fmt.Fprintf(w, "usage: timetravel %s [flags] %s\n\n%s\n\nFlags:\n", name, cmd.args, cmd.summary)
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+}
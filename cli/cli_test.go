@@ -0,0 +1,233 @@
+package cli_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rainbowmga/timetravel/app"
+	"github.com/rainbowmga/timetravel/cli"
+	"github.com/rainbowmga/timetravel/replay"
+)
+
+// newServer serves the full API over a migrated SQLite file and returns both,
+// so --db runs can read what HTTP runs wrote
+func newServer(t *testing.T) (url, dbPath string) {
+	t.Helper()
+	dbPath = filepath.Join(t.TempDir(), "cli.db")
+	built, err := app.BuildRouter(dbPath, true)
+	if err != nil {
+		t.Fatalf("build router: %v", err)
+	}
+	t.Cleanup(built.Close)
+	router := built.Router
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+	return srv.URL, dbPath
+}
+
+func run(args ...string) (string, error) {
+	var out bytes.Buffer
+	err := cli.Run(context.Background(), args, strings.NewReader(""), &out)
+	return out.String(), err
+}
+
+// runJSON runs a command with -o json against the server and decodes its output
+func runJSON(t *testing.T, url string, v interface{}, args ...string) {
+	t.Helper()
+	out, err := run(append(args, "--server", url, "--user", "1", "-o", "json")...)
+	if err != nil {
+		t.Fatalf("%v: %v", args, err)
+	}
+	if err := json.Unmarshal([]byte(out), v); err != nil {
+		t.Fatalf("%v: decode %q: %v", args, out, err)
+	}
+}
+
+// runRecord runs a command that prints one record
+func runRecord(t *testing.T, url string, args ...string) cli.Record {
+	t.Helper()
+	var rec cli.Record
+	runJSON(t, url, &rec, args...)
+	return rec
+}
+
+func TestRun_EditAndTravelOverHTTP(t *testing.T) {
+	url, _ := newServer(t)
+
+	rec := runRecord(t, url, "put", "7", "name=ada", "city=london")
+	if rec.Version != 1 || rec.Data["city"] != "london" {
+		t.Fatalf("put = %+v", rec)
+	}
+	time.Sleep(20 * time.Millisecond)
+	between := time.Now().UTC()
+	time.Sleep(20 * time.Millisecond)
+
+	rec = runRecord(t, url, "patch", "7", "name=ada lovelace", "--unset", "city")
+	if rec.Version != 2 || len(rec.Data) != 1 || rec.Data["name"] != "ada lovelace" {
+		t.Fatalf("patch = %+v", rec)
+	}
+
+	var history []cli.Version
+	runJSON(t, url, &history, "history", "7")
+	if len(history) != 2 || len(history[1].Changes) != 2 {
+		t.Fatalf("history = %+v", history)
+	}
+	if c := history[1].Changes[0]; c.Key != "city" || c.Kind != "removed" {
+		t.Errorf("first change of version 2 = %+v, want city removed", c)
+	}
+
+	rec = runRecord(t, url, "as-of", "7", between.Format(time.RFC3339Nano))
+	if rec.Version != 1 || rec.Data["city"] != "london" {
+		t.Errorf("as-of = %+v, want version 1", rec)
+	}
+
+	out, err := run("diff", "7", "1", "--server", url, "--user", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"KEY", "city", "removed", "london", "changed", "ada lovelace"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("diff output missing %q:\n%s", want, out)
+		}
+	}
+
+	rec = runRecord(t, url, "revert", "7", "1")
+	if rec.Version != 3 || rec.Data["name"] != "ada" || rec.Data["city"] != "london" {
+		t.Errorf("revert = %+v, want version 1's data as version 3", rec)
+	}
+	req, _ := http.NewRequest(http.MethodGet, url+"/api/v2/records/7/versions/3", nil)
+	req.Header.Set("X-User-ID", "1")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var version struct {
+		Source string `json:"source"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&version); err != nil || version.Source != "revert" {
+		t.Errorf("version 3 source = %q, %v; want revert", version.Source, err)
+	}
+
+	out, err = run("get", "7", "2", "--server", url, "--user", "1", "-o", "yaml")
+	if err != nil || !strings.Contains(out, "name: ada lovelace") {
+		t.Errorf("get 7 2 -o yaml = %q, %v", out, err)
+	}
+}
+
+func TestRun_ReadsTheFileWithDB(t *testing.T) {
+	url, dbPath := newServer(t)
+	runRecord(t, url, "put", "7", "name=ada")
+	runRecord(t, url, "put", "7", "name=grace")
+
+	out, err := run("history", "7", "--db", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, `name: "ada" → "grace"`) {
+		t.Errorf("history --db:\n%s", out)
+	}
+
+	out, err = run("get", "7", "--db", dbPath, "-o", "json")
+	if err != nil || !strings.Contains(out, `"grace"`) {
+		t.Errorf("get --db = %q, %v", out, err)
+	}
+
+	for _, args := range [][]string{{"put", "7", "name=x"}, {"patch", "7", "--unset", "name"}, {"revert", "7", "1"}} {
+		if _, err := run(append(args, "--db", dbPath)...); !errors.Is(err, cli.ErrReadOnly) {
+			t.Errorf("%v --db: error = %v, want ErrReadOnly", args, err)
+		}
+	}
+
+	if _, err := run("get", "7", "--db", filepath.Join(t.TempDir(), "missing.db")); err == nil {
+		t.Error("--db with a missing file succeeded")
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	url, _ := newServer(t)
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantExit int
+		wantErr  string
+	}{
+		{"unknown command", []string{"frobnicate"}, 2, "unknown command"},
+		{"no user", []string{"get", "7", "--server", url}, 2, "user id is required"},
+		// the test server trusts X-User-ID only, which an API key replaces
+		{"api key instead of user", []string{"get", "7", "--server", url, "--api-key", "tt_abc"}, 1, "401 unauthenticated: missing X-User-ID"},
+		{"bad id", []string{"get", "seven", "--server", url, "--user", "1"}, 2, "positive integer"},
+		{"too many args", []string{"history", "7", "8", "--server", url, "--user", "1"}, 2, "usage"},
+		{"bad output", []string{"get", "7", "--server", url, "--user", "1", "-o", "xml"}, 2, "--output"},
+		{"bad assignment", []string{"put", "7", "name", "--server", url, "--user", "1"}, 2, "key=value"},
+		{"set and unset", []string{"patch", "7", "a=1", "--unset", "a", "--server", url, "--user", "1"}, 2, "both set and unset"},
+		{"bad time", []string{"as-of", "7", "yesterday", "--server", url, "--user", "1"}, 2, "RFC 3339"},
+		{"missing record", []string{"get", "404", "--server", url, "--user", "1"}, 1, "404 not_found"},
+		{"no history", []string{"history", "404", "--server", url, "--user", "1"}, 1, "has no record"},
+		{"before it existed", []string{"as-of", "404", "2000-01-01", "--server", url, "--user", "1"}, 1, "had no record"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := run(tt.args...)
+			if got := cli.ExitCode(err); got != tt.wantExit {
+				t.Errorf("exit code = %d, want %d (err %v)", got, tt.wantExit, err)
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRun_DataFromStdin(t *testing.T) {
+	url, _ := newServer(t)
+
+	var out bytes.Buffer
+	err := cli.Run(context.Background(),
+		[]string{"put", "7", "--data", "-", "--server", url, "--user", "1", "-o", "json"},
+		strings.NewReader(`{"name":"ada","email":"ada@example.com"}`), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rec cli.Record
+	if err := json.Unmarshal(out.Bytes(), &rec); err != nil || rec.Data["email"] != "ada@example.com" {
+		t.Errorf("put --data - = %s, %v", out.String(), err)
+	}
+}
+
+func TestRun_ReplayInProcess(t *testing.T) {
+	reportPath := filepath.Join(t.TempDir(), "report.json")
+	out, err := run("replay", "--in-process", "../test/replay_v2.jsonl", "--report", reportPath)
+	if err != nil {
+		t.Fatalf("replay of test/replay_v2.jsonl: %v\n%s", err, out)
+	}
+	if !strings.Contains(out, "PATCH /api/v2/records/{n}") {
+		t.Errorf("report:\n%s", out)
+	}
+	if b, err := os.ReadFile(reportPath); err != nil || !strings.Contains(string(b), `"mismatches": 0`) {
+		t.Errorf("--report file = %s, %v", b, err)
+	}
+
+	log := filepath.Join(t.TempDir(), "log.jsonl")
+	if err := os.WriteFile(log, []byte(`{"method": "GET", "path": "/api/v2/records/1", "expect": {"status": 200}}`+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err = run("replay", log, "--in-process", "--user", "1", "-o", "json")
+	if !errors.Is(err, replay.ErrFailed) || cli.ExitCode(err) != 1 {
+		t.Fatalf("replay of a failing log: error = %v", err)
+	}
+	var report replay.Report
+	if err := json.Unmarshal([]byte(out), &report); err != nil || report.Mismatches != 1 || report.Statuses[404] != 1 {
+		t.Errorf("report = %+v, %v", report, err)
+	}
+}
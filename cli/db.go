@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/controller"
+	"github.com/rainbowmga/timetravel/gateways"
+	"github.com/rainbowmga/timetravel/service"
+)
+
+// ErrReadOnly is returned by writes in --db mode
+var ErrReadOnly = errors.New("--db opens the database read-only; put, patch and revert go through the server")
+
+// dbBackend reads the SQLite file through the same controllers the server
+// uses, without the server: for forensics when it is down or suspected
+type dbBackend struct {
+	db      *gateways.Database
+	records *controller.SQLiteRecordController
+	history *controller.HistoryController
+}
+
+func newDBBackend(path, environment string) (*dbBackend, error) {
+	db, err := gateways.OpenDatabase(path, gateways.DatabaseOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	return &dbBackend{
+		db:      db,
+		records: controller.NewSQLiteRecordControllerWithService(service.NewSQLiteRecordServiceWithDB(db, nil, environment)),
+		history: controller.NewHistoryController(service.NewHistoryServiceWithDB(db)),
+	}, nil
+}
+
+func (b *dbBackend) Get(ctx context.Context, policyholderID int64) (Record, error) {
+	rec, err := b.records.GetRecord(ctx, policyholderID)
+	if err != nil {
+		return Record{}, err
+	}
+	return Record{
+		PolicyholderID: policyholderID,
+		RecordID:       rec.ID,
+		Version:        rec.Version,
+		Data:           rec.Data,
+		CreatedAt:      rec.CreatedAt,
+		UpdatedAt:      rec.UpdatedAt,
+	}, nil
+}
+
+func (b *dbBackend) Put(context.Context, int64, map[string]string) (Record, error) {
+	return Record{}, ErrReadOnly
+}
+
+func (b *dbBackend) Patch(context.Context, int64, map[string]*string) (Record, error) {
+	return Record{}, ErrReadOnly
+}
+
+func (b *dbBackend) Version(ctx context.Context, policyholderID int64, version int) (map[string]string, error) {
+	return b.records.GetVersion(ctx, int(policyholderID), version)
+}
+
+func (b *dbBackend) History(ctx context.Context, policyholderID int64) ([]Version, error) {
+	rec, err := b.history.Record(ctx, policyholderID, time.Time{})
+	if errors.Is(err, apperr.ErrNotFound) {
+		return nil, errNoRecord(policyholderID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []Version
+	after := 0
+	for {
+		entries, more, err := b.history.Versions(ctx, rec.ID, after, time.Time{}, controller.MaxPageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			versions = append(versions, Version{
+				Version:   e.Version,
+				Action:    e.EventType,
+				ChangedAt: e.ChangedAt,
+				Data:      e.Data,
+				Changes:   controller.DiffData(e.Previous, e.Data),
+			})
+			after = e.Version
+		}
+		if !more {
+			return versions, nil
+		}
+	}
+}
+
+func (b *dbBackend) AsOf(ctx context.Context, policyholderID int64, at time.Time) (Record, error) {
+	rec, err := b.history.Record(ctx, policyholderID, at)
+	if errors.Is(err, apperr.ErrNotFound) {
+		return Record{}, errNoRecordAt(policyholderID, at)
+	}
+	if err != nil {
+		return Record{}, err
+	}
+	return Record{
+		PolicyholderID: policyholderID,
+		RecordID:       rec.ID,
+		Version:        rec.Version,
+		Data:           rec.Data,
+		CreatedAt:      rec.CreatedAt,
+		UpdatedAt:      rec.UpdatedAt,
+	}, nil
+}
+
+func (b *dbBackend) Close() error {
+	return b.db.Close()
+}
+
+func errNoRecord(policyholderID int64) error {
+	return fmt.Errorf("policyholder %d has no record", policyholderID)
+}
+
+func errNoRecordAt(policyholderID int64, at time.Time) error {
+	return fmt.Errorf("policyholder %d had no record at %s", policyholderID, at.UTC().Format(time.RFC3339))
+}
@@ -0,0 +1,293 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rainbowmga/timetravel/entity"
+)
+
+// APIError is a problem+json or GraphQL error answered by the server
+type APIError struct {
+	Status int
+	Code   string
+	Detail string
+}
+
+func (e *APIError) Error() string {
+	if e.Status == 0 {
+		return fmt.Sprintf("%s (%s)", e.Detail, e.Code)
+	}
+	return fmt.Sprintf("%d %s: %s", e.Status, e.Code, e.Detail)
+}
+
+// httpBackend calls the v2 record routes, and GraphQL for history and as-of
+type httpBackend struct {
+	base   string // server URL including /api/v2
+	userID int64
+	apiKey string // sent instead of userID when set
+	client *http.Client
+}
+
+func newHTTPBackend(server string, userID int64, apiKey string, timeout time.Duration) *httpBackend {
+	return &httpBackend{
+		base:   strings.TrimRight(server, "/") + "/api/v2",
+		userID: userID,
+		apiKey: apiKey,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// recordResponse is the v2 record body
+type recordResponse struct {
+	PolicyholderID int64             `json:"policyholder_id"`
+	RecordID       int64             `json:"record_id"`
+	Version        int               `json:"version"`
+	Data           map[string]string `json:"data"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+}
+
+func (r recordResponse) record() Record {
+	return Record(r)
+}
+
+func (b *httpBackend) Get(ctx context.Context, policyholderID int64) (Record, error) {
+	var resp recordResponse
+	err := b.do(ctx, "GET", recordPath(policyholderID), nil, &resp)
+	return resp.record(), err
+}
+
+func (b *httpBackend) Put(ctx context.Context, policyholderID int64, data map[string]string) (Record, error) {
+	var resp recordResponse
+	err := b.do(ctx, "POST", recordPath(policyholderID), data, &resp)
+	return resp.record(), err
+}
+
+func (b *httpBackend) Patch(ctx context.Context, policyholderID int64, updates map[string]*string) (Record, error) {
+	var resp recordResponse
+	err := b.do(ctx, "PATCH", recordPath(policyholderID), updates, &resp)
+	return resp.record(), err
+}
+
+func (b *httpBackend) Version(ctx context.Context, policyholderID int64, version int) (map[string]string, error) {
+	var resp struct {
+		Data map[string]string `json:"data"`
+	}
+	err := b.do(ctx, "GET", recordPath(policyholderID)+"/versions/"+strconv.Itoa(version), nil, &resp)
+	return resp.Data, err
+}
+
+// historyQuery pages through versions at the largest page size the server
+// allows; its cost stays well under the default graphql.max_cost
+const historyQuery = `query($id: Int!, $after: String) {
+	record(policyholderId: $id) {
+		versions(first: 100, after: $after) {
+			edges { node { version action changedAt data { key value } diff { key kind before after } } }
+			pageInfo { hasNextPage endCursor }
+		}
+	}
+}`
+
+type gqlField struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+func gqlData(fields []gqlField) map[string]string {
+	data := make(map[string]string, len(fields))
+	for _, f := range fields {
+		data[f.Key] = f.Value
+	}
+	return data
+}
+
+func (b *httpBackend) History(ctx context.Context, policyholderID int64) ([]Version, error) {
+	var versions []Version
+	var after interface{}
+	for {
+		var resp struct {
+			Record *struct {
+				Versions struct {
+					Edges []struct {
+						Node struct {
+							Version   int                  `json:"version"`
+							Action    string               `json:"action"`
+							ChangedAt time.Time            `json:"changedAt"`
+							Data      []gqlField           `json:"data"`
+							Diff      []entity.FieldChange `json:"diff"`
+						} `json:"node"`
+					} `json:"edges"`
+					PageInfo struct {
+						HasNextPage bool   `json:"hasNextPage"`
+						EndCursor   string `json:"endCursor"`
+					} `json:"pageInfo"`
+				} `json:"versions"`
+			} `json:"record"`
+		}
+		vars := map[string]interface{}{"id": policyholderID, "after": after}
+		if err := b.graphql(ctx, historyQuery, vars, &resp); err != nil {
+			return nil, err
+		}
+		if resp.Record == nil {
+			return nil, errNoRecord(policyholderID)
+		}
+		for _, e := range resp.Record.Versions.Edges {
+			n := e.Node
+			for i := range n.Diff {
+				n.Diff[i].Kind = strings.ToLower(n.Diff[i].Kind)
+			}
+			versions = append(versions, Version{
+				Version:   n.Version,
+				Action:    n.Action,
+				ChangedAt: n.ChangedAt,
+				Data:      gqlData(n.Data),
+				Changes:   n.Diff,
+			})
+		}
+		if !resp.Record.Versions.PageInfo.HasNextPage {
+			return versions, nil
+		}
+		after = resp.Record.Versions.PageInfo.EndCursor
+	}
+}
+
+const asOfQuery = `query($id: Int!, $at: DateTime) {
+	record(policyholderId: $id, asOf: $at) { recordId version data { key value } createdAt updatedAt }
+}`
+
+func (b *httpBackend) AsOf(ctx context.Context, policyholderID int64, at time.Time) (Record, error) {
+	var resp struct {
+		Record *struct {
+			RecordID  int64      `json:"recordId"`
+			Version   int        `json:"version"`
+			Data      []gqlField `json:"data"`
+			CreatedAt time.Time  `json:"createdAt"`
+			UpdatedAt time.Time  `json:"updatedAt"`
+		} `json:"record"`
+	}
+	vars := map[string]interface{}{"id": policyholderID, "at": at.UTC().Format(time.RFC3339Nano)}
+	if err := b.graphql(ctx, asOfQuery, vars, &resp); err != nil {
+		return Record{}, err
+	}
+	if resp.Record == nil {
+		return Record{}, errNoRecordAt(policyholderID, at)
+	}
+	r := resp.Record
+	return Record{
+		PolicyholderID: policyholderID,
+		RecordID:       r.RecordID,
+		Version:        r.Version,
+		Data:           gqlData(r.Data),
+		CreatedAt:      r.CreatedAt,
+		UpdatedAt:      r.UpdatedAt,
+	}, nil
+}
+
+func (b *httpBackend) Close() error {
+	b.client.CloseIdleConnections()
+	return nil
+}
+
+func recordPath(policyholderID int64) string {
+	return "/records/" + strconv.FormatInt(policyholderID, 10)
+}
+
+// graphql runs a query and decodes its data into out; the first error
+// reported, whether the query was rejected or a field failed, is returned
+func (b *httpBackend) graphql(ctx context.Context, query string, vars map[string]interface{}, out interface{}) error {
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message    string `json:"message"`
+			Extensions struct {
+				Code string `json:"code"`
+			} `json:"extensions"`
+		} `json:"errors"`
+	}
+	body := map[string]interface{}{"query": query, "variables": vars}
+	if err := b.do(ctx, "POST", "/graphql", body, &resp); err != nil {
+		return err
+	}
+	if len(resp.Errors) > 0 {
+		return &APIError{Code: resp.Errors[0].Extensions.Code, Detail: resp.Errors[0].Message}
+	}
+	return json.Unmarshal(resp.Data, out)
+}
+
+// changeSourceKey carries the change source a command declares for its writes
+type changeSourceKey struct{}
+
+// withChangeSource marks the writes made under ctx as coming from source
+func withChangeSource(ctx context.Context, source string) context.Context {
+	return context.WithValue(ctx, changeSourceKey{}, source)
+}
+
+// do sends body as JSON and decodes a 200 answer into out. Problem responses
+// become an *APIError; a 400 with GraphQL errors is decoded like a 200.
+func (b *httpBackend) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(buf)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, b.base+path, reqBody)
+	if err != nil {
+		return err
+	}
+	if b.apiKey != "" {
+		req.Header.Set("X-API-Key", b.apiKey)
+	} else {
+		req.Header.Set("X-User-ID", strconv.FormatInt(b.userID, 10))
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if source, ok := ctx.Value(changeSourceKey{}).(string); ok {
+		req.Header.Set("X-Change-Source", source)
+	}
+
+	res, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	contentType := res.Header.Get("Content-Type")
+	graphqlRejected := res.StatusCode == http.StatusBadRequest && path == "/graphql" && strings.HasPrefix(contentType, "application/json")
+	if res.StatusCode != http.StatusOK && !graphqlRejected {
+		return responseError(res)
+	}
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode %s %s response: %w", method, path, err)
+	}
+	return nil
+}
+
+// responseError reads an RFC 7807 problem, or keeps the start of any other body
+func responseError(res *http.Response) error {
+	raw, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+	var problem struct {
+		Title  string `json:"title"`
+		Detail string `json:"detail"`
+		Code   string `json:"code"`
+	}
+	if json.Unmarshal(raw, &problem) == nil && problem.Code != "" {
+		detail := problem.Detail
+		if detail == "" {
+			detail = problem.Title
+		}
+		return &APIError{Status: res.StatusCode, Code: problem.Code, Detail: detail}
+	}
+	return &APIError{Status: res.StatusCode, Code: http.StatusText(res.StatusCode), Detail: strings.TrimSpace(string(raw))}
+}
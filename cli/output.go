@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/rainbowmga/timetravel/controller"
+	"github.com/rainbowmga/timetravel/entity"
+)
+
+// Output formats for -o
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+)
+
+// printer writes command results in the chosen format; table is for people,
+// json and yaml keep the API's field names for scripts
+type printer struct {
+	out    io.Writer
+	format string
+}
+
+func (p printer) record(r Record) error {
+	if p.format != OutputTable {
+		return p.encode(r)
+	}
+	w := tabwriter.NewWriter(p.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "POLICYHOLDER\t%d\n", r.PolicyholderID)
+	fmt.Fprintf(w, "RECORD\t%d\n", r.RecordID)
+	fmt.Fprintf(w, "VERSION\t%d\n", r.Version)
+	fmt.Fprintf(w, "CREATED AT\t%s\n", formatTime(r.CreatedAt))
+	fmt.Fprintf(w, "UPDATED AT\t%s\n", formatTime(r.UpdatedAt))
+	fmt.Fprintln(w)
+	writeData(w, r.Data)
+	return w.Flush()
+}
+
+func (p printer) data(policyholderID int64, version int, data map[string]string) error {
+	if p.format != OutputTable {
+		return p.encode(map[string]interface{}{"policyholder_id": policyholderID, "version": version, "data": data})
+	}
+	w := tabwriter.NewWriter(p.out, 0, 0, 2, ' ', 0)
+	writeData(w, data)
+	return w.Flush()
+}
+
+func (p printer) history(versions []Version) error {
+	if p.format != OutputTable {
+		return p.encode(versions)
+	}
+	w := tabwriter.NewWriter(p.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tACTION\tCHANGED AT\tCHANGES")
+	for _, v := range versions {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", v.Version, v.Action, formatTime(v.ChangedAt), summarize(v.Changes))
+	}
+	return w.Flush()
+}
+
+func (p printer) diff(policyholderID int64, from, to int, changes []entity.FieldChange) error {
+	if p.format != OutputTable {
+		return p.encode(map[string]interface{}{"policyholder_id": policyholderID, "from": from, "to": to, "changes": changes})
+	}
+	if len(changes) == 0 {
+		_, err := fmt.Fprintf(p.out, "versions %d and %d are identical\n", from, to)
+		return err
+	}
+	w := tabwriter.NewWriter(p.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tCHANGE\tBEFORE\tAFTER")
+	for _, c := range changes {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.Key, c.Kind, deref(c.Before), deref(c.After))
+	}
+	return w.Flush()
+}
+
+// encode writes JSON, or YAML with the same keys (converted through JSON so
+// the struct tags apply)
+func (p printer) encode(v interface{}) error {
+	if p.format == OutputJSON {
+		enc := json.NewEncoder(p.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var generic interface{}
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return err
+	}
+	enc := yaml.NewEncoder(p.out)
+	enc.SetIndent(2)
+	if err := enc.Encode(generic); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+func writeData(w io.Writer, data map[string]string) {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fmt.Fprintln(w, "KEY\tVALUE")
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s\t%s\n", k, data[k])
+	}
+}
+
+// summarize renders changes on one line: +added -removed changed: old → new
+func summarize(changes []entity.FieldChange) string {
+	parts := make([]string, 0, len(changes))
+	for _, c := range changes {
+		switch c.Kind {
+		case controller.FieldAdded:
+			parts = append(parts, "+"+c.Key)
+		case controller.FieldRemoved:
+			parts = append(parts, "-"+c.Key)
+		default:
+			parts = append(parts, fmt.Sprintf("%s: %q → %q", c.Key, deref(c.Before), deref(c.After)))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
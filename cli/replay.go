@@ -0,0 +1,196 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rainbowmga/timetravel/app"
+	"github.com/rainbowmga/timetravel/auth"
+	"github.com/rainbowmga/timetravel/replay"
+)
+
+const replayArgs = "<log.jsonl | ->"
+
+// runReplay is `timetravel replay`: it sends a recorded request log to a
+// server, or to an in-process router on a fresh database, and reports
+// mismatches and latency. It fails when any request errored or mismatched.
+func runReplay(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	configPath := fs.String("config", defaultConfig, "config file; its cli section and, with --in-process, the app settings")
+	target := fs.String("target", "", "server to replay against (default cli.server, else "+defaultServer+")")
+	inProcess := fs.Bool("in-process", false, "serve the requests in-process with a router on a fresh temporary database")
+	userID := fs.Int64("user", 0, "X-User-ID for entries without one (default cli.user_id)")
+	apiKey := fs.String("api-key", "", "X-API-Key for entries without one (default cli.api_key)")
+	concurrency := fs.Int("concurrency", 1, "parallel workers; with more than one, requests interleave")
+	rate := fs.Float64("rate", 0, "requests per second across workers; 0 is unlimited")
+	repeat := fs.Int("repeat", 1, "send the whole log this many times")
+	timeout := fs.Duration("timeout", 10*time.Second, "limit per request")
+	compare := fs.Bool("compare", true, "check answers against the entries' expect")
+	ignore := fs.String("ignore", strings.Join(replay.DefaultIgnore, ","), "comma-separated response keys not compared")
+	output := fs.String("output", OutputTable, "table | json")
+	fs.StringVar(output, "o", OutputTable, "shorthand for --output")
+	reportPath := fs.String("report", "", "also write the JSON report to this file")
+
+	positional, err := parseInterleaved(fs, args)
+	if errors.Is(err, flag.ErrHelp) {
+		writeCommandUsage(stdout, "replay", command{args: replayArgs, summary: replaySummary}, fs)
+		return nil
+	}
+	if err != nil {
+		return usageErrorf("replay: %v", err)
+	}
+	if len(positional) != 1 {
+		return usageErrorf("usage: timetravel replay [flags] %s", replayArgs)
+	}
+	if *output != OutputTable && *output != OutputJSON {
+		return usageErrorf("--output must be table or json")
+	}
+	if *inProcess && *target != "" {
+		return usageErrorf("--target and --in-process are exclusive")
+	}
+	if *concurrency < 1 || *repeat < 1 || *rate < 0 {
+		return usageErrorf("--concurrency and --repeat must be at least 1, --rate at least 0")
+	}
+
+	entries, err := readLog(positional[0], stdin)
+	if err != nil {
+		return err
+	}
+
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	cfg, err := loadConfig(*configPath, set["config"])
+	if err != nil {
+		return err
+	}
+	if !set["user"] {
+		*userID = cfg.CLI.UserID
+	}
+	if !set["api-key"] {
+		*apiKey = cfg.CLI.APIKey
+	}
+
+	opts := replay.Options{
+		Concurrency: *concurrency,
+		Rate:        *rate,
+		Repeat:      *repeat,
+		Timeout:     *timeout,
+		Compare:     *compare,
+		Ignore:      splitList(*ignore),
+	}
+	opts.Header = map[string]string{}
+	if *userID > 0 {
+		opts.Header["X-User-ID"] = fmt.Sprint(*userID)
+	}
+	if *apiKey != "" {
+		opts.Header["X-API-Key"] = *apiKey
+	}
+
+	var t replay.Target
+	if *inProcess {
+		// the configured app on a scratch database, so every replay starts from
+		// the same empty state and recorded ids and versions line up
+		dir, err := os.MkdirTemp("", "timetravel-replay-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		appCfg := *cfg
+		appCfg.Database.Path = filepath.Join(dir, "replay.db")
+		appCfg.Database.Migrations.RunOnStartup = true
+		appCfg.Logging.Level, appCfg.Logging.Output = "error", "stderr"
+		appCfg.GRPC.Addr = ""
+		appCfg.Capture.Path = ""
+		// a scratch database holds no API keys and recorded credentials are
+		// redacted, so the local app trusts X-User-ID like a dev server
+		appCfg.Environment = "development"
+		appCfg.Auth.Methods = []string{auth.MethodHeader}
+		// replays run faster than recorded traffic; their latency report is
+		// about the app, not the limiter
+		appCfg.RateLimit.Read.PerMinute, appCfg.RateLimit.Write.PerMinute, appCfg.RateLimit.Admin.PerMinute = 0, 0, 0
+		if err := app.ConfigureLogging(&appCfg); err != nil {
+			return err
+		}
+		a, err := app.BuildApp(&appCfg)
+		if err != nil {
+			return err
+		}
+		defer a.Close()
+		a.Health.MarkReady()
+		t = replay.HandlerTarget{Handler: a.Router}
+	} else {
+		base := *target
+		if base == "" {
+			base = cfg.CLI.Server
+		}
+		if base == "" {
+			base = defaultServer
+		}
+		t = replay.URLTarget{Base: base, Client: &http.Client{}}
+	}
+
+	report := replay.Run(ctx, t, entries, opts)
+
+	if *reportPath != "" {
+		if err := writeJSONFile(*reportPath, report); err != nil {
+			return err
+		}
+	}
+	if *output == OutputJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report)
+	} else {
+		err = report.WriteText(stdout)
+	}
+	if err != nil {
+		return err
+	}
+	return report.Err()
+}
+
+const replaySummary = "Replay a recorded request log, compare the answers and report latency"
+
+func readLog(path string, stdin io.Reader) ([]replay.Entry, error) {
+	if path == "-" {
+		return replay.ReadLog(stdin)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	entries, err := replay.ReadLog(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return entries, nil
+}
+
+func writeJSONFile(path string, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+func splitList(s string) []string {
+	list := []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -7,8 +7,47 @@ import (
 type contextKey string
 
 const UserIDKey contextKey = "userID"
+const RequestIDKey contextKey = "requestID"
+const RouteKey contextKey = "route"
+
+// ChangeSourceKey and ChangeReasonKey attribute record writes; they are
+// stored with every version written under the context
+const ChangeSourceKey contextKey = "changeSource"
+const ChangeReasonKey contextKey = "changeReason"
+
+// Sources of a record change (audit_history.source)
+const (
+	SourceAPIV1  = "api_v1" // v1 writes mirrored by the dual-write migration mode
+	SourceAPIV2  = "api_v2" // v2 HTTP and gRPC writes
+	SourceImport = "import" // bulk loads declared by the client
+	SourceRevert = "revert" // old data written back as a new version
+)
 
 func GetUserID(ctx context.Context) (int64, bool) {
 	id, ok := ctx.Value(UserIDKey).(int64)
 	return id, ok
-}
\ No newline at end of file
+}
+
+// GetRequestID returns the correlation id assigned to the current request
+func GetRequestID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(RequestIDKey).(string)
+	return id, ok && id != ""
+}
+
+// GetRoute returns the matched route template (e.g. /api/v2/records/{policyholder_id})
+func GetRoute(ctx context.Context) (string, bool) {
+	route, ok := ctx.Value(RouteKey).(string)
+	return route, ok && route != ""
+}
+
+// GetChangeSource returns how the current write reached the store
+func GetChangeSource(ctx context.Context) (string, bool) {
+	source, ok := ctx.Value(ChangeSourceKey).(string)
+	return source, ok && source != ""
+}
+
+// GetChangeReason returns the caller's free-text reason for the current write
+func GetChangeReason(ctx context.Context) (string, bool) {
+	reason, ok := ctx.Value(ChangeReasonKey).(string)
+	return reason, ok && reason != ""
+}
@@ -46,3 +46,41 @@ func TestGetUserID_TableDriven(t *testing.T) {
 		})
 	}
 }
+
+func TestGetRequestIDAndRoute(t *testing.T) {
+	ctx := context.WithValue(context.Background(), RequestIDKey, "req-1")
+	ctx = context.WithValue(ctx, RouteKey, "/records/{id}")
+
+	if id, ok := GetRequestID(ctx); !ok || id != "req-1" {
+		t.Fatalf("expected request id req-1, got %q %v", id, ok)
+	}
+	if route, ok := GetRoute(ctx); !ok || route != "/records/{id}" {
+		t.Fatalf("expected route /records/{id}, got %q %v", route, ok)
+	}
+
+	empty := context.WithValue(context.Background(), RequestIDKey, "")
+	if _, ok := GetRequestID(empty); ok {
+		t.Fatalf("expected empty request id to be reported missing")
+	}
+	if _, ok := GetRoute(context.Background()); ok {
+		t.Fatalf("expected missing route")
+	}
+}
+
+func TestGetChangeSourceAndReason(t *testing.T) {
+	ctx := context.WithValue(context.Background(), ChangeSourceKey, SourceRevert)
+	ctx = context.WithValue(ctx, ChangeReasonKey, "undo bad import")
+
+	if source, ok := GetChangeSource(ctx); !ok || source != SourceRevert {
+		t.Fatalf("expected source revert, got %q %v", source, ok)
+	}
+	if reason, ok := GetChangeReason(ctx); !ok || reason != "undo bad import" {
+		t.Fatalf("expected reason, got %q %v", reason, ok)
+	}
+	if _, ok := GetChangeSource(context.Background()); ok {
+		t.Fatalf("expected missing source")
+	}
+	if _, ok := GetChangeReason(context.WithValue(context.Background(), ChangeReasonKey, "")); ok {
+		t.Fatalf("expected empty reason to be reported missing")
+	}
+}
@@ -4,15 +4,187 @@ import (
     "gopkg.in/yaml.v3"
     "io/ioutil"
     "log"
+    "time"
 )
 
 type Config struct {
+    // Environment is production unless set otherwise; some safety switches
+    // (e.g. disabling audit logging) only work outside production.
+    Environment string `yaml:"environment"`
+
+    // Server controls graceful shutdown: /readyz fails for DrainDelay before
+    // in-flight requests get up to ShutdownTimeout (default 15s) to finish.
+    // Timeouts caps each request's context; Routes is keyed "METHOD /path/template".
+    Server struct {
+        DrainDelay      time.Duration `yaml:"drain_delay"`
+        ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+        Timeouts        struct {
+            Default time.Duration            `yaml:"default"` // 0 means no limit
+            Routes  map[string]time.Duration `yaml:"routes"`
+        } `yaml:"timeouts"`
+    } `yaml:"server"`
+
+    // Database is opened once and shared: one writer connection plus a pool of
+    // MaxReaders read-only connections, all in WAL mode.
     Database struct {
-        Path       string `yaml:"path"`
-        Migrations struct {
+        Path        string        `yaml:"path"`
+        BusyTimeout time.Duration `yaml:"busy_timeout"` // default 200ms; keep it well under the 2s write retry budget
+        Synchronous string        `yaml:"synchronous"`  // OFF | NORMAL (default) | FULL | EXTRA
+        MaxReaders  int           `yaml:"max_readers"`  // default 4
+        Migrations  struct {
             RunOnStartup bool `yaml:"run_on_startup"`
         } `yaml:"migrations"`
     } `yaml:"database"`
+
+    Logging struct {
+        Level    string `yaml:"level"`  // debug | info (default) | warn | error
+        Format   string `yaml:"format"` // text (default) | json
+        Output   string `yaml:"output"` // stdout (default) | stderr | file path
+        Sampling struct {
+            Initial    int           `yaml:"initial"`    // 0 disables sampling
+            Thereafter int           `yaml:"thereafter"`
+            Window     time.Duration `yaml:"window"`
+        } `yaml:"sampling"` // debug/info only, per message
+    } `yaml:"logging"`
+
+    // Metrics configures persistence of request metrics to observability_metrics
+    // and their per-minute/per-hour rollups.
+    Metrics struct {
+        Region string `yaml:"region"` // stored with each sample (default us-east-1)
+        Writer struct {
+            BatchSize     int           `yaml:"batch_size"`     // default 100
+            BufferSize    int           `yaml:"buffer_size"`    // default 1000; samples beyond are dropped
+            FlushInterval time.Duration `yaml:"flush_interval"` // default 1s
+        } `yaml:"writer"`
+        Rollup struct {
+            Interval        time.Duration `yaml:"interval"`         // 0 disables rollups and retention
+            RawRetention    time.Duration `yaml:"raw_retention"`    // 0 keeps forever
+            MinuteRetention time.Duration `yaml:"minute_retention"` // 0 keeps forever
+            HourRetention   time.Duration `yaml:"hour_retention"`   // 0 keeps forever
+        } `yaml:"rollup"`
+    } `yaml:"metrics"`
+
+    // OpenAPI selects how requests (and, in strict mode, responses) are checked
+    // against openapi/openapi.yaml: off (default) | requests | strict
+    OpenAPI struct {
+        Validation   string `yaml:"validation"`
+        MaxBodyBytes int64  `yaml:"max_body_bytes"` // larger request bodies are rejected; default 1 MiB
+    } `yaml:"openapi"`
+
+    // GRPC serves the v2 record operations on their own listener; empty Addr
+    // disables it. WatchChanges polls for new changes every WatchInterval.
+    GRPC struct {
+        Addr          string        `yaml:"addr"`           // e.g. :9090
+        WatchInterval time.Duration `yaml:"watch_interval"` // default 1s
+    } `yaml:"grpc"`
+
+    // GraphQL bounds /api/v2/graphql queries before they run: MaxCost is the
+    // estimated fields read (page sizes multiply nested lists), MaxDepth the nesting.
+    GraphQL struct {
+        MaxCost  int `yaml:"max_cost"`  // default 2000
+        MaxDepth int `yaml:"max_depth"` // default 10
+    } `yaml:"graphql"`
+
+    // Auth identifies v2 callers with the methods listed, tried in order:
+    // api_key (X-API-Key), jwt (Authorization: Bearer) and header, which
+    // trusts X-User-ID and is only accepted in development. Empty Methods means
+    // api_key, plus jwt when JWT keys are configured.
+    Auth struct {
+        Methods []string `yaml:"methods"`
+        JWT     struct {
+            Issuer      string        `yaml:"issuer"`   // checked against iss when set
+            Audience    string        `yaml:"audience"` // must be in aud when set
+            Leeway      time.Duration `yaml:"leeway"`   // clock skew allowed for exp/nbf
+            Keys        []JWTKey      `yaml:"keys"`
+            JWKSFile    string        `yaml:"jwks_file"`    // RSA and oct keys, by kid
+            UserClaim   string        `yaml:"user_claim"`   // default sub
+            TenantClaim string        `yaml:"tenant_claim"` // default tenant
+            RolesClaim  string        `yaml:"roles_claim"`  // default roles
+        } `yaml:"jwt"`
+        DevRoles []string `yaml:"dev_roles"` // granted to X-User-ID callers (default admin)
+    } `yaml:"auth"`
+
+    // Authz maps roles to permissions (records:read, records:write, history:read,
+    // metrics:read, flags:refresh, api_versions:manage, api_keys:manage,
+    // assignments:manage or *). Roles listed here replace or add to the built-in
+    // reader, underwriter, auditor, broker and admin; ScopedRoles (default broker)
+    // act only on the policyholders assigned to the caller.
+    Authz struct {
+        Roles       map[string][]string `yaml:"roles"`
+        ScopedRoles []string            `yaml:"scoped_roles"`
+    } `yaml:"authz"`
+
+    // RateLimit throttles each authenticated user with a token bucket per route
+    // class (read, write, admin), over HTTP v2 and gRPC. A class without
+    // per_minute is unlimited. Overrides per user are stored in SQLite by the
+    // rate-limits admin endpoints and re-read every ReloadInterval.
+    RateLimit struct {
+        Read           RateLimit     `yaml:"read"`
+        Write          RateLimit     `yaml:"write"`
+        Admin          RateLimit     `yaml:"admin"`
+        ReloadInterval time.Duration `yaml:"reload_interval"` // default 30s
+    } `yaml:"rate_limit"`
+
+    // Capture samples requests and their answers into a replay log for
+    // `timetravel replay`; empty Path disables it. The file is rotated at
+    // MaxSizeMB, keeping MaxFiles older ones as path.1, path.2, ...
+    Capture struct {
+        Path          string   `yaml:"path"`
+        SampleRate    *float64 `yaml:"sample_rate"`    // default 1 (every request); 0 captures nothing
+        Paths         []string `yaml:"paths"`          // path prefixes (default /api/)
+        RedactHeaders []string `yaml:"redact_headers"` // default Authorization, Cookie, X-Api-Key
+        RedactKeys    []string `yaml:"redact_keys"`    // JSON keys at any depth in request and response bodies
+        MaxBodyBytes  int      `yaml:"max_body_bytes"` // default 65536
+        MaxSizeMB     int      `yaml:"max_size_mb"`    // default 100
+        MaxFiles      int      `yaml:"max_files"`      // default 5
+    } `yaml:"capture"`
+
+    // CLI is read by the record subcommands (`timetravel get 7`, ...): the server
+    // they call and the X-API-Key or X-User-ID they send; --server, --api-key and
+    // --user override them.
+    CLI struct {
+        Server string `yaml:"server"`  // default http://localhost:8000
+        UserID int64  `yaml:"user_id"`
+        APIKey string `yaml:"api_key"` // sent instead of user_id when set
+    } `yaml:"cli"`
+
+    // Tracing configures OpenTelemetry export; spans are dropped when exporter is none.
+    Tracing struct {
+        Exporter    string  `yaml:"exporter"`     // none (default) | stdout
+        SampleRatio float64 `yaml:"sample_ratio"` // 0 means always sample; parent decisions are honoured
+        ServiceName string  `yaml:"service_name"` // defaults to timetravel
+    } `yaml:"tracing"`
+
+    // FeatureFlags selects where flag definitions come from.
+    // provider: sqlite (default) | file | static | layered
+    FeatureFlags struct {
+        Provider      string                  `yaml:"provider"`
+        File          string                  `yaml:"file"`           // YAML or JSON flag file
+        WatchInterval time.Duration           `yaml:"watch_interval"` // 0 disables file watching
+        Static        map[string]FlagOverride `yaml:"static"`
+    } `yaml:"feature_flags"`
+}
+
+// JWTKey is a locally configured token key: an HS256 secret (inline or in a
+// file) or an RS256 public key PEM file
+type JWTKey struct {
+    KID           string `yaml:"kid"`
+    Alg           string `yaml:"alg"` // HS256 | RS256
+    Secret        string `yaml:"secret"`
+    SecretFile    string `yaml:"secret_file"`
+    PublicKeyFile string `yaml:"public_key_file"`
+}
+
+// RateLimit refills PerMinute tokens a minute into a bucket of Burst tokens
+type RateLimit struct {
+    PerMinute int `yaml:"per_minute"`
+    Burst     int `yaml:"burst"` // default one minute's worth
+}
+
+// FlagOverride is a flag definition declared inline in config
+type FlagOverride struct {
+    Enabled           bool `yaml:"enabled"`
+    RolloutPercentage *int `yaml:"rollout_percentage"`
 }
 
 func LoadConfig(path string) *Config {
@@ -83,3 +83,40 @@ database:
 		})
 	}
 }
+
+// the deployable config runs as production without trusting X-User-ID; the dev
+// config does both the other way round
+func TestShippedConfigs(t *testing.T) {
+	tests := []struct {
+		file       string
+		wantHeader bool
+	}{
+		{"config.yaml", false},
+		{"config.dev.yaml", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			cfg := LoadConfig(tt.file)
+			if cfg.Database.Path == "./db/timetravel.db" {
+				t.Error("database.path is the checked-in fixture db/timetravel.db")
+			}
+			header := false
+			for _, m := range cfg.Auth.Methods {
+				header = header || m == "header"
+			}
+			if header != tt.wantHeader {
+				t.Errorf("auth.methods = %v, header enabled = %v, want %v", cfg.Auth.Methods, header, tt.wantHeader)
+			}
+			if !tt.wantHeader && cfg.Auth.DevRoles != nil {
+				t.Errorf("auth.dev_roles = %v, want unset", cfg.Auth.DevRoles)
+			}
+			wantEnv := "production"
+			if tt.wantHeader {
+				wantEnv = "development"
+			}
+			if cfg.Environment != wantEnv {
+				t.Errorf("environment = %q, want %s", cfg.Environment, wantEnv)
+			}
+		})
+	}
+}
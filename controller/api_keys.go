@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/entity"
+	"github.com/rainbowmga/timetravel/service"
+)
+
+// maxRotationGrace bounds how long a rotated key keeps working
+const maxRotationGrace = 30 * 24 * time.Hour
+
+var (
+	ErrAPIKeyDoesNotExist  = service.ErrAPIKeyDoesNotExist
+	ErrAPIKeyNameRequired  = apperr.InvalidArgument("name is required")
+	ErrAPIKeyUserInvalid   = apperr.InvalidArgument("user_id must be a positive integer")
+	ErrAPIKeyRoleInvalid   = apperr.InvalidArgument("roles must be non-empty names without spaces")
+	ErrAPIKeyExpiryInvalid = apperr.InvalidArgument("expires_at must be in the future")
+	ErrAPIKeyGraceInvalid  = apperr.InvalidArgument("grace_period_seconds must be between 0 and 30 days")
+)
+
+// APIKeyController manages API keys for the admin endpoints
+type APIKeyController struct {
+	service service.APIKeyServiceInterface
+}
+
+// NewAPIKeyControllerWithService allows injecting a mock service for testing
+func NewAPIKeyControllerWithService(svc service.APIKeyServiceInterface) *APIKeyController {
+	return &APIKeyController{service: svc}
+}
+
+// CreateAPIKey issues a key; the returned secret is not stored anywhere
+func (c *APIKeyController) CreateAPIKey(ctx context.Context, in service.APIKeyInput) (entity.APIKey, string, error) {
+	in.Name = strings.TrimSpace(in.Name)
+	if in.Name == "" {
+		return entity.APIKey{}, "", ErrAPIKeyNameRequired
+	}
+	if in.UserID <= 0 {
+		return entity.APIKey{}, "", ErrAPIKeyUserInvalid
+	}
+	if err := validRoles(in.Roles); err != nil {
+		return entity.APIKey{}, "", err
+	}
+	if in.ExpiresAt != nil && !in.ExpiresAt.After(time.Now()) {
+		return entity.APIKey{}, "", ErrAPIKeyExpiryInvalid
+	}
+
+	key, secret, err := c.service.Create(ctx, in)
+	return key, secret, storeError(ctx, err)
+}
+
+// ListAPIKeys returns every key without secrets
+func (c *APIKeyController) ListAPIKeys(ctx context.Context) ([]entity.APIKey, error) {
+	keys, err := c.service.List(ctx)
+	return keys, storeError(ctx, err)
+}
+
+// GetAPIKey returns one key without its secret
+func (c *APIKeyController) GetAPIKey(ctx context.Context, id int64) (entity.APIKey, error) {
+	key, err := c.service.Get(ctx, id)
+	return key, storeError(ctx, err)
+}
+
+// UpdateAPIKey renames a key or changes its tenant or roles
+func (c *APIKeyController) UpdateAPIKey(ctx context.Context, id int64, u service.APIKeyUpdate) (entity.APIKey, error) {
+	if u.Name != nil {
+		name := strings.TrimSpace(*u.Name)
+		if name == "" {
+			return entity.APIKey{}, ErrAPIKeyNameRequired
+		}
+		u.Name = &name
+	}
+	if err := validRoles(u.Roles); err != nil {
+		return entity.APIKey{}, err
+	}
+	key, err := c.service.Update(ctx, id, u)
+	return key, storeError(ctx, err)
+}
+
+// RevokeAPIKey ends a key at once
+func (c *APIKeyController) RevokeAPIKey(ctx context.Context, id int64) (entity.APIKey, error) {
+	key, err := c.service.Revoke(ctx, id)
+	return key, storeError(ctx, err)
+}
+
+// RotateAPIKey issues a replacement key; the old one works for grace more
+func (c *APIKeyController) RotateAPIKey(ctx context.Context, id int64, grace time.Duration) (entity.APIKey, string, error) {
+	if grace < 0 || grace > maxRotationGrace {
+		return entity.APIKey{}, "", ErrAPIKeyGraceInvalid
+	}
+	key, secret, err := c.service.Rotate(ctx, id, grace)
+	return key, secret, storeError(ctx, err)
+}
+
+func validRoles(roles []string) error {
+	for _, r := range roles {
+		if r == "" || strings.ContainsAny(r, " \t\n") {
+			return ErrAPIKeyRoleInvalid
+		}
+	}
+	return nil
+}
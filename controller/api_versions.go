@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"context"
+	"github.com/rainbowmga/timetravel/apperr"
+
+	"github.com/rainbowmga/timetravel/entity"
+	"github.com/rainbowmga/timetravel/service"
+)
+
+var ErrAPIVersionDoesNotExist = service.ErrAPIVersionDoesNotExist
+var ErrRolloutPercentageInvalid = apperr.InvalidArgument("rollout_percentage must be between 0 and 100")
+
+// APIVersionController exposes v1/v2 traffic shifting
+type APIVersionController struct {
+	service service.APIVersionServiceInterface
+}
+
+// NewAPIVersionControllerWithService allows injecting a mock service for testing
+func NewAPIVersionControllerWithService(svc service.APIVersionServiceInterface) *APIVersionController {
+	return &APIVersionController{service: svc}
+}
+
+// NewAPIVersionController loads the api_versions rollout config
+func NewAPIVersionController(dbPath string) (*APIVersionController, error) {
+	svc, err := service.NewAPIVersionService(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	return &APIVersionController{service: svc}, nil
+}
+
+// Resolve returns the version that should serve an unversioned request
+func (c *APIVersionController) Resolve(userID int64, hasUser bool) string {
+	return c.service.Resolve(userID, hasUser)
+}
+
+// ListVersions returns every configured API version
+func (c *APIVersionController) ListVersions(ctx context.Context) []entity.APIVersionConfig {
+	return c.service.List()
+}
+
+// UpdateVersion shifts traffic to or away from a version
+func (c *APIVersionController) UpdateVersion(ctx context.Context, version string, isActive bool, rolloutPercentage int) (entity.APIVersionConfig, error) {
+	if rolloutPercentage < 0 || rolloutPercentage > 100 {
+		return entity.APIVersionConfig{}, ErrRolloutPercentageInvalid
+	}
+
+	cfg, err := c.service.Update(ctx, version, isActive, rolloutPercentage)
+	if err != nil {
+		return entity.APIVersionConfig{}, storeError(ctx, err)
+	}
+	return cfg, nil
+}
+
+// Refresh reloads the rollout config from the DB
+func (c *APIVersionController) Refresh(ctx context.Context) error {
+	return c.service.Refresh(ctx)
+}
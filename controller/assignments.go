@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/entity"
+	"github.com/rainbowmga/timetravel/service"
+)
+
+var (
+	ErrAssignmentDoesNotExist        = service.ErrAssignmentDoesNotExist
+	ErrAssignmentUserInvalid         = apperr.InvalidArgument("user_id must be a positive integer")
+	ErrAssignmentPolicyholderInvalid = apperr.InvalidArgument("policyholder_id must be a positive integer")
+)
+
+// AssignmentController manages which policyholders scoped roles may act on
+type AssignmentController struct {
+	service service.AuthzServiceInterface
+}
+
+// NewAssignmentControllerWithService allows injecting a mock service for testing
+func NewAssignmentControllerWithService(svc service.AuthzServiceInterface) *AssignmentController {
+	return &AssignmentController{service: svc}
+}
+
+// ListAssignments returns the policyholders assigned to a user
+func (c *AssignmentController) ListAssignments(ctx context.Context, userID int64) ([]entity.PolicyholderAssignment, error) {
+	if userID <= 0 {
+		return nil, ErrAssignmentUserInvalid
+	}
+	assignments, err := c.service.ListAssignments(ctx, userID)
+	return assignments, storeError(ctx, err)
+}
+
+// Assign gives a user access to a policyholder
+func (c *AssignmentController) Assign(ctx context.Context, userID, policyholderID int64) (entity.PolicyholderAssignment, error) {
+	if err := validAssignment(userID, policyholderID); err != nil {
+		return entity.PolicyholderAssignment{}, err
+	}
+	a, err := c.service.Assign(ctx, userID, policyholderID)
+	return a, storeError(ctx, err)
+}
+
+// Unassign takes a policyholder away from a user
+func (c *AssignmentController) Unassign(ctx context.Context, userID, policyholderID int64) error {
+	if err := validAssignment(userID, policyholderID); err != nil {
+		return err
+	}
+	return storeError(ctx, c.service.Unassign(ctx, userID, policyholderID))
+}
+
+func validAssignment(userID, policyholderID int64) error {
+	if userID <= 0 {
+		return ErrAssignmentUserInvalid
+	}
+	if policyholderID <= 0 {
+		return ErrAssignmentPolicyholderInvalid
+	}
+	return nil
+}
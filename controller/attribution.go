@@ -0,0 +1,40 @@
+package controller
+
+import (
+	"context"
+	"unicode/utf8"
+
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/common"
+)
+
+// MaxChangeReasonLength bounds the free-text reason stored with a version, in characters
+const MaxChangeReasonLength = 500
+
+var (
+	ErrChangedByInvalid    = apperr.InvalidArgument("changed_by must be a positive integer")
+	ErrChangeSourceInvalid = apperr.InvalidArgument("change source must be import or revert")
+	ErrChangeReasonInvalid = apperr.InvalidArgument("change reason must be valid UTF-8 of at most 500 characters")
+)
+
+// WithChange attributes the writes made under ctx. source is the path the
+// request came in by (common.SourceAPIV2); a client may narrow it to import or
+// revert with declared. reason is the client's optional free text.
+func WithChange(ctx context.Context, source, declared, reason string) (context.Context, error) {
+	switch declared {
+	case "":
+	case common.SourceImport, common.SourceRevert:
+		source = declared
+	default:
+		return ctx, ErrChangeSourceInvalid
+	}
+	if !utf8.ValidString(reason) || utf8.RuneCountInString(reason) > MaxChangeReasonLength {
+		return ctx, ErrChangeReasonInvalid
+	}
+
+	ctx = context.WithValue(ctx, common.ChangeSourceKey, source)
+	if reason != "" {
+		ctx = context.WithValue(ctx, common.ChangeReasonKey, reason)
+	}
+	return ctx, nil
+}
@@ -0,0 +1,47 @@
+package controller_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rainbowmga/timetravel/common"
+	"github.com/rainbowmga/timetravel/controller"
+)
+
+func TestWithChange(t *testing.T) {
+	tests := []struct {
+		name       string
+		declared   string
+		reason     string
+		wantSource string
+		wantErr    error
+	}{
+		{"api write", "", "", common.SourceAPIV2, nil},
+		{"declared import", common.SourceImport, "", common.SourceImport, nil},
+		{"declared revert with reason", common.SourceRevert, "restore address", common.SourceRevert, nil},
+		{"reason at the limit", "", strings.Repeat("é", controller.MaxChangeReasonLength), common.SourceAPIV2, nil},
+		{"undeclarable source", common.SourceAPIV1, "", "", controller.ErrChangeSourceInvalid},
+		{"unknown source", "sync", "", "", controller.ErrChangeSourceInvalid},
+		{"reason too long", "", strings.Repeat("x", controller.MaxChangeReasonLength+1), "", controller.ErrChangeReasonInvalid},
+		{"reason not UTF-8", "", "\xff", "", controller.ErrChangeReasonInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, err := controller.WithChange(context.Background(), common.SourceAPIV2, tt.declared, tt.reason)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("WithChange() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if source, _ := common.GetChangeSource(ctx); source != tt.wantSource {
+				t.Errorf("source = %q, want %q", source, tt.wantSource)
+			}
+			if reason, _ := common.GetChangeReason(ctx); reason != tt.reason {
+				t.Errorf("reason = %q, want %q", reason, tt.reason)
+			}
+		})
+	}
+}
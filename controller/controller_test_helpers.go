@@ -1,6 +1,10 @@
 package controller
 
-import "github.com/rainbowmga/timetravel/observability"
+import (
+	"context"
+
+	"github.com/rainbowmga/timetravel/observability"
+)
 
 // Mock logger implements LoggerInterface
 type mockLogger struct {
@@ -12,6 +16,14 @@ func (m *mockLogger) Debug(msg string, keysAndValues ...interface{}) {}
 func (m *mockLogger) Info(msg string, keysAndValues ...interface{})  { m.Infos = append(m.Infos, msg) }
 func (m *mockLogger) Warn(msg string, keysAndValues ...interface{})  {}
 func (m *mockLogger) Error(msg string, keysAndValues ...interface{}) { m.Errors = append(m.Errors, msg) }
+func (m *mockLogger) DebugContext(ctx context.Context, msg string, keysAndValues ...interface{}) {}
+func (m *mockLogger) InfoContext(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	m.Infos = append(m.Infos, msg)
+}
+func (m *mockLogger) WarnContext(ctx context.Context, msg string, keysAndValues ...interface{}) {}
+func (m *mockLogger) ErrorContext(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	m.Errors = append(m.Errors, msg)
+}
 
 // setLoggerForTest temporarily swaps DefaultLogger for testing
 func setLoggerForTest(l observability.LoggerInterface) func() {
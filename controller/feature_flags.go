@@ -36,7 +36,7 @@ func NewFeatureFlagController(dbPath string) (*FeatureFlagController, error) {
 func (c *FeatureFlagController) IsEnabled(ctx context.Context, flag string) bool {
 	userID, ok := common.GetUserID(ctx)
 	if !ok {
-		observability.DefaultLogger.Error("IsEnabled failed to fetch context error")
+		observability.DefaultLogger.ErrorContext(ctx, "feature_flag_missing_user_context", "flag", flag)
 		return false
 	}
 	return c.service.IsEnabled(flag,userID);
@@ -7,7 +7,7 @@ import (
 
 	"github.com/rainbowmga/timetravel/common"
 	"database/sql"
-	"os"
+	"path/filepath"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -90,9 +90,7 @@ func TestFeatureFlagController_IsEnabled(t *testing.T) {
 
 func TestFeatureFlagController_Integration(t *testing.T) {
 	// temporary SQLite file
-	dbFile := "test_flags.db"
-	os.Remove(dbFile)
-	defer os.Remove(dbFile)
+	dbFile := filepath.Join(t.TempDir(), "test_flags.db")
 
 	db, err := sql.Open("sqlite3", dbFile)
 	if err != nil {
@@ -0,0 +1,155 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/entity"
+	"github.com/rainbowmga/timetravel/service"
+)
+
+const (
+	// DefaultPageSize is the page size when a listing does not ask for one
+	DefaultPageSize = 20
+	// MaxPageSize caps one page of a history listing
+	MaxPageSize = 100
+)
+
+var (
+	ErrPolicyholderDoesNotExist = service.ErrPolicyholderDoesNotExist
+	ErrPolicyholderIDInvalid    = apperr.InvalidArgument("policyholder id must be positive")
+	ErrPageSizeInvalid          = apperr.InvalidArgument(fmt.Sprintf("page size must be between 1 and %d", MaxPageSize))
+)
+
+// field change kinds reported by DiffData
+const (
+	FieldAdded   = "added"
+	FieldRemoved = "removed"
+	FieldChanged = "changed"
+)
+
+// HistoryReader reads policyholders, records and their history (service.HistoryService)
+type HistoryReader interface {
+	GetPolicyholder(ctx context.Context, id int64) (*entity.Policyholder, error)
+	ListPolicyholders(ctx context.Context, afterID int64, limit int) ([]entity.Policyholder, error)
+	GetRecordAsOf(ctx context.Context, policyholderID int64, asOf time.Time) (*entity.PolicyholderRecord, error)
+	ListAuditEntries(ctx context.Context, recordID int64, afterVersion int, asOf time.Time, limit int) ([]entity.AuditEntry, error)
+	ListEvents(ctx context.Context, recordID, afterID int64, asOf time.Time, limit int) ([]entity.EventLog, error)
+	EventsAt(ctx context.Context, recordID int64, at time.Time) ([]entity.EventLog, error)
+}
+
+var _ HistoryReader = (*service.HistoryService)(nil)
+
+// HistoryController serves read-only history exploration. Listings return one
+// page plus whether more rows follow; a zero asOf means "now".
+type HistoryController struct {
+	reader HistoryReader
+}
+
+// NewHistoryController wraps a history reader
+func NewHistoryController(reader HistoryReader) *HistoryController {
+	return &HistoryController{reader: reader}
+}
+
+// Policyholder returns one policyholder
+func (c *HistoryController) Policyholder(ctx context.Context, id int64) (entity.Policyholder, error) {
+	if id <= 0 {
+		return entity.Policyholder{}, ErrPolicyholderIDInvalid
+	}
+	p, err := c.reader.GetPolicyholder(ctx, id)
+	if err != nil {
+		return entity.Policyholder{}, storeError(ctx, err)
+	}
+	return *p, nil
+}
+
+// Policyholders returns up to first policyholders with ids after afterID
+func (c *HistoryController) Policyholders(ctx context.Context, afterID int64, first int) ([]entity.Policyholder, bool, error) {
+	if err := checkPageSize(first); err != nil {
+		return nil, false, err
+	}
+	holders, err := c.reader.ListPolicyholders(ctx, afterID, first+1)
+	if err != nil {
+		return nil, false, storeError(ctx, err)
+	}
+	return page(holders, first)
+}
+
+// Record returns a policyholder's record as it was at asOf
+func (c *HistoryController) Record(ctx context.Context, policyholderID int64, asOf time.Time) (entity.PolicyholderRecord, error) {
+	if policyholderID <= 0 {
+		return entity.PolicyholderRecord{}, ErrPolicyholderIDInvalid
+	}
+	rec, err := c.reader.GetRecordAsOf(ctx, policyholderID, asOf)
+	if err != nil {
+		return entity.PolicyholderRecord{}, storeError(ctx, err)
+	}
+	return *rec, nil
+}
+
+// Versions returns up to first versions of a record after afterVersion, written by asOf
+func (c *HistoryController) Versions(ctx context.Context, recordID int64, afterVersion int, asOf time.Time, first int) ([]entity.AuditEntry, bool, error) {
+	if err := checkPageSize(first); err != nil {
+		return nil, false, err
+	}
+	entries, err := c.reader.ListAuditEntries(ctx, recordID, afterVersion, asOf, first+1)
+	if err != nil {
+		return nil, false, storeError(ctx, err)
+	}
+	return page(entries, first)
+}
+
+// Events returns up to first event log entries of a record after afterID, logged by asOf
+func (c *HistoryController) Events(ctx context.Context, recordID, afterID int64, asOf time.Time, first int) ([]entity.EventLog, bool, error) {
+	if err := checkPageSize(first); err != nil {
+		return nil, false, err
+	}
+	events, err := c.reader.ListEvents(ctx, recordID, afterID, asOf, first+1)
+	if err != nil {
+		return nil, false, storeError(ctx, err)
+	}
+	return page(events, first)
+}
+
+// VersionEvents returns the event log entries written together with a version
+func (c *HistoryController) VersionEvents(ctx context.Context, entry entity.AuditEntry) ([]entity.EventLog, error) {
+	events, err := c.reader.EventsAt(ctx, entry.RecordID, entry.ChangedAt)
+	return events, storeError(ctx, err)
+}
+
+// DiffData lists the keys that differ between two versions' data, sorted by key
+func DiffData(before, after map[string]string) []entity.FieldChange {
+	changes := []entity.FieldChange{}
+	for k, b := range before {
+		if a, ok := after[k]; !ok {
+			changes = append(changes, entity.FieldChange{Key: k, Kind: FieldRemoved, Before: &b})
+		} else if a != b {
+			changes = append(changes, entity.FieldChange{Key: k, Kind: FieldChanged, Before: &b, After: &a})
+		}
+	}
+	for k, a := range after {
+		if _, ok := before[k]; !ok {
+			changes = append(changes, entity.FieldChange{Key: k, Kind: FieldAdded, After: &a})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+func checkPageSize(first int) error {
+	if first < 1 || first > MaxPageSize {
+		return ErrPageSizeInvalid
+	}
+	return nil
+}
+
+// page trims the extra row fetched to learn whether another page follows
+func page[T any](rows []T, first int) ([]T, bool, error) {
+	if len(rows) > first {
+		return rows[:first], true, nil
+	}
+	return rows, false, nil
+}
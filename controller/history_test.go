@@ -0,0 +1,129 @@
+package controller_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rainbowmga/timetravel/controller"
+	"github.com/rainbowmga/timetravel/entity"
+)
+
+type mockHistoryReader struct {
+	holders []entity.Policyholder
+	limit   int
+	err     error
+}
+
+func (m *mockHistoryReader) GetPolicyholder(ctx context.Context, id int64) (*entity.Policyholder, error) {
+	for _, p := range m.holders {
+		if p.ID == id {
+			return &p, nil
+		}
+	}
+	return nil, controller.ErrPolicyholderDoesNotExist
+}
+
+func (m *mockHistoryReader) ListPolicyholders(ctx context.Context, afterID int64, limit int) ([]entity.Policyholder, error) {
+	m.limit = limit
+	var out []entity.Policyholder
+	for _, p := range m.holders {
+		if p.ID > afterID && len(out) < limit {
+			out = append(out, p)
+		}
+	}
+	return out, m.err
+}
+
+func (m *mockHistoryReader) GetRecordAsOf(ctx context.Context, id int64, asOf time.Time) (*entity.PolicyholderRecord, error) {
+	return nil, m.err
+}
+
+func (m *mockHistoryReader) ListAuditEntries(ctx context.Context, recordID int64, after int, asOf time.Time, limit int) ([]entity.AuditEntry, error) {
+	return nil, m.err
+}
+
+func (m *mockHistoryReader) ListEvents(ctx context.Context, recordID, afterID int64, asOf time.Time, limit int) ([]entity.EventLog, error) {
+	return nil, m.err
+}
+
+func (m *mockHistoryReader) EventsAt(ctx context.Context, recordID int64, at time.Time) ([]entity.EventLog, error) {
+	return nil, m.err
+}
+
+func TestHistoryController_Paging(t *testing.T) {
+	reader := &mockHistoryReader{holders: []entity.Policyholder{{ID: 1}, {ID: 2}, {ID: 3}}}
+	c := controller.NewHistoryController(reader)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		after    int64
+		first    int
+		wantIDs  []int64
+		wantMore bool
+		wantErr  error
+	}{
+		{"first page", 0, 2, []int64{1, 2}, true, nil},
+		{"last page", 2, 2, []int64{3}, false, nil},
+		{"exact fit", 0, 3, []int64{1, 2, 3}, false, nil},
+		{"zero page size", 0, 0, nil, false, controller.ErrPageSizeInvalid},
+		{"page size over the cap", 0, controller.MaxPageSize + 1, nil, false, controller.ErrPageSizeInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			holders, more, err := c.Policyholders(ctx, tt.after, tt.first)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			var ids []int64
+			for _, p := range holders {
+				ids = append(ids, p.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) || more != tt.wantMore {
+				t.Errorf("got %v more=%v, want %v more=%v", ids, more, tt.wantIDs, tt.wantMore)
+			}
+			if tt.wantErr == nil && reader.limit != tt.first+1 {
+				t.Errorf("reader asked for %d rows, want first+1", reader.limit)
+			}
+		})
+	}
+
+	if _, err := c.Policyholder(ctx, 0); !errors.Is(err, controller.ErrPolicyholderIDInvalid) {
+		t.Errorf("Policyholder(0) err = %v", err)
+	}
+	if _, err := c.Policyholder(ctx, 9); !errors.Is(err, controller.ErrPolicyholderDoesNotExist) {
+		t.Errorf("Policyholder(9) err = %v", err)
+	}
+}
+
+func TestHistoryController_MapsStoreErrors(t *testing.T) {
+	c := controller.NewHistoryController(&mockHistoryReader{err: context.DeadlineExceeded})
+
+	if _, _, err := c.Versions(context.Background(), 1, 0, time.Time{}, 10); !errors.Is(err, controller.ErrRequestTimeout) {
+		t.Errorf("Versions err = %v, want ErrRequestTimeout", err)
+	}
+}
+
+func TestDiffData(t *testing.T) {
+	str := func(s string) *string { return &s }
+
+	got := controller.DiffData(
+		map[string]string{"name": "ann", "city": "oslo", "zip": "0150"},
+		map[string]string{"name": "bea", "zip": "0150", "phone": "123"},
+	)
+	want := []entity.FieldChange{
+		{Key: "city", Kind: controller.FieldRemoved, Before: str("oslo")},
+		{Key: "name", Kind: controller.FieldChanged, Before: str("ann"), After: str("bea")},
+		{Key: "phone", Kind: controller.FieldAdded, After: str("123")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffData() = %+v, want %+v", got, want)
+	}
+
+	if first := controller.DiffData(nil, map[string]string{"a": "1"}); len(first) != 1 || first[0].Kind != controller.FieldAdded {
+		t.Errorf("first version diff = %+v", first)
+	}
+}
@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"context"
+	"github.com/rainbowmga/timetravel/apperr"
+	"time"
+
+	"github.com/rainbowmga/timetravel/entity"
+	"github.com/rainbowmga/timetravel/gateways"
+)
+
+var (
+	ErrMetricNameRequired = apperr.InvalidArgument("metric name is required")
+	ErrMetricRangeInvalid = apperr.InvalidArgument("from must be before to")
+	ErrMetricStepInvalid  = apperr.InvalidArgument("step must be a positive whole number of minutes")
+	ErrMetricTooManySteps = apperr.InvalidArgument("range/step yields too many points")
+)
+
+// maxMetricPoints caps a single query's response size
+const maxMetricPoints = 10000
+
+// MetricsQuerier reads the per-minute/per-hour rollups (gateways.MetricsRepository)
+type MetricsQuerier interface {
+	QueryRollups(ctx context.Context, name, region, resolution string, from, to time.Time) ([]entity.MetricRollup, error)
+}
+
+// MetricsQuery selects one metric over [From, To) in Step-sized buckets
+type MetricsQuery struct {
+	Name   string
+	Region string // empty aggregates all regions
+	From   time.Time
+	To     time.Time
+	Step   time.Duration
+}
+
+// MetricsController serves historical metrics from the rollup store
+type MetricsController struct {
+	repo MetricsQuerier
+}
+
+// NewMetricsController wraps a rollup reader
+func NewMetricsController(repo MetricsQuerier) *MetricsController {
+	return &MetricsController{repo: repo}
+}
+
+// QueryMetric reads the coarsest rollup that divides the step and merges it into step buckets
+func (c *MetricsController) QueryMetric(ctx context.Context, q MetricsQuery) ([]entity.MetricRollup, error) {
+	if q.Name == "" {
+		return nil, ErrMetricNameRequired
+	}
+	if !q.From.Before(q.To) {
+		return nil, ErrMetricRangeInvalid
+	}
+	if q.Step <= 0 || q.Step%time.Minute != 0 {
+		return nil, ErrMetricStepInvalid
+	}
+	if q.To.Sub(q.From)/q.Step > maxMetricPoints {
+		return nil, ErrMetricTooManySteps
+	}
+
+	resolution := gateways.ResolutionMinute
+	if q.Step%time.Hour == 0 {
+		resolution = gateways.ResolutionHour
+	}
+
+	rollups, err := c.repo.QueryRollups(ctx, q.Name, q.Region, resolution, q.From, q.To)
+	if err != nil {
+		return nil, storeError(ctx, err)
+	}
+	return mergeRollups(rollups, q.Step), nil
+}
+
+// mergeRollups regroups ordered rollups into step-aligned (UTC) buckets
+func mergeRollups(rollups []entity.MetricRollup, step time.Duration) []entity.MetricRollup {
+	points := []entity.MetricRollup{}
+	for _, r := range rollups {
+		bucket := r.BucketStart.UTC().Truncate(step)
+		if n := len(points); n > 0 && points[n-1].BucketStart.Equal(bucket) {
+			p := &points[n-1]
+			p.Count += r.Count
+			p.Sum += r.Sum
+			if r.Min < p.Min {
+				p.Min = r.Min
+			}
+			if r.Max > p.Max {
+				p.Max = r.Max
+			}
+			continue
+		}
+		r.BucketStart = bucket
+		points = append(points, r)
+	}
+	return points
+}
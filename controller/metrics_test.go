@@ -0,0 +1,80 @@
+package controller_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rainbowmga/timetravel/controller"
+	"github.com/rainbowmga/timetravel/entity"
+	"github.com/rainbowmga/timetravel/gateways"
+)
+
+type mockMetricsQuerier struct {
+	resolution string
+	rollups    []entity.MetricRollup
+}
+
+func (m *mockMetricsQuerier) QueryRollups(ctx context.Context, name, region, resolution string, from, to time.Time) ([]entity.MetricRollup, error) {
+	m.resolution = resolution
+	return m.rollups, nil
+}
+
+func TestMetricsController_QueryMetric(t *testing.T) {
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	minute := func(offset int, count int64, sum, min, max float64) entity.MetricRollup {
+		return entity.MetricRollup{BucketStart: base.Add(time.Duration(offset) * time.Minute), Count: count, Sum: sum, Min: min, Max: max}
+	}
+
+	repo := &mockMetricsQuerier{rollups: []entity.MetricRollup{
+		minute(0, 2, 4, 1, 3),
+		minute(3, 1, 9, 9, 9),
+		minute(5, 1, 5, 5, 5),
+	}}
+	c := controller.NewMetricsController(repo)
+
+	points, err := c.QueryMetric(context.Background(), controller.MetricsQuery{
+		Name: "latency", From: base, To: base.Add(10 * time.Minute), Step: 5 * time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("QueryMetric() error: %v", err)
+	}
+	if repo.resolution != gateways.ResolutionMinute {
+		t.Errorf("expected minute rollups for a 5m step, got %s", repo.resolution)
+	}
+	if len(points) != 2 {
+		t.Fatalf("expected 2 merged points, got %+v", points)
+	}
+	if p := points[0]; !p.BucketStart.Equal(base) || p.Count != 3 || p.Sum != 13 || p.Min != 1 || p.Max != 9 {
+		t.Errorf("unexpected merged point: %+v", p)
+	}
+
+	if _, err := c.QueryMetric(context.Background(), controller.MetricsQuery{
+		Name: "latency", From: base, To: base.Add(48 * time.Hour), Step: 2 * time.Hour,
+	}); err != nil || repo.resolution != gateways.ResolutionHour {
+		t.Errorf("expected hour rollups for a 2h step, got %s, %v", repo.resolution, err)
+	}
+}
+
+func TestMetricsController_QueryMetricValidation(t *testing.T) {
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	c := controller.NewMetricsController(&mockMetricsQuerier{})
+
+	tests := []struct {
+		name string
+		q    controller.MetricsQuery
+		want error
+	}{
+		{"missing name", controller.MetricsQuery{From: base, To: base.Add(time.Hour), Step: time.Minute}, controller.ErrMetricNameRequired},
+		{"inverted range", controller.MetricsQuery{Name: "m", From: base, To: base, Step: time.Minute}, controller.ErrMetricRangeInvalid},
+		{"sub-minute step", controller.MetricsQuery{Name: "m", From: base, To: base.Add(time.Hour), Step: 30 * time.Second}, controller.ErrMetricStepInvalid},
+		{"too many points", controller.MetricsQuery{Name: "m", From: base, To: base.Add(365 * 24 * time.Hour), Step: time.Minute}, controller.ErrMetricTooManySteps},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := c.QueryMetric(context.Background(), tt.q); err != tt.want {
+				t.Errorf("QueryMetric() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"github.com/rainbowmga/timetravel/common"
+	"github.com/rainbowmga/timetravel/entity"
+	"github.com/rainbowmga/timetravel/observability"
+	"github.com/rainbowmga/timetravel/service"
+)
+
+// Flag keys for the v1 -> v2 migration mode
+const (
+	FlagV1DualWrite  = "enable_v1_dual_write"
+	FlagV1ShadowRead = "enable_v1_shadow_read"
+)
+
+// Shadow-read outcomes reported to observability.ShadowReadResult
+const (
+	shadowMatch    = "match"
+	shadowMismatch = "mismatch"
+	shadowError    = "error"
+)
+
+// MigratingRecordService wraps the v1 RecordService while v1 is retired.
+// v1 stays the source of truth: v2 failures and mismatches are logged and
+// counted but never change the v1 response.
+type MigratingRecordService struct {
+	primary RecordService
+	v2      SQLiteRecordServiceInterface
+	flags   service.FlagEvaluator
+}
+
+// Ensure MigratingRecordService implements RecordService
+var _ RecordService = (*MigratingRecordService)(nil)
+
+// NewMigratingRecordService mirrors v1 traffic to the v2 store when the flags are on
+func NewMigratingRecordService(primary RecordService, v2 SQLiteRecordServiceInterface, flags service.FlagEvaluator) *MigratingRecordService {
+	return &MigratingRecordService{primary: primary, v2: v2, flags: flags}
+}
+
+// v1 has no user context, so migration flags are evaluated globally
+func (s *MigratingRecordService) enabled(flag string) bool {
+	return s.flags != nil && s.flags.IsEnabledWithDefault(flag, 0, false)
+}
+
+func (s *MigratingRecordService) GetRecord(ctx context.Context, id int) (entity.Record, error) {
+	record, err := s.primary.GetRecord(ctx, id)
+	if s.enabled(FlagV1ShadowRead) {
+		s.shadowRead(ctx, id, record, err)
+	}
+	return record, err
+}
+
+func (s *MigratingRecordService) CreateRecord(ctx context.Context, record entity.Record) error {
+	if err := s.primary.CreateRecord(ctx, record); err != nil {
+		return err
+	}
+	if s.enabled(FlagV1DualWrite) {
+		s.dualWrite(ctx, record)
+	}
+	return nil
+}
+
+func (s *MigratingRecordService) UpdateRecord(ctx context.Context, id int, updates map[string]*string) (entity.Record, error) {
+	record, err := s.primary.UpdateRecord(ctx, id, updates)
+	if err != nil {
+		return record, err
+	}
+	if s.enabled(FlagV1DualWrite) {
+		// v2 upserts replace the whole document, so mirror the merged v1 result
+		s.dualWrite(ctx, record)
+	}
+	return record, nil
+}
+
+func (s *MigratingRecordService) dualWrite(ctx context.Context, record entity.Record) {
+	// v1 callers are anonymous: the versions record the source but no user
+	ctx = context.WithValue(ctx, common.ChangeSourceKey, common.SourceAPIV1)
+	if _, err := s.v2.CreateOrUpdate(ctx, int64(record.ID), record.Data); err != nil {
+		observability.DefaultLogger.ErrorContext(ctx, "dual_write_failed", "record_id", record.ID, "error", err)
+		observability.DualWriteResult("error")
+		return
+	}
+	observability.DualWriteResult("success")
+}
+
+// shadowRead compares the v1 result with the v2 store and reports the outcome
+func (s *MigratingRecordService) shadowRead(ctx context.Context, id int, v1Record entity.Record, v1Err error) {
+	v1Missing := errors.Is(v1Err, ErrRecordDoesNotExist)
+	if v1Err != nil && !v1Missing {
+		// nothing meaningful to compare against
+		return
+	}
+
+	v2Record, err := s.v2.Get(ctx, int64(id))
+	v2Missing := errors.Is(err, service.ErrRecordDoesNotExist) || errors.Is(err, ErrRecordDoesNotExist)
+	if err != nil && !v2Missing {
+		observability.DefaultLogger.ErrorContext(ctx, "shadow_read_failed", "record_id", id, "error", err)
+		observability.ShadowReadResult(shadowError)
+		return
+	}
+
+	switch {
+	case v1Missing && v2Missing:
+		observability.ShadowReadResult(shadowMatch)
+	case v1Missing:
+		observability.DefaultLogger.WarnContext(ctx, "shadow_read_mismatch", "record_id", id, "reason", "missing_in_v1")
+		observability.ShadowReadResult(shadowMismatch)
+	case v2Missing:
+		observability.DefaultLogger.WarnContext(ctx, "shadow_read_mismatch", "record_id", id, "reason", "missing_in_v2")
+		observability.ShadowReadResult(shadowMismatch)
+	default:
+		diff := diffRecordData(v1Record.Data, v2Record.Data)
+		if len(diff) == 0 {
+			observability.ShadowReadResult(shadowMatch)
+			return
+		}
+		diffJSON, _ := json.Marshal(diff)
+		observability.DefaultLogger.WarnContext(ctx, "shadow_read_mismatch", "record_id", id, "reason", "data", "diff", string(diffJSON))
+		observability.ShadowReadResult(shadowMismatch)
+	}
+}
+
+// FieldDiff describes one key whose value differs between stores; absent values are nil
+type FieldDiff struct {
+	Key string  `json:"key"`
+	V1  *string `json:"v1"`
+	V2  *string `json:"v2"`
+}
+
+// diffRecordData returns the keys whose values differ, sorted by key
+func diffRecordData(v1, v2 map[string]string) []FieldDiff {
+	keys := map[string]struct{}{}
+	for k := range v1 {
+		keys[k] = struct{}{}
+	}
+	for k := range v2 {
+		keys[k] = struct{}{}
+	}
+
+	var diff []FieldDiff
+	for k := range keys {
+		a, inV1 := v1[k]
+		b, inV2 := v2[k]
+		if inV1 && inV2 && a == b {
+			continue
+		}
+		d := FieldDiff{Key: k}
+		if inV1 {
+			d.V1 = &a
+		}
+		if inV2 {
+			d.V2 = &b
+		}
+		diff = append(diff, d)
+	}
+
+	sort.Slice(diff, func(i, j int) bool { return diff[i].Key < diff[j].Key })
+	return diff
+}
@@ -0,0 +1,117 @@
+package controller_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rainbowmga/timetravel/common"
+	"github.com/rainbowmga/timetravel/controller"
+	"github.com/rainbowmga/timetravel/entity"
+	"github.com/rainbowmga/timetravel/observability"
+	"github.com/rainbowmga/timetravel/service"
+)
+
+func newMigratingService(dualWrite, shadowRead bool) (*controller.MigratingRecordService, *mockSQLiteService, *controller.InMemoryRecordService) {
+	observability.InitMetricsRepository(nil)
+
+	flags := service.NewFeatureFlagServiceWithProvider(service.NewStaticFlagProvider(map[string]service.FeatureFlag{
+		controller.FlagV1DualWrite:  {Enabled: dualWrite, RolloutPercentage: 100},
+		controller.FlagV1ShadowRead: {Enabled: shadowRead, RolloutPercentage: 100},
+	}))
+	v1 := controller.NewInMemoryRecordService()
+	v2 := &mockSQLiteService{records: make(map[int64]*entity.PolicyholderRecord)}
+	return controller.NewMigratingRecordService(&v1, v2, flags), v2, &v1
+}
+
+func TestMigratingRecordService_DualWrite(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("flag off keeps v2 untouched", func(t *testing.T) {
+		svc, v2, _ := newMigratingService(false, false)
+		if err := svc.CreateRecord(ctx, entity.Record{ID: 1, Data: map[string]string{"a": "1"}}); err != nil {
+			t.Fatalf("create failed: %v", err)
+		}
+		if len(v2.records) != 0 {
+			t.Errorf("expected no v2 writes, got %v", v2.records)
+		}
+	})
+
+	t.Run("flag on mirrors create and merged update", func(t *testing.T) {
+		svc, v2, _ := newMigratingService(true, false)
+		if err := svc.CreateRecord(ctx, entity.Record{ID: 1, Data: map[string]string{"a": "1", "b": "2"}}); err != nil {
+			t.Fatalf("create failed: %v", err)
+		}
+		if v2.records[1] == nil || v2.records[1].Data["a"] != "1" {
+			t.Fatalf("expected create mirrored to v2, got %v", v2.records[1])
+		}
+		if v2.source != common.SourceAPIV1 {
+			t.Errorf("expected the mirrored write attributed to %s, got %q", common.SourceAPIV1, v2.source)
+		}
+
+		if _, err := svc.UpdateRecord(ctx, 1, map[string]*string{"a": strPtr("3"), "b": nil}); err != nil {
+			t.Fatalf("update failed: %v", err)
+		}
+		got := v2.records[1].Data
+		if got["a"] != "3" || len(got) != 1 {
+			t.Errorf("expected merged v1 record mirrored to v2, got %v", got)
+		}
+	})
+
+	t.Run("v2 failure does not fail v1", func(t *testing.T) {
+		svc, v2, _ := newMigratingService(true, false)
+		v2.updErr = errors.New("v2 unavailable")
+		before := testutil.ToFloat64(observability.DualWrites.WithLabelValues("error"))
+
+		if err := svc.CreateRecord(ctx, entity.Record{ID: 5, Data: map[string]string{}}); err != nil {
+			t.Fatalf("expected v1 create to succeed, got %v", err)
+		}
+		if after := testutil.ToFloat64(observability.DualWrites.WithLabelValues("error")); after != before+1 {
+			t.Errorf("expected dual write error counted")
+		}
+	})
+}
+
+func TestMigratingRecordService_ShadowRead(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		v1     map[string]string
+		v2     map[string]string
+		result string
+	}{
+		{"identical", map[string]string{"a": "1"}, map[string]string{"a": "1"}, "match"},
+		{"value differs", map[string]string{"a": "1"}, map[string]string{"a": "2"}, "mismatch"},
+		{"missing in v2", map[string]string{"a": "1"}, nil, "mismatch"},
+		{"missing in both", nil, nil, "match"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, v2, v1 := newMigratingService(false, true)
+			if tt.v1 != nil {
+				_ = v1.CreateRecord(ctx, entity.Record{ID: 1, Data: tt.v1})
+			}
+			if tt.v2 != nil {
+				v2.records[1] = &entity.PolicyholderRecord{ID: 1, Data: tt.v2}
+			}
+
+			before := testutil.ToFloat64(observability.ShadowReads.WithLabelValues(tt.result))
+			got, err := svc.GetRecord(ctx, 1)
+			after := testutil.ToFloat64(observability.ShadowReads.WithLabelValues(tt.result))
+
+			if after != before+1 {
+				t.Errorf("expected %s shadow read counted", tt.result)
+			}
+			// v1 result is returned unchanged
+			if tt.v1 != nil && (err != nil || got.Data["a"] != tt.v1["a"]) {
+				t.Errorf("expected v1 record returned, got %+v, %v", got, err)
+			}
+			if tt.v1 == nil && err != controller.ErrRecordDoesNotExist {
+				t.Errorf("expected ErrRecordDoesNotExist, got %v", err)
+			}
+		})
+	}
+}
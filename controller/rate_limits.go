@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/entity"
+	"github.com/rainbowmga/timetravel/ratelimit"
+	"github.com/rainbowmga/timetravel/service"
+)
+
+// maxPerMinute keeps overrides within what a token bucket can meaningfully count
+const maxPerMinute = 1_000_000
+
+var (
+	ErrRateLimitOverrideDoesNotExist = service.ErrRateLimitOverrideDoesNotExist
+	ErrRateLimitUserInvalid          = apperr.InvalidArgument("user_id must be a positive integer")
+	ErrRateLimitClassInvalid         = apperr.InvalidArgument("class must be read, write or admin")
+	ErrRateLimitInvalid              = apperr.InvalidArgument("per_minute and burst must be between 0 and 1000000")
+)
+
+// RateLimitController manages per-user overrides and keeps the limiter in step with them
+type RateLimitController struct {
+	service service.RateLimitServiceInterface
+	limiter *ratelimit.Limiter
+}
+
+// NewRateLimitControllerWithService allows injecting a mock service for testing
+func NewRateLimitControllerWithService(svc service.RateLimitServiceInterface, limiter *ratelimit.Limiter) *RateLimitController {
+	return &RateLimitController{service: svc, limiter: limiter}
+}
+
+// ListRateLimits returns the configured default of each class and every override
+func (c *RateLimitController) ListRateLimits(ctx context.Context) (map[ratelimit.Class]ratelimit.Limit, []entity.RateLimitOverride, error) {
+	overrides, err := c.service.ListOverrides(ctx)
+	if err != nil {
+		return nil, nil, storeError(ctx, err)
+	}
+	return c.limiter.Defaults(), overrides, nil
+}
+
+// SetOverride replaces the limit of one user and class; per_minute 0 lifts it
+func (c *RateLimitController) SetOverride(ctx context.Context, userID int64, class string, limit ratelimit.Limit) (entity.RateLimitOverride, error) {
+	if err := validOverride(userID, class); err != nil {
+		return entity.RateLimitOverride{}, err
+	}
+	if limit.PerMinute < 0 || limit.PerMinute > maxPerMinute || limit.Burst < 0 || limit.Burst > maxPerMinute {
+		return entity.RateLimitOverride{}, ErrRateLimitInvalid
+	}
+	o, err := c.service.PutOverride(ctx, entity.RateLimitOverride{
+		UserID:    userID,
+		Class:     class,
+		PerMinute: limit.PerMinute,
+		Burst:     limit.Burst,
+	})
+	if err != nil {
+		return entity.RateLimitOverride{}, storeError(ctx, err)
+	}
+	return o, c.Reload(ctx)
+}
+
+// DeleteOverride puts a user back on the class default
+func (c *RateLimitController) DeleteOverride(ctx context.Context, userID int64, class string) error {
+	if err := validOverride(userID, class); err != nil {
+		return err
+	}
+	if err := c.service.DeleteOverride(ctx, userID, class); err != nil {
+		return storeError(ctx, err)
+	}
+	return c.Reload(ctx)
+}
+
+// Reload hands the stored overrides to the limiter; other instances pick
+// changes up on their next periodic reload
+func (c *RateLimitController) Reload(ctx context.Context) error {
+	overrides, err := c.service.ListOverrides(ctx)
+	if err != nil {
+		return storeError(ctx, err)
+	}
+	limits := make(map[ratelimit.Key]ratelimit.Limit, len(overrides))
+	for _, o := range overrides {
+		limits[ratelimit.Key{UserID: o.UserID, Class: ratelimit.Class(o.Class)}] = ratelimit.Limit{PerMinute: o.PerMinute, Burst: o.Burst}
+	}
+	c.limiter.SetOverrides(limits)
+	return nil
+}
+
+func validOverride(userID int64, class string) error {
+	if userID <= 0 {
+		return ErrRateLimitUserInvalid
+	}
+	if _, err := ratelimit.ParseClass(class); err != nil {
+		return ErrRateLimitClassInvalid
+	}
+	return nil
+}
@@ -2,14 +2,16 @@ package controller
 
 import (
 	"context"
-	"errors"
 
+	"github.com/rainbowmga/timetravel/apperr"
 	"github.com/rainbowmga/timetravel/entity"
+	"github.com/rainbowmga/timetravel/service"
 )
 
-var ErrRecordDoesNotExist = errors.New("record with that id does not exist")
-var ErrRecordIDInvalid = errors.New("record id must >= 0")
-var ErrRecordAlreadyExists = errors.New("record already exists")
+// ErrRecordDoesNotExist is the service's sentinel, so v1 and v2 stores report a missing record the same way
+var ErrRecordDoesNotExist = service.ErrRecordDoesNotExist
+var ErrRecordIDInvalid = apperr.InvalidArgument("record id must >= 0")
+var ErrRecordAlreadyExists = apperr.Conflict("record already exists")
 
 // Implements method to get, create, and update record data.
 type RecordService interface {
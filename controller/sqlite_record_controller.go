@@ -7,14 +7,19 @@ import (
 	"github.com/rainbowmga/timetravel/entity"
 	"github.com/rainbowmga/timetravel/service"
 	"github.com/rainbowmga/timetravel/observability"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // define interface for the controller to allow testing with mocks
 type SQLiteRecordServiceInterface interface {
-	Get(int64) (*entity.PolicyholderRecord, error)
-	CreateOrUpdate(int64, map[string]string) (*entity.PolicyholderRecord, error)
-	GetVersion(int64, int) (map[string]string, error)
-	ListVersions(int64) ([]int, error)
+	Get(context.Context, int64) (*entity.PolicyholderRecord, error)
+	CreateOrUpdate(context.Context, int64, map[string]string) (*entity.PolicyholderRecord, error)
+	GetVersion(context.Context, int64, int) (map[string]string, error)
+	ListVersions(context.Context, int64) ([]int, error)
+	ListVersionEntries(context.Context, int64, service.VersionFilter) ([]entity.AuditHistory, error)
+	GetAsOf(context.Context, int64, time.Time) (*entity.RecordChange, error)
+	ListChanges(ctx context.Context, afterID, policyholderID int64, limit int) ([]entity.RecordChange, error)
+	LatestChangeID(context.Context) (int64, error)
 }
 
 type SQLiteRecordController struct {
@@ -33,6 +38,20 @@ func NewSQLiteRecordController(dbPath string) (*SQLiteRecordController, error) {
 	return &SQLiteRecordController{service: svc}, nil
 }
 
+// NewSQLiteRecordControllerWithFlags lets runtime flags (enable_audit_logging) control the service
+func NewSQLiteRecordControllerWithFlags(dbPath string, flags service.FlagEvaluator, environment string) (*SQLiteRecordController, error) {
+	svc, err := service.NewSQLiteRecordServiceWithFlags(dbPath, flags, environment)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteRecordController{service: svc}, nil
+}
+
+// NewSQLiteRecordControllerWithService wraps an already configured service (flags, metrics)
+func NewSQLiteRecordControllerWithService(svc SQLiteRecordServiceInterface) *SQLiteRecordController {
+	return &SQLiteRecordController{service: svc}
+}
+
 // NewSQLiteRecordControllerForTest allows injecting a mock service for testing
 func NewSQLiteRecordControllerForTest(svc SQLiteRecordServiceInterface) *SQLiteRecordController {
 	return &SQLiteRecordController{
@@ -50,17 +69,17 @@ func NewSQLiteRecordControllerInMemory(service SQLiteRecordServiceInterface) *SQ
 //
 // GET RECORD
 //
-func (c *SQLiteRecordController) GetRecord(ctx context.Context, id int64) (entity.PolicyholderRecord, error) {
+func (c *SQLiteRecordController) GetRecord(ctx context.Context, id int64) (_ entity.PolicyholderRecord, err error) {
+	ctx, span := observability.StartSpan(ctx, "SQLiteRecordController.GetRecord", attribute.Int64("policyholder.id", id))
+	defer func() { observability.EndSpan(span, err) }()
+
 	if id <= 0 {
 		return entity.PolicyholderRecord{}, ErrRecordIDInvalid
 	}
 
-	rec, err := c.service.Get(id)
+	rec, err := c.service.Get(ctx, id)
 	if err != nil {
-		if err == service.ErrRecordDoesNotExist {
-			return entity.PolicyholderRecord{}, ErrRecordDoesNotExist
-		}
-		return entity.PolicyholderRecord{}, err
+		return entity.PolicyholderRecord{}, storeError(ctx, err)
 	}
 
 	return *rec, nil
@@ -74,16 +93,18 @@ func (c *SQLiteRecordController) UpsertRecord(
 	ctx context.Context,
 	policyholderID int64,
 	data map[string]string,
-) (entity.PolicyholderRecord, error) {
+) (_ entity.PolicyholderRecord, err error) {
+	ctx, span := observability.StartSpan(ctx, "SQLiteRecordController.UpsertRecord", attribute.Int64("policyholder.id", policyholderID))
+	defer func() { observability.EndSpan(span, err) }()
 
 	if policyholderID <= 0 {
 		return entity.PolicyholderRecord{}, ErrRecordIDInvalid
 	}
 
-	rec, err := c.service.CreateOrUpdate(policyholderID, data)
+	rec, err := c.service.CreateOrUpdate(ctx, policyholderID, data)
 	if err != nil {
-		observability.DefaultLogger.Error(" CreateOrUpdate error %v", err)
-		return entity.PolicyholderRecord{}, err
+		observability.DefaultLogger.ErrorContext(ctx, "upsert_record_failed", "policyholder_id", policyholderID, "error", err)
+		return entity.PolicyholderRecord{}, storeError(ctx, err)
 	}
 
 	return *rec, nil
@@ -97,18 +118,17 @@ func (c *SQLiteRecordController) UpdateRecord(
 	ctx context.Context,
 	id int,
 	updates map[string]*string,
-) (entity.PolicyholderRecord, error) {
+) (_ entity.PolicyholderRecord, err error) {
+	ctx, span := observability.StartSpan(ctx, "SQLiteRecordController.UpdateRecord", attribute.Int("policyholder.id", id))
+	defer func() { observability.EndSpan(span, err) }()
 
 	if id <= 0 {
 		return entity.PolicyholderRecord{}, ErrRecordIDInvalid
 	}
 
-	rec, err := c.service.Get(int64(id))
+	rec, err := c.service.Get(ctx, int64(id))
 	if err != nil {
-		if err == service.ErrRecordDoesNotExist {
-			return entity.PolicyholderRecord{}, ErrRecordDoesNotExist
-		}
-		return entity.PolicyholderRecord{}, err
+		return entity.PolicyholderRecord{}, storeError(ctx, err)
 	}
 
 	// apply updates
@@ -122,27 +142,101 @@ func (c *SQLiteRecordController) UpdateRecord(
 
 	rec.UpdatedAt = time.Now().UTC()
 
-	updated, err := c.service.CreateOrUpdate(int64(id), rec.Data)
+	updated, err := c.service.CreateOrUpdate(ctx, int64(id), rec.Data)
 	if err != nil {
-		observability.DefaultLogger.Error(" updated error %v", err)
-		return entity.PolicyholderRecord{}, err
+		observability.DefaultLogger.ErrorContext(ctx, "update_record_failed", "policyholder_id", id, "error", err)
+		return entity.PolicyholderRecord{}, storeError(ctx, err)
 	}
 
 	return *updated, nil
 }
 
 // GetVersion returns a historical version
-func (c *SQLiteRecordController) GetVersion(ctx context.Context, id int, version int) (map[string]string, error) {
+func (c *SQLiteRecordController) GetVersion(ctx context.Context, id int, version int) (_ map[string]string, err error) {
+	ctx, span := observability.StartSpan(ctx, "SQLiteRecordController.GetVersion", attribute.Int("policyholder.id", id))
+	defer func() { observability.EndSpan(span, err) }()
+
 	if id <= 0 {
 		return nil, ErrRecordIDInvalid
 	}
-	return c.service.GetVersion(int64(id), version)
+	data, err := c.service.GetVersion(ctx, int64(id), version)
+	return data, storeError(ctx, err)
 }
 
 // ListVersions returns all versions
-func (c *SQLiteRecordController) ListVersions(ctx context.Context, id int) ([]int, error) {
+func (c *SQLiteRecordController) ListVersions(ctx context.Context, id int) (_ []int, err error) {
+	ctx, span := observability.StartSpan(ctx, "SQLiteRecordController.ListVersions", attribute.Int("policyholder.id", id))
+	defer func() { observability.EndSpan(span, err) }()
+
 	if id <= 0 {
 		return nil, ErrRecordIDInvalid
 	}
-	return c.service.ListVersions(int64(id))
+	versions, err := c.service.ListVersions(ctx, int64(id))
+	return versions, storeError(ctx, err)
+}
+
+// VersionEntry returns one version with its attribution
+func (c *SQLiteRecordController) VersionEntry(ctx context.Context, id int, version int) (_ entity.AuditHistory, err error) {
+	ctx, span := observability.StartSpan(ctx, "SQLiteRecordController.VersionEntry", attribute.Int("policyholder.id", id))
+	defer func() { observability.EndSpan(span, err) }()
+
+	if id <= 0 {
+		return entity.AuditHistory{}, ErrRecordIDInvalid
+	}
+	entries, err := c.service.ListVersionEntries(ctx, int64(id), service.VersionFilter{Version: version})
+	if err != nil {
+		return entity.AuditHistory{}, storeError(ctx, err)
+	}
+	if len(entries) == 0 {
+		return entity.AuditHistory{}, ErrRecordDoesNotExist
+	}
+	return entries[0], nil
+}
+
+// VersionEntries returns every version with its attribution, oldest first;
+// changedBy > 0 keeps the versions written by that user
+func (c *SQLiteRecordController) VersionEntries(ctx context.Context, id int, changedBy int64) (_ []entity.AuditHistory, err error) {
+	ctx, span := observability.StartSpan(ctx, "SQLiteRecordController.VersionEntries", attribute.Int("policyholder.id", id))
+	defer func() { observability.EndSpan(span, err) }()
+
+	if id <= 0 {
+		return nil, ErrRecordIDInvalid
+	}
+	if changedBy < 0 {
+		return nil, ErrChangedByInvalid
+	}
+	entries, err := c.service.ListVersionEntries(ctx, int64(id), service.VersionFilter{ChangedBy: changedBy})
+	return entries, storeError(ctx, err)
+}
+
+// GetAsOf returns the version that was current at asOf
+func (c *SQLiteRecordController) GetAsOf(ctx context.Context, id int, asOf time.Time) (_ entity.RecordChange, err error) {
+	ctx, span := observability.StartSpan(ctx, "SQLiteRecordController.GetAsOf", attribute.Int("policyholder.id", id))
+	defer func() { observability.EndSpan(span, err) }()
+
+	if id <= 0 {
+		return entity.RecordChange{}, ErrRecordIDInvalid
+	}
+	change, err := c.service.GetAsOf(ctx, int64(id), asOf)
+	if err != nil {
+		return entity.RecordChange{}, storeError(ctx, err)
+	}
+	return *change, nil
+}
+
+// ChangesSince returns up to limit changes after the change id afterID, oldest
+// first; id 0 follows every policyholder
+func (c *SQLiteRecordController) ChangesSince(ctx context.Context, afterID int64, id int, limit int) (_ []entity.RecordChange, err error) {
+	if id < 0 {
+		return nil, ErrRecordIDInvalid
+	}
+	changes, err := c.service.ListChanges(ctx, afterID, int64(id), limit)
+	return changes, storeError(ctx, err)
+}
+
+// LatestChangeID is the id of the newest change; a feed without a starting
+// point begins after it
+func (c *SQLiteRecordController) LatestChangeID(ctx context.Context) (int64, error) {
+	id, err := c.service.LatestChangeID(ctx)
+	return id, storeError(ctx, err)
 }
@@ -6,9 +6,13 @@ import (
 	"testing"
 	"time"
 
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/common"
 	"github.com/rainbowmga/timetravel/controller"
 	"github.com/rainbowmga/timetravel/entity"
+	"github.com/rainbowmga/timetravel/gateways"
 	"github.com/rainbowmga/timetravel/observability"
+	"github.com/rainbowmga/timetravel/service"
 )
 
 // --- Mock Logger ---
@@ -22,6 +26,14 @@ func (m *mockLogger) Debug(msg string, keysAndValues ...interface{}) {}
 func (m *mockLogger) Info(msg string, keysAndValues ...interface{})  { m.Infos = append(m.Infos, msg) }
 func (m *mockLogger) Warn(msg string, keysAndValues ...interface{})  {}
 func (m *mockLogger) Error(msg string, keysAndValues ...interface{}) { m.Errors = append(m.Errors, msg) }
+func (m *mockLogger) DebugContext(ctx context.Context, msg string, keysAndValues ...interface{}) {}
+func (m *mockLogger) InfoContext(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	m.Infos = append(m.Infos, msg)
+}
+func (m *mockLogger) WarnContext(ctx context.Context, msg string, keysAndValues ...interface{}) {}
+func (m *mockLogger) ErrorContext(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	m.Errors = append(m.Errors, msg)
+}
 
 // --- Mock SQLite Service ---
 
@@ -29,9 +41,10 @@ type mockSQLiteService struct {
 	records map[int64]*entity.PolicyholderRecord
 	getErr  error
 	updErr  error
+	source  string // change source of the last write
 }
 
-func (m *mockSQLiteService) Get(id int64) (*entity.PolicyholderRecord, error) {
+func (m *mockSQLiteService) Get(ctx context.Context, id int64) (*entity.PolicyholderRecord, error) {
 	if m.getErr != nil {
 		return nil, m.getErr
 	}
@@ -42,7 +55,8 @@ func (m *mockSQLiteService) Get(id int64) (*entity.PolicyholderRecord, error) {
 	return rec, nil
 }
 
-func (m *mockSQLiteService) CreateOrUpdate(id int64, data map[string]string) (*entity.PolicyholderRecord, error) {
+func (m *mockSQLiteService) CreateOrUpdate(ctx context.Context, id int64, data map[string]string) (*entity.PolicyholderRecord, error) {
+	m.source, _ = common.GetChangeSource(ctx)
 	if m.records == nil {
 		m.records = make(map[int64]*entity.PolicyholderRecord)
 	}
@@ -61,20 +75,53 @@ func (m *mockSQLiteService) CreateOrUpdate(id int64, data map[string]string) (*e
 	return rec, nil
 }
 
-func (m *mockSQLiteService) GetVersion(id int64, v int) (map[string]string, error) {
+func (m *mockSQLiteService) GetVersion(ctx context.Context, id int64, v int) (map[string]string, error) {
 	if id <= 0 {
 		return nil, errors.New("invalid id")
 	}
 	return map[string]string{"version": "data"}, nil
 }
 
-func (m *mockSQLiteService) ListVersions(id int64) ([]int, error) {
+func (m *mockSQLiteService) ListVersions(ctx context.Context, id int64) ([]int, error) {
 	if id <= 0 {
 		return nil, errors.New("invalid id")
 	}
 	return []int{1, 2}, nil
 }
 
+func (m *mockSQLiteService) ListVersionEntries(ctx context.Context, id int64, filter service.VersionFilter) ([]entity.AuditHistory, error) {
+	if m.getErr != nil {
+		return nil, m.getErr
+	}
+	entries := []entity.AuditHistory{}
+	for v, by := range []int64{7, 8} {
+		e := entity.AuditHistory{Version: v + 1, ChangeAttribution: entity.ChangeAttribution{ChangedBy: by}}
+		if (filter.Version == 0 || filter.Version == e.Version) && (filter.ChangedBy == 0 || filter.ChangedBy == by) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+func (m *mockSQLiteService) GetAsOf(ctx context.Context, id int64, asOf time.Time) (*entity.RecordChange, error) {
+	if m.getErr != nil {
+		return nil, m.getErr
+	}
+	rec, ok := m.records[id]
+	if !ok || rec.UpdatedAt.After(asOf) {
+		return nil, controller.ErrRecordDoesNotExist
+	}
+	return &entity.RecordChange{PolicyholderID: id, Version: rec.Version, Data: rec.Data, ChangedAt: rec.UpdatedAt}, nil
+}
+
+func (m *mockSQLiteService) ListChanges(ctx context.Context, afterID, id int64, limit int) ([]entity.RecordChange, error) {
+	return nil, m.getErr
+}
+
+func (m *mockSQLiteService) LatestChangeID(ctx context.Context) (int64, error) {
+	return 0, m.getErr
+}
+
 // --- Test Helpers ---
 
 func newControllerWithMocks() (*controller.SQLiteRecordController, *mockSQLiteService, *mockLogger) {
@@ -209,4 +256,77 @@ func TestSQLiteRecordController_Versions(t *testing.T) {
 			t.Errorf("ListVersions() len = %v, want 2", len(got))
 		}
 	})
+
+	t.Run("VersionEntry", func(t *testing.T) {
+		got, err := ctrl.VersionEntry(context.Background(), 1, 2)
+		if err != nil || got.Version != 2 || got.ChangedBy != 8 {
+			t.Errorf("VersionEntry() = %+v, %v", got, err)
+		}
+		if _, err := ctrl.VersionEntry(context.Background(), 1, 9); !errors.Is(err, controller.ErrRecordDoesNotExist) {
+			t.Errorf("VersionEntry(missing) error = %v", err)
+		}
+	})
+
+	t.Run("VersionEntries by user", func(t *testing.T) {
+		got, err := ctrl.VersionEntries(context.Background(), 1, 7)
+		if err != nil || len(got) != 1 || got[0].Version != 1 {
+			t.Errorf("VersionEntries(changed_by 7) = %+v, %v", got, err)
+		}
+		if _, err := ctrl.VersionEntries(context.Background(), 1, -1); !errors.Is(err, controller.ErrChangedByInvalid) {
+			t.Errorf("VersionEntries(-1) error = %v", err)
+		}
+	})
+}
+
+func TestSQLiteRecordController_BusyStoreIsUnavailable(t *testing.T) {
+	ctrl, mockSvc, _ := newControllerWithMocks()
+	busy := &gateways.BusyError{Operation: "create_or_update", Attempts: 3, RetryAfter: 2 * time.Second, Err: errors.New("database is locked")}
+	mockSvc.updErr = busy
+
+	_, err := ctrl.UpsertRecord(context.Background(), 1, map[string]string{"a": "b"})
+	if after, ok := apperr.RetryAfter(err); !ok || after != 2*time.Second {
+		t.Fatalf("UpsertRecord() error = %v, want unavailable with RetryAfter 2s", err)
+	}
+	if !errors.Is(err, controller.ErrStoreUnavailable) || !errors.Is(err, apperr.ErrUnavailable) || !errors.Is(err, gateways.ErrBusy) {
+		t.Errorf("error %v should match ErrStoreUnavailable, apperr.ErrUnavailable and gateways.ErrBusy", err)
+	}
+
+	mockSvc.updErr = errors.New("disk I/O error")
+	if _, err := ctrl.UpsertRecord(context.Background(), 1, map[string]string{"a": "b"}); errors.Is(err, controller.ErrStoreUnavailable) {
+		t.Errorf("non-lock error %v should not be reported as unavailable", err)
+	}
+}
+
+func TestSQLiteRecordController_CancellationIsDistinct(t *testing.T) {
+	ctrl, mockSvc, _ := newControllerWithMocks()
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		svcErr  error
+		wantErr error
+	}{
+		{"service returns context.Canceled", context.Background(), context.Canceled, controller.ErrRequestCanceled},
+		{"service returns DeadlineExceeded", context.Background(), context.DeadlineExceeded, controller.ErrRequestTimeout},
+		{"driver error after client hung up", canceled, errors.New("interrupted"), controller.ErrRequestCanceled},
+		{"driver error after budget ran out", expired, errors.New("interrupted"), controller.ErrRequestTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc.getErr = tt.svcErr
+			_, err := ctrl.GetRecord(tt.ctx, 1)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetRecord() error = %v, want %v", err, tt.wantErr)
+			}
+			if errors.Is(err, controller.ErrStoreUnavailable) {
+				t.Errorf("cancellation %v should not be reported as unavailable", err)
+			}
+		})
+	}
 }
@@ -0,0 +1,48 @@
+package controller
+
+import (
+	"context"
+	"errors"
+
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/gateways"
+)
+
+var (
+	// ErrStoreUnavailable means the database stayed locked past the retry budget
+	ErrStoreUnavailable = apperr.New(apperr.ErrUnavailable, "record store is busy, retry later")
+	// ErrRequestCanceled means the caller went away before the store answered
+	ErrRequestCanceled = apperr.New(apperr.ErrCanceled, "request canceled")
+	// ErrRequestTimeout means the route's time budget ran out before the store answered
+	ErrRequestTimeout = apperr.New(apperr.ErrTimeout, "request timed out")
+)
+
+// storeError classifies a store failure: typed errors pass through, cancellation
+// and deadline (from the error or the request context) become ErrRequestCanceled/
+// ErrRequestTimeout, SQLite lock failures become ErrStoreUnavailable with a
+// Retry-After hint, anything else is returned as is (and answered as internal)
+func storeError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var typed *apperr.Error
+	var busy *gateways.BusyError
+	switch {
+	case errors.As(err, &typed):
+		return err
+	case errors.Is(err, context.Canceled) || errors.Is(ctx.Err(), context.Canceled):
+		return apperr.Wrap(ErrRequestCanceled, err)
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return apperr.Wrap(ErrRequestTimeout, err)
+	case errors.As(err, &busy):
+		unavailable := apperr.Wrap(ErrStoreUnavailable, err)
+		unavailable.RetryAfter = busy.RetryAfter
+		return unavailable
+	case gateways.IsBusy(err):
+		unavailable := apperr.Wrap(ErrStoreUnavailable, err)
+		unavailable.RetryAfter = gateways.DefaultBusyRetryAfter
+		return unavailable
+	}
+	return err
+}
@@ -35,6 +35,45 @@ type AuditHistory struct {
 	Data      map[string]string `db:"-" json:"data"`
 	ChangedAt time.Time         `db:"changed_at" json:"changed_at"`
 	EventType string            `db:"event_type" json:"event_type"` // create/update/delete
+	ChangeAttribution
+}
+
+// ChangeAttribution is who wrote a version and how: the authenticated user
+// (0 when unknown, e.g. v1 writes), the request id, the write path
+// (common.SourceAPIV1 ...) and the caller's optional reason. Versions written
+// before attribution was recorded have none.
+type ChangeAttribution struct {
+	ChangedBy int64  `db:"changed_by" json:"changed_by,omitempty"`
+	RequestID string `db:"request_id" json:"request_id,omitempty"`
+	Source    string `db:"source" json:"source,omitempty"`
+	Reason    string `db:"reason" json:"reason,omitempty"`
+}
+
+// RecordChange is one audit_history snapshot addressed by policyholder: what
+// as-of queries return and what change feeds stream. ID orders changes globally.
+type RecordChange struct {
+	ID             int64             `db:"audit_id" json:"change_id"`
+	PolicyholderID int64             `db:"policyholder_id" json:"policyholder_id"`
+	Version        int               `db:"version" json:"version"`
+	Action         string            `db:"event_type" json:"action"` // create/update
+	Data           map[string]string `db:"-" json:"data"`
+	ChangedAt      time.Time         `db:"changed_at" json:"changed_at"`
+}
+
+// AuditEntry is an audit_history snapshot together with the data of the version
+// it replaced (nil for the first version), so the two can be diffed
+type AuditEntry struct {
+	AuditHistory
+	Previous map[string]string `db:"-" json:"-"`
+}
+
+// FieldChange is one key that differs between two versions of a record;
+// Before is nil when the key was added, After when it was removed
+type FieldChange struct {
+	Key    string  `json:"key"`
+	Kind   string  `json:"kind"` // added/removed/changed
+	Before *string `json:"before"`
+	After  *string `json:"after"`
 }
 
 // ------------------------------
@@ -46,6 +85,7 @@ type EventLog struct {
 	Action    string    `db:"action" json:"action"`   // create/update/delete/read
 	Timestamp time.Time `db:"timestamp" json:"timestamp"`
 	Details   string    `db:"details" json:"details"` // JSON string or human-readable message
+	ChangedBy int64     `db:"changed_by" json:"changed_by,omitempty"` // acting user; 0 when unknown
 }
 
 // ------------------------------
@@ -79,4 +119,55 @@ type APIVersionConfig struct {
 	IsActive          bool      `db:"is_active" json:"is_active"`
 	RolloutPercentage int       `db:"rollout_percentage" json:"rollout_percentage"`
 	UpdatedAt         time.Time `db:"updated_at" json:"updated_at"`
-}
\ No newline at end of file
+}
+// ------------------------------
+// API KEYS (HASHED; THE SECRET IS SHOWN ONCE)
+// ------------------------------
+type APIKey struct {
+	ID          int64      `db:"api_key_id" json:"id"`
+	Prefix      string     `db:"prefix" json:"prefix"`
+	Hash        string     `db:"key_hash" json:"-"`
+	Name        string     `db:"name" json:"name"`
+	UserID      int64      `db:"user_id" json:"user_id"`
+	Tenant      string     `db:"tenant" json:"tenant"`
+	Roles       []string   `db:"-" json:"roles"` // stored as JSON in DB
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	ExpiresAt   *time.Time `db:"expires_at" json:"expires_at"`
+	RevokedAt   *time.Time `db:"revoked_at" json:"revoked_at"`
+	LastUsedAt  *time.Time `db:"last_used_at" json:"last_used_at"`
+	RotatedFrom *int64     `db:"rotated_from" json:"rotated_from,omitempty"`
+}
+
+// ------------------------------
+// POLICYHOLDER ASSIGNMENTS (WHAT SCOPED ROLES SEE)
+// ------------------------------
+type PolicyholderAssignment struct {
+	UserID         int64     `db:"user_id" json:"user_id"`
+	PolicyholderID int64     `db:"policyholder_id" json:"policyholder_id"`
+	CreatedAt      time.Time `db:"created_at" json:"created_at"`
+}
+
+// ------------------------------
+// RATE LIMIT OVERRIDES (PER USER AND ROUTE CLASS)
+// ------------------------------
+type RateLimitOverride struct {
+	UserID    int64     `db:"user_id" json:"user_id"`
+	Class     string    `db:"route_class" json:"class"`
+	PerMinute int       `db:"per_minute" json:"per_minute"`
+	Burst     int       `db:"burst" json:"burst"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// ------------------------------
+// OBSERVABILITY METRIC ROLLUPS (PER-MINUTE / PER-HOUR AGGREGATES)
+// ------------------------------
+type MetricRollup struct {
+	MetricName  string    `db:"metric_name" json:"metric_name"`
+	Region      string    `db:"region" json:"region,omitempty"`
+	Resolution  string    `db:"resolution" json:"resolution"` // minute/hour
+	BucketStart time.Time `db:"bucket_start" json:"time"`
+	Count       int64     `db:"count" json:"count"`
+	Sum         float64   `db:"sum" json:"sum"`
+	Min         float64   `db:"min" json:"min"`
+	Max         float64   `db:"max" json:"max"`
+}
@@ -0,0 +1,180 @@
+package gateways
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Defaults for DatabaseOptions. The busy timeout is kept well under
+// DefaultTxMaxWait: the driver blocks for it before RunInTx sees SQLITE_BUSY,
+// so RunInTx's back-off and the request deadline decide how long a write waits.
+const (
+	DefaultBusyTimeout = 200 * time.Millisecond
+	DefaultSynchronous = "NORMAL"
+	DefaultMaxReaders  = 4
+)
+
+// DatabaseOptions tunes the connections opened by OpenDatabase; zero values use the defaults
+type DatabaseOptions struct {
+	BusyTimeout time.Duration // how long a connection waits on a lock before SQLITE_BUSY
+	Synchronous string        // OFF | NORMAL | FULL | EXTRA
+	MaxReaders  int           // size of the reader pool
+	// ReadOnly opens an existing file for inspection only: a single query-only
+	// pool serves as both Reader and Writer, and nothing is created or migrated
+	ReadOnly bool
+}
+
+func (o DatabaseOptions) withDefaults() DatabaseOptions {
+	if o.BusyTimeout <= 0 {
+		o.BusyTimeout = DefaultBusyTimeout
+	}
+	if o.Synchronous == "" {
+		o.Synchronous = DefaultSynchronous
+	}
+	if o.MaxReaders <= 0 {
+		o.MaxReaders = DefaultMaxReaders
+	}
+	return o
+}
+
+// Database is the process-wide handle on one SQLite file. All writes go
+// through a single-connection writer pool, so they queue in Go instead of
+// contending for the file lock; reads use a separate query-only pool that WAL
+// lets run alongside the writer. Pragmas are set in the DSN so they apply to
+// every connection either pool opens.
+type Database struct {
+	path   string
+	writer *sql.DB
+	reader *sql.DB
+}
+
+// OpenDatabase opens the writer and reader pools for path.
+// A private ":memory:" database only exists on one connection, so both pools are the same.
+func OpenDatabase(path string, opts DatabaseOptions) (*Database, error) {
+	opts = opts.withDefaults()
+	switch strings.ToUpper(opts.Synchronous) {
+	case "OFF", "NORMAL", "FULL", "EXTRA":
+	default:
+		return nil, fmt.Errorf("invalid synchronous mode %q", opts.Synchronous)
+	}
+
+	if opts.ReadOnly {
+		return openReadOnly(path, opts)
+	}
+
+	writer, err := sql.Open("sqlite3", databaseDSN(path, opts, false))
+	if err != nil {
+		return nil, err
+	}
+	writer.SetMaxOpenConns(1)
+	writer.SetMaxIdleConns(1)
+	writer.SetConnMaxLifetime(0)
+
+	// open eagerly so a bad path or DSN fails here rather than on first use
+	if err := writer.Ping(); err != nil {
+		writer.Close()
+		return nil, fmt.Errorf("failed to open SQLite DB %s: %w", path, err)
+	}
+
+	d := &Database{path: path, writer: writer, reader: writer}
+	if path == ":memory:" {
+		return d, nil
+	}
+
+	reader, err := sql.Open("sqlite3", databaseDSN(path, opts, true))
+	if err != nil {
+		writer.Close()
+		return nil, err
+	}
+	reader.SetMaxOpenConns(opts.MaxReaders)
+	reader.SetMaxIdleConns(opts.MaxReaders)
+	d.reader = reader
+	return d, nil
+}
+
+// openReadOnly opens path with mode=ro, which fails rather than creating a
+// missing file, and leaves the journal mode as the file has it
+func openReadOnly(path string, opts DatabaseOptions) (*Database, error) {
+	uri := path
+	if !strings.HasPrefix(uri, "file:") {
+		uri = "file:" + path
+	}
+	sep := "?"
+	if strings.Contains(uri, "?") {
+		sep = "&"
+	}
+	dsn := uri + sep + strings.Join([]string{
+		"mode=ro",
+		fmt.Sprintf("_busy_timeout=%d", opts.BusyTimeout.Milliseconds()),
+		"_query_only=1",
+	}, "&")
+
+	reader, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	reader.SetMaxOpenConns(opts.MaxReaders)
+	reader.SetMaxIdleConns(opts.MaxReaders)
+	if err := reader.Ping(); err != nil {
+		reader.Close()
+		return nil, fmt.Errorf("failed to open SQLite DB %s read-only: %w", path, err)
+	}
+	return &Database{path: path, writer: reader, reader: reader}, nil
+}
+
+// Writer is the single-connection pool for transactions and other writes
+func (d *Database) Writer() *sql.DB {
+	return d.writer
+}
+
+// Reader is the pool for queries; its connections reject writes
+func (d *Database) Reader() *sql.DB {
+	return d.reader
+}
+
+// Path is the path or URI the database was opened with
+func (d *Database) Path() string {
+	return d.path
+}
+
+// Close closes both pools
+func (d *Database) Close() error {
+	var errs []error
+	if d.reader != d.writer {
+		errs = append(errs, d.reader.Close())
+	}
+	errs = append(errs, d.writer.Close())
+	return errors.Join(errs...)
+}
+
+// databaseDSN appends the per-connection pragmas understood by go-sqlite3.
+// In-memory databases have no WAL, so journal_mode is left alone for them.
+// Writer transactions start with BEGIN IMMEDIATE (see RunInTx).
+func databaseDSN(path string, opts DatabaseOptions, readOnly bool) string {
+	params := []string{
+		fmt.Sprintf("_busy_timeout=%d", opts.BusyTimeout.Milliseconds()),
+		"_foreign_keys=1",
+		"_synchronous=" + strings.ToUpper(opts.Synchronous),
+	}
+	if !isMemoryPath(path) {
+		params = append(params, "_journal_mode=WAL")
+	}
+	if readOnly {
+		params = append(params, "_query_only=1")
+	} else {
+		params = append(params, "_txlock=immediate")
+	}
+
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return path + sep + strings.Join(params, "&")
+}
+
+func isMemoryPath(path string) bool {
+	return path == ":memory:" || strings.Contains(path, "mode=memory")
+}
@@ -0,0 +1,119 @@
+package gateways_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rainbowmga/timetravel/gateways"
+)
+
+func TestOpenDatabase_PragmasOnEveryConnection(t *testing.T) {
+	db, err := gateways.OpenDatabase(filepath.Join(t.TempDir(), "shared.db"), gateways.DatabaseOptions{
+		BusyTimeout: 2 * time.Second,
+		Synchronous: "full",
+		MaxReaders:  3,
+	})
+	if err != nil {
+		t.Fatalf("OpenDatabase() error = %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Writer().Exec("CREATE TABLE t (id INTEGER PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+
+	// hold several reader connections at once so each one is checked
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		conn, err := db.Reader().Conn(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		var journal string
+		var foreignKeys, busyTimeout, synchronous int
+		if err := conn.QueryRowContext(ctx, "PRAGMA journal_mode").Scan(&journal); err != nil {
+			t.Fatal(err)
+		}
+		if err := conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&foreignKeys); err != nil {
+			t.Fatal(err)
+		}
+		if err := conn.QueryRowContext(ctx, "PRAGMA busy_timeout").Scan(&busyTimeout); err != nil {
+			t.Fatal(err)
+		}
+		if err := conn.QueryRowContext(ctx, "PRAGMA synchronous").Scan(&synchronous); err != nil {
+			t.Fatal(err)
+		}
+		if journal != "wal" || foreignKeys != 1 || busyTimeout != 2000 || synchronous != 2 {
+			t.Errorf("reader conn %d: journal=%s foreign_keys=%d busy_timeout=%d synchronous=%d", i, journal, foreignKeys, busyTimeout, synchronous)
+		}
+
+		if _, err := conn.ExecContext(ctx, "INSERT INTO t (id) VALUES (1)"); err == nil {
+			t.Errorf("reader conn %d accepted a write", i)
+		}
+	}
+
+	if got := db.Writer().Stats().MaxOpenConnections; got != 1 {
+		t.Errorf("writer MaxOpenConnections = %d, want 1", got)
+	}
+	if got := db.Reader().Stats().MaxOpenConnections; got != 3 {
+		t.Errorf("reader MaxOpenConnections = %d, want 3", got)
+	}
+}
+
+func TestOpenDatabase_Memory(t *testing.T) {
+	db, err := gateways.OpenDatabase(":memory:", gateways.DatabaseOptions{})
+	if err != nil {
+		t.Fatalf("OpenDatabase() error = %v", err)
+	}
+	defer db.Close()
+
+	// a private in-memory database only exists on one connection, so reads must see writes
+	if _, err := db.Writer().Exec("CREATE TABLE t (id INTEGER PRIMARY KEY); INSERT INTO t VALUES (1)"); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := db.Reader().QueryRow("SELECT count(*) FROM t").Scan(&n); err != nil || n != 1 {
+		t.Errorf("reader count = %d, %v", n, err)
+	}
+}
+
+func TestOpenDatabase_ReadOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ro.db")
+	if _, err := gateways.OpenDatabase(path, gateways.DatabaseOptions{ReadOnly: true}); err == nil {
+		t.Fatal("read-only open of a missing file succeeded, want an error")
+	}
+
+	rw, err := gateways.OpenDatabase(path, gateways.DatabaseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rw.Close()
+	if _, err := rw.Writer().Exec("CREATE TABLE t (id INTEGER PRIMARY KEY); INSERT INTO t VALUES (1)"); err != nil {
+		t.Fatal(err)
+	}
+
+	ro, err := gateways.OpenDatabase(path, gateways.DatabaseOptions{ReadOnly: true})
+	if err != nil {
+		t.Fatalf("OpenDatabase(ReadOnly) error = %v", err)
+	}
+	defer ro.Close()
+	var n int
+	if err := ro.Reader().QueryRow("SELECT count(*) FROM t").Scan(&n); err != nil || n != 1 {
+		t.Errorf("count = %d, %v", n, err)
+	}
+	if _, err := ro.Writer().Exec("INSERT INTO t VALUES (2)"); err == nil {
+		t.Error("write through a read-only database succeeded")
+	}
+}
+
+func TestOpenDatabase_InvalidSynchronous(t *testing.T) {
+	_, err := gateways.OpenDatabase(":memory:", gateways.DatabaseOptions{Synchronous: "sometimes"})
+	if err == nil || !strings.Contains(err.Error(), "synchronous") {
+		t.Errorf("OpenDatabase() error = %v, want invalid synchronous", err)
+	}
+}
@@ -12,7 +12,7 @@ import (
 // setupInMemoryDB creates an in-memory SQLite DB with minimal tables.
 func setupInMemoryDB(t *testing.T) *sql.DB {
 	t.Helper()
-	db := gateways.ConnectDB(":memory:", "") // empty path skips SQL file
+	db := gateways.ConnectDB(":memory:")
 
 	schema := `
 	CREATE TABLE IF NOT EXISTS feature_flags (
@@ -1,7 +1,10 @@
 package gateways
 
 import (
+	"context"
 	"database/sql"
+
+	"github.com/rainbowmga/timetravel/entity"
 )
 
 
@@ -14,20 +17,30 @@ type MetricsRepo interface {
 // change the global variable to the interface type
 var metricsRepo MetricsRepo
 
+// MetricsRepository writes through DB; queries use Reader when set
 type MetricsRepository struct {
-	DB *sql.DB
+	DB     *sql.DB
+	Reader *sql.DB
 }
 
 func NewMetricsRepository(dbPath string) (*MetricsRepository,error) {
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := OpenDatabase(dbPath, DatabaseOptions{})
 	if err != nil {
 		return nil, err
 	}
+	return NewMetricsRepositoryWithDB(db), nil
+}
 
-	// Enforce foreign keys
-	_, _ = db.Exec("PRAGMA foreign_keys = ON;")
+// NewMetricsRepositoryWithDB uses the shared writer and reader pools
+func NewMetricsRepositoryWithDB(db *Database) *MetricsRepository {
+	return &MetricsRepository{DB: db.Writer(), Reader: db.Reader()}
+}
 
-	return &MetricsRepository{DB: db},nil
+func (r *MetricsRepository) readDB() *sql.DB {
+	if r.Reader != nil {
+		return r.Reader
+	}
+	return r.DB
 }
 
 func (r *MetricsRepository) InsertMetric(metricType, metricName string, value float64, region string) error {
@@ -44,11 +57,45 @@ func (r *MetricsRepository) InsertMetric(metricType, metricName string, value fl
 	return err
 }
 
+// SumMetric totals the raw (not yet pruned) samples of a metric; 0 when there are none
 func (r *MetricsRepository) SumMetric(metricName string) (float64, error) {
-    var sum float64
-    err := r.DB.QueryRow(`SELECT SUM(value) FROM metrics WHERE name = ?`, metricName).Scan(&sum)
-    if err != nil {
-        return 0, err
-    }
-    return sum, nil
+	var sum float64
+	err := r.readDB().QueryRow(`
+		SELECT COALESCE(SUM(value), 0)
+		FROM observability_metrics
+		WHERE metric_name = ?`, metricName).Scan(&sum)
+	if err != nil {
+		return 0, err
+	}
+	return sum, nil
+}
+
+// InsertMetrics writes a batch of samples in a single transaction
+func (r *MetricsRepository) InsertMetrics(batch []entity.ObservabilityMetric) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	return RunInTx(context.Background(), r.DB, TxOptions{Operation: "insert_metrics"}, func(tx *sql.Tx) error {
+		stmt, err := tx.Prepare(`
+			INSERT INTO observability_metrics (
+				metric_type,
+				metric_name,
+				value,
+				region,
+				recorded_at
+			) VALUES (?, ?, ?, ?, ?)
+		`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		for _, m := range batch {
+			if _, err := stmt.Exec(m.MetricType, m.MetricName, m.Value, m.Region, formatSQLiteTime(m.RecordedAt)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
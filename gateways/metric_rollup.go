@@ -0,0 +1,168 @@
+package gateways
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/rainbowmga/timetravel/entity"
+)
+
+// Rollup resolutions stored in observability_metric_rollups
+const (
+	ResolutionMinute = "minute"
+	ResolutionHour   = "hour"
+)
+
+// sqliteTimeFormat matches CURRENT_TIMESTAMP so text comparisons stay ordered
+const sqliteTimeFormat = "2006-01-02 15:04:05"
+
+func formatSQLiteTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeFormat)
+}
+
+// MetricsRetention bounds how long each resolution is kept; zero keeps forever
+type MetricsRetention struct {
+	Raw    time.Duration
+	Minute time.Duration
+	Hour   time.Duration
+}
+
+// RollupMetrics folds completed buckets into the minute and hour rollups.
+// The most recent bucket of each resolution is recomputed so late samples are
+// picked up; recomputing is idempotent because buckets are rebuilt from source.
+func (r *MetricsRepository) RollupMetrics(now time.Time) error {
+	if err := r.rollup(ResolutionMinute, time.Minute, now, `
+		INSERT INTO observability_metric_rollups
+			(metric_name, metric_type, region, resolution, bucket_start, count, sum, min, max)
+		SELECT metric_name, metric_type, COALESCE(region, ''), 'minute',
+			strftime('%Y-%m-%d %H:%M:00', recorded_at) AS bucket,
+			COUNT(value), COALESCE(SUM(value), 0), MIN(value), MAX(value)
+		FROM observability_metrics
+		WHERE recorded_at >= ? AND recorded_at < ?
+		GROUP BY metric_name, metric_type, COALESCE(region, ''), bucket
+		ON CONFLICT(metric_name, metric_type, region, resolution, bucket_start) DO UPDATE SET
+			count = excluded.count, sum = excluded.sum, min = excluded.min, max = excluded.max`); err != nil {
+		return err
+	}
+
+	return r.rollup(ResolutionHour, time.Hour, now, `
+		INSERT INTO observability_metric_rollups
+			(metric_name, metric_type, region, resolution, bucket_start, count, sum, min, max)
+		SELECT metric_name, metric_type, region, 'hour',
+			strftime('%Y-%m-%d %H:00:00', bucket_start) AS bucket,
+			SUM(count), SUM(sum), MIN(min), MAX(max)
+		FROM observability_metric_rollups
+		WHERE resolution = 'minute' AND bucket_start >= ? AND bucket_start < ?
+		GROUP BY metric_name, metric_type, region, bucket
+		ON CONFLICT(metric_name, metric_type, region, resolution, bucket_start) DO UPDATE SET
+			count = excluded.count, sum = excluded.sum, min = excluded.min, max = excluded.max`)
+}
+
+// rollup runs one resolution's INSERT ... SELECT over [last bucket, start of current bucket)
+func (r *MetricsRepository) rollup(resolution string, unit time.Duration, now time.Time, query string) error {
+	var last sql.NullString
+	if err := r.DB.QueryRow(`
+		SELECT MAX(bucket_start)
+		FROM observability_metric_rollups
+		WHERE resolution = ?`, resolution).Scan(&last); err != nil {
+		return fmt.Errorf("failed to read last %s rollup: %w", resolution, err)
+	}
+
+	from := time.Time{}
+	if last.Valid {
+		t, err := parseSQLiteTime(last.String)
+		if err != nil {
+			return fmt.Errorf("failed to parse last %s rollup %q: %w", resolution, last.String, err)
+		}
+		from = t
+	}
+	until := now.UTC().Truncate(unit)
+	if !from.Before(until) {
+		return nil
+	}
+
+	if _, err := r.DB.Exec(query, formatSQLiteTime(from), formatSQLiteTime(until)); err != nil {
+		return fmt.Errorf("failed to roll up %s metrics: %w", resolution, err)
+	}
+	return nil
+}
+
+// parseSQLiteTime accepts both the text layout and the driver's RFC3339 rendering of DATETIME values
+func parseSQLiteTime(s string) (time.Time, error) {
+	if t, err := time.Parse(sqliteTimeFormat, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// PruneMetrics deletes raw samples and rollups older than their retention and
+// returns the number of rows removed
+func (r *MetricsRepository) PruneMetrics(now time.Time, retention MetricsRetention) (int64, error) {
+	var removed int64
+
+	prune := func(keep time.Duration, query string, args ...interface{}) error {
+		if keep <= 0 {
+			return nil
+		}
+		res, err := r.DB.Exec(query, append(args, formatSQLiteTime(now.Add(-keep)))...)
+		if err != nil {
+			return err
+		}
+		n, _ := res.RowsAffected()
+		removed += n
+		return nil
+	}
+
+	if err := prune(retention.Raw, `DELETE FROM observability_metrics WHERE recorded_at < ?`); err != nil {
+		return removed, fmt.Errorf("failed to prune raw metrics: %w", err)
+	}
+	for _, p := range []struct {
+		resolution string
+		keep       time.Duration
+	}{{ResolutionMinute, retention.Minute}, {ResolutionHour, retention.Hour}} {
+		if err := prune(p.keep, `
+			DELETE FROM observability_metric_rollups
+			WHERE resolution = ? AND bucket_start < ?`, p.resolution); err != nil {
+			return removed, fmt.Errorf("failed to prune %s rollups: %w", p.resolution, err)
+		}
+	}
+	return removed, nil
+}
+
+// QueryRollups returns a metric's buckets in [from, to) ordered by time.
+// An empty region aggregates across all regions.
+func (r *MetricsRepository) QueryRollups(ctx context.Context, name, region, resolution string, from, to time.Time) ([]entity.MetricRollup, error) {
+	rows, err := r.readDB().QueryContext(ctx, `
+		SELECT bucket_start, SUM(count), SUM(sum), MIN(min), MAX(max)
+		FROM observability_metric_rollups
+		WHERE metric_name = ?
+		AND resolution = ?
+		AND (? = '' OR region = ?)
+		AND bucket_start >= ? AND bucket_start < ?
+		GROUP BY bucket_start
+		ORDER BY bucket_start`,
+		name, resolution, region, region, formatSQLiteTime(from), formatSQLiteTime(to),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	points := []entity.MetricRollup{}
+	for rows.Next() {
+		var bucket string
+		var min, max sql.NullFloat64
+		p := entity.MetricRollup{MetricName: name, Region: region, Resolution: resolution}
+		if err := rows.Scan(&bucket, &p.Count, &p.Sum, &min, &max); err != nil {
+			return nil, err
+		}
+		if p.BucketStart, err = parseSQLiteTime(bucket); err != nil {
+			return nil, err
+		}
+		p.Min, p.Max = min.Float64, max.Float64
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
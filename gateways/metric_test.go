@@ -1,9 +1,12 @@
 package gateways_test
 
 import (
+	"context"
 	"database/sql"
 	"testing"
+	"time"
 
+	"github.com/rainbowmga/timetravel/entity"
 	"github.com/rainbowmga/timetravel/gateways"
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -25,6 +28,19 @@ func setupDB(t *testing.T) *sql.DB {
 		region TEXT,
 		recorded_at DATETIME
 	);
+
+	CREATE TABLE observability_metric_rollups (
+		metric_name TEXT NOT NULL,
+		metric_type TEXT NOT NULL,
+		region TEXT NOT NULL DEFAULT '',
+		resolution TEXT NOT NULL,
+		bucket_start DATETIME NOT NULL,
+		count INTEGER NOT NULL,
+		sum REAL NOT NULL,
+		min REAL,
+		max REAL,
+		PRIMARY KEY (metric_name, metric_type, region, resolution, bucket_start)
+	);
 	`)
 	if err != nil {
 		t.Fatalf("failed to create table: %v", err)
@@ -95,3 +111,124 @@ func TestInsertMetric_Failure(t *testing.T) {
 		t.Fatal("expected error inserting into missing table, got nil")
 	}
 }
+
+func TestSumMetric(t *testing.T) {
+	db := setupDB(t)
+	defer db.Close()
+
+	repo := &gateways.MetricsRepository{DB: db}
+
+	sum, err := repo.SumMetric("requests")
+	if err != nil || sum != 0 {
+		t.Fatalf("expected 0 for unknown metric, got %v, %v", sum, err)
+	}
+
+	_ = repo.InsertMetric("platform", "requests", 2, "eu-west-1")
+	_ = repo.InsertMetric("platform", "requests", 3, "us-east-1")
+	_ = repo.InsertMetric("platform", "latency", 9, "us-east-1")
+
+	sum, err = repo.SumMetric("requests")
+	if err != nil || sum != 5 {
+		t.Fatalf("SumMetric() = %v, %v, want 5", sum, err)
+	}
+}
+
+func sample(name, region string, value float64, at time.Time) entity.ObservabilityMetric {
+	return entity.ObservabilityMetric{MetricType: "platform", MetricName: name, Value: value, Region: region, RecordedAt: at}
+}
+
+func TestRollupAndQueryMetrics(t *testing.T) {
+	db := setupDB(t)
+	defer db.Close()
+
+	repo := &gateways.MetricsRepository{DB: db}
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	err := repo.InsertMetrics([]entity.ObservabilityMetric{
+		sample("latency", "us-east-1", 1, base.Add(5*time.Second)),
+		sample("latency", "us-east-1", 3, base.Add(50*time.Second)),
+		sample("latency", "eu-west-1", 8, base.Add(30*time.Second)),
+		sample("latency", "us-east-1", 2, base.Add(90*time.Second)),
+		sample("latency", "us-east-1", 4, base.Add(time.Hour+10*time.Second)),
+		sample("requests", "us-east-1", 1, base.Add(5*time.Second)),
+	})
+	if err != nil {
+		t.Fatalf("InsertMetrics() error: %v", err)
+	}
+
+	// the 11:00 minute is still open, so only the 10:00 hour is complete
+	now := base.Add(time.Hour + 30*time.Second)
+	if err := repo.RollupMetrics(now); err != nil {
+		t.Fatalf("RollupMetrics() error: %v", err)
+	}
+	// re-running must not double count
+	if err := repo.RollupMetrics(now); err != nil {
+		t.Fatalf("second RollupMetrics() error: %v", err)
+	}
+
+	minutes, err := repo.QueryRollups(context.Background(), "latency", "", gateways.ResolutionMinute, base, base.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("QueryRollups() error: %v", err)
+	}
+	if len(minutes) != 2 {
+		t.Fatalf("expected 2 minute buckets, got %+v", minutes)
+	}
+	first := minutes[0]
+	if !first.BucketStart.Equal(base) || first.Count != 3 || first.Sum != 12 || first.Min != 1 || first.Max != 8 {
+		t.Errorf("unexpected first bucket: %+v", first)
+	}
+
+	regional, err := repo.QueryRollups(context.Background(), "latency", "us-east-1", gateways.ResolutionMinute, base, base.Add(time.Minute))
+	if err != nil || len(regional) != 1 || regional[0].Count != 2 || regional[0].Sum != 4 {
+		t.Errorf("unexpected regional bucket: %+v, %v", regional, err)
+	}
+
+	hours, err := repo.QueryRollups(context.Background(), "latency", "", gateways.ResolutionHour, base, base.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("QueryRollups(hour) error: %v", err)
+	}
+	if len(hours) != 1 || hours[0].Count != 4 || hours[0].Sum != 14 || hours[0].Max != 8 {
+		t.Errorf("unexpected hour buckets: %+v", hours)
+	}
+
+	// a later run folds in the 11:00 minute and picks up a late sample for it
+	_ = repo.InsertMetrics([]entity.ObservabilityMetric{sample("latency", "us-east-1", 6, base.Add(time.Hour+40*time.Second))})
+	if err := repo.RollupMetrics(base.Add(time.Hour + 2*time.Minute)); err != nil {
+		t.Fatalf("RollupMetrics() error: %v", err)
+	}
+	late, _ := repo.QueryRollups(context.Background(), "latency", "", gateways.ResolutionMinute, base.Add(time.Hour), base.Add(2*time.Hour))
+	if len(late) != 1 || late[0].Count != 2 || late[0].Sum != 10 {
+		t.Errorf("expected late sample in 11:00 bucket, got %+v", late)
+	}
+}
+
+func TestPruneMetrics(t *testing.T) {
+	db := setupDB(t)
+	defer db.Close()
+
+	repo := &gateways.MetricsRepository{DB: db}
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	_ = repo.InsertMetrics([]entity.ObservabilityMetric{
+		sample("latency", "us-east-1", 1, base),
+		sample("latency", "us-east-1", 1, base.Add(3*time.Hour)),
+	})
+	if err := repo.RollupMetrics(base.Add(4 * time.Hour)); err != nil {
+		t.Fatalf("RollupMetrics() error: %v", err)
+	}
+
+	now := base.Add(4 * time.Hour)
+	removed, err := repo.PruneMetrics(now, gateways.MetricsRetention{Raw: 2 * time.Hour, Minute: 2 * time.Hour})
+	if err != nil {
+		t.Fatalf("PruneMetrics() error: %v", err)
+	}
+	// one raw row and one minute bucket are older than 2h; hours are kept forever
+	if removed != 2 {
+		t.Errorf("expected 2 rows pruned, got %d", removed)
+	}
+
+	hours, _ := repo.QueryRollups(context.Background(), "latency", "", gateways.ResolutionHour, base, now)
+	if len(hours) != 2 {
+		t.Errorf("expected hour rollups to survive, got %+v", hours)
+	}
+}
@@ -0,0 +1,534 @@
+package gateways
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMigrationLocked        = errors.New("migrations are locked by another runner")
+	ErrChecksumMismatch       = errors.New("applied migration has been modified")
+	ErrIrreversibleMigration  = errors.New("migration has no down file")
+	ErrUnknownMigration       = errors.New("unknown migration version")
+	ErrMissingMigrationFile   = errors.New("applied migration has no file")
+	ErrPendingMigrations      = errors.New("migrations pending")
+	ErrDuplicateMigrationFile = errors.New("duplicate migration version")
+)
+
+// migrationFile matches NNN_name.sql, NNN_name.up.sql and NNN_name.down.sql.
+// Plain .sql files are treated as up-only (irreversible) migrations.
+var migrationFile = regexp.MustCompile(`^(\d+)_([A-Za-z0-9_\-]+?)(?:\.(up|down))?\.sql$`)
+
+// legacyVersion matches schema_migrations rows written by the file-name based runner
+var legacyVersion = regexp.MustCompile(`^(\d+)_(.+?)(?:\.sql)?$`)
+
+// Migration is one numbered schema change
+type Migration struct {
+	Version  string // numeric prefix as written in the file name, e.g. "003"
+	Name     string
+	Up       string
+	Down     string
+	HasDown  bool
+	Checksum string // sha256 of Up
+}
+
+func (m Migration) number() int {
+	n, _ := strconv.Atoi(m.Version)
+	return n
+}
+
+// MigrationStatus describes one migration as seen by `migrate status`
+type MigrationStatus struct {
+	Version   string
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	Modified  bool // checksum differs from the applied file
+	Missing   bool // applied but no longer on disk
+}
+
+// LoadMigrations reads and pairs migration files from fsys (e.g. os.DirFS("script/migrations"))
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations dir: %w", err)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		n, _ := strconv.Atoi(match[1])
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[n]
+		if !ok {
+			m = &Migration{Version: match[1], Name: match[2]}
+			byVersion[n] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateMigrationFile, match[1])
+		}
+
+		switch match[3] {
+		case "down":
+			m.Down = string(content)
+			m.HasDown = true
+		default:
+			if m.Checksum != "" {
+				return nil, fmt.Errorf("%w: %s", ErrDuplicateMigrationFile, match[1])
+			}
+			m.Up = string(content)
+			sum := sha256.Sum256(content)
+			m.Checksum = hex.EncodeToString(sum[:])
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Checksum == "" {
+			return nil, fmt.Errorf("migration %s_%s has a down file but no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].number() < migrations[j].number() })
+	return migrations, nil
+}
+
+// Migrator applies and reverts migrations. Each migration runs in its own
+// transaction together with its schema_migrations row, and a lock row in
+// schema_migrations_lock keeps concurrent runners out.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	holder     string
+}
+
+// NewMigrator loads migrations from fsys for db
+func NewMigrator(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := LoadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations, holder: lockHolder()}, nil
+}
+
+// Migrations returns the known migrations in order
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// Up applies every pending migration and returns the versions applied
+func (m *Migrator) Up(ctx context.Context) ([]string, error) {
+	var applied []string
+	err := m.withLock(ctx, func(conn *sql.Conn, state map[string]appliedMigration) error {
+		for _, mig := range m.migrations {
+			if _, ok := state[mig.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, mig); err != nil {
+				return err
+			}
+			applied = append(applied, mig.Version)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the most recently applied migration and returns its version
+func (m *Migrator) Down(ctx context.Context) (string, error) {
+	var reverted string
+	err := m.withLock(ctx, func(conn *sql.Conn, state map[string]appliedMigration) error {
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			if _, ok := state[m.migrations[i].Version]; ok {
+				reverted = m.migrations[i].Version
+				return m.revert(ctx, conn, m.migrations[i])
+			}
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// To migrates up or down until version is the newest applied migration.
+// Version "0" reverts everything.
+func (m *Migrator) To(ctx context.Context, version string) error {
+	target, err := strconv.Atoi(version)
+	if err != nil || target < 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownMigration, version)
+	}
+	if target != 0 && !m.known(target) {
+		return fmt.Errorf("%w: %s", ErrUnknownMigration, version)
+	}
+
+	return m.withLock(ctx, func(conn *sql.Conn, state map[string]appliedMigration) error {
+		// revert newer migrations first, newest to oldest
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mig := m.migrations[i]
+			if _, ok := state[mig.Version]; ok && mig.number() > target {
+				if err := m.revert(ctx, conn, mig); err != nil {
+					return err
+				}
+			}
+		}
+		for _, mig := range m.migrations {
+			if _, ok := state[mig.Version]; !ok && mig.number() <= target {
+				if err := m.apply(ctx, conn, mig); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Status lists every migration on disk plus applied migrations whose file is gone
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := ensureMigrationTables(ctx, conn); err != nil {
+		return nil, err
+	}
+	state, err := loadApplied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	return m.statuses(state), nil
+}
+
+// statuses matches the applied migrations in state against those on disk
+func (m *Migrator) statuses(state map[string]appliedMigration) []MigrationStatus {
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		s := MigrationStatus{Version: mig.Version, Name: mig.Name}
+		if a, ok := state[mig.Version]; ok {
+			s.Applied = true
+			s.AppliedAt = a.appliedAt
+			s.Modified = a.checksum != "" && a.checksum != mig.Checksum
+			delete(state, mig.Version)
+		}
+		statuses = append(statuses, s)
+	}
+	for version, a := range state {
+		statuses = append(statuses, MigrationStatus{Version: version, Name: a.name, Applied: true, AppliedAt: a.appliedAt, Missing: true})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		a, _ := strconv.Atoi(statuses[i].Version)
+		b, _ := strconv.Atoi(statuses[j].Version)
+		return a < b
+	})
+	return statuses
+}
+
+// Verify fails if any migration is pending, modified or missing
+func (m *Migrator) Verify(ctx context.Context) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	return verifyStatuses(statuses)
+}
+
+// VerifyReadOnly is Verify over a read-only handle such as Database.Reader().
+// It reads schema_migrations as it is, without creating or upgrading it, so
+// frequent callers like readiness probes never take the writer connection.
+func (m *Migrator) VerifyReadOnly(ctx context.Context, reader *sql.DB) error {
+	state, err := loadApplied(ctx, reader)
+	if err != nil {
+		return err
+	}
+	return verifyStatuses(m.statuses(state))
+}
+
+func verifyStatuses(statuses []MigrationStatus) error {
+	for _, s := range statuses {
+		switch {
+		case s.Modified:
+			return fmt.Errorf("%w: %s_%s", ErrChecksumMismatch, s.Version, s.Name)
+		case s.Missing:
+			return fmt.Errorf("%w: %s", ErrMissingMigrationFile, s.Version)
+		case !s.Applied:
+			return fmt.Errorf("%w: %s_%s not applied", ErrPendingMigrations, s.Version, s.Name)
+		}
+	}
+	return nil
+}
+
+// Unlock removes a lock left behind by a runner that crashed
+func (m *Migrator) Unlock(ctx context.Context) error {
+	if _, err := m.db.ExecContext(ctx, `DELETE FROM schema_migrations_lock`); err != nil {
+		return fmt.Errorf("failed to remove migration lock: %w", err)
+	}
+	return nil
+}
+
+func (m *Migrator) known(target int) bool {
+	for _, mig := range m.migrations {
+		if mig.number() == target {
+			return true
+		}
+	}
+	return false
+}
+
+// withLock pins one connection, takes the runner lock, verifies checksums and
+// runs fn with foreign key enforcement off (table rebuilds need it; SQLite
+// ignores the pragma inside a transaction).
+func (m *Migrator) withLock(ctx context.Context, fn func(*sql.Conn, map[string]appliedMigration) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := ensureMigrationTables(ctx, conn); err != nil {
+		return err
+	}
+	if err := m.lock(ctx, conn); err != nil {
+		return err
+	}
+	defer m.unlock(conn)
+
+	state, err := loadApplied(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if err := m.verifyChecksums(ctx, conn, state); err != nil {
+		return err
+	}
+
+	var foreignKeys int
+	_ = conn.QueryRowContext(ctx, `PRAGMA foreign_keys`).Scan(&foreignKeys)
+	if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF`); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), fmt.Sprintf(`PRAGMA foreign_keys = %d`, foreignKeys))
+
+	return fn(conn, state)
+}
+
+func (m *Migrator) lock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `
+		INSERT INTO schema_migrations_lock (id, holder, locked_at)
+		VALUES (1, ?, ?)`, m.holder, formatSQLiteTime(time.Now()))
+	if err == nil {
+		return nil
+	}
+
+	var holder, lockedAt string
+	if qerr := conn.QueryRowContext(ctx, `SELECT holder, locked_at FROM schema_migrations_lock WHERE id = 1`).Scan(&holder, &lockedAt); qerr == nil {
+		return fmt.Errorf("%w: held by %s since %s (run `migrate unlock` if it crashed)", ErrMigrationLocked, holder, lockedAt)
+	}
+	return fmt.Errorf("failed to take migration lock: %w", err)
+}
+
+func (m *Migrator) unlock(conn *sql.Conn) {
+	_, _ = conn.ExecContext(context.Background(), `DELETE FROM schema_migrations_lock WHERE id = 1 AND holder = ?`, m.holder)
+}
+
+// verifyChecksums rejects edited migrations and records checksums for rows
+// written before checksums existed
+func (m *Migrator) verifyChecksums(ctx context.Context, conn *sql.Conn, state map[string]appliedMigration) error {
+	for _, mig := range m.migrations {
+		a, ok := state[mig.Version]
+		if !ok {
+			continue
+		}
+		if a.checksum == "" {
+			if _, err := conn.ExecContext(ctx, `
+				UPDATE schema_migrations SET checksum = ?, name = ?
+				WHERE version = ?`, mig.Checksum, mig.Name, mig.Version); err != nil {
+				return fmt.Errorf("failed to backfill checksum for %s: %w", mig.Version, err)
+			}
+			continue
+		}
+		if a.checksum != mig.Checksum {
+			return fmt.Errorf("%w: %s_%s", ErrChecksumMismatch, mig.Version, mig.Name)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, mig Migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, mig.Up); err != nil {
+		return fmt.Errorf("failed to apply migration %s_%s: %w", mig.Version, mig.Name, err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO schema_migrations (version, name, checksum, applied_at)
+		VALUES (?, ?, ?, ?)`, mig.Version, mig.Name, mig.Checksum, formatSQLiteTime(time.Now())); err != nil {
+		return fmt.Errorf("failed to record migration %s: %w", mig.Version, err)
+	}
+	return tx.Commit()
+}
+
+func (m *Migrator) revert(ctx context.Context, conn *sql.Conn, mig Migration) error {
+	if !mig.HasDown {
+		return fmt.Errorf("%w: %s_%s", ErrIrreversibleMigration, mig.Version, mig.Name)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if strings.TrimSpace(mig.Down) != "" {
+		if _, err := tx.ExecContext(ctx, mig.Down); err != nil {
+			return fmt.Errorf("failed to revert migration %s_%s: %w", mig.Version, mig.Name, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = ?`, mig.Version); err != nil {
+		return fmt.Errorf("failed to unrecord migration %s: %w", mig.Version, err)
+	}
+	return tx.Commit()
+}
+
+type appliedMigration struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// querier is a *sql.Conn or *sql.DB
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func loadApplied(ctx context.Context, conn querier) (map[string]appliedMigration, error) {
+	rows, err := conn.QueryContext(ctx, `
+		SELECT version, COALESCE(name, ''), COALESCE(checksum, ''), COALESCE(CAST(applied_at AS TEXT), '')
+		FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	state := map[string]appliedMigration{}
+	for rows.Next() {
+		var version, appliedAt string
+		var a appliedMigration
+		if err := rows.Scan(&version, &a.name, &a.checksum, &appliedAt); err != nil {
+			return nil, err
+		}
+		a.appliedAt, _ = parseSQLiteTime(appliedAt)
+		state[version] = a
+	}
+	return state, rows.Err()
+}
+
+// ensureMigrationTables creates the bookkeeping tables and upgrades the legacy
+// schema_migrations layout (version = file name, no checksum) in place
+func ensureMigrationTables(ctx context.Context, conn *sql.Conn) error {
+	if _, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version TEXT PRIMARY KEY,
+			name TEXT,
+			checksum TEXT,
+			applied_at DATETIME
+		);
+		CREATE TABLE IF NOT EXISTS schema_migrations_lock (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			holder TEXT NOT NULL,
+			locked_at DATETIME NOT NULL
+		);`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	columns := map[string]bool{}
+	rows, err := conn.QueryContext(ctx, `PRAGMA table_info(schema_migrations)`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, typ string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk); err != nil {
+			rows.Close()
+			return err
+		}
+		columns[name] = true
+	}
+	rows.Close()
+
+	for _, col := range []string{"name TEXT", "checksum TEXT", "applied_at DATETIME"} {
+		if !columns[strings.Fields(col)[0]] {
+			if _, err := conn.ExecContext(ctx, `ALTER TABLE schema_migrations ADD COLUMN `+col); err != nil {
+				return fmt.Errorf("failed to upgrade schema_migrations: %w", err)
+			}
+		}
+	}
+
+	// legacy rows were keyed by file name ("002_create_api_versions.sql");
+	// rows whose number is already recorded are dropped
+	legacy, err := conn.QueryContext(ctx, `SELECT version FROM schema_migrations WHERE version GLOB '[0-9]*_*'`)
+	if err != nil {
+		return err
+	}
+	var legacyVersions []string
+	for legacy.Next() {
+		var v string
+		if err := legacy.Scan(&v); err != nil {
+			legacy.Close()
+			return err
+		}
+		legacyVersions = append(legacyVersions, v)
+	}
+	legacy.Close()
+
+	for _, v := range legacyVersions {
+		match := legacyVersion.FindStringSubmatch(v)
+		if match == nil {
+			continue
+		}
+		if _, err := conn.ExecContext(ctx, `
+			UPDATE OR IGNORE schema_migrations
+			SET version = ?, name = COALESCE(name, ?)
+			WHERE version = ?`, match[1], match[2], v); err != nil {
+			return fmt.Errorf("failed to upgrade legacy migration row %s: %w", v, err)
+		}
+		if _, err := conn.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = ?`, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lockHolder identifies this runner in schema_migrations_lock
+func lockHolder() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("%s:%d:%s", host, os.Getpid(), hex.EncodeToString(b))
+}
@@ -0,0 +1,355 @@
+package gateways_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/rainbowmga/timetravel/gateways"
+	"github.com/rainbowmga/timetravel/script"
+)
+
+func migrationFS() fstest.MapFS {
+	return fstest.MapFS{
+		"001_create_a.up.sql":   {Data: []byte("CREATE TABLE a (id INTEGER PRIMARY KEY);")},
+		"001_create_a.down.sql": {Data: []byte("DROP TABLE a;")},
+		"002_create_b.up.sql":   {Data: []byte("CREATE TABLE b (id INTEGER PRIMARY KEY);")},
+		"002_create_b.down.sql": {Data: []byte("DROP TABLE b;")},
+		"003_seed_a.up.sql":     {Data: []byte("INSERT INTO a (id) VALUES (1);")},
+		"003_seed_a.down.sql":   {Data: []byte("DELETE FROM a WHERE id = 1;")},
+		"README.md":             {Data: []byte("not a migration")},
+	}
+}
+
+func openMigrationDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "migrate.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+	var n int
+	if err := db.QueryRow("SELECT count(*) FROM sqlite_master WHERE type='table' AND name=?", name).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n == 1
+}
+
+func appliedVersions(t *testing.T, m *gateways.Migrator) []string {
+	t.Helper()
+	statuses, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	var applied []string
+	for _, s := range statuses {
+		if s.Applied {
+			applied = append(applied, s.Version)
+		}
+	}
+	return applied
+}
+
+func TestMigrator_UpDownTo(t *testing.T) {
+	ctx := context.Background()
+	db := openMigrationDB(t)
+	m, err := gateways.NewMigrator(db, migrationFS())
+	if err != nil {
+		t.Fatalf("NewMigrator() error = %v", err)
+	}
+
+	applied, err := m.Up(ctx)
+	if err != nil || len(applied) != 3 {
+		t.Fatalf("Up() = %v, %v; want 3 migrations", applied, err)
+	}
+	if applied, err := m.Up(ctx); err != nil || len(applied) != 0 {
+		t.Fatalf("second Up() = %v, %v; want nothing", applied, err)
+	}
+	if err := m.Verify(ctx); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+
+	reverted, err := m.Down(ctx)
+	if err != nil || reverted != "003" {
+		t.Fatalf("Down() = %q, %v; want 003", reverted, err)
+	}
+	if err := m.Verify(ctx); !errors.Is(err, gateways.ErrPendingMigrations) {
+		t.Errorf("Verify() error = %v, want ErrPendingMigrations", err)
+	}
+
+	if err := m.To(ctx, "1"); err != nil {
+		t.Fatalf("To(1) error = %v", err)
+	}
+	if got := appliedVersions(t, m); len(got) != 1 || got[0] != "001" {
+		t.Errorf("applied after To(1) = %v", got)
+	}
+	if tableExists(t, db, "b") {
+		t.Error("table b should be dropped")
+	}
+
+	if err := m.To(ctx, "003"); err != nil {
+		t.Fatalf("To(003) error = %v", err)
+	}
+	if got := appliedVersions(t, m); len(got) != 3 {
+		t.Errorf("applied after To(003) = %v", got)
+	}
+
+	if err := m.To(ctx, "0"); err != nil {
+		t.Fatalf("To(0) error = %v", err)
+	}
+	if tableExists(t, db, "a") || len(appliedVersions(t, m)) != 0 {
+		t.Error("To(0) should revert everything")
+	}
+
+	if err := m.To(ctx, "9"); !errors.Is(err, gateways.ErrUnknownMigration) {
+		t.Errorf("To(9) error = %v, want ErrUnknownMigration", err)
+	}
+}
+
+func TestMigrator_FailedMigrationRollsBack(t *testing.T) {
+	ctx := context.Background()
+	db := openMigrationDB(t)
+	fsys := migrationFS()
+	fsys["002_create_b.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE b (id INTEGER PRIMARY KEY); INSERT INTO missing VALUES (1);")}
+
+	m, err := gateways.NewMigrator(db, fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	applied, err := m.Up(ctx)
+	if err == nil {
+		t.Fatal("expected Up() to fail")
+	}
+	if len(applied) != 1 || applied[0] != "001" {
+		t.Errorf("applied = %v, want only 001", applied)
+	}
+	if tableExists(t, db, "b") {
+		t.Error("partial migration 002 should be rolled back")
+	}
+	if got := appliedVersions(t, m); len(got) != 1 {
+		t.Errorf("recorded = %v, want only 001", got)
+	}
+}
+
+func TestMigrator_ChecksumMismatch(t *testing.T) {
+	ctx := context.Background()
+	db := openMigrationDB(t)
+	m, err := gateways.NewMigrator(db, migrationFS())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	edited := migrationFS()
+	edited["002_create_b.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE b (id INTEGER PRIMARY KEY, note TEXT);")}
+	m, err = gateways.NewMigrator(db, edited)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Up(ctx); !errors.Is(err, gateways.ErrChecksumMismatch) {
+		t.Errorf("Up() error = %v, want ErrChecksumMismatch", err)
+	}
+	if _, err := m.Down(ctx); !errors.Is(err, gateways.ErrChecksumMismatch) {
+		t.Errorf("Down() error = %v, want ErrChecksumMismatch", err)
+	}
+	if err := m.Verify(ctx); !errors.Is(err, gateways.ErrChecksumMismatch) {
+		t.Errorf("Verify() error = %v, want ErrChecksumMismatch", err)
+	}
+	statuses, err := m.Status(ctx)
+	if err != nil || !statuses[1].Modified {
+		t.Errorf("Status() = %+v, %v; want 002 modified", statuses, err)
+	}
+}
+
+func TestMigrator_Lock(t *testing.T) {
+	ctx := context.Background()
+	db := openMigrationDB(t)
+	m, err := gateways.NewMigrator(db, migrationFS())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Status(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// another runner holds the lock
+	if _, err := db.Exec("INSERT INTO schema_migrations_lock (id, holder, locked_at) VALUES (1, 'other:1', '2024-01-01 00:00:00')"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(ctx); !errors.Is(err, gateways.ErrMigrationLocked) {
+		t.Fatalf("Up() error = %v, want ErrMigrationLocked", err)
+	}
+	if tableExists(t, db, "a") {
+		t.Error("no migration should run while locked")
+	}
+
+	if err := m.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("Up() after unlock error = %v", err)
+	}
+
+	var n int
+	if err := db.QueryRow("SELECT count(*) FROM schema_migrations_lock").Scan(&n); err != nil || n != 0 {
+		t.Errorf("lock rows = %d, %v; want released", n, err)
+	}
+}
+
+func TestMigrator_LegacySchemaMigrations(t *testing.T) {
+	ctx := context.Background()
+	db := openMigrationDB(t)
+	// layout and rows written by the file-name based runner
+	if _, err := db.Exec(`
+		CREATE TABLE schema_migrations (version TEXT PRIMARY KEY, applied_at DATETIME DEFAULT CURRENT_TIMESTAMP);
+		CREATE TABLE a (id INTEGER PRIMARY KEY);
+		INSERT INTO schema_migrations (version) VALUES ('001_create_a.sql');`); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := gateways.NewMigrator(db, migrationFS())
+	if err != nil {
+		t.Fatal(err)
+	}
+	applied, err := m.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if len(applied) != 2 || applied[0] != "002" {
+		t.Errorf("applied = %v, want 002 and 003", applied)
+	}
+
+	var checksum string
+	if err := db.QueryRow("SELECT checksum FROM schema_migrations WHERE version = '001'").Scan(&checksum); err != nil || checksum == "" {
+		t.Errorf("legacy row checksum = %q, %v; want backfilled", checksum, err)
+	}
+}
+
+func TestMigrator_Irreversible(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "001_create_test.sql"), []byte("CREATE TABLE t (id INTEGER);"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := gateways.NewMigrator(openMigrationDB(t), os.DirFS(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Down(ctx); !errors.Is(err, gateways.ErrIrreversibleMigration) {
+		t.Errorf("Down() error = %v, want ErrIrreversibleMigration", err)
+	}
+}
+
+func TestLoadMigrations_Embedded(t *testing.T) {
+	migrations, err := gateways.LoadMigrations(script.Migrations())
+	if err != nil {
+		t.Fatalf("LoadMigrations() error = %v", err)
+	}
+	for i, m := range migrations {
+		if !m.HasDown {
+			t.Errorf("migration %s_%s has no down file", m.Version, m.Name)
+		}
+		if i > 0 && m.Version <= migrations[i-1].Version {
+			t.Errorf("migrations out of order: %s after %s", m.Version, migrations[i-1].Version)
+		}
+	}
+	if len(migrations) == 0 || migrations[0].Version != "000" {
+		t.Errorf("expected the base schema as migration 000, got %+v", migrations)
+	}
+}
+
+func TestMigrator_EmbeddedInMemory(t *testing.T) {
+	ctx := context.Background()
+	db := gateways.ConnectDB(":memory:")
+	defer db.Close()
+	db.SetMaxOpenConns(1) // every :memory: connection is a separate database
+
+	m, err := gateways.NewMigrator(db, script.Migrations())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if err := m.Verify(ctx); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+	for _, table := range []string{"policyholder_records", "audit_history", "event_logs", "feature_flags", "api_versions", "observability_metric_rollups"} {
+		if !tableExists(t, db, table) {
+			t.Errorf("expected table %s", table)
+		}
+	}
+
+	if err := m.To(ctx, "0"); err != nil {
+		t.Fatalf("To(0) error = %v", err)
+	}
+	if _, err := m.Down(ctx); err != nil {
+		t.Fatalf("Down() error = %v", err)
+	}
+	if tableExists(t, db, "policyholder_records") {
+		t.Error("reverting 000 should drop the base tables")
+	}
+}
+
+func TestMigrator_VerifyReadOnly(t *testing.T) {
+	ctx := context.Background()
+	db, err := gateways.OpenDatabase(filepath.Join(t.TempDir(), "verify.db"), gateways.DatabaseOptions{BusyTimeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	m, err := gateways.NewMigrator(db.Writer(), migrationFS())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.VerifyReadOnly(ctx, db.Reader()); err == nil {
+		t.Error("VerifyReadOnly() before any migration succeeded")
+	}
+	if tableExists(t, db.Reader(), "schema_migrations") {
+		t.Error("VerifyReadOnly() created schema_migrations")
+	}
+
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// the single writer connection is busy; the check must not wait for it
+	tx, err := db.Writer().BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("INSERT INTO a (id) VALUES (2)"); err != nil {
+		t.Fatal(err)
+	}
+	probeCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if err := m.VerifyReadOnly(probeCtx, db.Reader()); err != nil {
+		t.Errorf("VerifyReadOnly() during a write = %v", err)
+	}
+	tx.Rollback()
+
+	if _, err := m.Down(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.VerifyReadOnly(ctx, db.Reader()); !errors.Is(err, gateways.ErrPendingMigrations) {
+		t.Errorf("VerifyReadOnly() = %v, want ErrPendingMigrations", err)
+	}
+}
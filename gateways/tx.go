@@ -0,0 +1,133 @@
+package gateways
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Defaults for TxOptions
+const (
+	DefaultTxMaxWait      = 2 * time.Second
+	DefaultTxBaseDelay    = 10 * time.Millisecond
+	DefaultTxMaxDelay     = 250 * time.Millisecond
+	DefaultBusyRetryAfter = time.Second
+)
+
+// ErrBusy matches every *BusyError
+var ErrBusy = errors.New("database is busy")
+
+// BusyError is returned when a transaction still hit SQLITE_BUSY/SQLITE_LOCKED
+// after retrying until its deadline
+type BusyError struct {
+	Operation  string
+	Attempts   int
+	RetryAfter time.Duration // suggested client back-off
+	Err        error         // last driver error
+}
+
+func (e *BusyError) Error() string {
+	return fmt.Sprintf("%s: database is busy after %d attempts: %v", e.Operation, e.Attempts, e.Err)
+}
+
+func (e *BusyError) Unwrap() error { return e.Err }
+
+func (e *BusyError) Is(target error) bool { return target == ErrBusy }
+
+// IsBusy reports whether err is a transient lock error worth retrying
+func IsBusy(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+	}
+	return false
+}
+
+// TxOptions controls RunInTx retries; zero values use the defaults
+type TxOptions struct {
+	Operation string        // names the transaction in errors and OnRetry
+	MaxWait   time.Duration // retry budget; a sooner context deadline wins
+	BaseDelay time.Duration // first back-off, doubled per attempt
+	MaxDelay  time.Duration // back-off cap
+	// OnRetry is called before each retry with the attempt that failed
+	OnRetry func(attempt int, err error)
+}
+
+func (o TxOptions) withDefaults() TxOptions {
+	if o.MaxWait <= 0 {
+		o.MaxWait = DefaultTxMaxWait
+	}
+	if o.BaseDelay <= 0 {
+		o.BaseDelay = DefaultTxBaseDelay
+	}
+	if o.MaxDelay <= 0 {
+		o.MaxDelay = DefaultTxMaxDelay
+	}
+	return o
+}
+
+// RunInTx runs fn in a transaction and commits it. When BEGIN, fn or COMMIT
+// fails with SQLITE_BUSY/SQLITE_LOCKED the transaction is rolled back and fn
+// runs again after a jittered back-off, so fn must only touch tx and state it
+// resets itself. Write transactions should use the Database writer pool, whose
+// DSN makes BEGIN take the write lock up front (BEGIN IMMEDIATE) so lock
+// conflicts surface before any work is done.
+func RunInTx(ctx context.Context, db *sql.DB, opts TxOptions, fn func(*sql.Tx) error) error {
+	opts = opts.withDefaults()
+	deadline := time.Now().Add(opts.MaxWait)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		err := runTxOnce(ctx, db, fn)
+		if err == nil || !IsBusy(err) {
+			return err
+		}
+
+		// a busy attempt lasts about the busy timeout; don't start one that
+		// would run past the deadline
+		delay := backoff(opts, attempt)
+		if time.Now().Add(delay + time.Since(start)).After(deadline) {
+			return &BusyError{Operation: opts.Operation, Attempts: attempt, RetryAfter: DefaultBusyRetryAfter, Err: err}
+		}
+		if opts.OnRetry != nil {
+			opts.OnRetry(attempt, err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func runTxOnce(ctx context.Context, db *sql.DB, fn func(*sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// backoff is "full jitter": uniform in [0, min(MaxDelay, BaseDelay*2^(attempt-1))]
+func backoff(opts TxOptions, attempt int) time.Duration {
+	ceiling := opts.BaseDelay << (attempt - 1)
+	if ceiling <= 0 || ceiling > opts.MaxDelay {
+		ceiling = opts.MaxDelay
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
@@ -0,0 +1,176 @@
+package gateways_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rainbowmga/timetravel/gateways"
+)
+
+// lockedDB returns a second handle on a file whose write lock is held by the
+// first until release is called; the second handle does not wait on locks itself
+func lockedDB(t *testing.T) (db *sql.DB, release func()) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "locked.db")
+
+	holder, err := gateways.OpenDatabase(path, gateways.DatabaseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { holder.Close() })
+	if _, err := holder.Writer().Exec("CREATE TABLE t (id INTEGER PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := holder.Writer().Begin() // BEGIN IMMEDIATE: takes the write lock now
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = sql.Open("sqlite3", path+"?_busy_timeout=0&_txlock=immediate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, func() { tx.Rollback() }
+}
+
+func insertOne(tx *sql.Tx) error {
+	_, err := tx.Exec("INSERT INTO t (id) VALUES (1)")
+	return err
+}
+
+func TestRunInTx_GivesUpWithBusyError(t *testing.T) {
+	db, release := lockedDB(t)
+	defer release()
+
+	retries := 0
+	err := gateways.RunInTx(context.Background(), db, gateways.TxOptions{
+		Operation: "insert",
+		MaxWait:   100 * time.Millisecond,
+		OnRetry:   func(int, error) { retries++ },
+	}, insertOne)
+
+	var busy *gateways.BusyError
+	if !errors.As(err, &busy) || !errors.Is(err, gateways.ErrBusy) {
+		t.Fatalf("RunInTx() error = %v, want *BusyError", err)
+	}
+	if busy.Operation != "insert" || busy.Attempts != retries+1 || busy.RetryAfter <= 0 {
+		t.Errorf("BusyError = %+v after %d retries", busy, retries)
+	}
+	if retries == 0 {
+		t.Error("expected at least one retry")
+	}
+}
+
+func TestRunInTx_SucceedsOnceLockIsReleased(t *testing.T) {
+	db, release := lockedDB(t)
+	time.AfterFunc(50*time.Millisecond, release)
+
+	retries := 0
+	err := gateways.RunInTx(context.Background(), db, gateways.TxOptions{
+		MaxWait: 5 * time.Second,
+		OnRetry: func(int, error) { retries++ },
+	}, insertOne)
+	if err != nil {
+		t.Fatalf("RunInTx() error = %v", err)
+	}
+	if retries == 0 {
+		t.Error("expected retries while the lock was held")
+	}
+
+	var n int
+	if err := db.QueryRow("SELECT count(*) FROM t").Scan(&n); err != nil || n != 1 {
+		t.Errorf("rows = %d, %v; want 1", n, err)
+	}
+}
+
+func TestRunInTx_ContextDeadlineBoundsRetries(t *testing.T) {
+	db, release := lockedDB(t)
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 80*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := gateways.RunInTx(ctx, db, gateways.TxOptions{MaxWait: 10 * time.Second}, insertOne)
+	if !errors.Is(err, gateways.ErrBusy) && !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("RunInTx() error = %v, want busy or deadline", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("RunInTx() kept retrying for %v past the request deadline", elapsed)
+	}
+}
+
+func TestRunInTx_OtherErrorsAreNotRetried(t *testing.T) {
+	db := openMigrationDB(t)
+	if _, err := db.Exec("CREATE TABLE t (id INTEGER PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	err := gateways.RunInTx(context.Background(), db, gateways.TxOptions{}, func(tx *sql.Tx) error {
+		calls++
+		if err := insertOne(tx); err != nil {
+			return err
+		}
+		return insertOne(tx) // primary key violation
+	})
+	if err == nil || gateways.IsBusy(err) || calls != 1 {
+		t.Fatalf("RunInTx() error = %v after %d calls, want one constraint failure", err, calls)
+	}
+
+	var n int
+	if err := db.QueryRow("SELECT count(*) FROM t").Scan(&n); err != nil || n != 0 {
+		t.Errorf("rows = %d, %v; want rolled back", n, err)
+	}
+}
+
+// with the default busy timeout each attempt gives up quickly, so RunInTx backs
+// off and retries, and its BusyError arrives by the caller's deadline
+func TestRunInTx_DefaultBusyTimeoutKeepsTheDeadline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "busy.db")
+	holder, err := gateways.OpenDatabase(path, gateways.DatabaseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer holder.Close()
+	if _, err := holder.Writer().Exec("CREATE TABLE t (id INTEGER PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := holder.Writer().Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	db, err := gateways.OpenDatabase(path, gateways.DatabaseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	const budget = time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), budget)
+	defer cancel()
+	retries := 0
+	start := time.Now()
+	err = gateways.RunInTx(ctx, db.Writer(), gateways.TxOptions{
+		Operation: "insert",
+		OnRetry:   func(int, error) { retries++ },
+	}, insertOne)
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, gateways.ErrBusy) {
+		t.Fatalf("RunInTx() error = %v, want a *BusyError", err)
+	}
+	if elapsed > budget {
+		t.Errorf("RunInTx() took %v, past the %v deadline", elapsed, budget)
+	}
+	if retries == 0 {
+		t.Error("RunInTx() never backed off and retried")
+	}
+}
@@ -0,0 +1,175 @@
+// Package graphqlapi serves read-only record history exploration over GraphQL
+// (POST /api/v2/graphql): policyholders, records, versions with diffs, and
+// event logs, with as-of arguments, cursor pagination and query-cost limits.
+package graphqlapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/authz"
+	"github.com/rainbowmga/timetravel/controller"
+	"github.com/rainbowmga/timetravel/entity"
+	"github.com/rainbowmga/timetravel/observability"
+)
+
+// request failures answered as problem+json, like the other v2 routes
+var (
+	errV2Disabled     = apperr.New(apperr.ErrPermissionDenied, "enable_v2_api flag is disabled")
+	errInvalidRequest = apperr.InvalidArgument(`body must be JSON: {"query": "...", "variables": {...}, "operationName": "..."}`)
+)
+
+// codes under extensions.code for documents rejected before execution
+const (
+	codeQueryInvalid      = "query_invalid"
+	codeQueryTooExpensive = "query_too_expensive"
+	codeQueryTooDeep      = "query_too_deep"
+)
+
+type HistoryController interface {
+	Policyholder(ctx context.Context, id int64) (entity.Policyholder, error)
+	Policyholders(ctx context.Context, afterID int64, first int) ([]entity.Policyholder, bool, error)
+	Record(ctx context.Context, policyholderID int64, asOf time.Time) (entity.PolicyholderRecord, error)
+	Versions(ctx context.Context, recordID int64, afterVersion int, asOf time.Time, first int) ([]entity.AuditEntry, bool, error)
+	Events(ctx context.Context, recordID, afterID int64, asOf time.Time, first int) ([]entity.EventLog, bool, error)
+	VersionEvents(ctx context.Context, entry entity.AuditEntry) ([]entity.EventLog, error)
+}
+
+var _ HistoryController = (*controller.HistoryController)(nil)
+
+type FeatureFlagService interface {
+	IsEnabled(ctx context.Context, key string) bool
+}
+
+// Authorizer checks the permission of the endpoint (authz.Authorizer)
+type Authorizer interface {
+	Require(perm authz.Permission) mux.MiddlewareFunc
+}
+
+// API answers GraphQL queries against the history controller
+type API struct {
+	Flags  FeatureFlagService
+	Limits Limits
+	Authz  Authorizer
+
+	schema graphql.Schema
+}
+
+// NewAPI builds the schema; limits with zero fields use the defaults
+func NewAPI(history HistoryController, flags FeatureFlagService, a Authorizer, limits Limits) (*API, error) {
+	schema, err := newSchema(history)
+	if err != nil {
+		return nil, fmt.Errorf("graphql schema: %w", err)
+	}
+	return &API{Flags: flags, Limits: limits.withDefaults(), Authz: a, schema: schema}, nil
+}
+
+// CreateRoutes registers the GraphQL endpoint; every query reads history
+func (api *API) CreateRoutes(router *mux.Router) {
+	router.Handle("/graphql", api.Authz.Require(authz.HistoryRead)(http.HandlerFunc(api.Query))).Methods("POST")
+}
+
+type queryRequest struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+// POST /api/v2/graphql
+// Documents that fail to parse, validate or fit the cost limits are answered
+// 400 with a GraphQL errors body; executed queries are 200, with per-field
+// errors (extensions.code is the apperr code) next to partial data.
+func (api *API) Query(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if !api.Flags.IsEnabled(ctx, "enable_v2_api") {
+		respondError(w, r, errV2Disabled)
+		return
+	}
+
+	var req queryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Query == "" {
+		respondError(w, r, errInvalidRequest)
+		return
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{
+		Body: []byte(req.Query),
+		Name: "GraphQL request",
+	})})
+	if err != nil {
+		respondRejected(w, codeQueryInvalid, nil, gqlerrors.FormatError(err))
+		return
+	}
+	if result := graphql.ValidateDocument(&api.schema, doc, nil); !result.IsValid {
+		respondRejected(w, codeQueryInvalid, nil, result.Errors...)
+		return
+	}
+
+	cost, depth := queryCost(&api.schema, doc, req.OperationName, req.Variables)
+	if depth > api.Limits.MaxDepth {
+		respondRejected(w, codeQueryTooDeep, map[string]interface{}{"depth": depth, "max_depth": api.Limits.MaxDepth},
+			gqlerrors.NewFormattedError(fmt.Sprintf("query depth %d exceeds the limit of %d", depth, api.Limits.MaxDepth)))
+		return
+	}
+	if cost > api.Limits.MaxCost {
+		respondRejected(w, codeQueryTooExpensive, map[string]interface{}{"cost": cost, "max_cost": api.Limits.MaxCost},
+			gqlerrors.NewFormattedError(fmt.Sprintf("query cost %d exceeds the limit of %d; request smaller pages or fewer nested lists", cost, api.Limits.MaxCost)))
+		return
+	}
+
+	ctx, span := observability.StartSpan(ctx, "GraphQL.Execute",
+		attribute.String("graphql.operation", req.OperationName),
+		attribute.Int("graphql.cost", cost),
+		attribute.Int("graphql.depth", depth),
+	)
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        api.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       ctx,
+	})
+	observability.EndSpan(span, nil)
+
+	observability.DefaultLogger.InfoContext(ctx, "graphql_query",
+		"operation", req.OperationName, "cost", cost, "depth", depth, "errors", len(result.Errors))
+	respondJSON(w, http.StatusOK, result)
+}
+
+// respondRejected answers 400 with GraphQL errors; each carries code and the
+// given details under extensions
+func respondRejected(w http.ResponseWriter, code string, details map[string]interface{}, errs ...gqlerrors.FormattedError) {
+	for i := range errs {
+		ext := map[string]interface{}{"code": code}
+		for k, v := range details {
+			ext[k] = v
+		}
+		errs[i].Extensions = ext
+	}
+	respondJSON(w, http.StatusBadRequest, &graphql.Result{Errors: errs})
+}
+
+func respondJSON(w http.ResponseWriter, status int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+// respondError answers with an RFC 7807 problem, as the v2 handlers do
+func respondError(w http.ResponseWriter, r *http.Request, err error) {
+	if status := apperr.HTTPStatus(err); status >= http.StatusInternalServerError {
+		observability.DefaultLogger.ErrorContext(r.Context(), "request_failed", "status", status, "error", err)
+	}
+	apperr.WriteProblem(w, r, err)
+}
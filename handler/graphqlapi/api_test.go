@@ -0,0 +1,329 @@
+package graphqlapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rainbowmga/timetravel/auth"
+	"github.com/rainbowmga/timetravel/authz"
+	"github.com/rainbowmga/timetravel/common"
+	"github.com/rainbowmga/timetravel/controller"
+	"github.com/rainbowmga/timetravel/gateways"
+	"github.com/rainbowmga/timetravel/handler/graphqlapi"
+	"github.com/rainbowmga/timetravel/script"
+	"github.com/rainbowmga/timetravel/service"
+)
+
+type stubFlags struct{ enabled bool }
+
+func (f stubFlags) IsEnabled(ctx context.Context, key string) bool {
+	return f.enabled && key == "enable_v2_api"
+}
+
+// newRouter serves the API over a migrated SQLite file; records is used to
+// write history for the queries to read
+func newRouter(t *testing.T, flags stubFlags, limits graphqlapi.Limits) (*mux.Router, *service.SQLiteRecordService) {
+	t.Helper()
+	return newRouterAs(t, flags, limits, authz.RoleAuditor)
+}
+
+// newRouterAs is newRouter for callers holding roles
+func newRouterAs(t *testing.T, flags stubFlags, limits graphqlapi.Limits, roles ...string) (*mux.Router, *service.SQLiteRecordService) {
+	t.Helper()
+
+	db, err := gateways.OpenDatabase(filepath.Join(t.TempDir(), "graphql.db"), gateways.DatabaseOptions{})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	m, err := gateways.NewMigrator(db.Writer(), script.Migrations())
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	authorizer := authz.New(authz.DefaultPolicy(), nil, nil)
+	api, err := graphqlapi.NewAPI(controller.NewHistoryController(service.NewHistoryServiceWithDB(db)), flags, authorizer, limits)
+	if err != nil {
+		t.Fatalf("NewAPI: %v", err)
+	}
+	router := mux.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := auth.WithPrincipal(r.Context(), auth.Principal{UserID: 1, Roles: roles})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+	api.CreateRoutes(router.PathPrefix("/api/v2").Subrouter())
+	return router, service.NewSQLiteRecordServiceWithDB(db, nil, "development")
+}
+
+type gqlResponse struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func post(t *testing.T, router http.Handler, body string) (*httptest.ResponseRecorder, gqlResponse) {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/api/v2/graphql", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	var resp gqlResponse
+	if strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") {
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode %s: %v", rec.Body.String(), err)
+		}
+	}
+	return rec, resp
+}
+
+func query(t *testing.T, router http.Handler, q string, vars map[string]interface{}) gqlResponse {
+	t.Helper()
+	body, _ := json.Marshal(map[string]interface{}{"query": q, "variables": vars})
+	rec, resp := post(t, router, string(body))
+	if rec.Code != http.StatusOK || len(resp.Errors) > 0 {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	return resp
+}
+
+// path walks decoded JSON by object keys and array indexes
+func path(v interface{}, keys ...interface{}) interface{} {
+	for _, k := range keys {
+		switch k := k.(type) {
+		case string:
+			v = v.(map[string]interface{})[k]
+		case int:
+			v = v.([]interface{})[k]
+		}
+	}
+	return v
+}
+
+func TestQuery_VersionsWithDiffsAndEvents(t *testing.T) {
+	router, records := newRouter(t, stubFlags{enabled: true}, graphqlapi.Limits{})
+	ctx := context.Background()
+	_, _ = records.CreateOrUpdate(ctx, 1, map[string]string{"name": "ada", "city": "london"})
+	by := context.WithValue(ctx, common.UserIDKey, int64(7))
+	by = context.WithValue(by, common.ChangeSourceKey, common.SourceAPIV2)
+	by = context.WithValue(by, common.ChangeReasonKey, "legal name")
+	_, _ = records.CreateOrUpdate(by, 1, map[string]string{"name": "ada lovelace", "city": "london"})
+
+	resp := query(t, router, `{
+		policyholder(id: 1) {
+			id
+			record {
+				version
+				data { key value }
+				versions {
+					edges { node { version action changedBy source reason diff { key kind before after } events { action changedBy } } }
+				}
+			}
+		}
+	}`, nil)
+
+	rec := path(resp.Data, "policyholder", "record")
+	if v := path(rec, "version"); v != float64(2) {
+		t.Fatalf("version = %v, want 2", v)
+	}
+	edges := path(rec, "versions", "edges").([]interface{})
+	if len(edges) != 2 {
+		t.Fatalf("got %d versions, want 2", len(edges))
+	}
+	if got := len(path(edges[0], "node", "diff").([]interface{})); got != 2 {
+		t.Errorf("first version diff has %d keys, want every key (2)", got)
+	}
+	diff := path(edges[1], "node", "diff").([]interface{})
+	if len(diff) != 1 || path(diff[0], "key") != "name" || path(diff[0], "kind") != "CHANGED" ||
+		path(diff[0], "before") != "ada" || path(diff[0], "after") != "ada lovelace" {
+		t.Errorf("second version diff = %v", diff)
+	}
+	if events := path(edges[1], "node", "events").([]interface{}); len(events) == 0 || path(events[0], "changedBy") != float64(7) {
+		t.Errorf("second version events = %v, want one by user 7", events)
+	}
+	if path(edges[0], "node", "changedBy") != nil || path(edges[0], "node", "source") != nil {
+		t.Errorf("unattributed version = %v, want null changedBy and source", path(edges[0], "node"))
+	}
+	second := path(edges[1], "node")
+	if path(second, "changedBy") != float64(7) || path(second, "source") != "api_v2" || path(second, "reason") != "legal name" {
+		t.Errorf("attributed version = %v", second)
+	}
+}
+
+func TestQuery_AsOf(t *testing.T) {
+	router, records := newRouter(t, stubFlags{enabled: true}, graphqlapi.Limits{})
+	ctx := context.Background()
+	_, _ = records.CreateOrUpdate(ctx, 1, map[string]string{"name": "a"})
+	time.Sleep(20 * time.Millisecond)
+	between := time.Now().UTC()
+	time.Sleep(20 * time.Millisecond)
+	_, _ = records.CreateOrUpdate(ctx, 1, map[string]string{"name": "b"})
+
+	q := `query($at: DateTime) {
+		record(policyholderId: 1, asOf: $at) { version data { value } versions { edges { node { version } } } }
+	}`
+
+	resp := query(t, router, q, map[string]interface{}{"at": between.Format(time.RFC3339Nano)})
+	rec := path(resp.Data, "record")
+	if v := path(rec, "version"); v != float64(1) {
+		t.Errorf("version as of before the update = %v, want 1", v)
+	}
+	if v := path(rec, "data", 0, "value"); v != "a" {
+		t.Errorf("data as of before the update = %v, want a", v)
+	}
+	if n := len(path(rec, "versions", "edges").([]interface{})); n != 1 {
+		t.Errorf("versions inherit asOf: got %d, want 1", n)
+	}
+
+	resp = query(t, router, q, map[string]interface{}{"at": between.Add(-time.Hour).Format(time.RFC3339Nano)})
+	if rec := path(resp.Data, "record"); rec != nil {
+		t.Errorf("record before it existed = %v, want null", rec)
+	}
+}
+
+func TestQuery_CursorPagination(t *testing.T) {
+	router, records := newRouter(t, stubFlags{enabled: true}, graphqlapi.Limits{})
+	ctx := context.Background()
+	for id := int64(1); id <= 5; id++ {
+		_, _ = records.CreateOrUpdate(ctx, id, map[string]string{"n": "x"})
+	}
+
+	q := `query($after: String) {
+		policyholders(first: 2, after: $after) { edges { node { id } } pageInfo { hasNextPage endCursor } }
+	}`
+	var ids []float64
+	var after interface{}
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("pagination did not end")
+		}
+		conn := path(query(t, router, q, map[string]interface{}{"after": after}).Data, "policyholders")
+		for _, e := range path(conn, "edges").([]interface{}) {
+			ids = append(ids, path(e, "node", "id").(float64))
+		}
+		if path(conn, "pageInfo", "hasNextPage") != true {
+			break
+		}
+		after = path(conn, "pageInfo", "endCursor")
+	}
+	if len(ids) != 5 || ids[0] != 1 || ids[4] != 5 {
+		t.Errorf("ids = %v, want 1..5", ids)
+	}
+}
+
+func TestQuery_Rejections(t *testing.T) {
+	router, _ := newRouter(t, stubFlags{enabled: true}, graphqlapi.Limits{MaxCost: 500, MaxDepth: 8})
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode string
+	}{
+		{
+			name:     "syntax error",
+			body:     `{"query":"{ policyholders {"}`,
+			wantCode: "query_invalid",
+		},
+		{
+			name:     "unknown field",
+			body:     `{"query":"{ policyholders { nope } }"}`,
+			wantCode: "query_invalid",
+		},
+		{
+			name:     "nested pages multiply",
+			body:     `{"query":"{ policyholders(first: 100) { edges { node { record { versions(first: 100) { edges { node { version } } } } } } } }"}`,
+			wantCode: "query_too_expensive",
+		},
+		{
+			name:     "page size from a variable",
+			body:     `{"query":"query($n: Int) { policyholders(first: $n) { edges { node { id name email } } } }","variables":{"n":100}}`,
+			wantCode: "query_too_expensive",
+		},
+		{
+			name:     "too deep",
+			body:     `{"query":"{ policyholders(first: 1) { edges { node { record { versions(first: 1) { edges { node { events { action } } } } } } } } }"}`,
+			wantCode: "query_too_deep",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, resp := post(t, router, tt.body)
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400: %s", rec.Code, rec.Body.String())
+			}
+			if len(resp.Errors) == 0 || resp.Errors[0].Extensions["code"] != tt.wantCode {
+				t.Fatalf("errors = %+v, want code %s", resp.Errors, tt.wantCode)
+			}
+			if resp.Data != nil {
+				t.Errorf("data = %v, want none for a rejected query", resp.Data)
+			}
+		})
+	}
+}
+
+func TestQuery_ProblemsAndFieldErrors(t *testing.T) {
+	t.Run("flag disabled", func(t *testing.T) {
+		router, _ := newRouter(t, stubFlags{}, graphqlapi.Limits{})
+		rec, _ := post(t, router, `{"query":"{ policyholders { edges { cursor } } }"}`)
+		if rec.Code != http.StatusForbidden || rec.Header().Get("Content-Type") != "application/problem+json" {
+			t.Errorf("got %d %q, want 403 problem", rec.Code, rec.Header().Get("Content-Type"))
+		}
+	})
+
+	t.Run("history:read required", func(t *testing.T) {
+		router, _ := newRouterAs(t, stubFlags{enabled: true}, graphqlapi.Limits{}, authz.RoleReader)
+		rec, _ := post(t, router, `{"query":"{ policyholders { edges { cursor } } }"}`)
+		if rec.Code != http.StatusForbidden || rec.Header().Get("Content-Type") != "application/problem+json" {
+			t.Errorf("got %d %q, want 403 problem", rec.Code, rec.Header().Get("Content-Type"))
+		}
+	})
+
+	router, _ := newRouter(t, stubFlags{enabled: true}, graphqlapi.Limits{})
+	for _, body := range []string{`not json`, `{"variables":{}}`} {
+		rec, _ := post(t, router, body)
+		if rec.Code != http.StatusBadRequest || rec.Header().Get("Content-Type") != "application/problem+json" {
+			t.Errorf("%s: got %d %q, want 400 problem", body, rec.Code, rec.Header().Get("Content-Type"))
+		}
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		wantCode string
+	}{
+		{"bad cursor", `{ policyholders(after: "bm9wZQ") { edges { cursor } } }`, "invalid_argument"},
+		{"cursor of another list", `{ policyholders(after: "dmVyc2lvbjox") { edges { cursor } } }`, "invalid_argument"},
+		{"page too large", `{ policyholders(first: 101) { edges { cursor } } }`, "invalid_argument"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(map[string]string{"query": tt.query})
+			rec, resp := post(t, router, string(body))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200 with a field error", rec.Code)
+			}
+			if len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != tt.wantCode {
+				t.Errorf("errors = %+v, want code %s", resp.Errors, tt.wantCode)
+			}
+		})
+	}
+
+	resp := query(t, router, `{ policyholder(id: 404) { id } record(policyholderId: 404) { version } }`, nil)
+	if resp.Data["policyholder"] != nil || resp.Data["record"] != nil {
+		t.Errorf("missing policyholder/record = %v, want nulls", resp.Data)
+	}
+}
@@ -0,0 +1,189 @@
+package graphqlapi
+
+import (
+	"strconv"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+
+	"github.com/rainbowmga/timetravel/controller"
+)
+
+const (
+	// DefaultMaxCost bounds the estimated rows and fields one query may read
+	DefaultMaxCost = 2000
+	// DefaultMaxDepth bounds how deeply fields may nest
+	DefaultMaxDepth = 10
+)
+
+// Limits reject queries before they run. Zero values mean the defaults.
+type Limits struct {
+	MaxCost  int
+	MaxDepth int
+}
+
+func (l Limits) withDefaults() Limits {
+	if l.MaxCost <= 0 {
+		l.MaxCost = DefaultMaxCost
+	}
+	if l.MaxDepth <= 0 {
+		l.MaxDepth = DefaultMaxDepth
+	}
+	return l
+}
+
+// queryCost estimates an operation's worst case before execution. Every field
+// costs 1; a paginated field (one taking first) multiplies the cost of its
+// selection by the page size it asks for, so nested listings such as
+// policyholders -> versions -> events grow multiplicatively, as their queries
+// against audit_history and event_logs do. Depth counts nested fields.
+func queryCost(schema *graphql.Schema, doc *ast.Document, operationName string, variables map[string]interface{}) (cost, depth int) {
+	w := costWalker{
+		schema:    schema,
+		fragments: map[string]*ast.FragmentDefinition{},
+		variables: variables,
+		visiting:  map[string]bool{},
+	}
+	var op *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *ast.FragmentDefinition:
+			w.fragments[def.Name.Value] = def
+		case *ast.OperationDefinition:
+			if op == nil && (operationName == "" || (def.Name != nil && def.Name.Value == operationName)) {
+				op = def
+			}
+		}
+	}
+	if op == nil {
+		return 0, 0
+	}
+	w.defaults = map[string]ast.Value{}
+	for _, v := range op.VariableDefinitions {
+		if v.DefaultValue != nil {
+			w.defaults[v.Variable.Name.Value] = v.DefaultValue
+		}
+	}
+	return w.selectionSet(op.SelectionSet, schema.QueryType(), 0)
+}
+
+type costWalker struct {
+	schema    *graphql.Schema
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+	defaults  map[string]ast.Value
+	visiting  map[string]bool // fragment spreads on the current path
+}
+
+// selectionSet returns the cost of a selection set on parent and the deepest
+// field level reached; depth is the level of the enclosing field
+func (w *costWalker) selectionSet(set *ast.SelectionSet, parent graphql.Type, depth int) (cost, maxDepth int) {
+	if set == nil {
+		return 0, depth
+	}
+	maxDepth = depth
+	add := func(c, d int) {
+		cost += c
+		if d > maxDepth {
+			maxDepth = d
+		}
+	}
+
+	for _, sel := range set.Selections {
+		switch sel := sel.(type) {
+		case *ast.Field:
+			def := fieldDef(parent, sel.Name.Value)
+			var child graphql.Type
+			pageSize := 1
+			if def != nil {
+				child = def.Type
+				pageSize = w.pageSize(sel, def)
+			}
+			c, d := w.selectionSet(sel.SelectionSet, child, depth+1)
+			add(1+pageSize*c, d)
+		case *ast.InlineFragment:
+			t := parent
+			if sel.TypeCondition != nil {
+				t = w.schema.Type(sel.TypeCondition.Name.Value)
+			}
+			add(w.selectionSet(sel.SelectionSet, t, depth))
+		case *ast.FragmentSpread:
+			name := sel.Name.Value
+			frag, ok := w.fragments[name]
+			if !ok || w.visiting[name] {
+				continue
+			}
+			w.visiting[name] = true
+			add(w.selectionSet(frag.SelectionSet, w.schema.Type(frag.TypeCondition.Name.Value), depth))
+			w.visiting[name] = false
+		}
+	}
+	return cost, maxDepth
+}
+
+// fieldDef looks a field up on an object type; introspection and unknown
+// fields have none and cost 1 per selected field
+func fieldDef(parent graphql.Type, name string) *graphql.FieldDefinition {
+	if parent == nil {
+		return nil
+	}
+	obj, ok := graphql.GetNamed(parent).(*graphql.Object)
+	if !ok {
+		return nil
+	}
+	return obj.Fields()[name]
+}
+
+// pageSize is the first argument as the resolver will see it, capped at
+// controller.MaxPageSize (larger pages are rejected when resolved); 1 for
+// fields that are not paginated
+func (w *costWalker) pageSize(field *ast.Field, def *graphql.FieldDefinition) int {
+	var arg *graphql.Argument
+	for _, a := range def.Args {
+		if a.Name() == "first" {
+			arg = a
+		}
+	}
+	if arg == nil {
+		return 1
+	}
+
+	n := controller.DefaultPageSize
+	if d, ok := arg.DefaultValue.(int); ok {
+		n = d
+	}
+	for _, a := range field.Arguments {
+		if a.Name.Value == "first" {
+			if v, ok := w.intValue(a.Value); ok {
+				n = v
+			}
+		}
+	}
+	if n < 1 {
+		return 1
+	}
+	if n > controller.MaxPageSize {
+		return controller.MaxPageSize
+	}
+	return n
+}
+
+func (w *costWalker) intValue(v ast.Value) (int, bool) {
+	switch v := v.(type) {
+	case *ast.IntValue:
+		n, err := strconv.Atoi(v.Value)
+		return n, err == nil
+	case *ast.Variable:
+		name := v.Name.Value
+		switch n := w.variables[name].(type) {
+		case float64:
+			return int(n), true
+		case int:
+			return n, true
+		}
+		if d, ok := w.defaults[name]; ok {
+			return w.intValue(d)
+		}
+	}
+	return 0, false
+}
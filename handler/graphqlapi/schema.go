@@ -0,0 +1,504 @@
+package graphqlapi
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/graphql-go/graphql"
+
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/controller"
+	"github.com/rainbowmga/timetravel/entity"
+	"github.com/rainbowmga/timetravel/observability"
+)
+
+var errInvalidCursor = apperr.InvalidArgument("invalid cursor")
+
+// resolver sources: records and versions remember the asOf they were read at,
+// so nested listings default to the same point in time
+type recordNode struct {
+	policyholderID int64
+	record         entity.PolicyholderRecord
+	asOf           time.Time
+}
+
+type edge struct {
+	cursor string
+	node   interface{}
+}
+
+type connection struct {
+	edges   []edge
+	hasNext bool
+}
+
+// newSchema builds the history schema on top of the controller:
+//
+//	Query.policyholder(id) / policyholders(first, after) -> Policyholder
+//	Policyholder.record(asOf) -> Record
+//	Record.versions(first, after, asOf) -> Version { data, diff, events }
+//	Record.events(first, after, asOf) -> Event
+func newSchema(history HistoryController) (graphql.Schema, error) {
+	pageInfo := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PageInfo",
+		Fields: graphql.Fields{
+			"hasNextPage": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(connection).hasNext, nil
+				},
+			},
+			"endCursor": &graphql.Field{
+				Type:        graphql.String,
+				Description: "Pass as after to read the next page",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					c := p.Source.(connection)
+					if len(c.edges) == 0 {
+						return nil, nil
+					}
+					return c.edges[len(c.edges)-1].cursor, nil
+				},
+			},
+		},
+	})
+
+	connectionOf := func(node *graphql.Object) *graphql.Object {
+		edgeType := graphql.NewObject(graphql.ObjectConfig{
+			Name: node.Name() + "Edge",
+			Fields: graphql.Fields{
+				"cursor": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.String),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return p.Source.(edge).cursor, nil
+					},
+				},
+				"node": &graphql.Field{
+					Type: graphql.NewNonNull(node),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return p.Source.(edge).node, nil
+					},
+				},
+			},
+		})
+		return graphql.NewObject(graphql.ObjectConfig{
+			Name: node.Name() + "Connection",
+			Fields: graphql.Fields{
+				"edges": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(edgeType))),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return p.Source.(connection).edges, nil
+					},
+				},
+				"pageInfo": &graphql.Field{
+					Type: graphql.NewNonNull(pageInfo),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return p.Source, nil
+					},
+				},
+			},
+		})
+	}
+
+	pageArgs := func(withAsOf bool) graphql.FieldConfigArgument {
+		args := graphql.FieldConfigArgument{
+			"first": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: controller.DefaultPageSize},
+			"after": &graphql.ArgumentConfig{Type: graphql.String, Description: "endCursor of the previous page"},
+		}
+		if withAsOf {
+			args["asOf"] = &graphql.ArgumentConfig{Type: graphql.DateTime, Description: "Defaults to the parent's asOf, else now"}
+		}
+		return args
+	}
+
+	field := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Field",
+		Description: "One key/value of a record's data",
+		Fields: graphql.Fields{
+			"key":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"value": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+
+	changeKind := graphql.NewEnum(graphql.EnumConfig{
+		Name: "FieldChangeKind",
+		Values: graphql.EnumValueConfigMap{
+			"ADDED":   &graphql.EnumValueConfig{Value: controller.FieldAdded},
+			"REMOVED": &graphql.EnumValueConfig{Value: controller.FieldRemoved},
+			"CHANGED": &graphql.EnumValueConfig{Value: controller.FieldChanged},
+		},
+	})
+
+	fieldChange := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "FieldChange",
+		Description: "A key that differs from the previous version",
+		Fields: graphql.Fields{
+			"key":  &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: func(p graphql.ResolveParams) (interface{}, error) { return p.Source.(entity.FieldChange).Key, nil }},
+			"kind": &graphql.Field{Type: graphql.NewNonNull(changeKind), Resolve: func(p graphql.ResolveParams) (interface{}, error) { return p.Source.(entity.FieldChange).Kind, nil }},
+			"before": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return stringOrNil(p.Source.(entity.FieldChange).Before), nil
+			}},
+			"after": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return stringOrNil(p.Source.(entity.FieldChange).After), nil
+			}},
+		},
+	})
+
+	event := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Event",
+		Description: "An event_logs entry",
+		Fields: graphql.Fields{
+			"eventId": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: func(p graphql.ResolveParams) (interface{}, error) { return p.Source.(entity.EventLog).ID, nil }},
+			"action":  &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: func(p graphql.ResolveParams) (interface{}, error) { return p.Source.(entity.EventLog).Action, nil }},
+			"timestamp": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(entity.EventLog).Timestamp, nil
+			}},
+			"details": &graphql.Field{
+				Type:        graphql.String,
+				Description: `JSON: {"request_id": ..., "source": ..., "reason": ..., "data": {...}}`,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(entity.EventLog).Details, nil
+				},
+			},
+			"changedBy": &graphql.Field{
+				Type:        graphql.Int,
+				Description: "The acting user; null when unknown",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return idOrNil(p.Source.(entity.EventLog).ChangedBy), nil
+				},
+			},
+		},
+	})
+
+	version := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Version",
+		Description: "An audit_history snapshot",
+		Fields: graphql.Fields{
+			"auditId": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: func(p graphql.ResolveParams) (interface{}, error) { return p.Source.(entity.AuditEntry).ID, nil }},
+			"version": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: func(p graphql.ResolveParams) (interface{}, error) { return p.Source.(entity.AuditEntry).Version, nil }},
+			"action": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(entity.AuditEntry).EventType, nil
+			}},
+			"changedAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(entity.AuditEntry).ChangedAt, nil
+			}},
+			"data": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(field))), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return fields(p.Source.(entity.AuditEntry).Data), nil
+			}},
+			"changedBy": &graphql.Field{
+				Type:        graphql.Int,
+				Description: "The user who wrote this version; null for v1 writes and older versions",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return idOrNil(p.Source.(entity.AuditEntry).ChangedBy), nil
+				},
+			},
+			"requestId": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return emptyToNil(p.Source.(entity.AuditEntry).RequestID), nil
+			}},
+			"source": &graphql.Field{
+				Type:        graphql.String,
+				Description: "api_v1, api_v2, import or revert",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return emptyToNil(p.Source.(entity.AuditEntry).Source), nil
+				},
+			},
+			"reason": &graphql.Field{
+				Type:        graphql.String,
+				Description: "The writer's X-Change-Reason",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return emptyToNil(p.Source.(entity.AuditEntry).Reason), nil
+				},
+			},
+			"diff": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(fieldChange))),
+				Description: "Keys changed since the previous version; every key for the first",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					e := p.Source.(entity.AuditEntry)
+					return controller.DiffData(e.Previous, e.Data), nil
+				},
+			},
+			"events": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(event))),
+				Description: "Event log entries written with this version",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					events, err := history.VersionEvents(p.Context, p.Source.(entity.AuditEntry))
+					if err != nil {
+						return nil, resolveError(p.Context, err)
+					}
+					return events, nil
+				},
+			},
+		},
+	})
+
+	record := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Record",
+		Description: "A policyholder's record, current or as of a point in time",
+		Fields: graphql.Fields{
+			"policyholderId": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: func(p graphql.ResolveParams) (interface{}, error) { return p.Source.(recordNode).policyholderID, nil }},
+			"recordId":       &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: func(p graphql.ResolveParams) (interface{}, error) { return p.Source.(recordNode).record.ID, nil }},
+			"version":        &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: func(p graphql.ResolveParams) (interface{}, error) { return p.Source.(recordNode).record.Version, nil }},
+			"data": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(field))), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return fields(p.Source.(recordNode).record.Data), nil
+			}},
+			"createdAt": &graphql.Field{Type: graphql.DateTime, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return timeOrNil(p.Source.(recordNode).record.CreatedAt), nil
+			}},
+			"updatedAt": &graphql.Field{
+				Type:        graphql.DateTime,
+				Description: "When this version was written",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return timeOrNil(p.Source.(recordNode).record.UpdatedAt), nil
+				},
+			},
+			"asOf": &graphql.Field{Type: graphql.DateTime, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return timeOrNil(p.Source.(recordNode).asOf), nil
+			}},
+			"versions": &graphql.Field{
+				Type: graphql.NewNonNull(connectionOf(version)),
+				Args: pageArgs(true),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					node := p.Source.(recordNode)
+					after, err := decodeCursor("version", p.Args["after"])
+					if err != nil {
+						return nil, resolveError(p.Context, err)
+					}
+					entries, more, err := history.Versions(p.Context, node.record.ID, int(after), asOfArg(p, node.asOf), firstArg(p))
+					if err != nil {
+						return nil, resolveError(p.Context, err)
+					}
+					c := connection{hasNext: more}
+					for _, e := range entries {
+						c.edges = append(c.edges, edge{cursor: encodeCursor("version", int64(e.Version)), node: e})
+					}
+					return c, nil
+				},
+			},
+			"events": &graphql.Field{
+				Type: graphql.NewNonNull(connectionOf(event)),
+				Args: pageArgs(true),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					node := p.Source.(recordNode)
+					after, err := decodeCursor("event", p.Args["after"])
+					if err != nil {
+						return nil, resolveError(p.Context, err)
+					}
+					events, more, err := history.Events(p.Context, node.record.ID, after, asOfArg(p, node.asOf), firstArg(p))
+					if err != nil {
+						return nil, resolveError(p.Context, err)
+					}
+					c := connection{hasNext: more}
+					for _, e := range events {
+						c.edges = append(c.edges, edge{cursor: encodeCursor("event", e.ID), node: e})
+					}
+					return c, nil
+				},
+			},
+		},
+	})
+
+	// resolveRecord is shared by Query.record and Policyholder.record; no record
+	// (yet, at asOf) is null rather than an error
+	resolveRecord := func(ctx context.Context, policyholderID int64, asOf time.Time) (interface{}, error) {
+		rec, err := history.Record(ctx, policyholderID, asOf)
+		if errors.Is(err, apperr.ErrNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, resolveError(ctx, err)
+		}
+		return recordNode{policyholderID: policyholderID, record: rec, asOf: asOf}, nil
+	}
+
+	policyholder := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Policyholder",
+		Fields: graphql.Fields{
+			"id":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: func(p graphql.ResolveParams) (interface{}, error) { return p.Source.(entity.Policyholder).ID, nil }},
+			"name": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: func(p graphql.ResolveParams) (interface{}, error) { return p.Source.(entity.Policyholder).Name, nil }},
+			"email": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return emptyToNil(p.Source.(entity.Policyholder).Email), nil
+			}},
+			"countryCode": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return emptyToNil(p.Source.(entity.Policyholder).Country), nil
+			}},
+			"createdAt": &graphql.Field{Type: graphql.DateTime, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return timeOrNil(p.Source.(entity.Policyholder).CreatedAt), nil
+			}},
+			"updatedAt": &graphql.Field{Type: graphql.DateTime, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return timeOrNil(p.Source.(entity.Policyholder).UpdatedAt), nil
+			}},
+			"record": &graphql.Field{
+				Type: record,
+				Args: graphql.FieldConfigArgument{
+					"asOf": &graphql.ArgumentConfig{Type: graphql.DateTime, Description: "Defaults to now"},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolveRecord(p.Context, p.Source.(entity.Policyholder).ID, asOfArg(p, time.Time{}))
+				},
+			},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"policyholder": &graphql.Field{
+				Type: policyholder,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					holder, err := history.Policyholder(p.Context, int64(p.Args["id"].(int)))
+					if errors.Is(err, controller.ErrPolicyholderDoesNotExist) {
+						return nil, nil
+					}
+					if err != nil {
+						return nil, resolveError(p.Context, err)
+					}
+					return holder, nil
+				},
+			},
+			"policyholders": &graphql.Field{
+				Type: graphql.NewNonNull(connectionOf(policyholder)),
+				Args: pageArgs(false),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					after, err := decodeCursor("policyholder", p.Args["after"])
+					if err != nil {
+						return nil, resolveError(p.Context, err)
+					}
+					holders, more, err := history.Policyholders(p.Context, after, firstArg(p))
+					if err != nil {
+						return nil, resolveError(p.Context, err)
+					}
+					c := connection{hasNext: more}
+					for _, h := range holders {
+						c.edges = append(c.edges, edge{cursor: encodeCursor("policyholder", h.ID), node: h})
+					}
+					return c, nil
+				},
+			},
+			"record": &graphql.Field{
+				Type:        record,
+				Description: "Shortcut for policyholder(id).record(asOf)",
+				Args: graphql.FieldConfigArgument{
+					"policyholderId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					"asOf":           &graphql.ArgumentConfig{Type: graphql.DateTime, Description: "Defaults to now"},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolveRecord(p.Context, int64(p.Args["policyholderId"].(int)), asOfArg(p, time.Time{}))
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query})
+}
+
+// fieldError is what clients see of a resolver error: the client-safe message
+// and the apperr code under extensions.code
+type fieldError struct {
+	err error
+}
+
+func (e *fieldError) Error() string { return apperr.Message(e.err) }
+
+func (e *fieldError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": apperr.Code(e.err)}
+}
+
+// resolveError hides causes from the response; server-side failures are logged
+func resolveError(ctx context.Context, err error) error {
+	if apperr.HTTPStatus(err) >= 500 {
+		observability.DefaultLogger.ErrorContext(ctx, "graphql_resolve_failed", "error", err)
+	}
+	return &fieldError{err: err}
+}
+
+// cursors are opaque to clients: base64 of "<kind>:<key>"
+func encodeCursor(kind string, key int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(kind + ":" + strconv.FormatInt(key, 10)))
+}
+
+// decodeCursor returns 0 (start) for a missing cursor
+func decodeCursor(kind string, arg interface{}) (int64, error) {
+	s, _ := arg.(string)
+	if s == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return 0, errInvalidCursor
+	}
+	key, ok := strings.CutPrefix(string(raw), kind+":")
+	if !ok {
+		return 0, errInvalidCursor
+	}
+	n, err := strconv.ParseInt(key, 10, 64)
+	if err != nil || n < 0 {
+		return 0, errInvalidCursor
+	}
+	return n, nil
+}
+
+// firstArg is the requested page size; an explicit null means the default
+func firstArg(p graphql.ResolveParams) int {
+	if n, ok := p.Args["first"].(int); ok {
+		return n
+	}
+	return controller.DefaultPageSize
+}
+
+func asOfArg(p graphql.ResolveParams, fallback time.Time) time.Time {
+	if t, ok := p.Args["asOf"].(time.Time); ok {
+		return t
+	}
+	return fallback
+}
+
+type keyValue struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// fields lists data as key/value pairs sorted by key
+func fields(data map[string]string) []keyValue {
+	out := make([]keyValue, 0, len(data))
+	for k, v := range data {
+		out = append(out, keyValue{Key: k, Value: v})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+func timeOrNil(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+func stringOrNil(s *string) interface{} {
+	if s == nil {
+		return nil
+	}
+	return *s
+}
+
+// idOrNil renders an unknown (zero) id as null
+func idOrNil(id int64) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+func emptyToNil(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
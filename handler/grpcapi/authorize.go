@@ -0,0 +1,90 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/authz"
+	recordsv1 "github.com/rainbowmga/timetravel/proto/records/v1"
+)
+
+// Authorizer checks a principal's permission on a policyholder (authz.Authorizer)
+type Authorizer interface {
+	Authorize(ctx context.Context, resource string, perm authz.Permission, policyholderID int64) error
+}
+
+// MethodPermissions is the permission each Records method needs, like the
+// per-route declarations of the HTTP API
+var MethodPermissions = map[string]authz.Permission{
+	recordsv1.Records_Get_FullMethodName:          authz.RecordsRead,
+	recordsv1.Records_Upsert_FullMethodName:       authz.RecordsWrite,
+	recordsv1.Records_Patch_FullMethodName:        authz.RecordsWrite,
+	recordsv1.Records_GetVersion_FullMethodName:   authz.RecordsRead,
+	recordsv1.Records_ListVersions_FullMethodName: authz.RecordsRead,
+	recordsv1.Records_GetAsOf_FullMethodName:      authz.RecordsRead,
+	recordsv1.Records_WatchChanges_FullMethodName: authz.RecordsRead,
+}
+
+// every Records request names the policyholder it acts on
+type policyholderRequest interface {
+	GetPolicyholderId() int64
+}
+
+// UnaryAuthorization refuses calls whose principal lacks the method's
+// permission on the requested policyholder; it runs after UnaryUserContext
+func UnaryAuthorization(a Authorizer) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := authorize(ctx, a, info.FullMethod, req); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamAuthorization is UnaryAuthorization for streaming calls; the check
+// runs once the request message has been received
+func StreamAuthorization(a Authorizer) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &authorizedStream{ServerStream: ss, authz: a, method: info.FullMethod})
+	}
+}
+
+func authorize(ctx context.Context, a Authorizer, method string, req interface{}) error {
+	perm, ok := MethodPermissions[method]
+	if !ok {
+		// undeclared methods are refused rather than left open
+		return apperr.GRPCStatus(apperr.New(apperr.ErrPermissionDenied, fmt.Sprintf("%s declares no permission", method))).Err()
+	}
+	var policyholderID int64
+	if r, ok := req.(policyholderRequest); ok {
+		policyholderID = r.GetPolicyholderId()
+	}
+	if err := a.Authorize(ctx, method, perm, policyholderID); err != nil {
+		return apperr.GRPCStatus(err).Err()
+	}
+	return nil
+}
+
+// authorizedStream authorizes the first message the handler receives
+type authorizedStream struct {
+	grpc.ServerStream
+	authz      Authorizer
+	method     string
+	authorized bool
+}
+
+func (s *authorizedStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if !s.authorized {
+		if err := authorize(s.Context(), s.authz, s.method, m); err != nil {
+			return err
+		}
+		s.authorized = true
+	}
+	return nil
+}
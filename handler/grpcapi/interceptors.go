@@ -0,0 +1,98 @@
+package grpcapi
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/auth"
+	"github.com/rainbowmga/timetravel/common"
+	"github.com/rainbowmga/timetravel/controller"
+	"github.com/rainbowmga/timetravel/observability"
+)
+
+// Metadata keys carrying credentials, like the HTTP headers of the same names
+const (
+	UserIDMetadataKey        = "x-user-id" // trusted only when the header method is enabled
+	APIKeyMetadataKey        = "x-api-key"
+	AuthorizationMetadataKey = "authorization"
+)
+
+// Metadata keys a writer may send to describe its change, like the
+// X-Change-Source and X-Change-Reason headers
+const (
+	ChangeSourceMetadataKey = "x-change-source" // import or revert
+	ChangeReasonMetadataKey = "x-change-reason"
+)
+
+// the check the v2 handlers apply over HTTP
+var errV2Disabled = apperr.New(apperr.ErrPermissionDenied, "enable_v2_api flag is disabled")
+
+type FeatureFlagService interface {
+	IsEnabled(ctx context.Context, key string) bool
+}
+
+// UnaryUserContext authenticates calls like the HTTP middleware, rejects
+// them while enable_v2_api is off, and stores the principal in the context
+func UnaryUserContext(authenticator auth.Authenticator, flags FeatureFlagService) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := userContext(ctx, authenticator, flags)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamUserContext is UnaryUserContext for streaming calls
+func StreamUserContext(authenticator auth.Authenticator, flags FeatureFlagService) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := userContext(ss.Context(), authenticator, flags)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func userContext(ctx context.Context, authenticator auth.Authenticator, flags FeatureFlagService) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	p, err := authenticator.Authenticate(ctx, auth.Credentials{
+		APIKey: first(md, APIKeyMetadataKey),
+		Bearer: auth.BearerToken(first(md, AuthorizationMetadataKey)),
+		UserID: first(md, UserIDMetadataKey),
+	})
+	if err != nil {
+		observability.DefaultLogger.WarnContext(ctx, "authentication_failed", "error", err)
+		return nil, apperr.GRPCStatus(err).Err()
+	}
+	ctx = auth.WithPrincipal(ctx, p)
+
+	if !flags.IsEnabled(ctx, "enable_v2_api") {
+		return nil, apperr.GRPCStatus(errV2Disabled).Err()
+	}
+	return ctx, nil
+}
+
+// changeContext attributes a write to the v2 API, or to the source the client declared
+func changeContext(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return controller.WithChange(ctx, common.SourceAPIV2, first(md, ChangeSourceMetadataKey), first(md, ChangeReasonMetadataKey))
+}
+
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// contextStream hands the handler the context carrying the user id
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context { return s.ctx }
@@ -0,0 +1,64 @@
+package grpcapi
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/auth"
+	"github.com/rainbowmga/timetravel/observability"
+	recordsv1 "github.com/rainbowmga/timetravel/proto/records/v1"
+	"github.com/rainbowmga/timetravel/ratelimit"
+)
+
+// RateLimiter throttles principals per route class (ratelimit.Limiter)
+type RateLimiter interface {
+	Allow(key ratelimit.Key, route string) ratelimit.Decision
+}
+
+// writeMethods share the write bucket with the HTTP record writes; every
+// other Records method is a read
+var writeMethods = map[string]bool{
+	recordsv1.Records_Upsert_FullMethodName: true,
+	recordsv1.Records_Patch_FullMethodName:  true,
+}
+
+// UnaryRateLimit refuses calls over the caller's limit with ResourceExhausted
+// and a RetryInfo detail; it runs after UnaryUserContext
+func UnaryRateLimit(l RateLimiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := rateLimit(ctx, l, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamRateLimit is UnaryRateLimit for streaming calls; opening a stream takes one token
+func StreamRateLimit(l RateLimiter) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := rateLimit(ss.Context(), l, info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func rateLimit(ctx context.Context, l RateLimiter, method string) error {
+	p, ok := auth.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	class := ratelimit.Read
+	if writeMethods[method] {
+		class = ratelimit.Write
+	}
+	d := l.Allow(ratelimit.Key{UserID: p.UserID, Class: class}, method)
+	if d.Allowed {
+		return nil
+	}
+	observability.DefaultLogger.WarnContext(ctx, "rate_limited",
+		"class", class, "per_minute", d.Limit.PerMinute, "retry_after_ms", d.RetryAfter.Milliseconds())
+	return apperr.GRPCStatus(ratelimit.Exceeded(class, d)).Err()
+}
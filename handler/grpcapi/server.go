@@ -0,0 +1,320 @@
+// Package grpcapi serves the v2 record operations over gRPC
+// (proto/records/v1/records.proto), backed by the same SQLite controller as the
+// HTTP handlers.
+package grpcapi
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/auth"
+	"github.com/rainbowmga/timetravel/controller"
+	"github.com/rainbowmga/timetravel/entity"
+	"github.com/rainbowmga/timetravel/observability"
+	recordsv1 "github.com/rainbowmga/timetravel/proto/records/v1"
+)
+
+// DefaultWatchInterval is how often WatchChanges polls for new changes
+const DefaultWatchInterval = time.Second
+
+// watchBatch caps the changes read per poll; a backlog is drained batch by batch
+const watchBatch = 100
+
+// request validation failures raised by the server itself
+var (
+	errInvalidPolicyholderID = apperr.InvalidArgument("invalid policyholder_id")
+	errInvalidVersion        = apperr.InvalidArgument("invalid version")
+	errInvalidAsOf           = apperr.InvalidArgument("as_of is required")
+	errPatchConflict         = apperr.InvalidArgument("a key cannot be both set and deleted")
+	errShuttingDown          = apperr.Unavailable("server is shutting down", 0, nil)
+)
+
+type RecordController interface {
+	GetRecord(ctx context.Context, id int64) (entity.PolicyholderRecord, error)
+	UpsertRecord(ctx context.Context, id int64, data map[string]string) (entity.PolicyholderRecord, error)
+	UpdateRecord(ctx context.Context, id int, updates map[string]*string) (entity.PolicyholderRecord, error)
+	GetVersion(ctx context.Context, id int, version int) (map[string]string, error)
+	ListVersions(ctx context.Context, id int) ([]int, error)
+	GetAsOf(ctx context.Context, id int, asOf time.Time) (entity.RecordChange, error)
+	ChangesSince(ctx context.Context, afterID int64, id int, limit int) ([]entity.RecordChange, error)
+	LatestChangeID(ctx context.Context) (int64, error)
+}
+
+var _ RecordController = (*controller.SQLiteRecordController)(nil)
+
+// Server implements recordsv1.RecordsServer
+type Server struct {
+	recordsv1.UnimplementedRecordsServer
+
+	Controller RecordController
+	// WatchInterval is how often WatchChanges polls; DefaultWatchInterval if zero
+	WatchInterval time.Duration
+
+	mu        sync.Mutex
+	draining  chan struct{}
+	drainOnce sync.Once
+}
+
+// NewServer wraps the v2 record controller
+func NewServer(c RecordController) *Server {
+	return &Server{Controller: c, WatchInterval: DefaultWatchInterval}
+}
+
+// Drain ends open WatchChanges streams with Unavailable so a graceful stop does
+// not wait on clients that never hang up; unary calls are unaffected
+func (s *Server) Drain() {
+	ch := s.drainChan()
+	s.drainOnce.Do(func() { close(ch) })
+}
+
+func (s *Server) drainChan() chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.draining == nil {
+		s.draining = make(chan struct{})
+	}
+	return s.draining
+}
+
+// NewGRPCServer registers srv on a gRPC server whose interceptors apply the
+// user-context, enable_v2_api, rate limit and permission checks to every
+// call; a nil limiter lets every call through
+func NewGRPCServer(srv *Server, authenticator auth.Authenticator, limiter RateLimiter, authorizer Authorizer, flags FeatureFlagService, opts ...grpc.ServerOption) *grpc.Server {
+	unary := []grpc.UnaryServerInterceptor{UnaryUserContext(authenticator, flags)}
+	stream := []grpc.StreamServerInterceptor{StreamUserContext(authenticator, flags)}
+	if limiter != nil {
+		// before authorization, so refused floods do not fill event_logs with denials
+		unary = append(unary, UnaryRateLimit(limiter))
+		stream = append(stream, StreamRateLimit(limiter))
+	}
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(append(unary, UnaryAuthorization(authorizer))...),
+		grpc.ChainStreamInterceptor(append(stream, StreamAuthorization(authorizer))...),
+	)
+	g := grpc.NewServer(opts...)
+	recordsv1.RegisterRecordsServer(g, srv)
+	return g
+}
+
+// Get returns the latest version of a record
+func (s *Server) Get(ctx context.Context, req *recordsv1.GetRequest) (*recordsv1.Record, error) {
+	if req.GetPolicyholderId() <= 0 {
+		return nil, statusError(ctx, errInvalidPolicyholderID)
+	}
+	record, err := s.Controller.GetRecord(ctx, req.GetPolicyholderId())
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+	return toRecord(req.GetPolicyholderId(), record), nil
+}
+
+// Upsert creates the record or replaces its data
+func (s *Server) Upsert(ctx context.Context, req *recordsv1.UpsertRequest) (*recordsv1.Record, error) {
+	if req.GetPolicyholderId() <= 0 {
+		return nil, statusError(ctx, errInvalidPolicyholderID)
+	}
+	data := req.GetData()
+	if data == nil {
+		data = map[string]string{}
+	}
+	ctx, err := changeContext(ctx)
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+	record, err := s.Controller.UpsertRecord(ctx, req.GetPolicyholderId(), data)
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+	observability.DefaultLogger.InfoContext(ctx, "record_upserted", "policyholder_id", req.GetPolicyholderId(), "version", record.Version)
+	return toRecord(req.GetPolicyholderId(), record), nil
+}
+
+// Patch merges set into an existing record and removes the delete keys
+func (s *Server) Patch(ctx context.Context, req *recordsv1.PatchRequest) (*recordsv1.Record, error) {
+	id, ok := policyholderID(req.GetPolicyholderId())
+	if !ok {
+		return nil, statusError(ctx, errInvalidPolicyholderID)
+	}
+	updates := make(map[string]*string, len(req.GetSet())+len(req.GetDelete()))
+	for k, v := range req.GetSet() {
+		updates[k] = &v
+	}
+	for _, k := range req.GetDelete() {
+		if _, ok := req.GetSet()[k]; ok {
+			return nil, statusError(ctx, errPatchConflict)
+		}
+		updates[k] = nil
+	}
+	ctx, err := changeContext(ctx)
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+	record, err := s.Controller.UpdateRecord(ctx, id, updates)
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+	observability.DefaultLogger.InfoContext(ctx, "record_patched", "policyholder_id", id, "version", record.Version)
+	return toRecord(req.GetPolicyholderId(), record), nil
+}
+
+// GetVersion returns the data of one stored version
+func (s *Server) GetVersion(ctx context.Context, req *recordsv1.GetVersionRequest) (*recordsv1.RecordVersion, error) {
+	id, ok := policyholderID(req.GetPolicyholderId())
+	if !ok {
+		return nil, statusError(ctx, errInvalidPolicyholderID)
+	}
+	if req.GetVersion() <= 0 {
+		return nil, statusError(ctx, errInvalidVersion)
+	}
+	data, err := s.Controller.GetVersion(ctx, id, int(req.GetVersion()))
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+	return &recordsv1.RecordVersion{
+		PolicyholderId: req.GetPolicyholderId(),
+		Version:        req.GetVersion(),
+		Data:           data,
+	}, nil
+}
+
+// ListVersions returns every stored version number, oldest first
+func (s *Server) ListVersions(ctx context.Context, req *recordsv1.ListVersionsRequest) (*recordsv1.ListVersionsResponse, error) {
+	id, ok := policyholderID(req.GetPolicyholderId())
+	if !ok {
+		return nil, statusError(ctx, errInvalidPolicyholderID)
+	}
+	versions, err := s.Controller.ListVersions(ctx, id)
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+	resp := &recordsv1.ListVersionsResponse{PolicyholderId: req.GetPolicyholderId(), Versions: make([]int32, len(versions))}
+	for i, v := range versions {
+		resp.Versions[i] = int32(v)
+	}
+	return resp, nil
+}
+
+// GetAsOf returns the version that was current at as_of
+func (s *Server) GetAsOf(ctx context.Context, req *recordsv1.GetAsOfRequest) (*recordsv1.RecordChange, error) {
+	id, ok := policyholderID(req.GetPolicyholderId())
+	if !ok {
+		return nil, statusError(ctx, errInvalidPolicyholderID)
+	}
+	if req.GetAsOf() == nil || req.GetAsOf().CheckValid() != nil {
+		return nil, statusError(ctx, errInvalidAsOf)
+	}
+	change, err := s.Controller.GetAsOf(ctx, id, req.GetAsOf().AsTime())
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+	return toChange(change), nil
+}
+
+// WatchChanges polls the audit history and streams each change written after
+// after_change_id (or after the call started) until the client goes away
+func (s *Server) WatchChanges(req *recordsv1.WatchChangesRequest, stream recordsv1.Records_WatchChangesServer) error {
+	ctx := stream.Context()
+	id, ok := policyholderID(req.GetPolicyholderId())
+	if !ok && req.GetPolicyholderId() != 0 {
+		return statusError(ctx, errInvalidPolicyholderID)
+	}
+
+	after := req.GetAfterChangeId()
+	if req.AfterChangeId == nil {
+		latest, err := s.Controller.LatestChangeID(ctx)
+		if err != nil {
+			return statusError(ctx, err)
+		}
+		after = latest
+	}
+
+	interval := s.WatchInterval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	draining := s.drainChan()
+
+	for {
+		for {
+			changes, err := s.Controller.ChangesSince(ctx, after, id, watchBatch)
+			if err != nil {
+				return statusError(ctx, err)
+			}
+			for _, c := range changes {
+				if err := stream.Send(toChange(c)); err != nil {
+					return err
+				}
+				after = c.ID
+			}
+			if len(changes) < watchBatch {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return statusError(ctx, controllerContextError(ctx))
+		case <-draining:
+			return statusError(ctx, errShuttingDown)
+		case <-ticker.C:
+		}
+	}
+}
+
+// policyholderID narrows a wire id to the controller's int; ok is false for ids
+// that are not positive
+func policyholderID(id int64) (int, bool) {
+	if id <= 0 || int64(int(id)) != id {
+		return 0, false
+	}
+	return int(id), true
+}
+
+func toRecord(policyholderID int64, r entity.PolicyholderRecord) *recordsv1.Record {
+	return &recordsv1.Record{
+		PolicyholderId: policyholderID,
+		RecordId:       r.ID,
+		Version:        int32(r.Version),
+		Data:           r.Data,
+		CreatedAt:      timestamppb.New(r.CreatedAt),
+		UpdatedAt:      timestamppb.New(r.UpdatedAt),
+	}
+}
+
+func toChange(c entity.RecordChange) *recordsv1.RecordChange {
+	return &recordsv1.RecordChange{
+		ChangeId:       c.ID,
+		PolicyholderId: c.PolicyholderID,
+		Version:        int32(c.Version),
+		Action:         c.Action,
+		Data:           c.Data,
+		ChangedAt:      timestamppb.New(c.ChangedAt),
+	}
+}
+
+// controllerContextError types a finished context the way the controller does
+func controllerContextError(ctx context.Context) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return apperr.Wrap(controller.ErrRequestTimeout, ctx.Err())
+	}
+	return apperr.Wrap(controller.ErrRequestCanceled, ctx.Err())
+}
+
+// statusError converts err to its gRPC status (apperr.GRPCStatus); causes of
+// server-side failures are only logged, as the HTTP handlers do
+func statusError(ctx context.Context, err error) error {
+	st := apperr.GRPCStatus(err)
+	switch st.Code() {
+	case codes.Internal, codes.Unavailable:
+		observability.DefaultLogger.ErrorContext(ctx, "request_failed", "code", st.Code().String(), "error", err)
+	}
+	return st.Err()
+}
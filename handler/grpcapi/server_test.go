@@ -0,0 +1,382 @@
+package grpcapi_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/rainbowmga/timetravel/auth"
+	"github.com/rainbowmga/timetravel/authz"
+	"github.com/rainbowmga/timetravel/common"
+	"github.com/rainbowmga/timetravel/controller"
+	"github.com/rainbowmga/timetravel/gateways"
+	"github.com/rainbowmga/timetravel/handler/grpcapi"
+	recordsv1 "github.com/rainbowmga/timetravel/proto/records/v1"
+	"github.com/rainbowmga/timetravel/ratelimit"
+	"github.com/rainbowmga/timetravel/script"
+	"github.com/rainbowmga/timetravel/service"
+)
+
+type stubFlags struct {
+	enabled bool
+	userID  atomic.Int64 // last caller seen
+}
+
+func (f *stubFlags) IsEnabled(ctx context.Context, key string) bool {
+	id, _ := common.GetUserID(ctx)
+	f.userID.Store(id)
+	return f.enabled && key == "enable_v2_api"
+}
+
+// newClient serves a Records server backed by a migrated SQLite file over bufconn
+func newClient(t *testing.T, flags *stubFlags) (recordsv1.RecordsClient, *grpcapi.Server) {
+	t.Helper()
+
+	db, err := gateways.OpenDatabase(filepath.Join(t.TempDir(), "grpc.db"), gateways.DatabaseOptions{})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	m, err := gateways.NewMigrator(db.Writer(), script.Migrations())
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	records := controller.NewSQLiteRecordControllerWithService(service.NewSQLiteRecordServiceWithDB(db, nil, "development"))
+	srv := grpcapi.NewServer(records)
+	srv.WatchInterval = 10 * time.Millisecond
+	keys := auth.NewKeySet()
+	if err := keys.AddHMAC("test", jwtSecret); err != nil {
+		t.Fatalf("add key: %v", err)
+	}
+	authn := auth.Chain{auth.NewJWTAuthenticator(auth.JWTOptions{Keys: keys}), auth.HeaderAuthenticator{Roles: []string{authz.RoleUnderwriter}}}
+	// only user 5 is throttled: one write a minute
+	limiter := ratelimit.New(nil)
+	limiter.SetOverrides(map[ratelimit.Key]ratelimit.Limit{{UserID: 5, Class: ratelimit.Write}: {PerMinute: 1}})
+	g := grpcapi.NewGRPCServer(srv, authn, limiter, authz.New(authz.DefaultPolicy(), brokerAssignments{}, nil), flags)
+
+	lis := bufconn.Listen(1 << 20)
+	go func() { _ = g.Serve(lis) }()
+	t.Cleanup(g.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return recordsv1.NewRecordsClient(conn), srv
+}
+
+func asUser(id string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), grpcapi.UserIDMetadataKey, id)
+}
+
+// brokerAssignments assigns policyholder 1 to user 9
+type brokerAssignments struct{}
+
+func (brokerAssignments) IsAssigned(ctx context.Context, userID, policyholderID int64) (bool, error) {
+	return userID == 9 && policyholderID == 1, nil
+}
+
+var jwtSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// withToken sends an HS256 bearer token whose claims are given as JSON
+func withToken(claims string) context.Context {
+	enc := base64.RawURLEncoding
+	signed := enc.EncodeToString([]byte(`{"alg":"HS256","kid":"test"}`)) + "." + enc.EncodeToString([]byte(claims))
+	mac := hmac.New(sha256.New, jwtSecret)
+	mac.Write([]byte(signed))
+	token := signed + "." + enc.EncodeToString(mac.Sum(nil))
+	return metadata.AppendToOutgoingContext(context.Background(), grpcapi.AuthorizationMetadataKey, "Bearer "+token)
+}
+
+func TestUserContextInterceptor(t *testing.T) {
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		enabled  bool
+		wantCode codes.Code
+	}{
+		{"missing user id", context.Background(), true, codes.Unauthenticated},
+		{"invalid user id", asUser("abc"), true, codes.InvalidArgument},
+		{"non-positive user id", asUser("0"), true, codes.InvalidArgument},
+		{"v2 flag disabled", asUser("7"), false, codes.PermissionDenied},
+		{"allowed", asUser("7"), true, codes.NotFound},
+		{"bearer token", withToken(`{"sub":"7","roles":["reader"],"exp":4102444800}`), true, codes.NotFound},
+		{"bearer token without roles", withToken(`{"sub":"7","exp":4102444800}`), true, codes.PermissionDenied},
+		{"assigned broker", withToken(`{"sub":"9","roles":["broker"],"exp":4102444800}`), true, codes.NotFound},
+		{"unassigned broker", withToken(`{"sub":"8","roles":["broker"],"exp":4102444800}`), true, codes.PermissionDenied},
+		{"expired bearer token", withToken(`{"sub":"7","exp":1}`), true, codes.Unauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := &stubFlags{enabled: tt.enabled}
+			client, _ := newClient(t, flags)
+
+			_, err := client.Get(tt.ctx, &recordsv1.GetRequest{PolicyholderId: 1})
+			if got := status.Code(err); got != tt.wantCode {
+				t.Fatalf("code = %v, want %v (%v)", got, tt.wantCode, err)
+			}
+
+			// the streaming interceptor applies the same checks
+			stream, err := client.WatchChanges(tt.ctx, &recordsv1.WatchChangesRequest{AfterChangeId: new(int64)})
+			if err == nil && tt.wantCode != codes.NotFound {
+				_, err = stream.Recv()
+				if got := status.Code(err); got != tt.wantCode {
+					t.Errorf("stream code = %v, want %v", got, tt.wantCode)
+				}
+			}
+		})
+	}
+
+	flags := &stubFlags{enabled: true}
+	client, _ := newClient(t, flags)
+	_, _ = client.Get(asUser("42"), &recordsv1.GetRequest{PolicyholderId: 1})
+	if got := flags.userID.Load(); got != 42 {
+		t.Errorf("flags saw user %d, want 42 from x-user-id", got)
+	}
+}
+
+func TestAuthorizationInterceptor(t *testing.T) {
+	client, _ := newClient(t, &stubFlags{enabled: true})
+	reader := withToken(`{"sub":"7","roles":["reader"],"exp":4102444800}`)
+	broker := withToken(`{"sub":"9","roles":["broker"],"exp":4102444800}`)
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		id       int64
+		wantCode codes.Code
+	}{
+		{"reader cannot write", reader, 1, codes.PermissionDenied},
+		{"broker writes an assigned policyholder", broker, 1, codes.OK},
+		{"broker cannot write another", broker, 2, codes.PermissionDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.Upsert(tt.ctx, &recordsv1.UpsertRequest{PolicyholderId: tt.id, Data: map[string]string{"n": "1"}})
+			if got := status.Code(err); got != tt.wantCode {
+				t.Fatalf("code = %v, want %v (%v)", got, tt.wantCode, err)
+			}
+		})
+	}
+
+	// a scoped role cannot follow every record
+	stream, err := client.WatchChanges(broker, &recordsv1.WatchChangesRequest{})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if got := status.Code(err); got != codes.PermissionDenied {
+		t.Errorf("watch all as broker = %v, want PermissionDenied", got)
+	}
+}
+
+func TestRateLimitInterceptor(t *testing.T) {
+	client, _ := newClient(t, &stubFlags{enabled: true})
+	upsert := &recordsv1.UpsertRequest{PolicyholderId: 1, Data: map[string]string{"n": "1"}}
+
+	if _, err := client.Upsert(asUser("5"), upsert); err != nil {
+		t.Fatalf("first upsert: %v", err)
+	}
+	_, err := client.Upsert(asUser("5"), upsert)
+	st := status.Convert(err)
+	if st.Code() != codes.ResourceExhausted {
+		t.Fatalf("second upsert = %v, want ResourceExhausted", err)
+	}
+	var retry *errdetails.RetryInfo
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.RetryInfo); ok {
+			retry = info
+		}
+	}
+	if retry == nil || retry.RetryDelay.AsDuration() <= 0 {
+		t.Errorf("details = %v, want a positive RetryInfo", st.Details())
+	}
+
+	// reads use their own bucket, and other users are not limited
+	if _, err := client.Get(asUser("5"), &recordsv1.GetRequest{PolicyholderId: 1}); err != nil {
+		t.Errorf("get as throttled writer: %v", err)
+	}
+	if _, err := client.Upsert(asUser("6"), upsert); err != nil {
+		t.Errorf("upsert as another user: %v", err)
+	}
+}
+
+func TestRecordOperations(t *testing.T) {
+	client, _ := newClient(t, &stubFlags{enabled: true})
+	ctx := asUser("7")
+
+	created, err := client.Upsert(ctx, &recordsv1.UpsertRequest{PolicyholderId: 5, Data: map[string]string{"name": "ann", "city": "oslo"}})
+	if err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+	if created.GetVersion() != 1 || created.GetData()["name"] != "ann" || created.GetPolicyholderId() != 5 {
+		t.Fatalf("unexpected record %v", created)
+	}
+	beforePatch := time.Now()
+
+	patched, err := client.Patch(ctx, &recordsv1.PatchRequest{PolicyholderId: 5, Set: map[string]string{"name": "bea"}, Delete: []string{"city"}})
+	if err != nil {
+		t.Fatalf("Patch: %v", err)
+	}
+	if patched.GetVersion() != 2 || patched.GetData()["name"] != "bea" || len(patched.GetData()) != 1 {
+		t.Fatalf("unexpected patched record %v", patched)
+	}
+
+	got, err := client.Get(ctx, &recordsv1.GetRequest{PolicyholderId: 5})
+	if err != nil || got.GetVersion() != 2 {
+		t.Fatalf("Get = %v, %v", got, err)
+	}
+
+	v1, err := client.GetVersion(ctx, &recordsv1.GetVersionRequest{PolicyholderId: 5, Version: 1})
+	if err != nil || v1.GetData()["city"] != "oslo" {
+		t.Fatalf("GetVersion = %v, %v", v1, err)
+	}
+
+	versions, err := client.ListVersions(ctx, &recordsv1.ListVersionsRequest{PolicyholderId: 5})
+	if err != nil || len(versions.GetVersions()) != 2 || versions.GetVersions()[1] != 2 {
+		t.Fatalf("ListVersions = %v, %v", versions, err)
+	}
+
+	asOf, err := client.GetAsOf(ctx, &recordsv1.GetAsOfRequest{PolicyholderId: 5, AsOf: timestamppb.New(beforePatch)})
+	if err != nil || asOf.GetVersion() != 1 || asOf.GetAction() != "create" {
+		t.Fatalf("GetAsOf = %v, %v", asOf, err)
+	}
+}
+
+func TestRecordErrors(t *testing.T) {
+	client, _ := newClient(t, &stubFlags{enabled: true})
+	ctx := asUser("7")
+
+	tests := []struct {
+		name     string
+		call     func() error
+		wantCode codes.Code
+	}{
+		{"get invalid id", func() error {
+			_, err := client.Get(ctx, &recordsv1.GetRequest{PolicyholderId: 0})
+			return err
+		}, codes.InvalidArgument},
+		{"get missing record", func() error {
+			_, err := client.Get(ctx, &recordsv1.GetRequest{PolicyholderId: 99})
+			return err
+		}, codes.NotFound},
+		{"patch missing record", func() error {
+			_, err := client.Patch(ctx, &recordsv1.PatchRequest{PolicyholderId: 99, Set: map[string]string{"a": "b"}})
+			return err
+		}, codes.NotFound},
+		{"patch sets and deletes a key", func() error {
+			_, err := client.Patch(ctx, &recordsv1.PatchRequest{PolicyholderId: 1, Set: map[string]string{"a": "b"}, Delete: []string{"a"}})
+			return err
+		}, codes.InvalidArgument},
+		{"undeclarable change source", func() error {
+			ctx := metadata.AppendToOutgoingContext(ctx, grpcapi.ChangeSourceMetadataKey, "api_v1")
+			_, err := client.Upsert(ctx, &recordsv1.UpsertRequest{PolicyholderId: 1, Data: map[string]string{"a": "b"}})
+			return err
+		}, codes.InvalidArgument},
+		{"version must be positive", func() error {
+			_, err := client.GetVersion(ctx, &recordsv1.GetVersionRequest{PolicyholderId: 1, Version: 0})
+			return err
+		}, codes.InvalidArgument},
+		{"as_of is required", func() error {
+			_, err := client.GetAsOf(ctx, &recordsv1.GetAsOfRequest{PolicyholderId: 1})
+			return err
+		}, codes.InvalidArgument},
+		{"as_of before the record existed", func() error {
+			_, err := client.GetAsOf(ctx, &recordsv1.GetAsOfRequest{PolicyholderId: 1, AsOf: timestamppb.New(time.Unix(0, 0))})
+			return err
+		}, codes.NotFound},
+		{"watch invalid id", func() error {
+			stream, err := client.WatchChanges(ctx, &recordsv1.WatchChangesRequest{PolicyholderId: -1})
+			if err != nil {
+				return err
+			}
+			_, err = stream.Recv()
+			return err
+		}, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := status.Code(tt.call()); got != tt.wantCode {
+				t.Errorf("code = %v, want %v", got, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestWatchChanges(t *testing.T) {
+	client, srv := newClient(t, &stubFlags{enabled: true})
+	ctx := asUser("7")
+
+	first, err := client.Upsert(ctx, &recordsv1.UpsertRequest{PolicyholderId: 1, Data: map[string]string{"n": "1"}})
+	if err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+
+	watchCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// no starting point: only changes written after the call
+	stream, err := client.WatchChanges(watchCtx, &recordsv1.WatchChangesRequest{PolicyholderId: 1})
+	if err != nil {
+		t.Fatalf("WatchChanges: %v", err)
+	}
+	// from the beginning, for every record
+	replay, err := client.WatchChanges(watchCtx, &recordsv1.WatchChangesRequest{AfterChangeId: new(int64)})
+	if err != nil {
+		t.Fatalf("WatchChanges: %v", err)
+	}
+	if c, err := replay.Recv(); err != nil || c.GetVersion() != first.GetVersion() || c.GetPolicyholderId() != 1 {
+		t.Fatalf("replayed change = %v, %v", c, err)
+	}
+
+	// give the first stream time to read its starting point
+	time.Sleep(50 * time.Millisecond)
+	for _, pid := range []int64{2, 1} {
+		if _, err := client.Upsert(ctx, &recordsv1.UpsertRequest{PolicyholderId: pid, Data: map[string]string{"n": "2"}}); err != nil {
+			t.Fatalf("Upsert: %v", err)
+		}
+	}
+
+	c, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+	if c.GetPolicyholderId() != 1 || c.GetVersion() != 2 || c.GetAction() != "update" || c.GetData()["n"] != "2" {
+		t.Fatalf("unexpected change %v", c)
+	}
+
+	for _, want := range []int64{2, 1} {
+		c, err := replay.Recv()
+		if err != nil || c.GetPolicyholderId() != want {
+			t.Fatalf("replay change = %v, %v; want policyholder %d", c, err, want)
+		}
+	}
+
+	// Drain ends open streams so a graceful stop does not hang
+	srv.Drain()
+	if _, err := stream.Recv(); status.Code(err) != codes.Unavailable {
+		t.Errorf("after Drain: %v, want Unavailable", err)
+	}
+}
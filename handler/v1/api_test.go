@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/gorilla/mux"
+	"github.com/rainbowmga/timetravel/controller"
 	"github.com/rainbowmga/timetravel/entity"
 	"github.com/rainbowmga/timetravel/handler/v1"
 )
@@ -18,8 +19,9 @@ import (
 // Simulated errors
 // -------------------------
 var (
-	ErrNotFound   = errors.New("record not found")
-	ErrInvalidID  = errors.New("invalid ID")
+	ErrNotFound   = controller.ErrRecordDoesNotExist
+	ErrInvalidID  = controller.ErrRecordIDInvalid
+	ErrStore      = errors.New("disk I/O error")
 )
 
 // -------------------------
@@ -31,6 +33,9 @@ func (m *mockRecordService) GetRecord(ctx context.Context, id int) (entity.Recor
 	if id <= 0 {
 		return entity.Record{}, ErrInvalidID
 	}
+	if id == 500 {
+		return entity.Record{}, ErrStore
+	}
 	if id == 42 {
 		return entity.Record{ID: 42, Data: map[string]string{"info": "ok"}}, nil
 	}
@@ -160,10 +165,30 @@ func TestGetRecord_NotFound(t *testing.T) {
 
 	router.ServeHTTP(rec, req)
 
-	if rec.Code != http.StatusBadRequest {
+	if rec.Code != http.StatusNotFound {
 		t.Fatalf("expected 404 Not Found, got %d", rec.Code)
 	}
 }
 
 
 
+
+func TestGetRecord_StoreErrorKeepsLegacyBody(t *testing.T) {
+	router := newTestRouter()
+
+	req := httptest.NewRequest("GET", "/records/500", nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+		t.Errorf("v1 must keep its legacy content type, got %q", ct)
+	}
+	var body map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body["error"] != "internal error" {
+		t.Errorf("expected legacy {\"error\": \"internal error\"} body, got %s", rec.Body.String())
+	}
+}
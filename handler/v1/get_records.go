@@ -1,11 +1,13 @@
 package v1
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/gorilla/mux"
+	"github.com/rainbowmga/timetravel/apperr"
 	"github.com/rainbowmga/timetravel/observability"
 )
 
@@ -18,18 +20,23 @@ func (a *API) GetRecords(w http.ResponseWriter, r *http.Request) {
 	idNumber, err := strconv.ParseInt(id, 10, 32)
 
 	if err != nil || idNumber <= 0 {
-		observability.DefaultLogger.Warn("get_records invalid id", "id", id, "error", err)
+		observability.DefaultLogger.WarnContext(ctx, "get_records invalid id", "id", id, "error", err)
 		_ = writeError(w, "invalid id; id must be a positive number", http.StatusBadRequest)
 		return
 	}
 
 	record, err := a.records.GetRecord(ctx, int(idNumber))
+	if errors.Is(err, apperr.ErrNotFound) {
+		observability.DefaultLogger.WarnContext(ctx, "get_records not found", "id", idNumber, "error", err)
+		_ = writeError(w, fmt.Sprintf("record of id %v does not exist", idNumber), http.StatusNotFound)
+		return
+	}
 	if err != nil {
-		observability.DefaultLogger.Warn("get_records not found", "id", idNumber, "error", err)
-		_ = writeError(w, fmt.Sprintf("record of id %v does not exist", idNumber), http.StatusBadRequest)
+		observability.DefaultLogger.ErrorContext(ctx, "get_records failed", "id", idNumber, "error", err)
+		_ = writeAppError(w, err)
 		return
 	}
 
-	observability.DefaultLogger.Info("record_fetched", "api", "v1", "id", idNumber)
+	observability.DefaultLogger.InfoContext(ctx, "record_fetched", "api", "v1", "id", idNumber)
 	_ = writeJSON(w, record, http.StatusOK)
 }
@@ -2,19 +2,20 @@ package v1
 
 import (
 	"encoding/json"
-	"errors"
-	"log"
 	"net/http"
+
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/observability"
 )
 
 var (
-	ErrInternal = errors.New("internal error")
+	ErrInternal = apperr.ErrInternal
 )
 
 // logs an error if it's not nil
 func logError(err error) {
 	if err != nil {
-		log.Printf("error: %v", err)
+		observability.DefaultLogger.Error("v1_error", "error", err)
 	}
 }
 
@@ -28,10 +29,22 @@ func writeJSON(w http.ResponseWriter, data interface{}, statusCode int) error {
 
 // writeError writes the message as an error
 func writeError(w http.ResponseWriter, message string, statusCode int) error {
-	log.Printf("response errored: %s", message)
+	observability.DefaultLogger.Warn("response_errored", "message", message, "status", statusCode)
 	return writeJSON(
 		w,
 		map[string]string{"error": message},
 		statusCode,
 	)
 }
+
+// RespondError answers err in the legacy v1 format; it has the shape of
+// openapi.Options.WriteError so the spec validator can reject v1 requests too
+func RespondError(w http.ResponseWriter, r *http.Request, err error) {
+	_ = writeAppError(w, err)
+}
+
+// writeAppError keeps the legacy {"error": "..."} body but takes the status and
+// client-safe message from the shared error model
+func writeAppError(w http.ResponseWriter, err error) error {
+	return writeError(w, apperr.Message(err), apperr.HTTPStatus(err))
+}
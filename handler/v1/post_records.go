@@ -21,7 +21,7 @@ func (a *API) PostRecords(w http.ResponseWriter, r *http.Request) {
 	idNumber, err := strconv.ParseInt(id, 10, 32)
 
 	if err != nil || idNumber <= 0 {
-		observability.DefaultLogger.Warn("post_records invalid id", "id", id, "error", err)
+		observability.DefaultLogger.WarnContext(ctx, "post_records invalid id", "id", id, "error", err)
 		_ = writeError(w, "invalid id; id must be a positive number", http.StatusBadRequest)
 		return
 	}
@@ -29,7 +29,7 @@ func (a *API) PostRecords(w http.ResponseWriter, r *http.Request) {
 	var body map[string]*string
 	err = json.NewDecoder(r.Body).Decode(&body)
 	if err != nil {
-		observability.DefaultLogger.Warn("post_records invalid JSON", "id", idNumber, "error", err)
+		observability.DefaultLogger.WarnContext(ctx, "post_records invalid JSON", "id", idNumber, "error", err)
 		_ = writeError(w, "invalid input; could not parse json", http.StatusBadRequest)
 		return
 	}
@@ -43,7 +43,7 @@ func (a *API) PostRecords(w http.ResponseWriter, r *http.Request) {
 	if !errors.Is(err, controller.ErrRecordDoesNotExist) { // record exists
 		record, err = a.records.UpdateRecord(ctx, int(idNumber), body)
 		if err == nil {
-			observability.DefaultLogger.Info("record_updated", "api", "v1", "id", idNumber)
+			observability.DefaultLogger.InfoContext(ctx, "record_updated", "api", "v1", "id", idNumber)
 		}
 	} else { // record does not exist
 		recordMap := map[string]string{}
@@ -55,13 +55,13 @@ func (a *API) PostRecords(w http.ResponseWriter, r *http.Request) {
 		record = entity.Record{ID: int(idNumber), Data: recordMap}
 		err = a.records.CreateRecord(ctx, record)
 		if err == nil {
-			observability.DefaultLogger.Info("record_created", "api", "v1", "id", idNumber)
+			observability.DefaultLogger.InfoContext(ctx, "record_created", "api", "v1", "id", idNumber)
 		}
 	}
 
 	if err != nil {
-		observability.DefaultLogger.Error("post_records failed", "id", idNumber, "error", err)
-		_ = writeError(w, ErrInternal.Error(), http.StatusInternalServerError)
+		observability.DefaultLogger.ErrorContext(ctx, "post_records failed", "id", idNumber, "error", err)
+		_ = writeAppError(w, err)
 		return
 	}
 
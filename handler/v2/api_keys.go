@@ -0,0 +1,197 @@
+package v2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/authz"
+	"github.com/rainbowmga/timetravel/controller"
+	"github.com/rainbowmga/timetravel/entity"
+	"github.com/rainbowmga/timetravel/observability"
+	"github.com/rainbowmga/timetravel/service"
+)
+
+var errInvalidAPIKeyID = apperr.InvalidArgument("invalid api key id")
+
+type APIKeyController interface {
+	CreateAPIKey(ctx context.Context, in service.APIKeyInput) (entity.APIKey, string, error)
+	ListAPIKeys(ctx context.Context) ([]entity.APIKey, error)
+	GetAPIKey(ctx context.Context, id int64) (entity.APIKey, error)
+	UpdateAPIKey(ctx context.Context, id int64, u service.APIKeyUpdate) (entity.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id int64) (entity.APIKey, error)
+	RotateAPIKey(ctx context.Context, id int64, grace time.Duration) (entity.APIKey, string, error)
+}
+
+// APIKeyAdminAPI manages API keys; every endpoint needs api_keys:manage
+type APIKeyAdminAPI struct {
+	Keys  APIKeyController
+	Authz Authorizer
+}
+
+// NewAPIKeyAdminAPI initializes the api key admin endpoints
+func NewAPIKeyAdminAPI(c APIKeyController, a Authorizer) *APIKeyAdminAPI {
+	return &APIKeyAdminAPI{Keys: c, Authz: a}
+}
+
+// issuedKey is the only response that carries a key's secret
+type issuedKey struct {
+	APIKey entity.APIKey `json:"api_key"`
+	Key    string        `json:"key"`
+}
+
+// CreateRoutes registers the api key admin endpoints
+func (api *APIKeyAdminAPI) CreateRoutes(router *mux.Router) {
+	router.Handle("/admin/api-keys", guard(api.Authz, authz.APIKeysManage, api.ListAPIKeys)).Methods("GET")
+	router.Handle("/admin/api-keys", guard(api.Authz, authz.APIKeysManage, api.CreateAPIKey)).Methods("POST")
+	router.Handle("/admin/api-keys/{id}", guard(api.Authz, authz.APIKeysManage, api.GetAPIKey)).Methods("GET")
+	router.Handle("/admin/api-keys/{id}", guard(api.Authz, authz.APIKeysManage, api.UpdateAPIKey)).Methods("PATCH")
+	router.Handle("/admin/api-keys/{id}", guard(api.Authz, authz.APIKeysManage, api.RevokeAPIKey)).Methods("DELETE")
+	router.Handle("/admin/api-keys/{id}/rotate", guard(api.Authz, authz.APIKeysManage, api.RotateAPIKey)).Methods("POST")
+}
+
+// GET /api/v2/admin/api-keys
+func (api *APIKeyAdminAPI) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := api.Keys.ListAPIKeys(r.Context())
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	if keys == nil {
+		keys = []entity.APIKey{}
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{"api_keys": keys})
+}
+
+// POST /api/v2/admin/api-keys
+// body: {"name": "billing", "user_id": 7, "tenant": "acme", "roles": ["underwriter"], "expires_at": "2027-01-01T00:00:00Z"}
+func (api *APIKeyAdminAPI) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name      string     `json:"name"`
+		UserID    int64      `json:"user_id"`
+		Tenant    string     `json:"tenant"`
+		Roles     []string   `json:"roles"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, r, errInvalidPayload)
+		return
+	}
+
+	key, secret, err := api.Keys.CreateAPIKey(r.Context(), service.APIKeyInput{
+		Name:      body.Name,
+		UserID:    body.UserID,
+		Tenant:    body.Tenant,
+		Roles:     body.Roles,
+		ExpiresAt: body.ExpiresAt,
+	})
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	observability.DefaultLogger.InfoContext(r.Context(), "api_key_created",
+		"api_key_id", key.ID, "prefix", key.Prefix, "for_user_id", key.UserID, "roles", key.Roles)
+	respondJSON(w, http.StatusCreated, issuedKey{APIKey: key, Key: secret})
+}
+
+// GET /api/v2/admin/api-keys/{id}
+func (api *APIKeyAdminAPI) GetAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiKeyID(w, r)
+	if !ok {
+		return
+	}
+	key, err := api.Keys.GetAPIKey(r.Context(), id)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, key)
+}
+
+// PATCH /api/v2/admin/api-keys/{id}
+// body: any of {"name": "...", "tenant": "...", "roles": [...]}
+func (api *APIKeyAdminAPI) UpdateAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiKeyID(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		Name   *string  `json:"name"`
+		Tenant *string  `json:"tenant"`
+		Roles  []string `json:"roles"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, r, errInvalidPayload)
+		return
+	}
+
+	key, err := api.Keys.UpdateAPIKey(r.Context(), id, service.APIKeyUpdate{Name: body.Name, Tenant: body.Tenant, Roles: body.Roles})
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	observability.DefaultLogger.InfoContext(r.Context(), "api_key_updated", "api_key_id", key.ID, "roles", key.Roles)
+	respondJSON(w, http.StatusOK, key)
+}
+
+// DELETE /api/v2/admin/api-keys/{id} revokes the key; it stays listed
+func (api *APIKeyAdminAPI) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiKeyID(w, r)
+	if !ok {
+		return
+	}
+	key, err := api.Keys.RevokeAPIKey(r.Context(), id)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	observability.DefaultLogger.InfoContext(r.Context(), "api_key_revoked", "api_key_id", key.ID, "prefix", key.Prefix)
+	respondJSON(w, http.StatusOK, key)
+}
+
+// POST /api/v2/admin/api-keys/{id}/rotate
+// body (optional): {"grace_period_seconds": 3600} keeps the old key working that long
+func (api *APIKeyAdminAPI) RotateAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiKeyID(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		GracePeriodSeconds int64 `json:"grace_period_seconds"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		respondError(w, r, errInvalidPayload)
+		return
+	}
+
+	// out of range before it can overflow a time.Duration
+	if body.GracePeriodSeconds < 0 || body.GracePeriodSeconds > int64(math.MaxInt32) {
+		respondError(w, r, controller.ErrAPIKeyGraceInvalid)
+		return
+	}
+
+	key, secret, err := api.Keys.RotateAPIKey(r.Context(), id, time.Duration(body.GracePeriodSeconds)*time.Second)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	observability.DefaultLogger.InfoContext(r.Context(), "api_key_rotated",
+		"api_key_id", key.ID, "prefix", key.Prefix, "rotated_from", id, "grace_period_seconds", body.GracePeriodSeconds)
+	respondJSON(w, http.StatusCreated, issuedKey{APIKey: key, Key: secret})
+}
+
+func apiKeyID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id <= 0 {
+		respondError(w, r, errInvalidAPIKeyID)
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,121 @@
+package v2_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rainbowmga/timetravel/controller"
+	"github.com/rainbowmga/timetravel/entity"
+	"github.com/rainbowmga/timetravel/handler/v2"
+	"github.com/rainbowmga/timetravel/service"
+)
+
+type mockAPIKeyController struct {
+	grace time.Duration // last rotation's grace period
+}
+
+func (m *mockAPIKeyController) CreateAPIKey(ctx context.Context, in service.APIKeyInput) (entity.APIKey, string, error) {
+	if in.Name == "" {
+		return entity.APIKey{}, "", controller.ErrAPIKeyNameRequired
+	}
+	return entity.APIKey{ID: 1, Prefix: "0123456789ab", Name: in.Name, UserID: in.UserID, Roles: in.Roles}, "tt_0123456789ab_secret", nil
+}
+
+func (m *mockAPIKeyController) ListAPIKeys(ctx context.Context) ([]entity.APIKey, error) {
+	return nil, nil
+}
+
+func (m *mockAPIKeyController) GetAPIKey(ctx context.Context, id int64) (entity.APIKey, error) {
+	if id != 1 {
+		return entity.APIKey{}, controller.ErrAPIKeyDoesNotExist
+	}
+	return entity.APIKey{ID: 1, Hash: "stored-hash"}, nil
+}
+
+func (m *mockAPIKeyController) UpdateAPIKey(ctx context.Context, id int64, u service.APIKeyUpdate) (entity.APIKey, error) {
+	return m.GetAPIKey(ctx, id)
+}
+
+func (m *mockAPIKeyController) RevokeAPIKey(ctx context.Context, id int64) (entity.APIKey, error) {
+	return m.GetAPIKey(ctx, id)
+}
+
+func (m *mockAPIKeyController) RotateAPIKey(ctx context.Context, id int64, grace time.Duration) (entity.APIKey, string, error) {
+	m.grace = grace
+	return entity.APIKey{ID: 2, RotatedFrom: &id}, "tt_ba9876543210_secret", nil
+}
+
+// newAPIKeyAdminRouter authenticates every request as user 1 with roles
+func newAPIKeyAdminRouter(c v2.APIKeyController, roles ...string) *mux.Router {
+	r := authenticatedAs(mux.NewRouter(), roles...)
+	v2.NewAPIKeyAdminAPI(c, testAuthorizer).CreateRoutes(r)
+	return r
+}
+
+func TestAPIKeyAdmin(t *testing.T) {
+	tests := []struct {
+		name   string
+		roles  []string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"requires api_keys:manage", []string{"underwriter"}, "GET", "/admin/api-keys", "", http.StatusForbidden},
+		{"list", []string{"admin"}, "GET", "/admin/api-keys", "", http.StatusOK},
+		{"create", []string{"admin"}, "POST", "/admin/api-keys", `{"name":"ci","user_id":7,"roles":["writer"]}`, http.StatusCreated},
+		{"create without name", []string{"admin"}, "POST", "/admin/api-keys", `{"user_id":7}`, http.StatusBadRequest},
+		{"create with bad json", []string{"admin"}, "POST", "/admin/api-keys", `{`, http.StatusBadRequest},
+		{"get", []string{"admin"}, "GET", "/admin/api-keys/1", "", http.StatusOK},
+		{"get unknown", []string{"admin"}, "GET", "/admin/api-keys/9", "", http.StatusNotFound},
+		{"invalid id", []string{"admin"}, "GET", "/admin/api-keys/abc", "", http.StatusBadRequest},
+		{"update", []string{"admin"}, "PATCH", "/admin/api-keys/1", `{"name":"deploys"}`, http.StatusOK},
+		{"revoke", []string{"admin"}, "DELETE", "/admin/api-keys/1", "", http.StatusOK},
+		{"rotate without body", []string{"admin"}, "POST", "/admin/api-keys/1/rotate", "", http.StatusCreated},
+		{"rotate with negative grace", []string{"admin"}, "POST", "/admin/api-keys/1/rotate", `{"grace_period_seconds":-1}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newAPIKeyAdminRouter(&mockAPIKeyController{}, tt.roles...)
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("expected %d got %d: %s", tt.want, rec.Code, rec.Body)
+			}
+			if strings.Contains(rec.Body.String(), "stored-hash") {
+				t.Errorf("response leaks the key hash: %s", rec.Body)
+			}
+		})
+	}
+}
+
+func TestAPIKeyAdmin_IssuedKeyAndGrace(t *testing.T) {
+	keys := &mockAPIKeyController{}
+	router := newAPIKeyAdminRouter(keys, "admin")
+
+	req := httptest.NewRequest("POST", "/admin/api-keys/1/rotate", strings.NewReader(`{"grace_period_seconds":3600}`))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	var body struct {
+		APIKey entity.APIKey `json:"api_key"`
+		Key    string        `json:"key"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusCreated || body.Key == "" || body.APIKey.RotatedFrom == nil || *body.APIKey.RotatedFrom != 1 {
+		t.Errorf("rotate = %d %+v", rec.Code, body)
+	}
+	if keys.grace != time.Hour {
+		t.Errorf("grace = %v, want 1h", keys.grace)
+	}
+}
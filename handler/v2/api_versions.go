@@ -0,0 +1,73 @@
+package v2
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/authz"
+	"github.com/rainbowmga/timetravel/controller"
+	"github.com/rainbowmga/timetravel/entity"
+	"github.com/rainbowmga/timetravel/observability"
+)
+
+var errAPIVersionBodyInvalid = apperr.InvalidArgument("is_active and rollout_percentage are required")
+
+type APIVersionController interface {
+	ListVersions(ctx context.Context) []entity.APIVersionConfig
+	UpdateVersion(ctx context.Context, version string, isActive bool, rolloutPercentage int) (entity.APIVersionConfig, error)
+}
+
+// VersionAdminAPI exposes v1 -> v2 traffic shifting
+type VersionAdminAPI struct {
+	Versions APIVersionController
+	Authz    Authorizer
+}
+
+// NewVersionAdminAPI initializes the api version admin endpoints
+func NewVersionAdminAPI(c *controller.APIVersionController, a Authorizer) *VersionAdminAPI {
+	return &VersionAdminAPI{Versions: c, Authz: a}
+}
+
+// CreateRoutes registers admin endpoints for api version rollout
+func (api *VersionAdminAPI) CreateRoutes(router *mux.Router) {
+	router.Handle("/admin/api-versions", guard(api.Authz, authz.VersionsManage, api.ListAPIVersions)).Methods("GET")
+	router.Handle("/admin/api-versions/{version}", guard(api.Authz, authz.VersionsManage, api.UpdateAPIVersion)).Methods("PUT")
+}
+
+// GET /api/v2/admin/api-versions
+func (api *VersionAdminAPI) ListAPIVersions(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"versions": api.Versions.ListVersions(r.Context()),
+	})
+}
+
+// PUT /api/v2/admin/api-versions/{version}
+// body: {"is_active": true, "rollout_percentage": 25}
+func (api *VersionAdminAPI) UpdateAPIVersion(w http.ResponseWriter, r *http.Request) {
+	version := mux.Vars(r)["version"]
+
+	var body struct {
+		IsActive          *bool `json:"is_active"`
+		RolloutPercentage *int  `json:"rollout_percentage"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.IsActive == nil || body.RolloutPercentage == nil {
+		respondError(w, r, errAPIVersionBodyInvalid)
+		return
+	}
+
+	cfg, err := api.Versions.UpdateVersion(r.Context(), version, *body.IsActive, *body.RolloutPercentage)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	observability.DefaultLogger.InfoContext(r.Context(), "api_version_updated",
+		"version", cfg.Version,
+		"is_active", cfg.IsActive,
+		"rollout_percentage", cfg.RolloutPercentage,
+	)
+	respondJSON(w, http.StatusOK, cfg)
+}
@@ -0,0 +1,77 @@
+package v2_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/rainbowmga/timetravel/controller"
+	"github.com/rainbowmga/timetravel/entity"
+	"github.com/rainbowmga/timetravel/handler/v2"
+)
+
+type mockVersionController struct{}
+
+func (m *mockVersionController) ListVersions(ctx context.Context) []entity.APIVersionConfig {
+	return []entity.APIVersionConfig{{Version: "v2", IsActive: true}, {Version: "v1", IsActive: true, RolloutPercentage: 100}}
+}
+
+func (m *mockVersionController) UpdateVersion(ctx context.Context, version string, isActive bool, rolloutPercentage int) (entity.APIVersionConfig, error) {
+	if version != "v1" && version != "v2" {
+		return entity.APIVersionConfig{}, controller.ErrAPIVersionDoesNotExist
+	}
+	if rolloutPercentage > 100 {
+		return entity.APIVersionConfig{}, controller.ErrRolloutPercentageInvalid
+	}
+	return entity.APIVersionConfig{Version: version, IsActive: isActive, RolloutPercentage: rolloutPercentage}, nil
+}
+
+func newVersionAdminRouter() *mux.Router {
+	api := &v2.VersionAdminAPI{Versions: &mockVersionController{}, Authz: testAuthorizer}
+	r := authenticatedAs(mux.NewRouter(), "admin")
+	api.CreateRoutes(r)
+	return r
+}
+
+func TestListAPIVersions(t *testing.T) {
+	router := newVersionAdminRouter()
+
+	req := httptest.NewRequest("GET", "/admin/api-versions", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d", rec.Code)
+	}
+}
+
+func TestUpdateAPIVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		body    string
+		want    int
+	}{
+		{"shift traffic", "v2", `{"is_active":true,"rollout_percentage":25}`, http.StatusOK},
+		{"missing fields", "v2", `{"is_active":true}`, http.StatusBadRequest},
+		{"invalid percentage", "v2", `{"is_active":true,"rollout_percentage":101}`, http.StatusBadRequest},
+		{"unknown version", "v9", `{"is_active":true,"rollout_percentage":10}`, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newVersionAdminRouter()
+
+			req := httptest.NewRequest("PUT", "/admin/api-versions/"+tt.version, bytes.NewBufferString(tt.body))
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("expected %d got %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
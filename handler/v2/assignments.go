@@ -0,0 +1,98 @@
+package v2
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/authz"
+	"github.com/rainbowmga/timetravel/entity"
+	"github.com/rainbowmga/timetravel/observability"
+)
+
+var errInvalidUserID = apperr.InvalidArgument("invalid user_id")
+
+type AssignmentController interface {
+	ListAssignments(ctx context.Context, userID int64) ([]entity.PolicyholderAssignment, error)
+	Assign(ctx context.Context, userID, policyholderID int64) (entity.PolicyholderAssignment, error)
+	Unassign(ctx context.Context, userID, policyholderID int64) error
+}
+
+// AssignmentAdminAPI manages which policyholders scoped roles (broker) see
+type AssignmentAdminAPI struct {
+	Assignments AssignmentController
+	Authz       Authorizer
+}
+
+// NewAssignmentAdminAPI initializes the assignment admin endpoints
+func NewAssignmentAdminAPI(c AssignmentController, a Authorizer) *AssignmentAdminAPI {
+	return &AssignmentAdminAPI{Assignments: c, Authz: a}
+}
+
+// CreateRoutes registers the assignment admin endpoints
+func (api *AssignmentAdminAPI) CreateRoutes(router *mux.Router) {
+	router.Handle("/admin/users/{user_id}/policyholders", guard(api.Authz, authz.AssignmentsManage, api.ListAssignments)).Methods("GET")
+	router.Handle("/admin/users/{user_id}/policyholders/{id}", guard(api.Authz, authz.AssignmentsManage, api.Assign)).Methods("PUT")
+	router.Handle("/admin/users/{user_id}/policyholders/{id}", guard(api.Authz, authz.AssignmentsManage, api.Unassign)).Methods("DELETE")
+}
+
+// GET /api/v2/admin/users/{user_id}/policyholders
+func (api *AssignmentAdminAPI) ListAssignments(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(mux.Vars(r)["user_id"], 10, 64)
+	if err != nil || userID <= 0 {
+		respondError(w, r, errInvalidUserID)
+		return
+	}
+	assignments, err := api.Assignments.ListAssignments(r.Context(), userID)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{"assignments": assignments})
+}
+
+// PUT /api/v2/admin/users/{user_id}/policyholders/{id}
+func (api *AssignmentAdminAPI) Assign(w http.ResponseWriter, r *http.Request) {
+	userID, policyholderID, ok := assignmentIDs(w, r)
+	if !ok {
+		return
+	}
+	a, err := api.Assignments.Assign(r.Context(), userID, policyholderID)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	observability.DefaultLogger.InfoContext(r.Context(), "policyholder_assigned", "for_user_id", userID, "policyholder_id", policyholderID)
+	respondJSON(w, http.StatusOK, a)
+}
+
+// DELETE /api/v2/admin/users/{user_id}/policyholders/{id}
+func (api *AssignmentAdminAPI) Unassign(w http.ResponseWriter, r *http.Request) {
+	userID, policyholderID, ok := assignmentIDs(w, r)
+	if !ok {
+		return
+	}
+	if err := api.Assignments.Unassign(r.Context(), userID, policyholderID); err != nil {
+		respondError(w, r, err)
+		return
+	}
+	observability.DefaultLogger.InfoContext(r.Context(), "policyholder_unassigned", "for_user_id", userID, "policyholder_id", policyholderID)
+	respondJSON(w, http.StatusOK, map[string]string{"status": "unassigned"})
+}
+
+func assignmentIDs(w http.ResponseWriter, r *http.Request) (userID, policyholderID int64, ok bool) {
+	vars := mux.Vars(r)
+	userID, err := strconv.ParseInt(vars["user_id"], 10, 64)
+	if err != nil || userID <= 0 {
+		respondError(w, r, errInvalidUserID)
+		return 0, 0, false
+	}
+	policyholderID, err = strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil || policyholderID <= 0 {
+		respondError(w, r, errInvalidPolicyholderID)
+		return 0, 0, false
+	}
+	return userID, policyholderID, true
+}
@@ -0,0 +1,90 @@
+package v2_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rainbowmga/timetravel/controller"
+	"github.com/rainbowmga/timetravel/entity"
+	"github.com/rainbowmga/timetravel/handler/v2"
+)
+
+type mockAssignmentController struct {
+	assigned map[int64]bool
+}
+
+func (m *mockAssignmentController) ListAssignments(ctx context.Context, userID int64) ([]entity.PolicyholderAssignment, error) {
+	out := []entity.PolicyholderAssignment{}
+	for id := range m.assigned {
+		out = append(out, entity.PolicyholderAssignment{UserID: userID, PolicyholderID: id})
+	}
+	return out, nil
+}
+
+func (m *mockAssignmentController) Assign(ctx context.Context, userID, policyholderID int64) (entity.PolicyholderAssignment, error) {
+	m.assigned[policyholderID] = true
+	return entity.PolicyholderAssignment{UserID: userID, PolicyholderID: policyholderID, CreatedAt: time.Now()}, nil
+}
+
+func (m *mockAssignmentController) Unassign(ctx context.Context, userID, policyholderID int64) error {
+	if !m.assigned[policyholderID] {
+		return controller.ErrAssignmentDoesNotExist
+	}
+	delete(m.assigned, policyholderID)
+	return nil
+}
+
+func TestAssignmentAdmin(t *testing.T) {
+	tests := []struct {
+		name   string
+		roles  []string
+		method string
+		path   string
+		want   int
+	}{
+		{"requires assignments:manage", []string{"underwriter"}, "PUT", "/admin/users/7/policyholders/3", http.StatusForbidden},
+		{"assign", []string{"admin"}, "PUT", "/admin/users/7/policyholders/3", http.StatusOK},
+		{"invalid user", []string{"admin"}, "PUT", "/admin/users/x/policyholders/3", http.StatusBadRequest},
+		{"invalid policyholder", []string{"admin"}, "PUT", "/admin/users/7/policyholders/0", http.StatusBadRequest},
+		{"unassign", []string{"admin"}, "DELETE", "/admin/users/7/policyholders/1", http.StatusOK},
+		{"unassign missing", []string{"admin"}, "DELETE", "/admin/users/7/policyholders/9", http.StatusNotFound},
+		{"list", []string{"admin"}, "GET", "/admin/users/7/policyholders", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &mockAssignmentController{assigned: map[int64]bool{1: true}}
+			r := authenticatedAs(mux.NewRouter(), tt.roles...)
+			v2.NewAssignmentAdminAPI(c, testAuthorizer).CreateRoutes(r)
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestAssignmentAdmin_List(t *testing.T) {
+	c := &mockAssignmentController{assigned: map[int64]bool{4: true}}
+	r := authenticatedAs(mux.NewRouter(), "admin")
+	v2.NewAssignmentAdminAPI(c, testAuthorizer).CreateRoutes(r)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/admin/users/7/policyholders", nil))
+
+	var body struct {
+		Assignments []entity.PolicyholderAssignment `json:"assignments"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Assignments) != 1 || body.Assignments[0].UserID != 7 || body.Assignments[0].PolicyholderID != 4 {
+		t.Errorf("assignments = %+v", body.Assignments)
+	}
+}
@@ -0,0 +1,48 @@
+package v2
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rainbowmga/timetravel/common"
+	"github.com/rainbowmga/timetravel/controller"
+	"github.com/rainbowmga/timetravel/entity"
+)
+
+// Headers a writer may send to describe its change; both are stored with the version
+const (
+	ChangeSourceHeader = "X-Change-Source" // import or revert; plain writes omit it
+	ChangeReasonHeader = "X-Change-Reason"
+)
+
+// changeContext attributes a write to the v2 API, or to the source the client declared
+func changeContext(r *http.Request) (context.Context, error) {
+	return controller.WithChange(r.Context(), common.SourceAPIV2, r.Header.Get(ChangeSourceHeader), r.Header.Get(ChangeReasonHeader))
+}
+
+// changedByParam reads ?changed_by=; 0 when absent
+func changedByParam(r *http.Request) (int64, error) {
+	raw := r.URL.Query().Get("changed_by")
+	if raw == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, controller.ErrChangedByInvalid
+	}
+	return id, nil
+}
+
+// versionInfo is a version's metadata as the version endpoints render it
+type versionInfo struct {
+	Version   int       `json:"version"`
+	Action    string    `json:"action"`
+	ChangedAt time.Time `json:"changed_at"`
+	entity.ChangeAttribution
+}
+
+func toVersionInfo(e entity.AuditHistory) versionInfo {
+	return versionInfo{Version: e.Version, Action: e.EventType, ChangedAt: e.ChangedAt, ChangeAttribution: e.ChangeAttribution}
+}
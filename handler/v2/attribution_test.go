@@ -0,0 +1,80 @@
+package v2_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/rainbowmga/timetravel/common"
+	"github.com/rainbowmga/timetravel/entity"
+	"github.com/rainbowmga/timetravel/handler/v2"
+)
+
+// capturingController keeps the context of the last write
+type capturingController struct {
+	mockController
+	ctx context.Context
+}
+
+func (c *capturingController) UpsertRecord(ctx context.Context, id int64, data map[string]string) (entity.PolicyholderRecord, error) {
+	c.ctx = ctx
+	return c.mockController.UpsertRecord(ctx, id, data)
+}
+
+func (c *capturingController) UpdateRecord(ctx context.Context, id int, updates map[string]*string) (entity.PolicyholderRecord, error) {
+	c.ctx = ctx
+	return c.mockController.UpdateRecord(ctx, id, updates)
+}
+
+func TestWriteAttribution(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		source     string
+		reason     string
+		want       int
+		wantSource string
+	}{
+		{"plain upsert", "POST", "", "", http.StatusOK, common.SourceAPIV2},
+		{"revert with reason", "POST", "revert", "undo bad import", http.StatusOK, common.SourceRevert},
+		{"import patch", "PATCH", "import", "", http.StatusOK, common.SourceImport},
+		{"undeclarable source", "POST", "api_v1", "", http.StatusBadRequest, ""},
+		{"reason too long", "POST", "", strings.Repeat("x", 501), http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &capturingController{}
+			r := authenticatedAs(mux.NewRouter(), "admin")
+			(&v2.API{Controller: c, Flags: &mockFlags{enabled: true}, Authz: testAuthorizer}).CreateRoutes(r)
+
+			req := httptest.NewRequest(tt.method, "/records/1", strings.NewReader(`{"name":"ann"}`))
+			if tt.source != "" {
+				req.Header.Set(v2.ChangeSourceHeader, tt.source)
+			}
+			if tt.reason != "" {
+				req.Header.Set(v2.ChangeReasonHeader, tt.reason)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if tt.want != http.StatusOK {
+				if c.ctx != nil {
+					t.Error("refused write reached the controller")
+				}
+				return
+			}
+			if source, _ := common.GetChangeSource(c.ctx); source != tt.wantSource {
+				t.Errorf("source = %q, want %q", source, tt.wantSource)
+			}
+			if reason, _ := common.GetChangeReason(c.ctx); reason != tt.reason {
+				t.Errorf("reason = %q, want %q", reason, tt.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package v2
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/rainbowmga/timetravel/authz"
+)
+
+// Authorizer checks the permission each route declares (authz.Authorizer)
+type Authorizer interface {
+	Require(perm authz.Permission) mux.MiddlewareFunc
+}
+
+// guard serves h only to callers holding perm
+func guard(a Authorizer, perm authz.Permission, h http.HandlerFunc) http.Handler {
+	return a.Require(perm)(h)
+}
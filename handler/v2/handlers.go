@@ -7,17 +7,28 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/authz"
 	"github.com/rainbowmga/timetravel/controller"
 	"github.com/rainbowmga/timetravel/observability"
 	"github.com/rainbowmga/timetravel/entity"
 	"context"
 )
 
+// request validation failures raised by the handlers themselves
+var (
+	errV2Disabled            = apperr.New(apperr.ErrPermissionDenied, "enable_v2_api flag is disabled")
+	errInvalidPolicyholderID = apperr.InvalidArgument("invalid policyholder_id")
+	errInvalidVersion        = apperr.InvalidArgument("invalid version")
+	errInvalidPayload        = apperr.InvalidArgument("invalid JSON payload")
+)
+
 type RecordController interface {
     UpsertRecord(ctx context.Context, id int64, data map[string]string) (entity.PolicyholderRecord, error)
     GetRecord(ctx context.Context, id int64) (entity.PolicyholderRecord, error)
-    GetVersion(ctx context.Context, id int, version int) (map[string]string, error)
-    ListVersions(ctx context.Context, id int) ([]int, error)
+    UpdateRecord(ctx context.Context, id int, updates map[string]*string) (entity.PolicyholderRecord, error)
+    VersionEntry(ctx context.Context, id int, version int) (entity.AuditHistory, error)
+    VersionEntries(ctx context.Context, id int, changedBy int64) ([]entity.AuditHistory, error)
 }
 
 type FeatureFlagService interface {
@@ -29,32 +40,34 @@ type FeatureFlagService interface {
 type API struct {
     Controller RecordController
     Flags      FeatureFlagService
+    Authz      Authorizer
 }
 
 
 
 
 // NewAPI initializes the v2 API
-func NewAPI(c *controller.SQLiteRecordController, flags *controller.FeatureFlagController) *API {
-	return &API{Controller: c, Flags: flags}
+func NewAPI(c *controller.SQLiteRecordController, flags *controller.FeatureFlagController, a Authorizer) *API {
+	return &API{Controller: c, Flags: flags, Authz: a}
 }
 
 
-// CreateRoutes registers v2 endpoints
+// CreateRoutes registers v2 endpoints with the permission each one needs
 func (api *API) CreateRoutes(router *mux.Router) {
-	router.HandleFunc("/records/{policyholder_id}", api.UpsertRecord).Methods("POST")
-	router.HandleFunc("/records/{policyholder_id}", api.GetRecord).Methods("GET")
+	router.Handle("/records/{policyholder_id}", guard(api.Authz, authz.RecordsWrite, api.UpsertRecord)).Methods("POST")
+	router.Handle("/records/{policyholder_id}", guard(api.Authz, authz.RecordsRead, api.GetRecord)).Methods("GET")
+	router.Handle("/records/{policyholder_id}", guard(api.Authz, authz.RecordsWrite, api.PatchRecord)).Methods("PATCH")
 	router.HandleFunc("/health", api.HealthCheck).Methods("POST")
-	router.HandleFunc("/records/{policyholder_id}/versions", api.ListVersions).Methods("GET")
-	router.HandleFunc("/records/{policyholder_id}/versions/{version}", api.GetVersion).Methods("GET")
-	router.HandleFunc("/admin/refresh-flags", api.RefreshFlags).Methods("POST")
+	router.Handle("/records/{policyholder_id}/versions", guard(api.Authz, authz.RecordsRead, api.ListVersions)).Methods("GET")
+	router.Handle("/records/{policyholder_id}/versions/{version}", guard(api.Authz, authz.RecordsRead, api.GetVersion)).Methods("GET")
+	router.Handle("/admin/refresh-flags", guard(api.Authz, authz.FlagsRefresh, api.RefreshFlags)).Methods("POST")
 }
 
 // UpsertRecord creates or updates a record
 func (api *API) UpsertRecord(w http.ResponseWriter, r *http.Request) {
 	// Feature flag check: enable v2 record logic
 	if !api.Flags.IsEnabled(r.Context(),"enable_v2_api") {
-		respondError(w, http.StatusForbidden, "enable_v2_api flag is disabled")
+		respondError(w, r, errV2Disabled)
 		return
 	}
 	
@@ -62,24 +75,28 @@ func (api *API) UpsertRecord(w http.ResponseWriter, r *http.Request) {
 	pidStr := vars["policyholder_id"]
 	policyholderID, err := strconv.ParseInt(pidStr, 10, 64)
 	if err != nil || policyholderID <= 0 {
-		respondError(w, http.StatusBadRequest, "invalid policyholder_id")
+		respondError(w, r, errInvalidPolicyholderID)
 		return
 	}
 
 	var data map[string]string
 	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-		respondError(w, http.StatusBadRequest, "invalid JSON payload")
+		respondError(w, r, errInvalidPayload)
 		return
 	}
 
-	ctx := r.Context()
+	ctx, err := changeContext(r)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
 	record, err := api.Controller.UpsertRecord(ctx, policyholderID, data)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, err.Error())
+		respondError(w, r, err)
 		return
 	}
 
-	observability.DefaultLogger.Info("record_upserted", "policyholder_id", policyholderID, "version", record.Version)
+	observability.DefaultLogger.InfoContext(ctx, "record_upserted", "policyholder_id", policyholderID, "version", record.Version)
 	respondJSON(w, http.StatusOK, map[string]interface{}{
 		"policyholder_id": policyholderID,
 		"record_id":       record.ID,
@@ -90,11 +107,53 @@ func (api *API) UpsertRecord(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// PatchRecord merges the body into an existing record as a new version;
+// a null value removes the key
+func (api *API) PatchRecord(w http.ResponseWriter, r *http.Request) {
+	if !api.Flags.IsEnabled(r.Context(), "enable_v2_api") {
+		respondError(w, r, errV2Disabled)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["policyholder_id"])
+	if err != nil || id <= 0 {
+		respondError(w, r, errInvalidPolicyholderID)
+		return
+	}
+
+	var updates map[string]*string
+	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
+		respondError(w, r, errInvalidPayload)
+		return
+	}
+
+	ctx, err := changeContext(r)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	record, err := api.Controller.UpdateRecord(ctx, id, updates)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	observability.DefaultLogger.InfoContext(ctx, "record_patched", "policyholder_id", id, "version", record.Version)
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"policyholder_id": id,
+		"record_id":       record.ID,
+		"version":         record.Version,
+		"data":            record.Data,
+		"created_at":      record.CreatedAt.Format(time.RFC3339),
+		"updated_at":      record.UpdatedAt.Format(time.RFC3339),
+	})
+}
+
 // GetRecord retrieves a policyholder record
 func (api *API) GetRecord(w http.ResponseWriter, r *http.Request) {
 	// Feature flag check: enable v2 record logic
 	if !api.Flags.IsEnabled(r.Context(),"enable_v2_api") {
-		respondError(w, http.StatusForbidden, "enable_v2_api flag is disabled")
+		respondError(w, r, errV2Disabled)
 		return
 	}
 
@@ -102,21 +161,17 @@ func (api *API) GetRecord(w http.ResponseWriter, r *http.Request) {
 	pidStr := vars["policyholder_id"]
 	policyholderID, err := strconv.ParseInt(pidStr, 10, 64)
 	if err != nil || policyholderID <= 0 {
-		respondError(w, http.StatusBadRequest, "invalid policyholder_id")
+		respondError(w, r, errInvalidPolicyholderID)
 		return
 	}
 
 	record, err := api.Controller.GetRecord(r.Context(), policyholderID)
 	if err != nil {
-		if err == controller.ErrRecordDoesNotExist {
-		respondError(w, http.StatusNotFound, err.Error())
-		return
-	}
-		respondError(w, http.StatusInternalServerError, err.Error())
+		respondError(w, r, err)
 		return
 	}
 
-	observability.DefaultLogger.Info("record_fetched", "policyholder_id", policyholderID, "version", record.Version)
+	observability.DefaultLogger.InfoContext(r.Context(), "record_fetched", "policyholder_id", policyholderID, "version", record.Version)
 	respondJSON(w, http.StatusOK, map[string]interface{}{
 		"policyholder_id": policyholderID,
 		"record_id":       record.ID,
@@ -130,7 +185,7 @@ func (api *API) GetRecord(w http.ResponseWriter, r *http.Request) {
 // POST /api/v2/admin/refresh-flags
 func (api *API) RefreshFlags(w http.ResponseWriter, r *http.Request) {
 	if err := api.Flags.Refresh(); err != nil {
-		respondError(w, http.StatusInternalServerError, err.Error())
+		respondError(w, r, err)
 		return
 	}
 	respondJSON(w, http.StatusOK, map[string]string{"status": "feature flags refreshed"})
@@ -140,7 +195,7 @@ func (api *API) RefreshFlags(w http.ResponseWriter, r *http.Request) {
 func (api *API) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	// Feature flag check: enable v2 record logic
 	if !api.Flags.IsEnabled(r.Context(),"enable_v2_api") {
-		respondError(w, http.StatusForbidden, "enable_v2_api flag is disabled")
+		respondError(w, r, errV2Disabled)
 	}else{
 		respondJSON(w, http.StatusOK, map[string]bool{"ok": true})
 	}
@@ -153,56 +208,86 @@ func respondJSON(w http.ResponseWriter, status int, payload interface{}) {
 	_ = json.NewEncoder(w).Encode(payload)
 }
 
-// Helper for error JSON
-func respondError(w http.ResponseWriter, status int, message string) {
-	respondJSON(w, status, map[string]string{"error": message})
+// respondError answers with an RFC 7807 problem; the status comes from the
+// error's kind (apperr.HTTPStatus) and causes of 5xx answers are only logged
+func respondError(w http.ResponseWriter, r *http.Request, err error) {
+	if status := apperr.HTTPStatus(err); status >= http.StatusInternalServerError {
+		observability.DefaultLogger.ErrorContext(r.Context(), "request_failed", "status", status, "error", err)
+	}
+	apperr.WriteProblem(w, r, err)
 }
 
 // GetVersion to record for given versionID
 func (api *API) GetVersion(w http.ResponseWriter, r *http.Request) {
 	// Feature flag check: enable v2 record logic
 	if !api.Flags.IsEnabled(r.Context(),"enable_v2_api") {
-		respondError(w, http.StatusForbidden, "enable_v2_api flag is disabled")
+		respondError(w, r, errV2Disabled)
 		return
 	}
 	
 	vars := mux.Vars(r)
 
-	id, _ := strconv.Atoi(vars["policyholder_id"])
-	version, _ := strconv.Atoi(vars["version"])
+	id, err := strconv.Atoi(vars["policyholder_id"])
+	if err != nil || id <= 0 {
+		respondError(w, r, errInvalidPolicyholderID)
+		return
+	}
+	version, err := strconv.Atoi(vars["version"])
+	if err != nil || version <= 0 {
+		respondError(w, r, errInvalidVersion)
+		return
+	}
 
-	data, err := api.Controller.GetVersion(r.Context(), id, version)
+	entry, err := api.Controller.VersionEntry(r.Context(), id, version)
 	if err != nil {
-		respondError(w, http.StatusNotFound, err.Error())
+		respondError(w, r, err)
 		return
 	}
 
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"policyholder_id": id,
-		"version":         version,
-		"data":            data,
-	})
+	respondJSON(w, http.StatusOK, struct {
+		PolicyholderID int               `json:"policyholder_id"`
+		Data           map[string]string `json:"data"`
+		versionInfo
+	}{id, entry.Data, toVersionInfo(entry)})
 }
 
-// ListVersions to fetch all the versionIDs 
+// ListVersions to fetch all the versionIDs with who wrote each;
+// ?changed_by= keeps the versions of one user
 func (api *API) ListVersions(w http.ResponseWriter, r *http.Request) {
 	// Feature flag check: enable v2 record logic
 	if !api.Flags.IsEnabled(r.Context(),"enable_v2_api") {
-		respondError(w, http.StatusForbidden, "enable_v2_api flag is disabled")
+		respondError(w, r, errV2Disabled)
 		return
 	}
 	
 	vars := mux.Vars(r)
-	id, _ := strconv.Atoi(vars["policyholder_id"])
+	id, err := strconv.Atoi(vars["policyholder_id"])
+	if err != nil || id <= 0 {
+		respondError(w, r, errInvalidPolicyholderID)
+		return
+	}
+
+	changedBy, err := changedByParam(r)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
 
-	versions, err := api.Controller.ListVersions(r.Context(), id)
+	entries, err := api.Controller.VersionEntries(r.Context(), id, changedBy)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, err.Error())
+		respondError(w, r, err)
 		return
 	}
 
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	versions := make([]int, 0, len(entries))
+	history := make([]versionInfo, 0, len(entries))
+	for _, e := range entries {
+		versions = append(versions, e.Version)
+		history = append(history, toVersionInfo(e))
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
 		"policyholder_id": id,
 		"versions":        versions,
-})
+		"history":         history,
+	})
 }
\ No newline at end of file
@@ -3,6 +3,7 @@ package v2_test
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	
 	"errors"
 	"net/http"
@@ -11,6 +12,9 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/auth"
+	"github.com/rainbowmga/timetravel/authz"
 	"github.com/rainbowmga/timetravel/controller"
 	"github.com/rainbowmga/timetravel/entity"
 	"github.com/rainbowmga/timetravel/handler/v2"
@@ -26,6 +30,15 @@ func (m *mockController) UpsertRecord(ctx context.Context, id int64, data map[st
 	if id == 500 {
 		return entity.PolicyholderRecord{}, errors.New("db error")
 	}
+	if id == 499 {
+		return entity.PolicyholderRecord{}, apperr.Wrap(controller.ErrRequestCanceled, context.Canceled)
+	}
+	if id == 504 {
+		return entity.PolicyholderRecord{}, apperr.Wrap(controller.ErrRequestTimeout, context.DeadlineExceeded)
+	}
+	if id == 503 {
+		return entity.PolicyholderRecord{}, apperr.Unavailable("record store is busy, retry later", 1500*time.Millisecond, errors.New("database is locked"))
+	}
 	return entity.PolicyholderRecord{
 		ID:        1,
 		Version:   1,
@@ -48,18 +61,46 @@ func (m *mockController) GetRecord(ctx context.Context, id int64) (entity.Policy
 	}, nil
 }
 
-func (m *mockController) GetVersion(ctx context.Context, id int, version int) (map[string]string, error) {
+func (m *mockController) UpdateRecord(ctx context.Context, id int, updates map[string]*string) (entity.PolicyholderRecord, error) {
+	if id == 404 {
+		return entity.PolicyholderRecord{}, controller.ErrRecordDoesNotExist
+	}
+	data := map[string]string{"name": "john", "city": "paris"}
+	for k, v := range updates {
+		if v == nil {
+			delete(data, k)
+		} else {
+			data[k] = *v
+		}
+	}
+	return entity.PolicyholderRecord{
+		ID:        1,
+		Version:   3,
+		Data:      data,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}, nil
+}
+
+func (m *mockController) VersionEntry(ctx context.Context, id int, version int) (entity.AuditHistory, error) {
 	if version == 404 {
-		return nil, errors.New("version not found")
+		return entity.AuditHistory{}, controller.ErrRecordDoesNotExist
 	}
-	return map[string]string{"name": "v1"}, nil
+	return entity.AuditHistory{Version: version, Data: map[string]string{"name": "v1"}, EventType: "create",
+		ChangeAttribution: entity.ChangeAttribution{ChangedBy: 7, RequestID: "req-1", Source: "api_v2", Reason: "onboarding"}}, nil
 }
 
-func (m *mockController) ListVersions(ctx context.Context, id int) ([]int, error) {
+func (m *mockController) VersionEntries(ctx context.Context, id int, changedBy int64) ([]entity.AuditHistory, error) {
 	if id == 500 {
 		return nil, errors.New("error")
 	}
-	return []int{1, 2, 3}, nil
+	entries := []entity.AuditHistory{}
+	for v, by := range []int64{7, 8, 7} {
+		if changedBy == 0 || changedBy == by {
+			entries = append(entries, entity.AuditHistory{Version: v + 1, ChangeAttribution: entity.ChangeAttribution{ChangedBy: by}})
+		}
+	}
+	return entries, nil
 }
 
 type mockFlags struct {
@@ -78,13 +119,32 @@ func (m *mockFlags) Refresh() error {
 // ---------------- HELPERS ----------------
 //
 
+// testAuthorizer applies the built-in roles; scoped roles see no policyholder
+var testAuthorizer = authz.New(authz.DefaultPolicy(), nil, nil)
+
+// authenticatedAs runs every request on r as user 1 with roles
+func authenticatedAs(r *mux.Router, roles ...string) *mux.Router {
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			ctx := auth.WithPrincipal(req.Context(), auth.Principal{UserID: 1, Roles: roles})
+			next.ServeHTTP(w, req.WithContext(ctx))
+		})
+	})
+	return r
+}
+
 func newTestRouter(flagEnabled bool) *mux.Router {
+	return newTestRouterAs(flagEnabled, authz.RoleAdmin)
+}
+
+func newTestRouterAs(flagEnabled bool, roles ...string) *mux.Router {
 	api := &v2.API{
 		Controller: &mockController{},
 		Flags:      &mockFlags{enabled: flagEnabled},
+		Authz:      testAuthorizer,
 	}
 
-	r := mux.NewRouter()
+	r := authenticatedAs(mux.NewRouter(), roles...)
 	api.CreateRoutes(r)
 	return r
 }
@@ -185,6 +245,46 @@ func TestGetRecord_NotFound(t *testing.T) {
 	}
 }
 
+func TestPatchRecord(t *testing.T) {
+	router := newTestRouter(true)
+
+	tests := []struct {
+		name     string
+		path     string
+		body     string
+		want     int
+		wantData map[string]string
+	}{
+		{"set and remove", "/records/1", `{"name":"jane","city":null}`, http.StatusOK, map[string]string{"name": "jane"}},
+		{"missing record", "/records/404", `{"name":"jane"}`, http.StatusNotFound, nil},
+		{"invalid id", "/records/abc", `{}`, http.StatusBadRequest, nil},
+		{"non-string value", "/records/1", `{"age":36}`, http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("PATCH", tt.path, bytes.NewBufferString(tt.body))
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("expected %d got %d", tt.want, rec.Code)
+			}
+			if tt.wantData == nil {
+				return
+			}
+			var resp struct {
+				Data map[string]string `json:"data"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Data) != len(tt.wantData) || resp.Data["name"] != tt.wantData["name"] {
+				t.Errorf("data = %v, want %v", resp.Data, tt.wantData)
+			}
+		})
+	}
+}
+
 func TestRefreshFlags(t *testing.T) {
 	router := newTestRouter(true)
 
@@ -201,13 +301,49 @@ func TestRefreshFlags(t *testing.T) {
 func TestListVersions(t *testing.T) {
 	router := newTestRouter(true)
 
-	req := httptest.NewRequest("GET", "/records/1/versions", nil)
-	rec := httptest.NewRecorder()
-
-	router.ServeHTTP(rec, req)
-
-	if rec.Code != http.StatusOK {
-		t.Fatalf("expected 200 got %d", rec.Code)
+	tests := []struct {
+		name         string
+		query        string
+		want         int
+		wantVersions []int
+	}{
+		{"all", "", http.StatusOK, []int{1, 2, 3}},
+		{"changed by", "?changed_by=7", http.StatusOK, []int{1, 3}},
+		{"changed by nobody", "?changed_by=9", http.StatusOK, []int{}},
+		{"invalid changed by", "?changed_by=abc", http.StatusBadRequest, nil},
+		{"non-positive changed by", "?changed_by=0", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/records/1/versions"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("expected %d got %d", tt.want, rec.Code)
+			}
+			if tt.wantVersions == nil {
+				return
+			}
+			var resp struct {
+				Versions []int `json:"versions"`
+				History  []struct {
+					Version   int   `json:"version"`
+					ChangedBy int64 `json:"changed_by"`
+				} `json:"history"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Versions) != len(tt.wantVersions) || len(resp.History) != len(tt.wantVersions) {
+				t.Fatalf("response = %s, want versions %v", rec.Body, tt.wantVersions)
+			}
+			for i, v := range tt.wantVersions {
+				if resp.Versions[i] != v || resp.History[i].Version != v || resp.History[i].ChangedBy == 0 {
+					t.Errorf("entry %d = %d %+v, want version %d with its author", i, resp.Versions[i], resp.History[i], v)
+				}
+			}
+		})
 	}
 }
 
@@ -222,6 +358,18 @@ func TestGetVersion_Success(t *testing.T) {
 	if rec.Code != http.StatusOK {
 		t.Fatalf("expected 200 got %d", rec.Code)
 	}
+	var resp map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]interface{}{
+		"policyholder_id": 1.0, "version": 1.0, "action": "create",
+		"changed_by": 7.0, "request_id": "req-1", "source": "api_v2", "reason": "onboarding",
+	} {
+		if resp[key] != want {
+			t.Errorf("%s = %v, want %v", key, resp[key], want)
+		}
+	}
 }
 
 func TestGetVersion_NotFound(t *testing.T) {
@@ -236,3 +384,139 @@ func TestGetVersion_NotFound(t *testing.T) {
 		t.Fatalf("expected 404 got %d", rec.Code)
 	}
 }
+
+func TestUpsertRecord_StoreBusy(t *testing.T) {
+	router := newTestRouter(true)
+
+	req := httptest.NewRequest("POST", "/records/503", bytes.NewBufferString(`{"name":"john"}`))
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("expected Retry-After 2, got %q", got)
+	}
+	if bytes.Contains(rec.Body.Bytes(), []byte("database is locked")) {
+		t.Errorf("driver message leaked: %s", rec.Body.String())
+	}
+}
+
+func TestUpsertRecord_InternalError(t *testing.T) {
+	router := newTestRouter(true)
+
+	req := httptest.NewRequest("POST", "/records/500", bytes.NewBufferString(`{"name":"john"}`))
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError || rec.Header().Get("Retry-After") != "" {
+		t.Fatalf("expected 500 without Retry-After, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+}
+
+func TestUpsertRecord_Cancellation(t *testing.T) {
+	router := newTestRouter(true)
+
+	tests := []struct {
+		id   string
+		want int
+	}{
+		{"499", apperr.StatusClientClosedRequest},
+		{"504", http.StatusGatewayTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/records/"+tt.id, bytes.NewBufferString(`{"name":"john"}`))
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("expected %d got %d", tt.want, rec.Code)
+			}
+			if rec.Header().Get("Retry-After") != "" {
+				t.Errorf("unexpected Retry-After on %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestGetRecord_NotFoundIsProblemJSON(t *testing.T) {
+	router := newTestRouter(true)
+
+	req := httptest.NewRequest("GET", "/records/404", nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); ct != apperr.ProblemContentType {
+		t.Fatalf("expected %s got %q", apperr.ProblemContentType, ct)
+	}
+	var p apperr.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+		t.Fatal(err)
+	}
+	want := apperr.Problem{
+		Type:     "about:blank",
+		Title:    "Not Found",
+		Status:   http.StatusNotFound,
+		Detail:   controller.ErrRecordDoesNotExist.Message,
+		Instance: "/records/404",
+		Code:     "not_found",
+	}
+	if p != want {
+		t.Errorf("problem = %+v, want %+v", p, want)
+	}
+}
+
+func TestUpsertRecord_InternalErrorHidesCause(t *testing.T) {
+	router := newTestRouter(true)
+
+	req := httptest.NewRequest("POST", "/records/500", bytes.NewBufferString(`{"name":"john"}`))
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	if bytes.Contains(rec.Body.Bytes(), []byte("db error")) {
+		t.Errorf("cause leaked into the response: %s", rec.Body.String())
+	}
+}
+
+func TestRoutePermissions(t *testing.T) {
+	tests := []struct {
+		name   string
+		roles  []string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"reader reads", []string{"reader"}, "GET", "/records/1", "", http.StatusOK},
+		{"reader lists versions", []string{"reader"}, "GET", "/records/1/versions", "", http.StatusOK},
+		{"reader cannot write", []string{"reader"}, "POST", "/records/1", `{"name":"john"}`, http.StatusForbidden},
+		{"reader cannot patch", []string{"reader"}, "PATCH", "/records/1", `{"name":"jane"}`, http.StatusForbidden},
+		{"underwriter writes", []string{"underwriter"}, "POST", "/records/1", `{"name":"john"}`, http.StatusOK},
+		{"underwriter cannot refresh flags", []string{"underwriter"}, "POST", "/admin/refresh-flags", "", http.StatusForbidden},
+		{"broker without assignments", []string{"broker"}, "GET", "/records/1", "", http.StatusForbidden},
+		{"broker and reader", []string{"broker", "reader"}, "GET", "/records/1", "", http.StatusOK},
+		{"no roles", nil, "GET", "/records/1", "", http.StatusForbidden},
+		{"health needs no permission", nil, "POST", "/health", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newTestRouterAs(true, tt.roles...)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body)))
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+			if rec.Code == http.StatusForbidden && rec.Header().Get("Content-Type") != "application/problem+json" {
+				t.Errorf("Content-Type = %q, want problem+json", rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package v2
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/authz"
+	"github.com/rainbowmga/timetravel/controller"
+	"github.com/rainbowmga/timetravel/entity"
+)
+
+// defaultMetricsWindow is used when from is omitted
+const defaultMetricsWindow = time.Hour
+
+var (
+	errMetricToInvalid   = apperr.InvalidArgument("to must be an RFC3339 timestamp")
+	errMetricFromInvalid = apperr.InvalidArgument("from must be an RFC3339 timestamp")
+	errMetricStepSyntax  = apperr.InvalidArgument("step must be a duration such as 1m, 5m or 1h")
+)
+
+type MetricsController interface {
+	QueryMetric(ctx context.Context, q controller.MetricsQuery) ([]entity.MetricRollup, error)
+}
+
+// MetricsAdminAPI exposes the persisted metric rollups
+type MetricsAdminAPI struct {
+	Metrics MetricsController
+	Authz   Authorizer
+}
+
+// NewMetricsAdminAPI initializes the metrics admin endpoint
+func NewMetricsAdminAPI(c MetricsController, a Authorizer) *MetricsAdminAPI {
+	return &MetricsAdminAPI{Metrics: c, Authz: a}
+}
+
+// CreateRoutes registers the metrics admin endpoint
+func (api *MetricsAdminAPI) CreateRoutes(router *mux.Router) {
+	router.Handle("/admin/metrics", guard(api.Authz, authz.MetricsRead, api.QueryMetrics)).Methods("GET")
+}
+
+type metricPoint struct {
+	Time  time.Time `json:"time"`
+	Count int64     `json:"count"`
+	Sum   float64   `json:"sum"`
+	Min   float64   `json:"min"`
+	Max   float64   `json:"max"`
+	Avg   float64   `json:"avg"`
+}
+
+// GET /api/v2/admin/metrics?name=&from=&to=&step=&region=
+// from/to are RFC3339 (default: the last hour), step a Go duration in whole minutes (default 1m)
+func (api *MetricsAdminAPI) QueryMetrics(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	q := controller.MetricsQuery{
+		Name:   params.Get("name"),
+		Region: params.Get("region"),
+		To:     time.Now().UTC(),
+		Step:   time.Minute,
+	}
+
+	var err error
+	if v := params.Get("to"); v != "" {
+		if q.To, err = time.Parse(time.RFC3339, v); err != nil {
+			respondError(w, r, errMetricToInvalid)
+			return
+		}
+	}
+	q.From = q.To.Add(-defaultMetricsWindow)
+	if v := params.Get("from"); v != "" {
+		if q.From, err = time.Parse(time.RFC3339, v); err != nil {
+			respondError(w, r, errMetricFromInvalid)
+			return
+		}
+	}
+	if v := params.Get("step"); v != "" {
+		if q.Step, err = time.ParseDuration(v); err != nil {
+			respondError(w, r, errMetricStepSyntax)
+			return
+		}
+	}
+
+	rollups, err := api.Metrics.QueryMetric(r.Context(), q)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	points := make([]metricPoint, 0, len(rollups))
+	for _, p := range rollups {
+		point := metricPoint{Time: p.BucketStart, Count: p.Count, Sum: p.Sum, Min: p.Min, Max: p.Max}
+		if p.Count > 0 {
+			point.Avg = p.Sum / float64(p.Count)
+		}
+		points = append(points, point)
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"name":   q.Name,
+		"region": q.Region,
+		"from":   q.From,
+		"to":     q.To,
+		"step":   q.Step.String(),
+		"points": points,
+	})
+}
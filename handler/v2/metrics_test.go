@@ -0,0 +1,76 @@
+package v2_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rainbowmga/timetravel/controller"
+	"github.com/rainbowmga/timetravel/entity"
+	"github.com/rainbowmga/timetravel/handler/v2"
+)
+
+type mockMetricsController struct {
+	got controller.MetricsQuery
+}
+
+func (m *mockMetricsController) QueryMetric(ctx context.Context, q controller.MetricsQuery) ([]entity.MetricRollup, error) {
+	m.got = q
+	if q.Name == "" {
+		return nil, controller.ErrMetricNameRequired
+	}
+	return []entity.MetricRollup{{BucketStart: q.From, Count: 4, Sum: 10, Min: 1, Max: 4}}, nil
+}
+
+func TestQueryMetrics(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		wantCode int
+	}{
+		{"explicit range", "?name=latency&from=2024-05-01T10:00:00Z&to=2024-05-01T11:00:00Z&step=5m", http.StatusOK},
+		{"defaults", "?name=latency", http.StatusOK},
+		{"missing name", "", http.StatusBadRequest},
+		{"bad from", "?name=latency&from=yesterday", http.StatusBadRequest},
+		{"bad step", "?name=latency&step=often", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := &mockMetricsController{}
+			r := authenticatedAs(mux.NewRouter(), "auditor")
+			v2.NewMetricsAdminAPI(ctrl, testAuthorizer).CreateRoutes(r)
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest("GET", "/admin/metrics"+tt.query, nil))
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("expected %d got %d: %s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			var body struct {
+				Step   string `json:"step"`
+				Points []struct {
+					Count int64   `json:"count"`
+					Avg   float64 `json:"avg"`
+				} `json:"points"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("invalid JSON: %v", err)
+			}
+			if len(body.Points) != 1 || body.Points[0].Avg != 2.5 {
+				t.Errorf("unexpected points: %+v", body.Points)
+			}
+			if span := ctrl.got.To.Sub(ctrl.got.From); tt.name == "defaults" && (span != time.Hour || body.Step != "1m0s") {
+				t.Errorf("expected default 1h window and 1m step, got %v and %s", span, body.Step)
+			}
+		})
+	}
+}
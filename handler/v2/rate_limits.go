@@ -0,0 +1,99 @@
+package v2
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/rainbowmga/timetravel/authz"
+	"github.com/rainbowmga/timetravel/entity"
+	"github.com/rainbowmga/timetravel/observability"
+	"github.com/rainbowmga/timetravel/ratelimit"
+)
+
+type RateLimitController interface {
+	ListRateLimits(ctx context.Context) (map[ratelimit.Class]ratelimit.Limit, []entity.RateLimitOverride, error)
+	SetOverride(ctx context.Context, userID int64, class string, limit ratelimit.Limit) (entity.RateLimitOverride, error)
+	DeleteOverride(ctx context.Context, userID int64, class string) error
+}
+
+// RateLimitAdminAPI manages per-user rate limit overrides
+type RateLimitAdminAPI struct {
+	Limits RateLimitController
+	Authz  Authorizer
+}
+
+// NewRateLimitAdminAPI initializes the rate limit admin endpoints
+func NewRateLimitAdminAPI(c RateLimitController, a Authorizer) *RateLimitAdminAPI {
+	return &RateLimitAdminAPI{Limits: c, Authz: a}
+}
+
+// CreateRoutes registers the rate limit admin endpoints
+func (api *RateLimitAdminAPI) CreateRoutes(router *mux.Router) {
+	router.Handle("/admin/rate-limits", guard(api.Authz, authz.RateLimitsManage, api.ListRateLimits)).Methods("GET")
+	router.Handle("/admin/users/{user_id}/rate-limits/{class}", guard(api.Authz, authz.RateLimitsManage, api.SetOverride)).Methods("PUT")
+	router.Handle("/admin/users/{user_id}/rate-limits/{class}", guard(api.Authz, authz.RateLimitsManage, api.DeleteOverride)).Methods("DELETE")
+}
+
+// GET /api/v2/admin/rate-limits
+func (api *RateLimitAdminAPI) ListRateLimits(w http.ResponseWriter, r *http.Request) {
+	defaults, overrides, err := api.Limits.ListRateLimits(r.Context())
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{"defaults": defaults, "overrides": overrides})
+}
+
+// PUT /api/v2/admin/users/{user_id}/rate-limits/{class}
+// body: {"per_minute": 600, "burst": 100}; per_minute 0 lifts the limit
+func (api *RateLimitAdminAPI) SetOverride(w http.ResponseWriter, r *http.Request) {
+	userID, ok := rateLimitUserID(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		PerMinute *int `json:"per_minute"`
+		Burst     int  `json:"burst"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.PerMinute == nil {
+		respondError(w, r, errInvalidPayload)
+		return
+	}
+
+	class := mux.Vars(r)["class"]
+	o, err := api.Limits.SetOverride(r.Context(), userID, class, ratelimit.Limit{PerMinute: *body.PerMinute, Burst: body.Burst})
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	observability.DefaultLogger.InfoContext(r.Context(), "rate_limit_override_set",
+		"for_user_id", userID, "class", class, "per_minute", o.PerMinute, "burst", o.Burst)
+	respondJSON(w, http.StatusOK, o)
+}
+
+// DELETE /api/v2/admin/users/{user_id}/rate-limits/{class}
+func (api *RateLimitAdminAPI) DeleteOverride(w http.ResponseWriter, r *http.Request) {
+	userID, ok := rateLimitUserID(w, r)
+	if !ok {
+		return
+	}
+	class := mux.Vars(r)["class"]
+	if err := api.Limits.DeleteOverride(r.Context(), userID, class); err != nil {
+		respondError(w, r, err)
+		return
+	}
+	observability.DefaultLogger.InfoContext(r.Context(), "rate_limit_override_deleted", "for_user_id", userID, "class", class)
+	respondJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+func rateLimitUserID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	userID, err := strconv.ParseInt(mux.Vars(r)["user_id"], 10, 64)
+	if err != nil || userID <= 0 {
+		respondError(w, r, errInvalidUserID)
+		return 0, false
+	}
+	return userID, true
+}
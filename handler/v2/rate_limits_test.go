@@ -0,0 +1,105 @@
+package v2_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rainbowmga/timetravel/controller"
+	"github.com/rainbowmga/timetravel/entity"
+	"github.com/rainbowmga/timetravel/handler/v2"
+	"github.com/rainbowmga/timetravel/ratelimit"
+)
+
+type mockRateLimitController struct {
+	overrides map[string]ratelimit.Limit // user_id/class
+}
+
+func (m *mockRateLimitController) ListRateLimits(ctx context.Context) (map[ratelimit.Class]ratelimit.Limit, []entity.RateLimitOverride, error) {
+	out := []entity.RateLimitOverride{}
+	for key, l := range m.overrides {
+		out = append(out, entity.RateLimitOverride{UserID: 7, Class: strings.TrimPrefix(key, "7/"), PerMinute: l.PerMinute, Burst: l.Burst})
+	}
+	return map[ratelimit.Class]ratelimit.Limit{ratelimit.Read: {PerMinute: 60}}, out, nil
+}
+
+func (m *mockRateLimitController) SetOverride(ctx context.Context, userID int64, class string, limit ratelimit.Limit) (entity.RateLimitOverride, error) {
+	if _, err := ratelimit.ParseClass(class); err != nil {
+		return entity.RateLimitOverride{}, controller.ErrRateLimitClassInvalid
+	}
+	if limit.PerMinute < 0 {
+		return entity.RateLimitOverride{}, controller.ErrRateLimitInvalid
+	}
+	m.overrides["7/"+class] = limit
+	return entity.RateLimitOverride{UserID: userID, Class: class, PerMinute: limit.PerMinute, Burst: limit.Burst, UpdatedAt: time.Now()}, nil
+}
+
+func (m *mockRateLimitController) DeleteOverride(ctx context.Context, userID int64, class string) error {
+	if _, ok := m.overrides["7/"+class]; !ok || userID != 7 {
+		return controller.ErrRateLimitOverrideDoesNotExist
+	}
+	delete(m.overrides, "7/"+class)
+	return nil
+}
+
+func TestRateLimitAdmin(t *testing.T) {
+	tests := []struct {
+		name   string
+		roles  []string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"requires rate_limits:manage", []string{"underwriter"}, "GET", "/admin/rate-limits", "", http.StatusForbidden},
+		{"list", []string{"admin"}, "GET", "/admin/rate-limits", "", http.StatusOK},
+		{"set", []string{"admin"}, "PUT", "/admin/users/7/rate-limits/write", `{"per_minute":600,"burst":100}`, http.StatusOK},
+		{"set without per_minute", []string{"admin"}, "PUT", "/admin/users/7/rate-limits/write", `{"burst":100}`, http.StatusBadRequest},
+		{"set negative", []string{"admin"}, "PUT", "/admin/users/7/rate-limits/write", `{"per_minute":-1}`, http.StatusBadRequest},
+		{"set unknown class", []string{"admin"}, "PUT", "/admin/users/7/rate-limits/delete", `{"per_minute":1}`, http.StatusBadRequest},
+		{"invalid user", []string{"admin"}, "PUT", "/admin/users/x/rate-limits/write", `{"per_minute":1}`, http.StatusBadRequest},
+		{"delete", []string{"admin"}, "DELETE", "/admin/users/7/rate-limits/read", "", http.StatusOK},
+		{"delete missing", []string{"admin"}, "DELETE", "/admin/users/7/rate-limits/admin", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &mockRateLimitController{overrides: map[string]ratelimit.Limit{"7/read": {PerMinute: 10}}}
+			r := authenticatedAs(mux.NewRouter(), tt.roles...)
+			v2.NewRateLimitAdminAPI(c, testAuthorizer).CreateRoutes(r)
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRateLimitAdmin_List(t *testing.T) {
+	c := &mockRateLimitController{overrides: map[string]ratelimit.Limit{"7/write": {PerMinute: 5, Burst: 1}}}
+	r := authenticatedAs(mux.NewRouter(), "admin")
+	v2.NewRateLimitAdminAPI(c, testAuthorizer).CreateRoutes(r)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/admin/rate-limits", nil))
+
+	var body struct {
+		Defaults  map[string]ratelimit.Limit `json:"defaults"`
+		Overrides []entity.RateLimitOverride `json:"overrides"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Defaults["read"].PerMinute != 60 {
+		t.Errorf("defaults = %+v", body.Defaults)
+	}
+	if len(body.Overrides) != 1 || body.Overrides[0].Class != "write" || body.Overrides[0].PerMinute != 5 {
+		t.Errorf("overrides = %+v", body.Overrides)
+	}
+}
@@ -1,25 +1,43 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/rainbowmga/timetravel/app"
+	"github.com/rainbowmga/timetravel/cli"
 	"github.com/rainbowmga/timetravel/conf"
 	"github.com/rainbowmga/timetravel/observability"
 )
 
-// RunServer starts the HTTP server and returns an error instead of exiting
+// RunServer starts the HTTP server (and the gRPC server when grpc.addr is set)
+// and returns an error instead of exiting.
+// On SIGINT/SIGTERM /readyz starts failing, the server drains for server.drain_delay,
+// in-flight requests get server.shutdown_timeout to finish, and background workers are flushed.
 func RunServer(configPath string, envPort string) error {
 	cfg := conf.LoadConfig(configPath)
-	dbPath := cfg.Database.Path
+	if err := app.ConfigureLogging(cfg); err != nil {
+		return err
+	}
 
-	router, err := app.BuildRouter(dbPath, cfg.Database.Migrations.RunOnStartup)
+	shutdownTracing, err := app.ConfigureTracing(cfg)
 	if err != nil {
 		return err
 	}
+	defer shutdownTracing(context.Background())
+
+	a, err := app.BuildApp(cfg)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
 
 	port := envPort
 	if port == "" {
@@ -29,18 +47,101 @@ func RunServer(configPath string, envPort string) error {
 	address := ":" + port
 
 	srv := &http.Server{
-		Handler:      router,
+		Handler:      a.Router,
 		Addr:         address,
-		WriteTimeout: 15 * time.Second,
+		WriteTimeout: app.WriteTimeout(cfg), // outlasts every route budget, so slow routes get their 504
 		ReadTimeout:  15 * time.Second,
 	}
 
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 2)
+	go func() { serveErr <- srv.Serve(ln) }()
+
+	if cfg.GRPC.Addr != "" {
+		grpcLn, err := net.Listen("tcp", cfg.GRPC.Addr)
+		if err != nil {
+			return err
+		}
+		go func() { serveErr <- a.GRPC.Serve(grpcLn) }()
+		observability.DefaultLogger.Info("grpc server listening", "address", cfg.GRPC.Addr)
+	}
+
+	a.Health.MarkReady()
 	observability.DefaultLogger.Info("server listening", "address", address)
-	return srv.ListenAndServe()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	a.Health.MarkShuttingDown()
+	observability.DefaultLogger.Info("server shutting down", "drain_delay", cfg.Server.DrainDelay)
+	time.Sleep(cfg.Server.DrainDelay)
+
+	timeout := cfg.Server.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// both listeners drain under the same deadline
+	grpcStopped := make(chan struct{})
+	go func() {
+		a.StopGRPC(shutdownCtx)
+		close(grpcStopped)
+	}()
+	err = srv.Shutdown(shutdownCtx)
+	<-grpcStopped
+	return err
+}
+
+// defaultShutdownTimeout bounds how long in-flight requests may run after a shutdown signal
+const defaultShutdownTimeout = 15 * time.Second
+
+// RunMigrate runs `timetravel migrate up|down|status|to <version>|unlock`
+func RunMigrate(configPath string, args []string) error {
+	cfg := conf.LoadConfig(configPath)
+	if err := app.ConfigureLogging(cfg); err != nil {
+		return err
+	}
+	return app.RunMigrateCommand(context.Background(), cfg, args, os.Stdout)
+}
+
+// configPath is conf/config.yaml unless TIMETRAVEL_CONFIG names another file,
+// such as conf/config.dev.yaml for local runs
+func configPath() string {
+	if path := os.Getenv("TIMETRAVEL_CONFIG"); path != "" {
+		return path
+	}
+	return "conf/config.yaml"
 }
 
 func main() {
-	if err := RunServer("conf/config.yaml", os.Getenv("PORT")); err != nil {
+	if len(os.Args) > 1 && cli.IsCommand(os.Args[1]) {
+		if err := cli.Run(context.Background(), os.Args[1:], os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "timetravel %s: %v\n", os.Args[1], err)
+			os.Exit(cli.ExitCode(err))
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := RunMigrate(configPath(), os.Args[2:]); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		return
+	}
+
+	if err := RunServer(configPath(), os.Getenv("PORT")); err != nil {
 		log.Fatalf("failed to start server: %v", err)
 	}
 }
@@ -1,59 +1,33 @@
 package main
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/rainbowmga/timetravel/gateways"
 	"github.com/rainbowmga/timetravel/app"
+	"github.com/rainbowmga/timetravel/script"
 	"time"
 )
 
-// prepareTestDB creates minimal tables for v2 controllers
+// prepareTestDB migrates a shared in-memory DB with the embedded migrations
 func prepareTestDB() (string, func(), error) {
 	dbPath := "file:testdb?mode=memory&cache=shared"
 
-	db := gateways.ConnectDB(dbPath,"script/create_v2_tables.sql")
-	_ = gateways.RunMigrations(db, "../script/migrations")
-
-
-	_, _ = db.Exec(`
-	CREATE TABLE IF NOT EXISTS feature_flags (
-		flag_key TEXT PRIMARY KEY,
-		enabled BOOLEAN NOT NULL,
-		description TEXT,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		rollout_percentage INTEGER DEFAULT 100
-	);
-	CREATE TABLE IF NOT EXISTS policyholders (
-		policyholder_id INTEGER PRIMARY KEY AUTOINCREMENT,
-		name TEXT NOT NULL
-	);
-	CREATE TABLE IF NOT EXISTS policyholder_records (
-		record_id INTEGER PRIMARY KEY AUTOINCREMENT,
-		policyholder_id INTEGER NOT NULL,
-		data TEXT NOT NULL,
-		version INTEGER NOT NULL DEFAULT 1,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-	CREATE TABLE IF NOT EXISTS audit_history (
-		audit_id INTEGER PRIMARY KEY AUTOINCREMENT,
-		record_id INTEGER NOT NULL,
-		version INTEGER NOT NULL,
-		data TEXT NOT NULL,
-		event_type TEXT NOT NULL,
-		changed_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-	CREATE TABLE IF NOT EXISTS event_logs (
-		event_id INTEGER PRIMARY KEY AUTOINCREMENT,
-		record_id INTEGER,
-		action TEXT NOT NULL,
-		details TEXT,
-		timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-	`)
+	db := gateways.ConnectDB(dbPath)
+	m, err := gateways.NewMigrator(db, script.Migrations())
+	if err != nil {
+		db.Close()
+		return "", nil, err
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		db.Close()
+		return "", nil, err
+	}
 
 	cleanup := func() {
 		db.Close()
@@ -62,7 +36,6 @@ func prepareTestDB() (string, func(), error) {
 	return dbPath, cleanup, nil
 }
 
-
 func TestBuildRouter_V1HealthExists(t *testing.T) {
 	dbPath, cleanup, err := prepareTestDB()
 	if err != nil {
@@ -70,10 +43,12 @@ func TestBuildRouter_V1HealthExists(t *testing.T) {
 	}
 	defer cleanup()
 
-	router, err := app.BuildRouter(dbPath, false)
+	built, err := app.BuildRouter(dbPath, false)
 	if err != nil {
 		t.Fatalf("failed to build router: %v", err)
 	}
+	t.Cleanup(built.Close)
+	router := built.Router
 
 	req := httptest.NewRequest("POST", "/api/v1/health", nil)
 	resp := httptest.NewRecorder()
@@ -91,10 +66,12 @@ func TestMetricsEndpointExists(t *testing.T) {
 	}
 	defer cleanup()
 
-	router, err := app.BuildRouter(dbPath, false)
+	built, err := app.BuildRouter(dbPath, false)
 	if err != nil {
 		t.Fatalf("failed to build router: %v", err)
 	}
+	t.Cleanup(built.Close)
+	router := built.Router
 
 	req := httptest.NewRequest("GET", "/metrics", nil)
 	resp := httptest.NewRecorder()
@@ -112,10 +89,12 @@ func TestV2RequiresUserContext(t *testing.T) {
 	}
 	defer cleanup()
 
-	router, err := app.BuildRouter(dbPath, false)
+	built, err := app.BuildRouter(dbPath, false)
 	if err != nil {
 		t.Fatalf("failed to build router: %v", err)
 	}
+	t.Cleanup(built.Close)
+	router := built.Router
 
 	t.Log("=== v2 Health Check: missing X-User-ID ===")
 	// Missing header → expect 401 Unauthorized
@@ -149,9 +128,17 @@ func TestV2RequiresUserContext(t *testing.T) {
 
 // TestRunServer initializes server with test config and shuts it down
 func TestRunServer(t *testing.T) {
+	// a scratch database, so the run leaves the checked-in db/ untouched
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	config := "database:\n  path: " + filepath.Join(dir, "timetravel.db") + "\n  migrations:\n    run_on_startup: true\n"
+	if err := os.WriteFile(configPath, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
 	// Run server in goroutine to avoid blocking
 	go func() {
-		err := RunServer("conf/config.yaml", "")
+		err := RunServer(configPath, "")
 		if err != nil && err != http.ErrServerClosed {
 			t.Errorf("server failed to start: %v", err)
 		}
@@ -163,10 +150,12 @@ func TestRunServer(t *testing.T) {
 	}
 	defer cleanup()
 
-	router, err := app.BuildRouter(dbPath, false)
+	built, err := app.BuildRouter(dbPath, false)
 	if err != nil {
 		t.Fatalf("failed to build router: %v", err)
 	}
+	t.Cleanup(built.Close)
+	router := built.Router
 
 	// Wait a short moment for server to start
 	time.Sleep(200 * time.Millisecond)
@@ -0,0 +1,174 @@
+package observability
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HealthState is the server lifecycle phase reported by /readyz
+type HealthState int32
+
+const (
+	HealthStarting HealthState = iota
+	HealthReady
+	HealthShuttingDown
+)
+
+func (s HealthState) String() string {
+	switch s {
+	case HealthReady:
+		return "ready"
+	case HealthShuttingDown:
+		return "shutting_down"
+	default:
+		return "starting"
+	}
+}
+
+// defaultCheckTimeout bounds each dependency check
+const defaultCheckTimeout = 2 * time.Second
+
+// HealthCheck returns nil when the dependency is healthy
+type HealthCheck func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check HealthCheck
+}
+
+// HealthChecker serves /livez and /readyz. Liveness checks run on both endpoints;
+// readiness checks only on /readyz, which also returns 503 until MarkReady and
+// again after MarkShuttingDown.
+type HealthChecker struct {
+	mu        sync.RWMutex
+	liveness  []namedCheck
+	readiness []namedCheck
+	state     atomic.Int32
+	timeout   time.Duration
+}
+
+// NewHealthChecker starts in the starting state
+func NewHealthChecker() *HealthChecker {
+	return &HealthChecker{timeout: defaultCheckTimeout}
+}
+
+// AddLivenessCheck registers a check whose failure means the process should be restarted
+func (h *HealthChecker) AddLivenessCheck(name string, check HealthCheck) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.liveness = append(h.liveness, namedCheck{name, check})
+}
+
+// AddReadinessCheck registers a check whose failure means traffic should go elsewhere
+func (h *HealthChecker) AddReadinessCheck(name string, check HealthCheck) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.readiness = append(h.readiness, namedCheck{name, check})
+}
+
+// MarkReady lets /readyz pass once startup has finished
+func (h *HealthChecker) MarkReady() {
+	h.state.CompareAndSwap(int32(HealthStarting), int32(HealthReady))
+}
+
+// MarkShuttingDown makes /readyz fail so load balancers drain the instance
+func (h *HealthChecker) MarkShuttingDown() {
+	h.state.Store(int32(HealthShuttingDown))
+}
+
+// State returns the current lifecycle phase
+func (h *HealthChecker) State() HealthState {
+	return HealthState(h.state.Load())
+}
+
+// CheckResult is one entry of the health report
+type CheckResult struct {
+	Status     string `json:"status"` // ok | error
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// HealthReport is the /livez and /readyz response body
+type HealthReport struct {
+	Status string                 `json:"status"` // ok | unavailable
+	State  string                 `json:"state,omitempty"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// LivezHandler reports liveness checks only; lifecycle state does not affect it
+func (h *HealthChecker) LivezHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.mu.RLock()
+		checks := append([]namedCheck(nil), h.liveness...)
+		h.mu.RUnlock()
+
+		report := h.run(r.Context(), checks)
+		writeHealthReport(w, report)
+	})
+}
+
+// ReadyzHandler reports every check plus the lifecycle state
+func (h *HealthChecker) ReadyzHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.mu.RLock()
+		checks := append(append([]namedCheck(nil), h.liveness...), h.readiness...)
+		h.mu.RUnlock()
+
+		report := h.run(r.Context(), checks)
+		state := h.State()
+		report.State = state.String()
+		if state != HealthReady {
+			report.Status = "unavailable"
+		}
+		writeHealthReport(w, report)
+	})
+}
+
+// run executes checks concurrently, each under its own timeout
+func (h *HealthChecker) run(ctx context.Context, checks []namedCheck) HealthReport {
+	report := HealthReport{Status: "ok", Checks: make(map[string]CheckResult, len(checks))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, c := range checks {
+		wg.Add(1)
+		go func(c namedCheck) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, h.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := c.check(checkCtx)
+			result := CheckResult{Status: "ok", DurationMS: time.Since(start).Milliseconds()}
+			if err != nil {
+				result.Status = "error"
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			report.Checks[c.name] = result
+			if err != nil {
+				report.Status = "unavailable"
+			}
+			mu.Unlock()
+		}(c)
+	}
+	wg.Wait()
+	return report
+}
+
+func writeHealthReport(w http.ResponseWriter, report HealthReport) {
+	status := http.StatusOK
+	if report.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(report)
+}
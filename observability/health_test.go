@@ -0,0 +1,91 @@
+package observability
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func serveHealth(t *testing.T, h http.Handler) (int, HealthReport) {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+
+	var report HealthReport
+	if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+		t.Fatalf("invalid health report %q: %v", rr.Body.String(), err)
+	}
+	return rr.Code, report
+}
+
+func TestHealthChecker_Lifecycle(t *testing.T) {
+	h := NewHealthChecker()
+	h.AddLivenessCheck("worker", func(context.Context) error { return nil })
+	h.AddReadinessCheck("db", func(context.Context) error { return nil })
+
+	tests := []struct {
+		name       string
+		transition func()
+		wantReady  int
+		wantState  string
+	}{
+		{"starting", func() {}, http.StatusServiceUnavailable, "starting"},
+		{"ready", h.MarkReady, http.StatusOK, "ready"},
+		{"shutting down", h.MarkShuttingDown, http.StatusServiceUnavailable, "shutting_down"},
+		{"ready after shutdown is ignored", h.MarkReady, http.StatusServiceUnavailable, "shutting_down"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.transition()
+
+			code, report := serveHealth(t, h.ReadyzHandler())
+			if code != tt.wantReady || report.State != tt.wantState {
+				t.Errorf("readyz = %d (%s), want %d (%s)", code, report.State, tt.wantReady, tt.wantState)
+			}
+			if len(report.Checks) != 2 {
+				t.Errorf("expected both checks in readyz report, got %v", report.Checks)
+			}
+
+			// liveness ignores the lifecycle state
+			code, report = serveHealth(t, h.LivezHandler())
+			if code != http.StatusOK || len(report.Checks) != 1 {
+				t.Errorf("livez = %d with %v, want 200 with one check", code, report.Checks)
+			}
+		})
+	}
+}
+
+func TestHealthChecker_FailingChecks(t *testing.T) {
+	h := NewHealthChecker()
+	h.timeout = 20 * time.Millisecond
+	h.MarkReady()
+	h.AddLivenessCheck("worker", func(context.Context) error { return nil })
+	h.AddReadinessCheck("db", func(context.Context) error { return errors.New("disk I/O error") })
+	h.AddReadinessCheck("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	code, report := serveHealth(t, h.ReadyzHandler())
+	if code != http.StatusServiceUnavailable || report.Status != "unavailable" {
+		t.Fatalf("expected 503 unavailable, got %d %s", code, report.Status)
+	}
+	if c := report.Checks["db"]; c.Status != "error" || c.Error != "disk I/O error" {
+		t.Errorf("unexpected db check: %+v", c)
+	}
+	if c := report.Checks["slow"]; c.Status != "error" {
+		t.Errorf("expected slow check to time out, got %+v", c)
+	}
+	if c := report.Checks["worker"]; c.Status != "ok" {
+		t.Errorf("unexpected worker check: %+v", c)
+	}
+
+	if code, _ := serveHealth(t, h.LivezHandler()); code != http.StatusOK {
+		t.Errorf("readiness failures must not fail livez, got %d", code)
+	}
+}
@@ -1,10 +1,17 @@
 package observability
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
 	"os"
+	"strings"
 	"sync"
+	"time"
+
+	"github.com/rainbowmga/timetravel/common"
 )
 
 // LogLevel represents logging severity
@@ -17,63 +24,202 @@ const (
 	LogError
 )
 
-// LoggerInterface defines methods for logging
+// LogFormat selects the output encoding
+type LogFormat string
+
+const (
+	LogFormatText LogFormat = "text" // legacy "LEVEL msg key=value" lines
+	LogFormatJSON LogFormat = "json" // one JSON object per line via log/slog
+)
+
+// LoggerInterface defines methods for logging.
+// The *Context variants add request_id, user_id and route from the context.
 type LoggerInterface interface {
 	Debug(msg string, keysAndValues ...interface{})
 	Info(msg string, keysAndValues ...interface{})
 	Warn(msg string, keysAndValues ...interface{})
 	Error(msg string, keysAndValues ...interface{})
+
+	DebugContext(ctx context.Context, msg string, keysAndValues ...interface{})
+	InfoContext(ctx context.Context, msg string, keysAndValues ...interface{})
+	WarnContext(ctx context.Context, msg string, keysAndValues ...interface{})
+	ErrorContext(ctx context.Context, msg string, keysAndValues ...interface{})
+}
+
+// LoggerOptions configures NewLoggerWithOptions
+type LoggerOptions struct {
+	Level  LogLevel
+	Format LogFormat
+	Output io.Writer // defaults to stdout
+
+	// Sampling applies to debug/info only. Within each SampleWindow the first
+	// SampleInitial entries per message are logged, then every SampleThereafter-th.
+	// SampleInitial == 0 disables sampling.
+	SampleInitial    int
+	SampleThereafter int
+	SampleWindow     time.Duration // defaults to 1s
 }
 
-// Logger provides structured key=value logging for observability
+// Logger provides structured logging for observability
 type Logger struct {
-	level  LogLevel
-	logger *log.Logger
-	mu     sync.Mutex
+	level   LogLevel
+	logger  *log.Logger  // text format
+	json    *slog.Logger // JSON format; nil in text mode
+	sampler *sampler
+	mu      sync.Mutex
 }
 
 // Ensure Logger implements LoggerInterface
 var _ LoggerInterface = (*Logger)(nil)
 
-// NewLogger creates a logger with optional level (default: Info)
+// NewLogger creates a text logger on stdout with optional level (default: Info)
 func NewLogger(level LogLevel) *Logger {
+	return NewLoggerWithOptions(LoggerOptions{Level: level})
+}
+
+// NewLoggerWithOptions creates a logger with the given format, output and sampling
+func NewLoggerWithOptions(opts LoggerOptions) *Logger {
+	level := opts.Level
 	if level < LogDebug || level > LogError {
 		level = LogInfo
 	}
-	return &Logger{
-		level:  level,
-		logger: log.New(os.Stdout, "", log.LstdFlags),
+	out := opts.Output
+	if out == nil {
+		out = os.Stdout
+	}
+
+	l := &Logger{level: level}
+	if opts.Format == LogFormatJSON {
+		// level filtering and sampling happen before the handler
+		l.json = slog.New(slog.NewJSONHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	} else {
+		l.logger = log.New(out, "", log.LstdFlags)
+	}
+
+	if opts.SampleInitial > 0 {
+		window := opts.SampleWindow
+		if window <= 0 {
+			window = time.Second
+		}
+		l.sampler = newSampler(opts.SampleInitial, opts.SampleThereafter, window)
 	}
+	return l
+}
+
+// ParseLogLevel maps a config string to a LogLevel; empty means info
+func ParseLogLevel(s string) (LogLevel, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return LogDebug, nil
+	case "", "info":
+		return LogInfo, nil
+	case "warn", "warning":
+		return LogWarn, nil
+	case "error":
+		return LogError, nil
+	}
+	return LogInfo, fmt.Errorf("unknown log level %q", s)
 }
 
 // DefaultLogger is the package-level logger used by middleware and handlers
 var DefaultLogger LoggerInterface = NewLogger(LogInfo)
 
+var slogLevels = map[LogLevel]slog.Level{
+	LogDebug: slog.LevelDebug,
+	LogInfo:  slog.LevelInfo,
+	LogWarn:  slog.LevelWarn,
+	LogError: slog.LevelError,
+}
+
 // internal log helper
-func (l *Logger) log(level LogLevel, levelStr, msg string, keysAndValues ...interface{}) {
+func (l *Logger) log(ctx context.Context, level LogLevel, levelStr, msg string, keysAndValues ...interface{}) {
 	if level < l.level {
 		return
 	}
+	if level <= LogInfo && l.sampler != nil && !l.sampler.allow(msg, time.Now()) {
+		return
+	}
+
+	fields := append(contextFields(ctx), keysAndValues...)
+
+	if l.json != nil {
+		attrs := make([]slog.Attr, 0, len(fields)/2)
+		for i := 0; i+1 < len(fields); i += 2 {
+			attrs = append(attrs, slog.Any(stringify(fields[i]), jsonValue(fields[i+1])))
+		}
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		l.json.LogAttrs(ctx, slogLevels[level], msg, attrs...)
+		return
+	}
+
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	buf := levelStr + " " + msg
-	for i := 0; i+1 < len(keysAndValues); i += 2 {
-		buf += " " + stringify(keysAndValues[i]) + "=" + stringify(keysAndValues[i+1])
+	for i := 0; i+1 < len(fields); i += 2 {
+		buf += " " + stringify(fields[i]) + "=" + stringify(fields[i+1])
 	}
 	l.logger.Output(3, buf)
 }
 
+// contextFields extracts correlation fields carried by the request context
+func contextFields(ctx context.Context) []interface{} {
+	if ctx == nil {
+		return nil
+	}
+	var fields []interface{}
+	if id, ok := common.GetRequestID(ctx); ok {
+		fields = append(fields, "request_id", id)
+	}
+	if id, ok := common.GetUserID(ctx); ok {
+		fields = append(fields, "user_id", id)
+	}
+	if route, ok := common.GetRoute(ctx); ok {
+		fields = append(fields, "route", route)
+	}
+	return fields
+}
+
 // Debug logs at debug level
-func (l *Logger) Debug(msg string, keysAndValues ...interface{}) { l.log(LogDebug, "DEBUG", msg, keysAndValues...) }
+func (l *Logger) Debug(msg string, keysAndValues ...interface{}) {
+	l.log(context.Background(), LogDebug, "DEBUG", msg, keysAndValues...)
+}
 
 // Info logs at info level
-func (l *Logger) Info(msg string, keysAndValues ...interface{}) { l.log(LogInfo, "INFO", msg, keysAndValues...) }
+func (l *Logger) Info(msg string, keysAndValues ...interface{}) {
+	l.log(context.Background(), LogInfo, "INFO", msg, keysAndValues...)
+}
 
 // Warn logs at warn level
-func (l *Logger) Warn(msg string, keysAndValues ...interface{}) { l.log(LogWarn, "WARN", msg, keysAndValues...) }
+func (l *Logger) Warn(msg string, keysAndValues ...interface{}) {
+	l.log(context.Background(), LogWarn, "WARN", msg, keysAndValues...)
+}
 
 // Error logs at error level
-func (l *Logger) Error(msg string, keysAndValues ...interface{}) { l.log(LogError, "ERROR", msg, keysAndValues...) }
+func (l *Logger) Error(msg string, keysAndValues ...interface{}) {
+	l.log(context.Background(), LogError, "ERROR", msg, keysAndValues...)
+}
+
+// DebugContext logs at debug level with request fields from ctx
+func (l *Logger) DebugContext(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.log(ctx, LogDebug, "DEBUG", msg, keysAndValues...)
+}
+
+// InfoContext logs at info level with request fields from ctx
+func (l *Logger) InfoContext(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.log(ctx, LogInfo, "INFO", msg, keysAndValues...)
+}
+
+// WarnContext logs at warn level with request fields from ctx
+func (l *Logger) WarnContext(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.log(ctx, LogWarn, "WARN", msg, keysAndValues...)
+}
+
+// ErrorContext logs at error level with request fields from ctx
+func (l *Logger) ErrorContext(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.log(ctx, LogError, "ERROR", msg, keysAndValues...)
+}
 
 // helper to stringify any value
 func stringify(v interface{}) string {
@@ -86,3 +232,59 @@ func stringify(v interface{}) string {
 		return fmt.Sprint(v)
 	}
 }
+
+// jsonValue keeps native JSON types but renders errors as their message
+func jsonValue(v interface{}) interface{} {
+	if err, ok := v.(error); ok {
+		return err.Error()
+	}
+	return v
+}
+
+// -----------------------------
+// Sampling
+// -----------------------------
+
+// sampler rate-limits repeated messages per time window
+type sampler struct {
+	initial    int
+	thereafter int
+	window     time.Duration
+
+	mu     sync.Mutex
+	counts map[string]*sampleCounter
+}
+
+type sampleCounter struct {
+	resetAt time.Time
+	n       int
+}
+
+func newSampler(initial, thereafter int, window time.Duration) *sampler {
+	return &sampler{
+		initial:    initial,
+		thereafter: thereafter,
+		window:     window,
+		counts:     make(map[string]*sampleCounter),
+	}
+}
+
+func (s *sampler) allow(msg string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.counts[msg]
+	if c == nil || !now.Before(c.resetAt) {
+		c = &sampleCounter{resetAt: now.Add(s.window)}
+		s.counts[msg] = c
+	}
+	c.n++
+
+	if c.n <= s.initial {
+		return true
+	}
+	if s.thereafter <= 0 {
+		return false
+	}
+	return (c.n-s.initial)%s.thereafter == 0
+}
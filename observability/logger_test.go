@@ -2,11 +2,16 @@ package observability
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"log"
 	"strings"
 	"sync"
 	"testing"
+	"time"
+
+	"github.com/rainbowmga/timetravel/common"
 )
 
 func newTestLogger(level LogLevel) (*Logger, *bytes.Buffer) {
@@ -110,3 +115,120 @@ func TestConcurrentLogging(t *testing.T) {
 		t.Errorf("expected multiple log lines from concurrent logging")
 	}
 }
+
+func requestContext() context.Context {
+	ctx := context.WithValue(context.Background(), common.RequestIDKey, "req-123")
+	ctx = context.WithValue(ctx, common.UserIDKey, int64(42))
+	return context.WithValue(ctx, common.RouteKey, "/records/{id}")
+}
+
+func TestJSONLogging_IncludesContextFields(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewLoggerWithOptions(LoggerOptions{Level: LogDebug, Format: LogFormatJSON, Output: buf})
+
+	l.InfoContext(requestContext(), "record_fetched", "version", 3, "error", errors.New("boom"))
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("expected a JSON log line, got %q: %v", buf.String(), err)
+	}
+
+	want := map[string]interface{}{
+		"level":      "INFO",
+		"msg":        "record_fetched",
+		"request_id": "req-123",
+		"user_id":    float64(42),
+		"route":      "/records/{id}",
+		"version":    float64(3),
+		"error":      "boom",
+	}
+	for k, v := range want {
+		if entry[k] != v {
+			t.Errorf("field %s = %v, want %v", k, entry[k], v)
+		}
+	}
+}
+
+func TestTextLogging_IncludesContextFields(t *testing.T) {
+	l, buf := newTestLogger(LogInfo)
+
+	l.WarnContext(requestContext(), "slow_query", "ms", 120)
+
+	out := buf.String()
+	for _, want := range []string{"WARN slow_query", "request_id=req-123", "user_id=42", "route=/records/{id}", "ms=120"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in %q", want, out)
+		}
+	}
+}
+
+func TestSampling_InfoOnly(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewLoggerWithOptions(LoggerOptions{
+		Level:            LogInfo,
+		Format:           LogFormatJSON,
+		Output:           buf,
+		SampleInitial:    2,
+		SampleThereafter: 5,
+		SampleWindow:     time.Hour,
+	})
+
+	for i := 0; i < 12; i++ {
+		l.Info("request")
+		l.Error("failure")
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var infos, errs int
+	for _, line := range lines {
+		switch {
+		case strings.Contains(line, `"msg":"request"`):
+			infos++
+		case strings.Contains(line, `"msg":"failure"`):
+			errs++
+		}
+	}
+
+	// first 2, then the 7th and 12th
+	if infos != 4 {
+		t.Errorf("expected 4 sampled info lines, got %d", infos)
+	}
+	if errs != 12 {
+		t.Errorf("expected errors never sampled, got %d", errs)
+	}
+}
+
+func TestSampler_WindowResets(t *testing.T) {
+	s := newSampler(1, 0, time.Second)
+	now := time.Now()
+
+	if !s.allow("m", now) {
+		t.Fatal("expected first entry allowed")
+	}
+	if s.allow("m", now.Add(100*time.Millisecond)) {
+		t.Fatal("expected second entry in window dropped")
+	}
+	if !s.allow("m", now.Add(2*time.Second)) {
+		t.Fatal("expected entry allowed after window reset")
+	}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    LogLevel
+		wantErr bool
+	}{
+		{"", LogInfo, false},
+		{"debug", LogDebug, false},
+		{"WARN", LogWarn, false},
+		{"error", LogError, false},
+		{"loud", LogInfo, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLogLevel(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseLogLevel(%q) = %v, %v", tt.in, got, err)
+		}
+	}
+}
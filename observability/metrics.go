@@ -18,16 +18,37 @@ type MetricsRepo interface {
 	// optional for DB collectors: SumMetric(metricName string) (float64, error)
 }
 
+// -----------------------------
+// FlagEvaluator Interface
+// -----------------------------
+// FlagEvaluator lets runtime flags switch metric persistence on and off.
+// defaultValue applies when the flag is not defined.
+type FlagEvaluator interface {
+	IsEnabledWithDefault(flagKey string, userID int64, defaultValue bool) bool
+}
+
+// FlagEnableMetrics gates writes to the observability_metrics table
+const FlagEnableMetrics = "enable_metrics"
+
+// DefaultMetricsRegion tags persisted samples unless InitMetricsRegion overrides it
+const DefaultMetricsRegion = "us-east-1"
+
 // -----------------------------
 // Globals
 // -----------------------------
 var (
-	metricsRepo  MetricsRepo
-	registerOnce sync.Once
+	metricsRepo   MetricsRepo
+	metricsFlags  FlagEvaluator
+	metricsRegion = DefaultMetricsRegion
+	registerOnce  sync.Once
 
 	HTTPRequestTotal           *prometheus.CounterVec
 	HTTPRequestDurationSeconds *prometheus.HistogramVec
 	FlagEvaluations            *prometheus.CounterVec
+	AuditWritesSkipped         prometheus.Counter
+	DualWrites                 *prometheus.CounterVec
+	ShadowReads                *prometheus.CounterVec
+	MetricSamplesDroppedTotal  prometheus.Counter
 )
 
 // -----------------------------
@@ -62,10 +83,60 @@ func InitMetricsRepository(repo MetricsRepo) {
 			[]string{"flag", "enabled"},
 		)
 
-		prometheus.MustRegister(HTTPRequestTotal, HTTPRequestDurationSeconds, FlagEvaluations)
+		AuditWritesSkipped = prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "audit_writes_skipped_total",
+				Help: "Record writes committed without audit_history/event_logs because enable_audit_logging is off",
+			},
+		)
+
+		DualWrites = prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "v1_dual_writes_total",
+				Help: "v1 writes mirrored to the v2 store, by result",
+			},
+			[]string{"result"},
+		)
+
+		ShadowReads = prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "v1_shadow_reads_total",
+				Help: "v1 reads compared against the v2 store, by result (match, mismatch, error)",
+			},
+			[]string{"result"},
+		)
+
+		MetricSamplesDroppedTotal = prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "metric_samples_dropped_total",
+				Help: "Samples not persisted to observability_metrics (buffer full or flush failed)",
+			},
+		)
+
+		prometheus.MustRegister(HTTPRequestTotal, HTTPRequestDurationSeconds, FlagEvaluations, AuditWritesSkipped, DualWrites, ShadowReads, MetricSamplesDroppedTotal)
 	})
 }
 
+// -----------------------------
+// InitMetricsFlags
+// -----------------------------
+// InitMetricsFlags makes RecordRequest consult enable_metrics before persisting to SQLite.
+// Prometheus counters are in-process and always recorded.
+func InitMetricsFlags(flags FlagEvaluator) {
+	metricsFlags = flags
+}
+
+// InitMetricsRegion sets the region stored with persisted samples; empty keeps the current one
+func InitMetricsRegion(region string) {
+	if region != "" {
+		metricsRegion = region
+	}
+}
+
+func persistMetricsEnabled() bool {
+	return metricsFlags == nil || metricsFlags.IsEnabledWithDefault(FlagEnableMetrics, 0, true)
+}
+
 // -----------------------------
 // MetricsHandler
 // -----------------------------
@@ -84,9 +155,9 @@ func RecordRequest(method, path string, status int, duration time.Duration) {
 		HTTPRequestDurationSeconds.WithLabelValues(method, path).Observe(duration.Seconds())
 	}
 
-	if metricsRepo != nil {
-		_ = metricsRepo.InsertMetric("platform", "http_requests_total", 1, "us-east-1")
-		_ = metricsRepo.InsertMetric("platform", "http_request_duration_seconds", duration.Seconds(), "us-east-1")
+	if metricsRepo != nil && persistMetricsEnabled() {
+		_ = metricsRepo.InsertMetric("platform", "http_requests_total", 1, metricsRegion)
+		_ = metricsRepo.InsertMetric("platform", "http_request_duration_seconds", duration.Seconds(), metricsRegion)
 	}
 }
 
@@ -103,3 +174,40 @@ func FlagEvaluated(flag string, enabled bool) {
 		FlagEvaluations.WithLabelValues(flag, state).Inc()
 	}
 }
+
+// -----------------------------
+// AuditWriteSkipped
+// -----------------------------
+func AuditWriteSkipped() {
+	if AuditWritesSkipped != nil {
+		AuditWritesSkipped.Inc()
+	}
+}
+
+// -----------------------------
+// DualWriteResult / ShadowReadResult
+// -----------------------------
+func DualWriteResult(result string) {
+	if DualWrites != nil {
+		DualWrites.WithLabelValues(result).Inc()
+	}
+}
+
+func ShadowReadResult(result string) {
+	if ShadowReads != nil {
+		ShadowReads.WithLabelValues(result).Inc()
+	}
+}
+
+// -----------------------------
+// MetricSampleDropped
+// -----------------------------
+func MetricSampleDropped() {
+	MetricSamplesDropped(1)
+}
+
+func MetricSamplesDropped(n int) {
+	if MetricSamplesDroppedTotal != nil {
+		MetricSamplesDroppedTotal.Add(float64(n))
+	}
+}
@@ -121,3 +121,51 @@ func TestFlagEvaluated(t *testing.T) {
 		t.Errorf("expected flag evaluation increment, got before=%f after=%f", before, after)
 	}
 }
+
+type mockFlagEvaluator struct {
+	flags map[string]bool
+}
+
+func (m *mockFlagEvaluator) IsEnabledWithDefault(flagKey string, userID int64, defaultValue bool) bool {
+	v, ok := m.flags[flagKey]
+	if !ok {
+		return defaultValue
+	}
+	return v
+}
+
+func TestRecordRequest_EnableMetricsFlag(t *testing.T) {
+	tests := []struct {
+		name      string
+		flags     FlagEvaluator
+		wantCalls int
+	}{
+		{"no evaluator persists", nil, 2},
+		{"flag on persists", &mockFlagEvaluator{flags: map[string]bool{FlagEnableMetrics: true}}, 2},
+		{"flag off skips DB", &mockFlagEvaluator{flags: map[string]bool{FlagEnableMetrics: false}}, 0},
+		{"flag undefined persists", &mockFlagEvaluator{}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetMetricsForTest()
+
+			mock := &mockMetricsRepo{}
+			oldRepo, oldFlags := metricsRepo, metricsFlags
+			metricsRepo = mock
+			InitMetricsFlags(tt.flags)
+			defer func() { metricsRepo, metricsFlags = oldRepo, oldFlags }()
+
+			before := testutil.ToFloat64(HTTPRequestTotal.WithLabelValues("GET", "/flagged", "200"))
+			RecordRequest("GET", "/flagged", 200, time.Millisecond)
+
+			if mock.calls != tt.wantCalls {
+				t.Errorf("expected %d DB metric calls, got %d", tt.wantCalls, mock.calls)
+			}
+			// prometheus is always recorded
+			if after := testutil.ToFloat64(HTTPRequestTotal.WithLabelValues("GET", "/flagged", "200")); after != before+1 {
+				t.Errorf("expected prometheus counter increment")
+			}
+		})
+	}
+}
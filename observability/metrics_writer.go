@@ -0,0 +1,149 @@
+package observability
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/rainbowmga/timetravel/entity"
+)
+
+var (
+	// ErrMetricsBufferFull is returned when a sample is dropped to keep the request path non-blocking
+	ErrMetricsBufferFull = errors.New("metrics buffer full")
+	// ErrMetricsWriterClosed is returned for samples sent after Close
+	ErrMetricsWriterClosed = errors.New("metrics writer closed")
+)
+
+// MetricsBatchRepo persists samples in bulk (gateways.MetricsRepository)
+type MetricsBatchRepo interface {
+	InsertMetrics(batch []entity.ObservabilityMetric) error
+}
+
+// MetricsWriterOptions tunes the asynchronous writer; zero values use the defaults
+type MetricsWriterOptions struct {
+	BatchSize     int           // flush once this many samples are pending (default 100)
+	BufferSize    int           // samples queued before new ones are dropped (default 1000)
+	FlushInterval time.Duration // flush pending samples at least this often (default 1s)
+}
+
+// MetricsWriter implements MetricsRepo by queueing samples and writing them to
+// SQLite in batches from a background goroutine.
+type MetricsWriter struct {
+	repo          MetricsBatchRepo
+	batchSize     int
+	flushInterval time.Duration
+	samples       chan entity.ObservabilityMetric
+	done          chan struct{}
+	mu            sync.RWMutex // guards closed against sends racing Close
+	closed        bool
+	now           func() time.Time
+}
+
+var _ MetricsRepo = (*MetricsWriter)(nil)
+
+// NewMetricsWriter starts the background writer; call Close to flush and stop it
+func NewMetricsWriter(repo MetricsBatchRepo, opts MetricsWriterOptions) *MetricsWriter {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = 1000
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = time.Second
+	}
+
+	w := &MetricsWriter{
+		repo:          repo,
+		batchSize:     opts.BatchSize,
+		flushInterval: opts.FlushInterval,
+		samples:       make(chan entity.ObservabilityMetric, opts.BufferSize),
+		done:          make(chan struct{}),
+		now:           time.Now,
+	}
+	go w.run()
+	return w
+}
+
+// InsertMetric queues a sample; it never blocks and drops the sample when the buffer is full
+func (w *MetricsWriter) InsertMetric(metricType, metricName string, value float64, region string) error {
+	sample := entity.ObservabilityMetric{
+		MetricType: metricType,
+		MetricName: metricName,
+		Value:      value,
+		Region:     region,
+		RecordedAt: w.now().UTC(),
+	}
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return ErrMetricsWriterClosed
+	}
+
+	select {
+	case w.samples <- sample:
+		return nil
+	default:
+		MetricSampleDropped()
+		return ErrMetricsBufferFull
+	}
+}
+
+// Close flushes queued samples and stops the writer; it is safe to call more than once
+func (w *MetricsWriter) Close() {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.samples)
+	}
+	w.mu.Unlock()
+	<-w.done
+}
+
+// Alive fails once the background goroutine has exited
+func (w *MetricsWriter) Alive() error {
+	select {
+	case <-w.done:
+		return errors.New("metrics writer stopped")
+	default:
+		return nil
+	}
+}
+
+func (w *MetricsWriter) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]entity.ObservabilityMetric, 0, w.batchSize)
+	for {
+		select {
+		case sample, ok := <-w.samples:
+			if !ok {
+				w.flush(batch)
+				return
+			}
+			batch = append(batch, sample)
+			if len(batch) >= w.batchSize {
+				batch = w.flush(batch)
+			}
+		case <-ticker.C:
+			batch = w.flush(batch)
+		}
+	}
+}
+
+// flush writes the batch and returns it emptied; failed batches are dropped, not retried
+func (w *MetricsWriter) flush(batch []entity.ObservabilityMetric) []entity.ObservabilityMetric {
+	if len(batch) == 0 {
+		return batch
+	}
+	if err := w.repo.InsertMetrics(batch); err != nil {
+		DefaultLogger.Warn("metrics_flush_failed", "samples", len(batch), "error", err)
+		MetricSamplesDropped(len(batch))
+	}
+	return batch[:0]
+}
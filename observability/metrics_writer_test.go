@@ -0,0 +1,127 @@
+package observability
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rainbowmga/timetravel/entity"
+)
+
+type batchRecorder struct {
+	mu      sync.Mutex
+	batches [][]entity.ObservabilityMetric
+	block   chan struct{}
+	err     error
+}
+
+func (b *batchRecorder) InsertMetrics(batch []entity.ObservabilityMetric) error {
+	if b.block != nil {
+		<-b.block
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.batches = append(b.batches, append([]entity.ObservabilityMetric(nil), batch...))
+	return b.err
+}
+
+func (b *batchRecorder) sizes() []int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var sizes []int
+	for _, batch := range b.batches {
+		sizes = append(sizes, len(batch))
+	}
+	return sizes
+}
+
+func TestMetricsWriter_FlushesBySizeAndOnClose(t *testing.T) {
+	repo := &batchRecorder{}
+	w := NewMetricsWriter(repo, MetricsWriterOptions{BatchSize: 2, BufferSize: 10, FlushInterval: time.Hour})
+
+	for i := 0; i < 5; i++ {
+		if err := w.InsertMetric("platform", "http_requests_total", 1, "eu-west-1"); err != nil {
+			t.Fatalf("InsertMetric() error: %v", err)
+		}
+	}
+	w.Close()
+
+	got := repo.sizes()
+	if len(got) != 3 || got[0] != 2 || got[1] != 2 || got[2] != 1 {
+		t.Fatalf("expected batches of 2,2,1, got %v", got)
+	}
+	if s := repo.batches[0][0]; s.Region != "eu-west-1" || s.RecordedAt.IsZero() {
+		t.Errorf("unexpected sample: %+v", s)
+	}
+	if err := w.InsertMetric("platform", "late", 1, ""); !errors.Is(err, ErrMetricsWriterClosed) {
+		t.Errorf("expected ErrMetricsWriterClosed after Close, got %v", err)
+	}
+	w.Close() // idempotent
+}
+
+func TestMetricsWriter_FlushesOnInterval(t *testing.T) {
+	repo := &batchRecorder{}
+	w := NewMetricsWriter(repo, MetricsWriterOptions{BatchSize: 100, FlushInterval: 10 * time.Millisecond})
+	defer w.Close()
+
+	_ = w.InsertMetric("platform", "http_requests_total", 1, "")
+
+	deadline := time.Now().Add(time.Second)
+	for len(repo.sizes()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected pending sample to be flushed by the interval")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestMetricsWriter_DropsWhenBufferFull(t *testing.T) {
+	repo := &batchRecorder{block: make(chan struct{})}
+	w := NewMetricsWriter(repo, MetricsWriterOptions{BatchSize: 1, BufferSize: 1, FlushInterval: time.Hour})
+
+	var dropped int
+	for i := 0; i < 10; i++ {
+		if err := w.InsertMetric("platform", "http_requests_total", 1, ""); errors.Is(err, ErrMetricsBufferFull) {
+			dropped++
+		}
+	}
+	close(repo.block)
+	w.Close()
+
+	if dropped == 0 {
+		t.Fatal("expected samples to be dropped instead of blocking")
+	}
+	var written int
+	for _, n := range repo.sizes() {
+		written += n
+	}
+	if written+dropped != 10 {
+		t.Errorf("written %d + dropped %d != 10", written, dropped)
+	}
+}
+
+func TestRecordRequest_UsesConfiguredRegion(t *testing.T) {
+	resetMetricsForTest()
+
+	repo := &batchRecorder{}
+	w := NewMetricsWriter(repo, MetricsWriterOptions{FlushInterval: time.Hour})
+	oldRepo, oldRegion := metricsRepo, metricsRegion
+	metricsRepo = w
+	InitMetricsRegion("ap-south-1")
+	defer func() { metricsRepo, metricsRegion = oldRepo, oldRegion }()
+
+	RecordRequest("GET", "/region", 200, time.Millisecond)
+	w.Close()
+
+	for _, batch := range repo.batches {
+		for _, s := range batch {
+			if s.Region != "ap-south-1" {
+				t.Errorf("sample %s stored with region %q", s.MetricName, s.Region)
+			}
+		}
+	}
+	if sizes := repo.sizes(); len(sizes) != 1 || sizes[0] != 2 {
+		t.Errorf("expected one batch of 2 samples, got %v", sizes)
+	}
+}
@@ -5,8 +5,8 @@ import (
 	"regexp"
 	"time"
 	"context"
-	"strconv"
 
+	"github.com/gorilla/mux"
 	"github.com/rainbowmga/timetravel/common"
 )
 
@@ -41,6 +41,13 @@ func LoggingAndMetrics(next http.Handler) http.Handler {
 		start := time.Now()
 		wrapped := &responseWriter{ResponseWriter: w, status: http.StatusOK}
 
+		// expose the matched route template to downstream logs
+		if route := mux.CurrentRoute(r); route != nil {
+			if tmpl, err := route.GetPathTemplate(); err == nil {
+				r = r.WithContext(context.WithValue(r.Context(), common.RouteKey, tmpl))
+			}
+		}
+
 		next.ServeHTTP(wrapped, r)
 
 		duration := time.Since(start)
@@ -50,7 +57,7 @@ func LoggingAndMetrics(next http.Handler) http.Handler {
 		}
 		metricsPath := pathForMetrics(path)
 		RecordRequest(r.Method, metricsPath, wrapped.status, duration)
-		DefaultLogger.Info("request",
+		DefaultLogger.InfoContext(r.Context(), "request",
 			"method", r.Method,
 			"path", path,
 			"status", wrapped.status,
@@ -59,25 +66,3 @@ func LoggingAndMetrics(next http.Handler) http.Handler {
 		)
 	})
 }
-
-// Request validation for v2 API
-func RequireUserContext(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		userHeader := r.Header.Get("X-User-ID")
-		if userHeader == "" {
-			http.Error(w, "missing X-User-ID header", http.StatusUnauthorized)
-			DefaultLogger.Error("missing X-User-ID header")
-			return
-		}
-
-		userID, err := strconv.ParseInt(userHeader, 10, 64)
-		if err != nil || userID <= 0 {
-			DefaultLogger.Error("invalid X-User-ID header")
-			http.Error(w, "invalid X-User-ID header", http.StatusBadRequest)
-			return
-		}
-
-		ctx := context.WithValue(r.Context(), common.UserIDKey, userID)
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
-}
\ No newline at end of file
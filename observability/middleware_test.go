@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/rainbowmga/timetravel/common"
 )
@@ -101,58 +102,17 @@ func TestLoggingAndMetrics_EmptyPath(t *testing.T) {
 }
 
 
-func TestRequireUserContext_MissingHeader(t *testing.T) {
-	handler := RequireUserContext(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		t.Fatal("handler should not be called")
-	}))
-
-	req := httptest.NewRequest("GET", "/", nil)
-	rr := httptest.NewRecorder()
-
-	handler.ServeHTTP(rr, req)
-
-	if rr.Code != http.StatusUnauthorized {
-		t.Errorf("expected 401 for missing header")
-	}
-}
-
-func TestRequireUserContext_InvalidHeader(t *testing.T) {
-	handler := RequireUserContext(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		t.Fatal("handler should not be called")
-	}))
-
-	req := httptest.NewRequest("GET", "/", nil)
-	req.Header.Set("X-User-ID", "abc")
-
-	rr := httptest.NewRecorder()
-
-	handler.ServeHTTP(rr, req)
-
-	if rr.Code != http.StatusBadRequest {
-		t.Errorf("expected 400 for invalid header")
-	}
-}
-
-func TestRequireUserContext_ValidHeader(t *testing.T) {
-	handler := RequireUserContext(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		val := r.Context().Value(common.UserIDKey)
-		if val == nil {
-			t.Errorf("expected user ID in context")
-		}
-		if val.(int64) != 42 {
-			t.Errorf("expected user ID 42")
-		}
-		w.WriteHeader(http.StatusOK)
-	}))
-
-	req := httptest.NewRequest("GET", "/", nil)
-	req.Header.Set("X-User-ID", "42")
-
-	rr := httptest.NewRecorder()
+func TestLoggingAndMetrics_SetsRouteInContext(t *testing.T) {
+	var route string
+	r := mux.NewRouter()
+	r.Use(LoggingAndMetrics)
+	r.HandleFunc("/records/{id}", func(w http.ResponseWriter, r *http.Request) {
+		route, _ = common.GetRoute(r.Context())
+	})
 
-	handler.ServeHTTP(rr, req)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/records/7", nil))
 
-	if rr.Code != http.StatusOK {
-		t.Errorf("expected 200 OK")
+	if route != "/records/{id}" {
+		t.Errorf("expected route template in context, got %q", route)
 	}
 }
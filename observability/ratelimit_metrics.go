@@ -0,0 +1,29 @@
+package observability
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// PrometheusRateLimitMetrics counts requests refused by the rate limiter. It
+// satisfies ratelimit.Metrics.
+type PrometheusRateLimitMetrics struct {
+	throttled *prometheus.CounterVec
+}
+
+// NewPrometheusRateLimitMetrics registers the rate limit counter on reg,
+// reusing it when the router is built twice in one process
+func NewPrometheusRateLimitMetrics(reg prometheus.Registerer) (*PrometheusRateLimitMetrics, error) {
+	throttled, err := registerOrReuse(reg, prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "rate_limited_requests_total",
+			Help: "Requests refused by the per-user rate limiter, by route class and route (or gRPC method)",
+		},
+		[]string{"class", "route"},
+	))
+	if err != nil {
+		return nil, err
+	}
+	return &PrometheusRateLimitMetrics{throttled: throttled}, nil
+}
+
+func (m *PrometheusRateLimitMetrics) Throttled(class, route string) {
+	m.throttled.WithLabelValues(class, route).Inc()
+}
@@ -0,0 +1,31 @@
+package observability
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestPrometheusRateLimitMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	first, err := NewPrometheusRateLimitMetrics(reg)
+	if err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+	second, err := NewPrometheusRateLimitMetrics(reg)
+	if err != nil {
+		t.Fatalf("second register failed: %v", err)
+	}
+
+	first.Throttled("write", "POST /api/v2/records/{policyholder_id}")
+	second.Throttled("write", "POST /api/v2/records/{policyholder_id}")
+	first.Throttled("read", "/timetravel.records.v1.Records/Get")
+
+	if got := testutil.ToFloat64(first.throttled.WithLabelValues("write", "POST /api/v2/records/{policyholder_id}")); got != 2 {
+		t.Errorf("rate_limited_requests_total{class=write} = %v, want 2", got)
+	}
+	if n := testutil.CollectAndCount(reg, "rate_limited_requests_total"); n != 2 {
+		t.Errorf("expected 2 series, got %d", n)
+	}
+}
@@ -0,0 +1,143 @@
+package observability
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PrometheusRecordMetrics exports record lifecycle metrics. It satisfies
+// service.RecordMetrics and is registered on an explicit Registerer rather
+// than through package globals.
+type PrometheusRecordMetrics struct {
+	recordChanges     *prometheus.CounterVec
+	versionsPerRecord prometheus.Histogram
+	payloadBytes      prometheus.Histogram
+	asOfQueries       *prometheus.CounterVec
+	txDuration        *prometheus.HistogramVec
+	txRetries         *prometheus.CounterVec
+	auditRows         prometheus.Counter
+}
+
+// NewPrometheusRecordMetrics registers the record metrics on reg. Collectors that
+// are already registered (e.g. the router is built twice in one process) are reused.
+func NewPrometheusRecordMetrics(reg prometheus.Registerer) (*PrometheusRecordMetrics, error) {
+	m := &PrometheusRecordMetrics{
+		recordChanges: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "records_changed_total",
+				Help: "Committed record changes by action (create, update, revert)",
+			},
+			[]string{"action"},
+		),
+		versionsPerRecord: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Name:    "record_versions",
+				Help:    "Version count of a record after each write",
+				Buckets: []float64{1, 2, 5, 10, 25, 50, 100, 250, 500, 1000},
+			},
+		),
+		payloadBytes: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Name:    "record_payload_bytes",
+				Help:    "Serialized record data size in bytes",
+				Buckets: prometheus.ExponentialBuckets(64, 4, 8), // 64B .. 1MiB
+			},
+		),
+		asOfQueries: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "record_as_of_queries_total",
+				Help: "Historical record reads by result (found, not_found, error)",
+			},
+			[]string{"result"},
+		),
+		txDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "sqlite_transaction_duration_seconds",
+				Help:    "SQLite transaction duration from BEGIN to commit or rollback",
+				Buckets: prometheus.ExponentialBuckets(0.0005, 2, 14), // 0.5ms .. ~4s
+			},
+			[]string{"operation", "outcome"},
+		),
+		txRetries: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "sqlite_transaction_retries_total",
+				Help: "SQLite transactions retried after a transient error",
+			},
+			[]string{"operation"},
+		),
+		auditRows: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "audit_history_rows_written_total",
+				Help: "Rows appended to audit_history",
+			},
+		),
+	}
+
+	var err error
+	if m.recordChanges, err = registerOrReuse(reg, m.recordChanges); err != nil {
+		return nil, err
+	}
+	if m.versionsPerRecord, err = registerOrReuse(reg, m.versionsPerRecord); err != nil {
+		return nil, err
+	}
+	if m.payloadBytes, err = registerOrReuse(reg, m.payloadBytes); err != nil {
+		return nil, err
+	}
+	if m.asOfQueries, err = registerOrReuse(reg, m.asOfQueries); err != nil {
+		return nil, err
+	}
+	if m.txDuration, err = registerOrReuse(reg, m.txDuration); err != nil {
+		return nil, err
+	}
+	if m.txRetries, err = registerOrReuse(reg, m.txRetries); err != nil {
+		return nil, err
+	}
+	if m.auditRows, err = registerOrReuse(reg, m.auditRows); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// registerOrReuse registers c, or returns the identical collector registered earlier
+func registerOrReuse[T prometheus.Collector](reg prometheus.Registerer, c T) (T, error) {
+	if err := reg.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(T); ok {
+				return existing, nil
+			}
+		}
+		return c, err
+	}
+	return c, nil
+}
+
+func (m *PrometheusRecordMetrics) RecordChanged(action string) {
+	m.recordChanges.WithLabelValues(action).Inc()
+}
+
+func (m *PrometheusRecordMetrics) VersionsPerRecord(versions int) {
+	m.versionsPerRecord.Observe(float64(versions))
+}
+
+func (m *PrometheusRecordMetrics) PayloadSize(bytes int) {
+	m.payloadBytes.Observe(float64(bytes))
+}
+
+func (m *PrometheusRecordMetrics) AsOfQuery(result string) {
+	m.asOfQueries.WithLabelValues(result).Inc()
+}
+
+func (m *PrometheusRecordMetrics) TxDuration(operation, outcome string, d time.Duration) {
+	m.txDuration.WithLabelValues(operation, outcome).Observe(d.Seconds())
+}
+
+func (m *PrometheusRecordMetrics) TxRetry(operation string) {
+	m.txRetries.WithLabelValues(operation).Inc()
+}
+
+func (m *PrometheusRecordMetrics) AuditRowsWritten(n int) {
+	m.auditRows.Add(float64(n))
+}
@@ -0,0 +1,62 @@
+package observability
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestPrometheusRecordMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m, err := NewPrometheusRecordMetrics(reg)
+	if err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+
+	m.RecordChanged("create")
+	m.RecordChanged("update")
+	m.RecordChanged("update")
+	m.VersionsPerRecord(3)
+	m.PayloadSize(120)
+	m.AsOfQuery("found")
+	m.TxDuration("create_or_update", "commit", 2*time.Millisecond)
+	m.TxRetry("create_or_update")
+	m.AuditRowsWritten(2)
+
+	if got := testutil.ToFloat64(m.recordChanges.WithLabelValues("update")); got != 2 {
+		t.Errorf("records_changed_total{action=update} = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.asOfQueries.WithLabelValues("found")); got != 1 {
+		t.Errorf("record_as_of_queries_total{result=found} = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.txRetries.WithLabelValues("create_or_update")); got != 1 {
+		t.Errorf("sqlite_transaction_retries_total = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.auditRows); got != 2 {
+		t.Errorf("audit_history_rows_written_total = %v, want 2", got)
+	}
+	if n := testutil.CollectAndCount(reg, "record_versions", "record_payload_bytes", "sqlite_transaction_duration_seconds"); n != 3 {
+		t.Errorf("expected 3 histogram series, got %d", n)
+	}
+}
+
+func TestPrometheusRecordMetrics_ReusesRegisteredCollectors(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	first, err := NewPrometheusRecordMetrics(reg)
+	if err != nil {
+		t.Fatalf("first register failed: %v", err)
+	}
+	second, err := NewPrometheusRecordMetrics(reg)
+	if err != nil {
+		t.Fatalf("second register failed: %v", err)
+	}
+
+	first.RecordChanged("create")
+	second.RecordChanged("create")
+
+	if got := testutil.ToFloat64(second.recordChanges.WithLabelValues("create")); got != 2 {
+		t.Errorf("expected both instances to share collectors, got %v", got)
+	}
+}
@@ -0,0 +1,138 @@
+package observability
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/rainbowmga/timetravel/common"
+)
+
+// RequestIDHeader carries the correlation id in requests and responses
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds client-supplied ids before they reach logs and event_logs
+const maxRequestIDLength = 128
+
+const tracerName = "github.com/rainbowmga/timetravel"
+
+// propagator reads and writes W3C traceparent/tracestate headers
+var propagator propagation.TextMapPropagator = propagation.TraceContext{}
+
+// Tracer returns the process tracer; a no-op until a TracerProvider is installed
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// StartSpan starts an internal span for a controller or service operation
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartSQLSpan starts a client span for a single SQL statement
+func StartSQLSpan(ctx context.Context, operation, statement string) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, "sql "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "sqlite"),
+			attribute.String("db.operation", operation),
+			attribute.String("db.statement", compactSQL(statement)),
+		),
+	)
+}
+
+// EndSpan records err (if any) on the span and ends it
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// compactSQL collapses whitespace so statements read well in trace UIs
+func compactSQL(statement string) string {
+	return strings.Join(strings.Fields(statement), " ")
+}
+
+// RequestTracing accepts or generates X-Request-ID, continues a W3C traceparent
+// if present, and wraps the handler in a server span. The request id is stored
+// in the context (common.RequestIDKey) and echoed in the response.
+func RequestTracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		route := r.URL.Path
+		if current := mux.CurrentRoute(r); current != nil {
+			if tmpl, err := current.GetPathTemplate(); err == nil {
+				route = tmpl
+			}
+		}
+
+		ctx, span := Tracer().Start(ctx, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("http.route", route),
+				attribute.String("url.path", r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		// re-dispatched requests (e.g. /api/records -> /api/v2/records) keep their id
+		requestID, ok := common.GetRequestID(ctx)
+		if !ok {
+			requestID = requestIDFrom(r.Header.Get(RequestIDHeader), span.SpanContext())
+		}
+		span.SetAttributes(attribute.String("request.id", requestID))
+
+		ctx = context.WithValue(ctx, common.RequestIDKey, requestID)
+		ctx = context.WithValue(ctx, common.RouteKey, route)
+
+		w.Header().Set(RequestIDHeader, requestID)
+		propagator.Inject(ctx, propagation.HeaderCarrier(w.Header()))
+
+		wrapped := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(wrapped, r.WithContext(ctx))
+
+		span.SetAttributes(attribute.Int("http.response.status_code", wrapped.status))
+		if wrapped.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(wrapped.status))
+		}
+	})
+}
+
+// requestIDFrom keeps a sane client id, else reuses the trace id, else generates one
+func requestIDFrom(header string, sc trace.SpanContext) string {
+	if header != "" && len(header) <= maxRequestIDLength && isPrintableASCII(header) {
+		return header
+	}
+	if sc.HasTraceID() {
+		return sc.TraceID().String()
+	}
+	return newRequestID()
+}
+
+func isPrintableASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x21 || s[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
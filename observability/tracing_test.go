@@ -0,0 +1,129 @@
+package observability
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/rainbowmga/timetravel/common"
+)
+
+func installTestTracer(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exp := tracetest.NewInMemoryExporter()
+	orig := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp)))
+	t.Cleanup(func() { otel.SetTracerProvider(orig) })
+	return exp
+}
+
+func tracedRouter(seen *string) *mux.Router {
+	r := mux.NewRouter()
+	r.Use(RequestTracing)
+	r.HandleFunc("/records/{id}", func(w http.ResponseWriter, r *http.Request) {
+		*seen, _ = common.GetRequestID(r.Context())
+		_, span := StartSpan(r.Context(), "handler.child")
+		span.End()
+	})
+	return r
+}
+
+func TestRequestTracing_RequestID(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   func(string) bool
+	}{
+		{"client id accepted", "client-42", func(id string) bool { return id == "client-42" }},
+		{"missing id generated", "", func(id string) bool { return len(id) == 32 }},
+		{"invalid id replaced", "bad id\n", func(id string) bool { return id != "bad id\n" && len(id) == 32 }},
+		{"oversized id replaced", strings.Repeat("a", maxRequestIDLength+1), func(id string) bool { return len(id) == 32 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			installTestTracer(t)
+
+			var seen string
+			req := httptest.NewRequest("GET", "/records/1", nil)
+			if tt.header != "" {
+				req.Header.Set(RequestIDHeader, tt.header)
+			}
+			rr := httptest.NewRecorder()
+			tracedRouter(&seen).ServeHTTP(rr, req)
+
+			if !tt.want(seen) {
+				t.Errorf("unexpected request id in context: %q", seen)
+			}
+			if got := rr.Header().Get(RequestIDHeader); got != seen {
+				t.Errorf("response %s = %q, want %q", RequestIDHeader, got, seen)
+			}
+		})
+	}
+}
+
+func TestRequestTracing_ContinuesTraceparent(t *testing.T) {
+	exp := installTestTracer(t)
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	var seen string
+	req := httptest.NewRequest("GET", "/records/1", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	rr := httptest.NewRecorder()
+	tracedRouter(&seen).ServeHTTP(rr, req)
+
+	spans := exp.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected server and child spans, got %d", len(spans))
+	}
+
+	var server, child tracetest.SpanStub
+	for _, s := range spans {
+		if s.SpanKind == trace.SpanKindServer {
+			server = s
+		} else {
+			child = s
+		}
+	}
+	if server.Name != "GET /records/{id}" {
+		t.Errorf("server span name = %q", server.Name)
+	}
+	if server.SpanContext.TraceID().String() != traceID {
+		t.Errorf("expected trace %s to continue, got %s", traceID, server.SpanContext.TraceID())
+	}
+	if server.Parent.SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("expected remote parent, got %s", server.Parent.SpanID())
+	}
+	if child.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Error("expected handler span to be a child of the server span")
+	}
+	// no X-Request-ID: the trace id doubles as the request id
+	if seen != traceID {
+		t.Errorf("expected request id %s, got %q", traceID, seen)
+	}
+	if !strings.Contains(rr.Header().Get("traceparent"), traceID) {
+		t.Errorf("expected traceparent in response, got %q", rr.Header().Get("traceparent"))
+	}
+}
+
+func TestRequestTracing_KeepsExistingRequestID(t *testing.T) {
+	installTestTracer(t)
+
+	var seen string
+	ctx := context.WithValue(context.Background(), common.RequestIDKey, "outer")
+	req := httptest.NewRequest("GET", "/records/1", nil).WithContext(ctx)
+	req.Header.Set(RequestIDHeader, "inner")
+	tracedRouter(&seen).ServeHTTP(httptest.NewRecorder(), req)
+
+	if seen != "outer" {
+		t.Errorf("expected re-dispatched request to keep its id, got %q", seen)
+	}
+}
@@ -0,0 +1,192 @@
+package openapi
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Schema is the subset of JSON Schema (2020-12, as used by OpenAPI 3.1) that the
+// spec relies on. Unsupported keywords are ignored rather than rejected.
+type Schema struct {
+	Ref                  string             `yaml:"$ref"`
+	Type                 Types              `yaml:"type"`
+	Format               string             `yaml:"format"`
+	Enum                 []interface{}      `yaml:"enum"`
+	Minimum              *float64           `yaml:"minimum"`
+	Maximum              *float64           `yaml:"maximum"`
+	MinLength            *int               `yaml:"minLength"`
+	Pattern              string             `yaml:"pattern"`
+	Properties           map[string]*Schema `yaml:"properties"`
+	Required             []string           `yaml:"required"`
+	AdditionalProperties *Additional        `yaml:"additionalProperties"`
+	Items                *Schema            `yaml:"items"`
+}
+
+// Types is a JSON Schema type keyword: a single name or a list such as [string, "null"]
+type Types []string
+
+func (t *Types) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*t = Types{node.Value}
+		return nil
+	}
+	var list []string
+	if err := node.Decode(&list); err != nil {
+		return err
+	}
+	*t = list
+	return nil
+}
+
+// Additional is additionalProperties: false, true or a schema for the extra values
+type Additional struct {
+	Allowed bool
+	Schema  *Schema
+}
+
+func (a *Additional) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&a.Allowed)
+	}
+	a.Allowed = true
+	return node.Decode(&a.Schema)
+}
+
+// validate checks a decoded JSON value (nil, bool, float64, string, []interface{},
+// map[string]interface{}) against s; at names the value in error messages
+func (d *Document) validate(s *Schema, v interface{}, at string) error {
+	if s == nil {
+		return nil
+	}
+	if s.Ref != "" {
+		name := strings.TrimPrefix(s.Ref, "#/components/schemas/")
+		resolved, ok := d.Components.Schemas[name]
+		if !ok {
+			return fmt.Errorf("openapi: unresolved schema %s", s.Ref)
+		}
+		return d.validate(resolved, v, at)
+	}
+
+	if len(s.Type) > 0 && !s.Type.match(v) {
+		return fmt.Errorf("%s must be %s", at, strings.Join(s.Type, " or "))
+	}
+	if len(s.Enum) > 0 && !inEnum(s.Enum, v) {
+		return fmt.Errorf("%s must be one of %v", at, s.Enum)
+	}
+
+	switch v := v.(type) {
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			return fmt.Errorf("%s must be >= %v", at, *s.Minimum)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			return fmt.Errorf("%s must be <= %v", at, *s.Maximum)
+		}
+	case string:
+		if s.MinLength != nil && len(v) < *s.MinLength {
+			return fmt.Errorf("%s must be at least %d characters", at, *s.MinLength)
+		}
+		if s.Pattern != "" {
+			re, err := regexp.Compile(s.Pattern)
+			if err != nil {
+				return fmt.Errorf("openapi: bad pattern %q: %w", s.Pattern, err)
+			}
+			if !re.MatchString(v) {
+				return fmt.Errorf("%s must match %s", at, s.Pattern)
+			}
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, v); err != nil {
+				return fmt.Errorf("%s must be an RFC3339 date-time", at)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			if err := d.validate(s.Items, item, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				return fmt.Errorf("%s.%s is required", at, name)
+			}
+		}
+		// sorted so the first reported error is stable
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if prop, ok := s.Properties[k]; ok {
+				if err := d.validate(prop, v[k], at+"."+k); err != nil {
+					return err
+				}
+				continue
+			}
+			if s.AdditionalProperties == nil {
+				continue
+			}
+			if !s.AdditionalProperties.Allowed {
+				return fmt.Errorf("%s.%s is not allowed", at, k)
+			}
+			if err := d.validate(s.AdditionalProperties.Schema, v[k], at+"."+k); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (t Types) match(v interface{}) bool {
+	for _, name := range t {
+		switch name {
+		case "null":
+			if v == nil {
+				return true
+			}
+		case "boolean":
+			if _, ok := v.(bool); ok {
+				return true
+			}
+		case "integer":
+			if n, ok := v.(float64); ok && n == math.Trunc(n) {
+				return true
+			}
+		case "number":
+			if _, ok := v.(float64); ok {
+				return true
+			}
+		case "string":
+			if _, ok := v.(string); ok {
+				return true
+			}
+		case "array":
+			if _, ok := v.([]interface{}); ok {
+				return true
+			}
+		case "object":
+			if _, ok := v.(map[string]interface{}); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// inEnum compares by printed value, so YAML ints match JSON float64s
+func inEnum(enum []interface{}, v interface{}) bool {
+	for _, e := range enum {
+		if fmt.Sprint(e) == fmt.Sprint(v) {
+			return true
+		}
+	}
+	return false
+}
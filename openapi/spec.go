@@ -0,0 +1,201 @@
+// Package openapi serves the API contract (openapi.yaml) and validates requests
+// and responses against it. The spec is written by hand rather than generated
+// from the router; the route/spec parity test in app keeps the two in step.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed openapi.yaml
+var specYAML []byte
+
+// Document is the subset of OpenAPI 3.1 the validator understands
+type Document struct {
+	OpenAPI    string              `yaml:"openapi"`
+	Paths      map[string]PathItem `yaml:"paths"`
+	Components Components          `yaml:"components"`
+}
+
+// PathItem holds the operations of one path template, keyed by lower-case method
+type PathItem struct {
+	Parameters []Parameter `yaml:"parameters"`
+	Get        *Operation  `yaml:"get"`
+	Put        *Operation  `yaml:"put"`
+	Post       *Operation  `yaml:"post"`
+	Delete     *Operation  `yaml:"delete"`
+	Patch      *Operation  `yaml:"patch"`
+}
+
+// Operations returns the declared operations keyed by upper-case method
+func (p PathItem) Operations() map[string]*Operation {
+	ops := map[string]*Operation{}
+	for method, op := range map[string]*Operation{
+		http.MethodGet: p.Get, http.MethodPut: p.Put, http.MethodPost: p.Post,
+		http.MethodDelete: p.Delete, http.MethodPatch: p.Patch,
+	} {
+		if op != nil {
+			ops[method] = op
+		}
+	}
+	return ops
+}
+
+type Operation struct {
+	OperationID string              `yaml:"operationId"`
+	Parameters  []Parameter         `yaml:"parameters"`
+	RequestBody *RequestBody        `yaml:"requestBody"`
+	Responses   map[string]Response `yaml:"responses"`
+}
+
+type Parameter struct {
+	Ref      string  `yaml:"$ref"`
+	Name     string  `yaml:"name"`
+	In       string  `yaml:"in"` // path | query | header; headers are documentation only
+	Required bool    `yaml:"required"`
+	Schema   *Schema `yaml:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `yaml:"required"`
+	Content  map[string]MediaType `yaml:"content"`
+}
+
+type Response struct {
+	Ref     string               `yaml:"$ref"`
+	Content map[string]MediaType `yaml:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `yaml:"schema"`
+}
+
+type Components struct {
+	Schemas    map[string]*Schema   `yaml:"schemas"`
+	Parameters map[string]Parameter `yaml:"parameters"`
+	Responses  map[string]Response  `yaml:"responses"`
+}
+
+var (
+	loadOnce sync.Once
+	spec     *Document
+	specJSON []byte
+	loadErr  error
+)
+
+// Spec returns the embedded document, parsed once
+func Spec() (*Document, error) {
+	loadOnce.Do(func() {
+		spec, specJSON, loadErr = parse(specYAML)
+	})
+	return spec, loadErr
+}
+
+// Parse reads an OpenAPI document in YAML or JSON (a JSON document is valid YAML)
+func Parse(b []byte) (*Document, error) {
+	doc, _, err := parse(b)
+	return doc, err
+}
+
+func parse(b []byte) (*Document, []byte, error) {
+	var doc Document
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, nil, fmt.Errorf("openapi: %w", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.1") {
+		return nil, nil, fmt.Errorf("openapi: unsupported version %q, want 3.1.x", doc.OpenAPI)
+	}
+
+	var generic interface{}
+	if err := yaml.Unmarshal(b, &generic); err != nil {
+		return nil, nil, fmt.Errorf("openapi: %w", err)
+	}
+	out, err := json.Marshal(generic)
+	if err != nil {
+		return nil, nil, fmt.Errorf("openapi: %w", err)
+	}
+	return &doc, out, nil
+}
+
+// Handler serves the embedded document as JSON (GET /openapi.json)
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := Spec(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(specJSON)
+	})
+}
+
+// Routes lists every documented operation as "METHOD /path/template", sorted
+func (d *Document) Routes() []string {
+	var routes []string
+	for path, item := range d.Paths {
+		for method := range item.Operations() {
+			routes = append(routes, method+" "+path)
+		}
+	}
+	sort.Strings(routes)
+	return routes
+}
+
+// operation finds the operation for a method and path template
+func (d *Document) operation(method, path string) (*PathItem, *Operation) {
+	item, ok := d.Paths[path]
+	if !ok {
+		return nil, nil
+	}
+	op := item.Operations()[method]
+	if op == nil {
+		return nil, nil
+	}
+	return &item, op
+}
+
+// parameters merges path-level and operation-level parameters, resolving $ref
+func (d *Document) parameters(item *PathItem, op *Operation) ([]Parameter, error) {
+	var params []Parameter
+	for _, p := range append(append([]Parameter(nil), item.Parameters...), op.Parameters...) {
+		if p.Ref != "" {
+			name := strings.TrimPrefix(p.Ref, "#/components/parameters/")
+			resolved, ok := d.Components.Parameters[name]
+			if !ok {
+				return nil, fmt.Errorf("openapi: unresolved parameter %s", p.Ref)
+			}
+			p = resolved
+		}
+		params = append(params, p)
+	}
+	return params, nil
+}
+
+// response picks the declared response for a status: exact code, then 4XX/5XX, then default
+func (d *Document) response(op *Operation, status int) (Response, bool, error) {
+	code := fmt.Sprint(status)
+	for _, key := range []string{code, code[:1] + "XX", "default"} {
+		resp, ok := op.Responses[key]
+		if !ok {
+			continue
+		}
+		if resp.Ref != "" {
+			name := strings.TrimPrefix(resp.Ref, "#/components/responses/")
+			resolved, ok := d.Components.Responses[name]
+			if !ok {
+				return Response{}, false, fmt.Errorf("openapi: unresolved response %s", resp.Ref)
+			}
+			resp = resolved
+		}
+		return resp, true, nil
+	}
+	return Response{}, false, nil
+}
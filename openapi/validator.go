@@ -0,0 +1,287 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/observability"
+)
+
+// Mode selects how much of the contract the middleware enforces
+type Mode string
+
+const (
+	// ModeOff skips validation
+	ModeOff Mode = "off"
+	// ModeRequests rejects requests that break the spec with 400
+	ModeRequests Mode = "requests"
+	// ModeStrict also buffers every response and replaces one that breaks the
+	// spec with a 500; meant for tests, where contract drift should fail loudly
+	ModeStrict Mode = "strict"
+)
+
+// ParseMode reads the openapi.validation config value; empty means off
+func ParseMode(s string) (Mode, error) {
+	switch Mode(s) {
+	case "", ModeOff:
+		return ModeOff, nil
+	case ModeRequests, ModeStrict:
+		return Mode(s), nil
+	}
+	return ModeOff, fmt.Errorf("unknown openapi.validation %q (off | requests | strict)", s)
+}
+
+// DefaultMaxBodyBytes bounds the request bodies Validate reads when
+// Options.MaxBodyBytes is unset
+const DefaultMaxBodyBytes = 1 << 20
+
+// Options configure Validate
+type Options struct {
+	Mode Mode
+	// MaxBodyBytes bounds request bodies, which are buffered for validation
+	// before authentication; longer ones are rejected with 400. Default
+	// DefaultMaxBodyBytes.
+	MaxBodyBytes int64
+	// WriteError answers rejected requests; defaults to apperr.WriteProblem.
+	// v1 routes need their legacy {"error": ...} body instead.
+	WriteError func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// Validate checks each request routed by mux against the operation documented
+// for its method and path template. Routes missing from the spec pass through;
+// the route/spec parity test keeps that set empty.
+func Validate(doc *Document, opts Options) mux.MiddlewareFunc {
+	writeError := opts.WriteError
+	if writeError == nil {
+		writeError = apperr.WriteProblem
+	}
+	maxBody := opts.MaxBodyBytes
+	if maxBody <= 0 {
+		maxBody = DefaultMaxBodyBytes
+	}
+
+	return func(next http.Handler) http.Handler {
+		if opts.Mode == ModeOff || opts.Mode == "" {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tmpl := routeTemplate(r)
+			if r.Body != nil && r.Body != http.NoBody {
+				r.Body = http.MaxBytesReader(w, r.Body, maxBody)
+			}
+			if err := doc.ValidateRequest(r, tmpl); err != nil {
+				writeError(w, r, err)
+				return
+			}
+			if opts.Mode != ModeStrict {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			buf := &bufferedResponse{header: http.Header{}, status: http.StatusOK}
+			next.ServeHTTP(buf, r)
+
+			if err := doc.ValidateResponse(r.Method, tmpl, buf.status, buf.header, buf.body.Bytes()); err != nil {
+				observability.DefaultLogger.ErrorContext(r.Context(), "openapi_response_invalid",
+					"method", r.Method, "route", tmpl, "status", buf.status, "error", err)
+				writeError(w, r, apperr.New(apperr.ErrInternal, "response does not match the OpenAPI spec: "+err.Error()))
+				return
+			}
+			buf.flush(w)
+		})
+	}
+}
+
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tmpl, err := route.GetPathTemplate(); err == nil {
+			return tmpl
+		}
+	}
+	return ""
+}
+
+// ValidateRequest checks path/query parameters and the JSON body. The body is
+// read and put back so the handler can decode it again.
+func (d *Document) ValidateRequest(r *http.Request, path string) error {
+	item, op := d.operation(r.Method, path)
+	if op == nil {
+		return nil
+	}
+
+	params, err := d.parameters(item, op)
+	if err != nil {
+		return err
+	}
+	vars := mux.Vars(r)
+	query := r.URL.Query()
+	for _, p := range params {
+		var raw string
+		var present bool
+		switch p.In {
+		case "path":
+			raw, present = vars[p.Name]
+		case "query":
+			present = query.Has(p.Name)
+			raw = query.Get(p.Name)
+		default:
+			continue
+		}
+		at := p.In + " parameter " + p.Name
+		if !present {
+			if p.Required {
+				return apperr.InvalidArgument(at + " is required")
+			}
+			continue
+		}
+		if err := d.validate(p.Schema, paramValue(p.Schema, raw), at); err != nil {
+			return apperr.InvalidArgument(err.Error())
+		}
+	}
+
+	if op.RequestBody == nil {
+		return nil
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return apperr.InvalidArgument(fmt.Sprintf("request body is larger than %d bytes", tooLarge.Limit))
+		}
+		return apperr.InvalidArgument("request body could not be read")
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		if op.RequestBody.Required {
+			return apperr.InvalidArgument("request body is required")
+		}
+		return nil
+	}
+	// clients often omit Content-Type (curl -d sends form encoding), so any
+	// body is read as the JSON media type the operation declares
+	schema, ok := jsonSchema(op.RequestBody.Content)
+	if !ok {
+		return nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return apperr.InvalidArgument("request body must be valid JSON")
+	}
+	if err := d.validate(schema, v, "body"); err != nil {
+		return apperr.InvalidArgument(err.Error())
+	}
+	return nil
+}
+
+// ValidateResponse checks that the status is declared, the Content-Type is one
+// of the declared media types and a JSON body matches its schema
+func (d *Document) ValidateResponse(method, path string, status int, header http.Header, body []byte) error {
+	_, op := d.operation(method, path)
+	if op == nil {
+		return nil
+	}
+	resp, ok, err := d.response(op, status)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("status %d is not declared", status)
+	}
+	if len(resp.Content) == 0 {
+		if len(body) > 0 {
+			return fmt.Errorf("status %d declares no body", status)
+		}
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		return fmt.Errorf("missing or invalid Content-Type %q", header.Get("Content-Type"))
+	}
+	media, ok := resp.Content[mediaType]
+	if !ok {
+		return fmt.Errorf("content type %s is not declared for status %d", mediaType, status)
+	}
+	if !strings.HasSuffix(mediaType, "json") {
+		return nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return fmt.Errorf("body is not valid JSON: %w", err)
+	}
+	return d.validate(media.Schema, v, "response")
+}
+
+// paramValue converts a raw path/query value to the JSON type its schema expects;
+// values that do not parse stay strings and fail the type check
+func paramValue(s *Schema, raw string) interface{} {
+	if s == nil {
+		return raw
+	}
+	for _, t := range s.Type {
+		switch t {
+		case "integer", "number":
+			if n, err := strconv.ParseFloat(raw, 64); err == nil {
+				return n
+			}
+		case "boolean":
+			if b, err := strconv.ParseBool(raw); err == nil {
+				return b
+			}
+		}
+	}
+	return raw
+}
+
+func jsonSchema(content map[string]MediaType) (*Schema, bool) {
+	for mediaType, media := range content {
+		if strings.HasSuffix(mediaType, "json") {
+			return media.Schema, true
+		}
+	}
+	return nil, false
+}
+
+// bufferedResponse holds a response until it has been validated
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+	wrote  bool
+}
+
+func (b *bufferedResponse) Header() http.Header { return b.header }
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if !b.wrote {
+		b.status = status
+		b.wrote = true
+	}
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	b.wrote = true
+	if b.header.Get("Content-Type") == "" {
+		b.header.Set("Content-Type", http.DetectContentType(p))
+	}
+	return b.body.Write(p)
+}
+
+func (b *bufferedResponse) flush(w http.ResponseWriter) {
+	for k, v := range b.header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(b.status)
+	_, _ = w.Write(b.body.Bytes())
+}
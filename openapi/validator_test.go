@@ -0,0 +1,284 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/openapi"
+)
+
+const testSpec = `
+openapi: 3.1.0
+paths:
+  /items/{id}:
+    parameters:
+      - $ref: "#/components/parameters/ID"
+    get:
+      parameters:
+        - name: limit
+          in: query
+          schema:
+            type: integer
+            maximum: 10
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/Item"
+        default:
+          description: error
+          content:
+            application/problem+json:
+              schema:
+                type: object
+    put:
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: "#/components/schemas/Item"
+      responses:
+        "204":
+          description: stored
+components:
+  parameters:
+    ID:
+      name: id
+      in: path
+      required: true
+      schema:
+        type: integer
+        minimum: 1
+  schemas:
+    Item:
+      type: object
+      required: [name]
+      additionalProperties: false
+      properties:
+        name:
+          type: string
+          minLength: 1
+        note:
+          type: [string, "null"]
+        tags:
+          type: array
+          items:
+            type: string
+            enum: [a, b]
+        at:
+          type: string
+          format: date-time
+`
+
+func testDoc(t *testing.T) *openapi.Document {
+	t.Helper()
+	doc, err := openapi.Parse([]byte(testSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestEmbeddedSpecParses(t *testing.T) {
+	doc, err := openapi.Spec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Routes()) == 0 {
+		t.Fatal("embedded spec documents no routes")
+	}
+
+	rec := httptest.NewRecorder()
+	openapi.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/openapi.json", nil))
+	var body map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body["openapi"] != "3.1.0" {
+		t.Fatalf("/openapi.json = %s (%v)", rec.Body.String(), err)
+	}
+}
+
+func TestParseRejectsOtherVersions(t *testing.T) {
+	if _, err := openapi.Parse([]byte("openapi: 3.0.3\npaths: {}\n")); err == nil {
+		t.Fatal("expected 3.0 documents to be rejected")
+	}
+}
+
+func TestValidateRequest(t *testing.T) {
+	doc := testDoc(t)
+
+	tests := []struct {
+		name    string
+		method  string
+		target  string
+		body    string
+		wantErr string
+	}{
+		{"valid get", "GET", "/items/1?limit=5", "", ""},
+		{"path parameter below minimum", "GET", "/items/0", "", "path parameter id must be >= 1"},
+		{"path parameter not an integer", "GET", "/items/x", "", "path parameter id must be integer"},
+		{"query parameter above maximum", "GET", "/items/1?limit=50", "", "query parameter limit must be <= 10"},
+		{"valid body", "PUT", "/items/1", `{"name":"n","note":null,"tags":["a"],"at":"2024-01-02T03:04:05Z"}`, ""},
+		{"missing body", "PUT", "/items/1", "", "request body is required"},
+		{"malformed body", "PUT", "/items/1", `{`, "request body must be valid JSON"},
+		{"missing required property", "PUT", "/items/1", `{}`, "body.name is required"},
+		{"wrong property type", "PUT", "/items/1", `{"name":1}`, "body.name must be string"},
+		{"empty string", "PUT", "/items/1", `{"name":""}`, "body.name must be at least 1 characters"},
+		{"unknown property", "PUT", "/items/1", `{"name":"n","x":1}`, "body.x is not allowed"},
+		{"enum item", "PUT", "/items/1", `{"name":"n","tags":["c"]}`, "body.tags[0] must be one of [a b]"},
+		{"date-time format", "PUT", "/items/1", `{"name":"n","at":"today"}`, "body.at must be an RFC3339 date-time"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got error
+			router := mux.NewRouter()
+			router.HandleFunc("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+				got = doc.ValidateRequest(r, "/items/{id}")
+			})
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body)))
+
+			if tt.wantErr == "" {
+				if got != nil {
+					t.Fatalf("unexpected error: %v", got)
+				}
+				return
+			}
+			if got == nil || got.Error() != tt.wantErr {
+				t.Fatalf("error = %v, want %q", got, tt.wantErr)
+			}
+			if !errors.Is(got, apperr.ErrInvalidArgument) {
+				t.Errorf("request errors must be invalid_argument, got %v", got)
+			}
+		})
+	}
+}
+
+func TestValidateResponse(t *testing.T) {
+	doc := testDoc(t)
+	jsonHeader := http.Header{"Content-Type": {"application/json; charset=utf-8"}}
+	problemHeader := http.Header{"Content-Type": {"application/problem+json"}}
+
+	tests := []struct {
+		name    string
+		method  string
+		status  int
+		header  http.Header
+		body    string
+		wantErr bool
+	}{
+		{"declared status and body", "GET", 200, jsonHeader, `{"name":"n"}`, false},
+		{"body breaks schema", "GET", 200, jsonHeader, `{"name":2}`, true},
+		{"undeclared media type", "GET", 200, http.Header{"Content-Type": {"text/plain"}}, `hi`, true},
+		{"default response", "GET", 404, problemHeader, `{}`, false},
+		{"undeclared status", "PUT", 200, jsonHeader, `{"name":"n"}`, true},
+		{"no-content status with a body", "PUT", 204, nil, `x`, true},
+		{"no-content status", "PUT", 204, nil, ``, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := doc.ValidateResponse(tt.method, "/items/{id}", tt.status, tt.header, []byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateResponse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateMiddleware(t *testing.T) {
+	doc := testDoc(t)
+
+	newRouter := func(mode openapi.Mode, handler http.HandlerFunc) *mux.Router {
+		router := mux.NewRouter()
+		router.Use(openapi.Validate(doc, openapi.Options{Mode: mode}))
+		router.HandleFunc("/items/{id}", handler).Methods("GET", "PUT")
+		return router
+	}
+	drifted := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name":42}`))
+	}
+
+	t.Run("requests mode rejects bad requests as problem+json", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newRouter(openapi.ModeRequests, drifted).ServeHTTP(rec, httptest.NewRequest("GET", "/items/0", nil))
+		if rec.Code != http.StatusBadRequest || rec.Header().Get("Content-Type") != apperr.ProblemContentType {
+			t.Fatalf("got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+		}
+	})
+
+	t.Run("requests mode passes responses through", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newRouter(openapi.ModeRequests, drifted).ServeHTTP(rec, httptest.NewRequest("GET", "/items/1", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("got %d", rec.Code)
+		}
+	})
+
+	t.Run("strict mode replaces responses that break the spec", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newRouter(openapi.ModeStrict, drifted).ServeHTTP(rec, httptest.NewRequest("GET", "/items/1", nil))
+		if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "response.name must be string") {
+			t.Fatalf("got %d %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("strict mode keeps valid responses and their headers", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newRouter(openapi.ModeStrict, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("X-Trace", "1")
+			_, _ = w.Write([]byte(`{"name":"n"}`))
+		}).ServeHTTP(rec, httptest.NewRequest("GET", "/items/1", nil))
+		if rec.Code != http.StatusOK || rec.Header().Get("X-Trace") != "1" || rec.Body.String() != `{"name":"n"}` {
+			t.Fatalf("got %d %v %s", rec.Code, rec.Header(), rec.Body.String())
+		}
+	})
+
+	t.Run("bodies over the limit are rejected before they are buffered", func(t *testing.T) {
+		router := mux.NewRouter()
+		router.Use(openapi.Validate(doc, openapi.Options{Mode: openapi.ModeRequests, MaxBodyBytes: 32}))
+		router.HandleFunc("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}).Methods("PUT")
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("PUT", "/items/1", strings.NewReader(`{"name":"`+strings.Repeat("x", 64)+`"}`)))
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "larger than 32 bytes") {
+			t.Fatalf("oversized body got %d %s", rec.Code, rec.Body.String())
+		}
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("PUT", "/items/1", strings.NewReader(`{"name":"n"}`)))
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("small body got %d %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("off mode does nothing", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newRouter(openapi.ModeOff, drifted).ServeHTTP(rec, httptest.NewRequest("GET", "/items/0", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("got %d", rec.Code)
+		}
+	})
+}
+
+func TestParseMode(t *testing.T) {
+	for in, want := range map[string]openapi.Mode{"": openapi.ModeOff, "off": openapi.ModeOff, "requests": openapi.ModeRequests, "strict": openapi.ModeStrict} {
+		if got, err := openapi.ParseMode(in); err != nil || got != want {
+			t.Errorf("ParseMode(%q) = %v, %v", in, got, err)
+		}
+	}
+	if _, err := openapi.ParseMode("loose"); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
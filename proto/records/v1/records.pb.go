@@ -0,0 +1,831 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        (unknown)
+// source: records/v1/records.proto
+
+// Records mirrors the v2 HTTP record operations over gRPC. Every call carries
+// the caller's id in the x-user-id metadata key, like the X-User-ID header.
+
+package recordsv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Record struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	PolicyholderId int64                  `protobuf:"varint,1,opt,name=policyholder_id,json=policyholderId,proto3" json:"policyholder_id,omitempty"`
+	RecordId       int64                  `protobuf:"varint,2,opt,name=record_id,json=recordId,proto3" json:"record_id,omitempty"`
+	Version        int32                  `protobuf:"varint,3,opt,name=version,proto3" json:"version,omitempty"`
+	Data           map[string]string      `protobuf:"bytes,4,rep,name=data,proto3" json:"data,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	CreatedAt      *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt      *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Record) Reset() {
+	*x = Record{}
+	mi := &file_records_v1_records_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Record) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Record) ProtoMessage() {}
+
+func (x *Record) ProtoReflect() protoreflect.Message {
+	mi := &file_records_v1_records_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Record.ProtoReflect.Descriptor instead.
+func (*Record) Descriptor() ([]byte, []int) {
+	return file_records_v1_records_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Record) GetPolicyholderId() int64 {
+	if x != nil {
+		return x.PolicyholderId
+	}
+	return 0
+}
+
+func (x *Record) GetRecordId() int64 {
+	if x != nil {
+		return x.RecordId
+	}
+	return 0
+}
+
+func (x *Record) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *Record) GetData() map[string]string {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *Record) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Record) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type RecordVersion struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	PolicyholderId int64                  `protobuf:"varint,1,opt,name=policyholder_id,json=policyholderId,proto3" json:"policyholder_id,omitempty"`
+	Version        int32                  `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	Data           map[string]string      `protobuf:"bytes,3,rep,name=data,proto3" json:"data,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *RecordVersion) Reset() {
+	*x = RecordVersion{}
+	mi := &file_records_v1_records_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RecordVersion) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RecordVersion) ProtoMessage() {}
+
+func (x *RecordVersion) ProtoReflect() protoreflect.Message {
+	mi := &file_records_v1_records_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RecordVersion.ProtoReflect.Descriptor instead.
+func (*RecordVersion) Descriptor() ([]byte, []int) {
+	return file_records_v1_records_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *RecordVersion) GetPolicyholderId() int64 {
+	if x != nil {
+		return x.PolicyholderId
+	}
+	return 0
+}
+
+func (x *RecordVersion) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *RecordVersion) GetData() map[string]string {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type RecordChange struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// change_id orders changes across all records; resume a watch after it.
+	ChangeId       int64 `protobuf:"varint,1,opt,name=change_id,json=changeId,proto3" json:"change_id,omitempty"`
+	PolicyholderId int64 `protobuf:"varint,2,opt,name=policyholder_id,json=policyholderId,proto3" json:"policyholder_id,omitempty"`
+	Version        int32 `protobuf:"varint,3,opt,name=version,proto3" json:"version,omitempty"`
+	// action is create or update.
+	Action        string                 `protobuf:"bytes,4,opt,name=action,proto3" json:"action,omitempty"`
+	Data          map[string]string      `protobuf:"bytes,5,rep,name=data,proto3" json:"data,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	ChangedAt     *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=changed_at,json=changedAt,proto3" json:"changed_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RecordChange) Reset() {
+	*x = RecordChange{}
+	mi := &file_records_v1_records_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RecordChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RecordChange) ProtoMessage() {}
+
+func (x *RecordChange) ProtoReflect() protoreflect.Message {
+	mi := &file_records_v1_records_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RecordChange.ProtoReflect.Descriptor instead.
+func (*RecordChange) Descriptor() ([]byte, []int) {
+	return file_records_v1_records_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *RecordChange) GetChangeId() int64 {
+	if x != nil {
+		return x.ChangeId
+	}
+	return 0
+}
+
+func (x *RecordChange) GetPolicyholderId() int64 {
+	if x != nil {
+		return x.PolicyholderId
+	}
+	return 0
+}
+
+func (x *RecordChange) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *RecordChange) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *RecordChange) GetData() map[string]string {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *RecordChange) GetChangedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ChangedAt
+	}
+	return nil
+}
+
+type GetRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	PolicyholderId int64                  `protobuf:"varint,1,opt,name=policyholder_id,json=policyholderId,proto3" json:"policyholder_id,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *GetRequest) Reset() {
+	*x = GetRequest{}
+	mi := &file_records_v1_records_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRequest) ProtoMessage() {}
+
+func (x *GetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_records_v1_records_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRequest.ProtoReflect.Descriptor instead.
+func (*GetRequest) Descriptor() ([]byte, []int) {
+	return file_records_v1_records_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetRequest) GetPolicyholderId() int64 {
+	if x != nil {
+		return x.PolicyholderId
+	}
+	return 0
+}
+
+type UpsertRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	PolicyholderId int64                  `protobuf:"varint,1,opt,name=policyholder_id,json=policyholderId,proto3" json:"policyholder_id,omitempty"`
+	Data           map[string]string      `protobuf:"bytes,2,rep,name=data,proto3" json:"data,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *UpsertRequest) Reset() {
+	*x = UpsertRequest{}
+	mi := &file_records_v1_records_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpsertRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpsertRequest) ProtoMessage() {}
+
+func (x *UpsertRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_records_v1_records_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpsertRequest.ProtoReflect.Descriptor instead.
+func (*UpsertRequest) Descriptor() ([]byte, []int) {
+	return file_records_v1_records_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *UpsertRequest) GetPolicyholderId() int64 {
+	if x != nil {
+		return x.PolicyholderId
+	}
+	return 0
+}
+
+func (x *UpsertRequest) GetData() map[string]string {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type PatchRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	PolicyholderId int64                  `protobuf:"varint,1,opt,name=policyholder_id,json=policyholderId,proto3" json:"policyholder_id,omitempty"`
+	Set            map[string]string      `protobuf:"bytes,2,rep,name=set,proto3" json:"set,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Delete         []string               `protobuf:"bytes,3,rep,name=delete,proto3" json:"delete,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *PatchRequest) Reset() {
+	*x = PatchRequest{}
+	mi := &file_records_v1_records_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PatchRequest) ProtoMessage() {}
+
+func (x *PatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_records_v1_records_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PatchRequest.ProtoReflect.Descriptor instead.
+func (*PatchRequest) Descriptor() ([]byte, []int) {
+	return file_records_v1_records_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *PatchRequest) GetPolicyholderId() int64 {
+	if x != nil {
+		return x.PolicyholderId
+	}
+	return 0
+}
+
+func (x *PatchRequest) GetSet() map[string]string {
+	if x != nil {
+		return x.Set
+	}
+	return nil
+}
+
+func (x *PatchRequest) GetDelete() []string {
+	if x != nil {
+		return x.Delete
+	}
+	return nil
+}
+
+type GetVersionRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	PolicyholderId int64                  `protobuf:"varint,1,opt,name=policyholder_id,json=policyholderId,proto3" json:"policyholder_id,omitempty"`
+	Version        int32                  `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *GetVersionRequest) Reset() {
+	*x = GetVersionRequest{}
+	mi := &file_records_v1_records_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetVersionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetVersionRequest) ProtoMessage() {}
+
+func (x *GetVersionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_records_v1_records_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetVersionRequest.ProtoReflect.Descriptor instead.
+func (*GetVersionRequest) Descriptor() ([]byte, []int) {
+	return file_records_v1_records_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetVersionRequest) GetPolicyholderId() int64 {
+	if x != nil {
+		return x.PolicyholderId
+	}
+	return 0
+}
+
+func (x *GetVersionRequest) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type ListVersionsRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	PolicyholderId int64                  `protobuf:"varint,1,opt,name=policyholder_id,json=policyholderId,proto3" json:"policyholder_id,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ListVersionsRequest) Reset() {
+	*x = ListVersionsRequest{}
+	mi := &file_records_v1_records_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListVersionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListVersionsRequest) ProtoMessage() {}
+
+func (x *ListVersionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_records_v1_records_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListVersionsRequest.ProtoReflect.Descriptor instead.
+func (*ListVersionsRequest) Descriptor() ([]byte, []int) {
+	return file_records_v1_records_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ListVersionsRequest) GetPolicyholderId() int64 {
+	if x != nil {
+		return x.PolicyholderId
+	}
+	return 0
+}
+
+type ListVersionsResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	PolicyholderId int64                  `protobuf:"varint,1,opt,name=policyholder_id,json=policyholderId,proto3" json:"policyholder_id,omitempty"`
+	Versions       []int32                `protobuf:"varint,2,rep,packed,name=versions,proto3" json:"versions,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ListVersionsResponse) Reset() {
+	*x = ListVersionsResponse{}
+	mi := &file_records_v1_records_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListVersionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListVersionsResponse) ProtoMessage() {}
+
+func (x *ListVersionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_records_v1_records_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListVersionsResponse.ProtoReflect.Descriptor instead.
+func (*ListVersionsResponse) Descriptor() ([]byte, []int) {
+	return file_records_v1_records_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ListVersionsResponse) GetPolicyholderId() int64 {
+	if x != nil {
+		return x.PolicyholderId
+	}
+	return 0
+}
+
+func (x *ListVersionsResponse) GetVersions() []int32 {
+	if x != nil {
+		return x.Versions
+	}
+	return nil
+}
+
+type GetAsOfRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	PolicyholderId int64                  `protobuf:"varint,1,opt,name=policyholder_id,json=policyholderId,proto3" json:"policyholder_id,omitempty"`
+	AsOf           *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=as_of,json=asOf,proto3" json:"as_of,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *GetAsOfRequest) Reset() {
+	*x = GetAsOfRequest{}
+	mi := &file_records_v1_records_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetAsOfRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetAsOfRequest) ProtoMessage() {}
+
+func (x *GetAsOfRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_records_v1_records_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetAsOfRequest.ProtoReflect.Descriptor instead.
+func (*GetAsOfRequest) Descriptor() ([]byte, []int) {
+	return file_records_v1_records_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetAsOfRequest) GetPolicyholderId() int64 {
+	if x != nil {
+		return x.PolicyholderId
+	}
+	return 0
+}
+
+func (x *GetAsOfRequest) GetAsOf() *timestamppb.Timestamp {
+	if x != nil {
+		return x.AsOf
+	}
+	return nil
+}
+
+type WatchChangesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// policyholder_id limits the stream to one record; 0 follows every record.
+	PolicyholderId int64 `protobuf:"varint,1,opt,name=policyholder_id,json=policyholderId,proto3" json:"policyholder_id,omitempty"`
+	// after_change_id resumes after a change already seen; unset starts with
+	// the next change written.
+	AfterChangeId *int64 `protobuf:"varint,2,opt,name=after_change_id,json=afterChangeId,proto3,oneof" json:"after_change_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchChangesRequest) Reset() {
+	*x = WatchChangesRequest{}
+	mi := &file_records_v1_records_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchChangesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchChangesRequest) ProtoMessage() {}
+
+func (x *WatchChangesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_records_v1_records_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchChangesRequest.ProtoReflect.Descriptor instead.
+func (*WatchChangesRequest) Descriptor() ([]byte, []int) {
+	return file_records_v1_records_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *WatchChangesRequest) GetPolicyholderId() int64 {
+	if x != nil {
+		return x.PolicyholderId
+	}
+	return 0
+}
+
+func (x *WatchChangesRequest) GetAfterChangeId() int64 {
+	if x != nil && x.AfterChangeId != nil {
+		return *x.AfterChangeId
+	}
+	return 0
+}
+
+var File_records_v1_records_proto protoreflect.FileDescriptor
+
+const file_records_v1_records_proto_rawDesc = "" +
+	"\n" +
+	"\x18records/v1/records.proto\x12\x15timetravel.records.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xd4\x02\n" +
+	"\x06Record\x12'\n" +
+	"\x0fpolicyholder_id\x18\x01 \x01(\x03R\x0epolicyholderId\x12\x1b\n" +
+	"\trecord_id\x18\x02 \x01(\x03R\brecordId\x12\x18\n" +
+	"\aversion\x18\x03 \x01(\x05R\aversion\x12;\n" +
+	"\x04data\x18\x04 \x03(\v2'.timetravel.records.v1.Record.DataEntryR\x04data\x129\n" +
+	"\n" +
+	"created_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x1a7\n" +
+	"\tDataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xcf\x01\n" +
+	"\rRecordVersion\x12'\n" +
+	"\x0fpolicyholder_id\x18\x01 \x01(\x03R\x0epolicyholderId\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\x05R\aversion\x12B\n" +
+	"\x04data\x18\x03 \x03(\v2..timetravel.records.v1.RecordVersion.DataEntryR\x04data\x1a7\n" +
+	"\tDataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xbd\x02\n" +
+	"\fRecordChange\x12\x1b\n" +
+	"\tchange_id\x18\x01 \x01(\x03R\bchangeId\x12'\n" +
+	"\x0fpolicyholder_id\x18\x02 \x01(\x03R\x0epolicyholderId\x12\x18\n" +
+	"\aversion\x18\x03 \x01(\x05R\aversion\x12\x16\n" +
+	"\x06action\x18\x04 \x01(\tR\x06action\x12A\n" +
+	"\x04data\x18\x05 \x03(\v2-.timetravel.records.v1.RecordChange.DataEntryR\x04data\x129\n" +
+	"\n" +
+	"changed_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tchangedAt\x1a7\n" +
+	"\tDataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"5\n" +
+	"\n" +
+	"GetRequest\x12'\n" +
+	"\x0fpolicyholder_id\x18\x01 \x01(\x03R\x0epolicyholderId\"\xb5\x01\n" +
+	"\rUpsertRequest\x12'\n" +
+	"\x0fpolicyholder_id\x18\x01 \x01(\x03R\x0epolicyholderId\x12B\n" +
+	"\x04data\x18\x02 \x03(\v2..timetravel.records.v1.UpsertRequest.DataEntryR\x04data\x1a7\n" +
+	"\tDataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xc7\x01\n" +
+	"\fPatchRequest\x12'\n" +
+	"\x0fpolicyholder_id\x18\x01 \x01(\x03R\x0epolicyholderId\x12>\n" +
+	"\x03set\x18\x02 \x03(\v2,.timetravel.records.v1.PatchRequest.SetEntryR\x03set\x12\x16\n" +
+	"\x06delete\x18\x03 \x03(\tR\x06delete\x1a6\n" +
+	"\bSetEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"V\n" +
+	"\x11GetVersionRequest\x12'\n" +
+	"\x0fpolicyholder_id\x18\x01 \x01(\x03R\x0epolicyholderId\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\x05R\aversion\">\n" +
+	"\x13ListVersionsRequest\x12'\n" +
+	"\x0fpolicyholder_id\x18\x01 \x01(\x03R\x0epolicyholderId\"[\n" +
+	"\x14ListVersionsResponse\x12'\n" +
+	"\x0fpolicyholder_id\x18\x01 \x01(\x03R\x0epolicyholderId\x12\x1a\n" +
+	"\bversions\x18\x02 \x03(\x05R\bversions\"j\n" +
+	"\x0eGetAsOfRequest\x12'\n" +
+	"\x0fpolicyholder_id\x18\x01 \x01(\x03R\x0epolicyholderId\x12/\n" +
+	"\x05as_of\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x04asOf\"\x7f\n" +
+	"\x13WatchChangesRequest\x12'\n" +
+	"\x0fpolicyholder_id\x18\x01 \x01(\x03R\x0epolicyholderId\x12+\n" +
+	"\x0fafter_change_id\x18\x02 \x01(\x03H\x00R\rafterChangeId\x88\x01\x01B\x12\n" +
+	"\x10_after_change_id2\xef\x04\n" +
+	"\aRecords\x12G\n" +
+	"\x03Get\x12!.timetravel.records.v1.GetRequest\x1a\x1d.timetravel.records.v1.Record\x12M\n" +
+	"\x06Upsert\x12$.timetravel.records.v1.UpsertRequest\x1a\x1d.timetravel.records.v1.Record\x12K\n" +
+	"\x05Patch\x12#.timetravel.records.v1.PatchRequest\x1a\x1d.timetravel.records.v1.Record\x12\\\n" +
+	"\n" +
+	"GetVersion\x12(.timetravel.records.v1.GetVersionRequest\x1a$.timetravel.records.v1.RecordVersion\x12g\n" +
+	"\fListVersions\x12*.timetravel.records.v1.ListVersionsRequest\x1a+.timetravel.records.v1.ListVersionsResponse\x12U\n" +
+	"\aGetAsOf\x12%.timetravel.records.v1.GetAsOfRequest\x1a#.timetravel.records.v1.RecordChange\x12a\n" +
+	"\fWatchChanges\x12*.timetravel.records.v1.WatchChangesRequest\x1a#.timetravel.records.v1.RecordChange0\x01B=Z;github.com/rainbowmga/timetravel/proto/records/v1;recordsv1b\x06proto3"
+
+var (
+	file_records_v1_records_proto_rawDescOnce sync.Once
+	file_records_v1_records_proto_rawDescData []byte
+)
+
+func file_records_v1_records_proto_rawDescGZIP() []byte {
+	file_records_v1_records_proto_rawDescOnce.Do(func() {
+		file_records_v1_records_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_records_v1_records_proto_rawDesc), len(file_records_v1_records_proto_rawDesc)))
+	})
+	return file_records_v1_records_proto_rawDescData
+}
+
+var file_records_v1_records_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
+var file_records_v1_records_proto_goTypes = []any{
+	(*Record)(nil),                // 0: timetravel.records.v1.Record
+	(*RecordVersion)(nil),         // 1: timetravel.records.v1.RecordVersion
+	(*RecordChange)(nil),          // 2: timetravel.records.v1.RecordChange
+	(*GetRequest)(nil),            // 3: timetravel.records.v1.GetRequest
+	(*UpsertRequest)(nil),         // 4: timetravel.records.v1.UpsertRequest
+	(*PatchRequest)(nil),          // 5: timetravel.records.v1.PatchRequest
+	(*GetVersionRequest)(nil),     // 6: timetravel.records.v1.GetVersionRequest
+	(*ListVersionsRequest)(nil),   // 7: timetravel.records.v1.ListVersionsRequest
+	(*ListVersionsResponse)(nil),  // 8: timetravel.records.v1.ListVersionsResponse
+	(*GetAsOfRequest)(nil),        // 9: timetravel.records.v1.GetAsOfRequest
+	(*WatchChangesRequest)(nil),   // 10: timetravel.records.v1.WatchChangesRequest
+	nil,                           // 11: timetravel.records.v1.Record.DataEntry
+	nil,                           // 12: timetravel.records.v1.RecordVersion.DataEntry
+	nil,                           // 13: timetravel.records.v1.RecordChange.DataEntry
+	nil,                           // 14: timetravel.records.v1.UpsertRequest.DataEntry
+	nil,                           // 15: timetravel.records.v1.PatchRequest.SetEntry
+	(*timestamppb.Timestamp)(nil), // 16: google.protobuf.Timestamp
+}
+var file_records_v1_records_proto_depIdxs = []int32{
+	11, // 0: timetravel.records.v1.Record.data:type_name -> timetravel.records.v1.Record.DataEntry
+	16, // 1: timetravel.records.v1.Record.created_at:type_name -> google.protobuf.Timestamp
+	16, // 2: timetravel.records.v1.Record.updated_at:type_name -> google.protobuf.Timestamp
+	12, // 3: timetravel.records.v1.RecordVersion.data:type_name -> timetravel.records.v1.RecordVersion.DataEntry
+	13, // 4: timetravel.records.v1.RecordChange.data:type_name -> timetravel.records.v1.RecordChange.DataEntry
+	16, // 5: timetravel.records.v1.RecordChange.changed_at:type_name -> google.protobuf.Timestamp
+	14, // 6: timetravel.records.v1.UpsertRequest.data:type_name -> timetravel.records.v1.UpsertRequest.DataEntry
+	15, // 7: timetravel.records.v1.PatchRequest.set:type_name -> timetravel.records.v1.PatchRequest.SetEntry
+	16, // 8: timetravel.records.v1.GetAsOfRequest.as_of:type_name -> google.protobuf.Timestamp
+	3,  // 9: timetravel.records.v1.Records.Get:input_type -> timetravel.records.v1.GetRequest
+	4,  // 10: timetravel.records.v1.Records.Upsert:input_type -> timetravel.records.v1.UpsertRequest
+	5,  // 11: timetravel.records.v1.Records.Patch:input_type -> timetravel.records.v1.PatchRequest
+	6,  // 12: timetravel.records.v1.Records.GetVersion:input_type -> timetravel.records.v1.GetVersionRequest
+	7,  // 13: timetravel.records.v1.Records.ListVersions:input_type -> timetravel.records.v1.ListVersionsRequest
+	9,  // 14: timetravel.records.v1.Records.GetAsOf:input_type -> timetravel.records.v1.GetAsOfRequest
+	10, // 15: timetravel.records.v1.Records.WatchChanges:input_type -> timetravel.records.v1.WatchChangesRequest
+	0,  // 16: timetravel.records.v1.Records.Get:output_type -> timetravel.records.v1.Record
+	0,  // 17: timetravel.records.v1.Records.Upsert:output_type -> timetravel.records.v1.Record
+	0,  // 18: timetravel.records.v1.Records.Patch:output_type -> timetravel.records.v1.Record
+	1,  // 19: timetravel.records.v1.Records.GetVersion:output_type -> timetravel.records.v1.RecordVersion
+	8,  // 20: timetravel.records.v1.Records.ListVersions:output_type -> timetravel.records.v1.ListVersionsResponse
+	2,  // 21: timetravel.records.v1.Records.GetAsOf:output_type -> timetravel.records.v1.RecordChange
+	2,  // 22: timetravel.records.v1.Records.WatchChanges:output_type -> timetravel.records.v1.RecordChange
+	16, // [16:23] is the sub-list for method output_type
+	9,  // [9:16] is the sub-list for method input_type
+	9,  // [9:9] is the sub-list for extension type_name
+	9,  // [9:9] is the sub-list for extension extendee
+	0,  // [0:9] is the sub-list for field type_name
+}
+
+func init() { file_records_v1_records_proto_init() }
+func file_records_v1_records_proto_init() {
+	if File_records_v1_records_proto != nil {
+		return
+	}
+	file_records_v1_records_proto_msgTypes[10].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_records_v1_records_proto_rawDesc), len(file_records_v1_records_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   16,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_records_v1_records_proto_goTypes,
+		DependencyIndexes: file_records_v1_records_proto_depIdxs,
+		MessageInfos:      file_records_v1_records_proto_msgTypes,
+	}.Build()
+	File_records_v1_records_proto = out.File
+	file_records_v1_records_proto_goTypes = nil
+	file_records_v1_records_proto_depIdxs = nil
+}
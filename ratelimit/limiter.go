@@ -0,0 +1,211 @@
+// Package ratelimit throttles authenticated callers with token buckets, one
+// per user and route class, so a single integration cannot monopolise the
+// SQLite writer. Limits come from config; per-user overrides replace them.
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Class groups routes that share a bucket
+type Class string
+
+const (
+	Read  Class = "read"  // GET routes, GraphQL and gRPC reads
+	Write Class = "write" // record writes
+	Admin Class = "admin" // /api/v2/admin/...
+)
+
+// Classes lists every route class
+var Classes = []Class{Read, Write, Admin}
+
+// ParseClass accepts read, write or admin
+func ParseClass(s string) (Class, error) {
+	for _, c := range Classes {
+		if string(c) == s {
+			return c, nil
+		}
+	}
+	return "", fmt.Errorf("unknown route class %q (want read, write or admin)", s)
+}
+
+// Limit refills PerMinute tokens a minute into a bucket of Burst tokens. A
+// zero PerMinute is unlimited; a zero Burst holds one minute's worth.
+type Limit struct {
+	PerMinute int `json:"per_minute"`
+	Burst     int `json:"burst"`
+}
+
+// Unlimited reports whether the limit lets everything through
+func (l Limit) Unlimited() bool { return l.PerMinute <= 0 }
+
+func (l Limit) burst() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return float64(l.PerMinute)
+}
+
+// perSecond is the refill rate
+func (l Limit) perSecond() float64 { return float64(l.PerMinute) / 60 }
+
+// Key identifies a bucket
+type Key struct {
+	UserID int64
+	Class  Class
+}
+
+// Decision is the outcome of one request against its bucket
+type Decision struct {
+	Allowed   bool
+	Limit     Limit
+	Remaining int           // whole tokens left
+	Reset     time.Duration // until the bucket is full again
+	// RetryAfter is how long a refused caller should wait for the next token
+	RetryAfter time.Duration
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	limit  Limit
+}
+
+// refill adds the tokens earned since the last request
+func (b *bucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(b.limit.burst(), b.tokens+elapsed*b.limit.perSecond())
+	}
+	b.last = now
+}
+
+// sweepInterval is how often idle, full buckets are dropped
+const sweepInterval = time.Minute
+
+// Metrics counts refused requests (observability.PrometheusRateLimitMetrics)
+type Metrics interface {
+	Throttled(class, route string)
+}
+
+// Limiter holds the buckets of every active user. It is safe for concurrent use.
+type Limiter struct {
+	mu        sync.Mutex
+	defaults  map[Class]Limit
+	overrides map[Key]Limit
+	buckets   map[Key]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+	metrics   Metrics
+}
+
+// New limits each class by defaults; classes missing from it are unlimited
+func New(defaults map[Class]Limit) *Limiter {
+	return &Limiter{
+		defaults:  defaults,
+		overrides: map[Key]Limit{},
+		buckets:   map[Key]*bucket{},
+		now:       time.Now,
+	}
+}
+
+// SetMetrics counts refusals on m
+func (l *Limiter) SetMetrics(m Metrics) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.metrics = m
+}
+
+// SetClock replaces time.Now, for tests
+func (l *Limiter) SetClock(now func() time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.now = now
+}
+
+// SetOverrides replaces every per-user override; buckets whose limit changed
+// start over full
+func (l *Limiter) SetOverrides(overrides map[Key]Limit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.overrides = overrides
+}
+
+// Defaults returns the configured limit of each class
+func (l *Limiter) Defaults() map[Class]Limit {
+	out := make(map[Class]Limit, len(Classes))
+	for _, c := range Classes {
+		out[c] = l.defaults[c]
+	}
+	return out
+}
+
+// LimitFor returns the override for key, or its class default
+func (l *Limiter) LimitFor(key Key) Limit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limitFor(key)
+}
+
+func (l *Limiter) limitFor(key Key) Limit {
+	if o, ok := l.overrides[key]; ok {
+		return o
+	}
+	return l.defaults[key.Class]
+}
+
+// Allow takes a token from key's bucket when one is left; route (a route
+// template or gRPC method) labels the refusal metric
+func (l *Limiter) Allow(key Key, route string) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limit := l.limitFor(key)
+	if limit.Unlimited() {
+		return Decision{Allowed: true, Limit: limit}
+	}
+
+	now := l.now()
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok || b.limit != limit {
+		b = &bucket{tokens: limit.burst(), last: now, limit: limit}
+		l.buckets[key] = b
+	}
+	b.refill(now)
+
+	d := Decision{Limit: limit}
+	if b.tokens >= 1 {
+		b.tokens--
+		d.Allowed = true
+	} else {
+		d.RetryAfter = seconds((1 - b.tokens) / limit.perSecond())
+		if l.metrics != nil {
+			l.metrics.Throttled(string(key.Class), route)
+		}
+	}
+	d.Remaining = int(b.tokens)
+	d.Reset = seconds((limit.burst() - b.tokens) / limit.perSecond())
+	return d
+}
+
+// sweep drops the buckets that have refilled completely; they would start
+// over full anyway
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		b.refill(now)
+		if b.tokens >= b.limit.burst() {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
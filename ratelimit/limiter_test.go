@@ -0,0 +1,145 @@
+package ratelimit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rainbowmga/timetravel/ratelimit"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+type countingMetrics struct{ throttled map[string]int }
+
+func (m *countingMetrics) Throttled(class, route string) {
+	m.throttled[class+" "+route]++
+}
+
+func newLimiter(defaults map[ratelimit.Class]ratelimit.Limit) (*ratelimit.Limiter, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := ratelimit.New(defaults)
+	l.SetClock(clock.now)
+	return l, clock
+}
+
+func TestAllowBurstAndRefill(t *testing.T) {
+	l, clock := newLimiter(map[ratelimit.Class]ratelimit.Limit{ratelimit.Write: {PerMinute: 60, Burst: 3}})
+	metrics := &countingMetrics{throttled: map[string]int{}}
+	l.SetMetrics(metrics)
+	key := ratelimit.Key{UserID: 1, Class: ratelimit.Write}
+
+	for i, wantRemaining := range []int{2, 1, 0} {
+		d := l.Allow(key, "POST /records/{id}")
+		if !d.Allowed || d.Remaining != wantRemaining {
+			t.Fatalf("request %d = %+v, want allowed with %d remaining", i+1, d, wantRemaining)
+		}
+	}
+	d := l.Allow(key, "POST /records/{id}")
+	if d.Allowed {
+		t.Fatal("request over the burst was allowed")
+	}
+	if d.RetryAfter != time.Second {
+		t.Errorf("RetryAfter = %v, want 1s at 60 a minute", d.RetryAfter)
+	}
+	if d.Reset != 3*time.Second {
+		t.Errorf("Reset = %v, want 3s to refill 3 tokens", d.Reset)
+	}
+	if got := metrics.throttled["write POST /records/{id}"]; got != 1 {
+		t.Errorf("throttled count = %d, want 1", got)
+	}
+
+	clock.advance(time.Second)
+	if d := l.Allow(key, "POST /records/{id}"); !d.Allowed {
+		t.Errorf("after one refill interval = %+v, want allowed", d)
+	}
+	if d := l.Allow(key, "POST /records/{id}"); d.Allowed {
+		t.Errorf("second request after one refill interval = %+v, want refused", d)
+	}
+
+	// refills never exceed the burst
+	clock.advance(time.Hour)
+	for i := 0; i < 3; i++ {
+		l.Allow(key, "POST /records/{id}")
+	}
+	if d := l.Allow(key, "POST /records/{id}"); d.Allowed {
+		t.Error("bucket held more than its burst after a long idle period")
+	}
+}
+
+func TestAllowKeysAreIndependent(t *testing.T) {
+	l, _ := newLimiter(map[ratelimit.Class]ratelimit.Limit{
+		ratelimit.Read:  {PerMinute: 1},
+		ratelimit.Write: {PerMinute: 1},
+	})
+	keys := []ratelimit.Key{
+		{UserID: 1, Class: ratelimit.Read},
+		{UserID: 1, Class: ratelimit.Write},
+		{UserID: 2, Class: ratelimit.Read},
+	}
+	for _, key := range keys {
+		if d := l.Allow(key, "r"); !d.Allowed {
+			t.Errorf("%+v first request refused", key)
+		}
+	}
+	for _, key := range keys {
+		if d := l.Allow(key, "r"); d.Allowed {
+			t.Errorf("%+v second request allowed", key)
+		}
+	}
+}
+
+func TestAllowOverridesAndUnlimited(t *testing.T) {
+	l, _ := newLimiter(map[ratelimit.Class]ratelimit.Limit{ratelimit.Read: {PerMinute: 1}})
+	l.SetOverrides(map[ratelimit.Key]ratelimit.Limit{
+		{UserID: 7, Class: ratelimit.Read}: {PerMinute: 120, Burst: 5},
+		{UserID: 8, Class: ratelimit.Read}: {PerMinute: 0},
+	})
+
+	tests := []struct {
+		name        string
+		key         ratelimit.Key
+		wantAllowed int
+	}{
+		{"default", ratelimit.Key{UserID: 1, Class: ratelimit.Read}, 1},
+		{"raised by override", ratelimit.Key{UserID: 7, Class: ratelimit.Read}, 5},
+		{"lifted by override", ratelimit.Key{UserID: 8, Class: ratelimit.Read}, 10},
+		{"unconfigured class", ratelimit.Key{UserID: 1, Class: ratelimit.Admin}, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed := 0
+			for i := 0; i < 10; i++ {
+				if l.Allow(tt.key, "r").Allowed {
+					allowed++
+				}
+			}
+			if allowed != tt.wantAllowed {
+				t.Errorf("allowed %d of 10, want %d", allowed, tt.wantAllowed)
+			}
+		})
+	}
+
+	// a changed limit starts the bucket over
+	key := ratelimit.Key{UserID: 1, Class: ratelimit.Read}
+	l.SetOverrides(map[ratelimit.Key]ratelimit.Limit{key: {PerMinute: 2}})
+	if got := l.LimitFor(key); got != (ratelimit.Limit{PerMinute: 2}) {
+		t.Errorf("LimitFor = %+v, want the override", got)
+	}
+	if d := l.Allow(key, "r"); !d.Allowed || d.Remaining != 1 {
+		t.Errorf("after override = %+v, want a fresh bucket of 2", d)
+	}
+}
+
+func TestParseClass(t *testing.T) {
+	for _, c := range ratelimit.Classes {
+		if got, err := ratelimit.ParseClass(string(c)); err != nil || got != c {
+			t.Errorf("ParseClass(%q) = %q, %v", c, got, err)
+		}
+	}
+	if _, err := ratelimit.ParseClass("delete"); err == nil {
+		t.Error("ParseClass(delete) succeeded")
+	}
+}
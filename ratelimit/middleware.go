@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/auth"
+	"github.com/rainbowmga/timetravel/observability"
+)
+
+// ClassOf picks the bucket of an HTTP request: admin routes, reads (GET, the
+// read-only GraphQL endpoint and POST /health pings) and writes
+func ClassOf(r *http.Request) Class {
+	switch {
+	case strings.Contains(r.URL.Path, "/admin/"):
+		return Admin
+	case r.Method == http.MethodGet || r.Method == http.MethodHead,
+		strings.HasSuffix(r.URL.Path, "/graphql"),
+		strings.HasSuffix(r.URL.Path, "/health"):
+		return Read
+	}
+	return Write
+}
+
+// Middleware throttles each authenticated principal per route class and
+// answers RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and
+// RateLimit-Policy on every limited response. Refusals are 429 problems with
+// Retry-After. It must run after auth.Middleware; requests without a
+// principal pass through.
+func Middleware(l *Limiter) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, ok := auth.FromContext(r.Context())
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			class := ClassOf(r)
+			d := l.Allow(Key{UserID: p.UserID, Class: class}, routeTemplate(r))
+			if d.Limit.Unlimited() {
+				next.ServeHTTP(w, r)
+				return
+			}
+			SetHeaders(w.Header(), d)
+			if !d.Allowed {
+				observability.DefaultLogger.WarnContext(r.Context(), "rate_limited",
+					"class", class, "per_minute", d.Limit.PerMinute, "retry_after_ms", d.RetryAfter.Milliseconds())
+				apperr.WriteProblem(w, r, Exceeded(class, d))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Exceeded is the error of a refused request; it carries the back-off hint
+func Exceeded(class Class, d Decision) error {
+	return apperr.ResourceExhausted(
+		fmt.Sprintf("rate limit of %d %s requests a minute exceeded", d.Limit.PerMinute, class), d.RetryAfter)
+}
+
+// SetHeaders writes the RateLimit-* fields of the IETF ratelimit-headers draft
+func SetHeaders(h http.Header, d Decision) {
+	burst := int(d.Limit.burst())
+	h.Set("RateLimit-Limit", strconv.Itoa(burst))
+	h.Set("RateLimit-Remaining", strconv.Itoa(d.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(int(math.Ceil(d.Reset.Seconds()))))
+	h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=60;burst=%d", d.Limit.PerMinute, burst))
+}
+
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			return r.Method + " " + tpl
+		}
+	}
+	return r.Method + " " + r.URL.Path
+}
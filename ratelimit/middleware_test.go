@@ -0,0 +1,98 @@
+package ratelimit_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/rainbowmga/timetravel/auth"
+	"github.com/rainbowmga/timetravel/ratelimit"
+)
+
+func TestClassOf(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   ratelimit.Class
+	}{
+		{http.MethodGet, "/api/v2/records/1", ratelimit.Read},
+		{http.MethodHead, "/api/v2/records/1", ratelimit.Read},
+		{http.MethodPost, "/api/v2/graphql", ratelimit.Read},
+		{http.MethodPost, "/api/v2/health", ratelimit.Read},
+		{http.MethodPost, "/api/v2/records/1", ratelimit.Write},
+		{http.MethodPatch, "/api/v2/records/1", ratelimit.Write},
+		{http.MethodGet, "/api/v2/admin/rate-limits", ratelimit.Admin},
+		{http.MethodPut, "/api/v2/admin/users/1/rate-limits/read", ratelimit.Admin},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			if got := ratelimit.ClassOf(httptest.NewRequest(tt.method, tt.path, nil)); got != tt.want {
+				t.Errorf("ClassOf = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	l, _ := newLimiter(map[ratelimit.Class]ratelimit.Limit{ratelimit.Write: {PerMinute: 30, Burst: 2}})
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v2/records/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	router.Use(ratelimit.Middleware(l))
+
+	do := func(method string, user int64) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/api/v2/records/1", nil)
+		if user > 0 {
+			r = r.WithContext(auth.WithPrincipal(r.Context(), auth.Principal{UserID: user}))
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	w := do(http.MethodPost, 1)
+	if w.Code != http.StatusOK {
+		t.Fatalf("first write = %d, want 200", w.Code)
+	}
+	for field, want := range map[string]string{
+		"RateLimit-Limit":     "2",
+		"RateLimit-Remaining": "1",
+		"RateLimit-Reset":     "2",
+		"RateLimit-Policy":    "30;w=60;burst=2",
+	} {
+		if got := w.Header().Get(field); got != want {
+			t.Errorf("%s = %q, want %q", field, got, want)
+		}
+	}
+
+	do(http.MethodPost, 1)
+	w = do(http.MethodPost, 1)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("third write = %d, want 429", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After = %q, want 2", got)
+	}
+	if got := w.Header().Get("RateLimit-Remaining"); got != "0" {
+		t.Errorf("RateLimit-Remaining = %q, want 0", got)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/problem+json") {
+		t.Errorf("Content-Type = %q, want a problem", ct)
+	}
+	if !strings.Contains(w.Body.String(), "30 write requests a minute") {
+		t.Errorf("body = %s, want the exceeded limit", w.Body)
+	}
+
+	// reads are unlimited here: no headers; anonymous requests are left to auth
+	w = do(http.MethodGet, 1)
+	if w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "" {
+		t.Errorf("read = %d with RateLimit-Limit %q, want 200 without headers", w.Code, w.Header().Get("RateLimit-Limit"))
+	}
+	if w = do(http.MethodPost, 0); w.Code != http.StatusOK {
+		t.Errorf("anonymous write = %d, want 200", w.Code)
+	}
+}
@@ -0,0 +1,322 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rainbowmga/timetravel/common"
+)
+
+// Redacted replaces captured header values and body fields that must not
+// reach a log
+const Redacted = "[REDACTED]"
+
+// DefaultRedactHeaders are headers that carry credentials
+var DefaultRedactHeaders = []string{"Authorization", "Cookie", "X-Api-Key"}
+
+// headers that describe one connection or request rather than the call, so
+// replays get their own
+var skipHeaders = map[string]bool{"Content-Length": true, "Connection": true, "X-Request-Id": true}
+
+// CaptureOptions tune a Capturer; zero values use the default redactions and
+// limits, and SampleRate must be set for anything to be captured
+type CaptureOptions struct {
+	// SampleRate is the fraction of requests captured, from 0 (none) to 1 (every request)
+	SampleRate float64
+	// Paths are path prefixes to capture; empty means all paths
+	Paths []string
+	// SkipPaths are path prefixes never captured, e.g. endpoints whose
+	// responses carry secrets
+	SkipPaths []string
+	// RedactHeaders are replaced by Redacted; nil means DefaultRedactHeaders
+	RedactHeaders []string
+	// RedactKeys are JSON object keys replaced by Redacted at any depth, in
+	// request and response bodies
+	RedactKeys []string
+	// MaxBodyBytes bounds captured bodies (default 64 KiB). Requests with a
+	// larger body are not captured; larger responses keep only their status.
+	MaxBodyBytes int
+	// BufferSize is how many entries wait for the writer before new ones
+	// are dropped (default 1000)
+	BufferSize int
+	// ErrorLog reports failed writes; nil discards them
+	ErrorLog func(error)
+}
+
+// Capturer records sampled requests and their answers as replay log entries.
+// Entries are written from a background goroutine so requests never wait on
+// the file.
+type Capturer struct {
+	out           io.WriteCloser
+	opts          CaptureOptions
+	redactHeaders map[string]bool
+	redactKeys    map[string]bool
+	entries       chan Entry
+	done          chan struct{}
+	mu            sync.RWMutex // guards closed against sends racing Close
+	closed        bool
+	dropped       atomic.Int64
+}
+
+// NewCapturer starts writing entries to out; call Close to flush and close it
+func NewCapturer(out io.WriteCloser, opts CaptureOptions) *Capturer {
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = 64 * 1024
+	}
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = 1000
+	}
+	if opts.RedactHeaders == nil {
+		opts.RedactHeaders = DefaultRedactHeaders
+	}
+	c := &Capturer{
+		out:           out,
+		opts:          opts,
+		redactHeaders: make(map[string]bool, len(opts.RedactHeaders)),
+		redactKeys:    make(map[string]bool, len(opts.RedactKeys)),
+		entries:       make(chan Entry, opts.BufferSize),
+		done:          make(chan struct{}),
+	}
+	for _, h := range opts.RedactHeaders {
+		c.redactHeaders[http.CanonicalHeaderKey(h)] = true
+	}
+	for _, k := range opts.RedactKeys {
+		c.redactKeys[strings.ToLower(k)] = true
+	}
+	go c.run()
+	return c
+}
+
+// Dropped counts sampled requests that were not written: the buffer was
+// full, or the request body was over MaxBodyBytes
+func (c *Capturer) Dropped() int64 {
+	return c.dropped.Load()
+}
+
+// Close writes the queued entries and closes the output
+func (c *Capturer) Close() {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.closed = true
+	close(c.entries)
+	c.mu.Unlock()
+
+	<-c.done
+	if err := c.out.Close(); err != nil {
+		c.report(err)
+	}
+}
+
+func (c *Capturer) run() {
+	defer close(c.done)
+	for e := range c.entries {
+		if err := WriteEntry(c.out, e); err != nil {
+			c.report(err)
+		}
+	}
+}
+
+func (c *Capturer) report(err error) {
+	if c.opts.ErrorLog != nil {
+		c.opts.ErrorLog(err)
+	}
+}
+
+// Middleware captures the requests it samples; the rest pass straight through
+func (c *Capturer) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !c.wants(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		body, complete := c.peekBody(r)
+		rec := &captureWriter{ResponseWriter: w, status: http.StatusOK, max: c.opts.MaxBodyBytes}
+		next.ServeHTTP(rec, r)
+		latency := time.Since(start)
+
+		if !complete {
+			c.dropped.Add(1)
+			return
+		}
+		c.enqueue(c.entry(r, body, rec, start, latency))
+	})
+}
+
+func (c *Capturer) wants(r *http.Request) bool {
+	for _, prefix := range c.opts.SkipPaths {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			return false
+		}
+	}
+	if len(c.opts.Paths) > 0 {
+		matched := false
+		for _, prefix := range c.opts.Paths {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return c.opts.SampleRate >= 1 || (c.opts.SampleRate > 0 && rand.Float64() < c.opts.SampleRate)
+}
+
+// peekBody reads up to MaxBodyBytes of the request body and puts it back for
+// the handler; complete is false when the body is longer
+func (c *Capturer) peekBody(r *http.Request) (body []byte, complete bool) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, true
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, int64(c.opts.MaxBodyBytes)+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+	if err != nil || len(body) > c.opts.MaxBodyBytes {
+		return nil, false
+	}
+	return body, true
+}
+
+func (c *Capturer) enqueue(e Entry) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.closed {
+		return
+	}
+	select {
+	case c.entries <- e:
+	default:
+		c.dropped.Add(1)
+	}
+}
+
+func (c *Capturer) entry(r *http.Request, body []byte, rec *captureWriter, start time.Time, latency time.Duration) Entry {
+	at := start.UTC()
+	e := Entry{
+		Method:     r.Method,
+		Path:       r.URL.RequestURI(),
+		CapturedAt: &at,
+		Expect: &Expectation{
+			Status:    rec.status,
+			LatencyMS: float64(latency.Microseconds()) / 1000,
+		},
+	}
+	if id, ok := common.GetRequestID(r.Context()); ok {
+		e.ID = id
+	}
+
+	for k, vs := range r.Header {
+		if skipHeaders[k] {
+			continue
+		}
+		if e.Header == nil {
+			e.Header = map[string]string{}
+		}
+		if c.redactHeaders[k] {
+			e.Header[k] = Redacted
+		} else {
+			e.Header[k] = strings.Join(vs, ", ")
+		}
+	}
+
+	if len(body) > 0 {
+		if redacted, ok := c.redactJSON(body); ok {
+			e.Body = redacted
+		} else {
+			e.BodyText = string(body)
+		}
+	}
+	if !rec.overflow {
+		// only JSON answers can be compared on replay
+		if redacted, ok := c.redactJSON(rec.body.Bytes()); ok {
+			e.Expect.Body = redacted
+		}
+	}
+	return e
+}
+
+// redactJSON compacts a JSON body with the redacted keys replaced; ok is
+// false when the body is not JSON
+func (c *Capturer) redactJSON(body []byte) (json.RawMessage, bool) {
+	if !json.Valid(body) {
+		return nil, false
+	}
+	if len(c.redactKeys) == 0 {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, body); err != nil {
+			return nil, false
+		}
+		return buf.Bytes(), true
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, false
+	}
+	out, err := json.Marshal(c.redactValue(v))
+	if err != nil {
+		return nil, false
+	}
+	return out, true
+}
+
+func (c *Capturer) redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, field := range v {
+			if c.redactKeys[strings.ToLower(k)] {
+				v[k] = Redacted
+			} else {
+				v[k] = c.redactValue(field)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = c.redactValue(v[i])
+		}
+	}
+	return v
+}
+
+// captureWriter keeps the status and up to max bytes of the response
+type captureWriter struct {
+	http.ResponseWriter
+	status   int
+	max      int
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (w *captureWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *captureWriter) Write(b []byte) (int, error) {
+	if !w.overflow {
+		if w.body.Len()+len(b) > w.max {
+			w.overflow = true
+			w.body.Reset()
+		} else {
+			w.body.Write(b)
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,197 @@
+package replay_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rainbowmga/timetravel/replay"
+)
+
+// buffer is an in-memory capture output
+type buffer struct{ bytes.Buffer }
+
+func (*buffer) Close() error { return nil }
+
+func TestCapturer_RecordsReplayableEntries(t *testing.T) {
+	var out buffer
+	c := replay.NewCapturer(&out, replay.CaptureOptions{
+		SampleRate: 1,
+		Paths:      []string{"/records/", "/missing"},
+		SkipPaths:  []string{"/records/secret"},
+		RedactKeys: []string{"email"},
+	})
+	// answers JSON bodies back with the server's own contact email
+	server := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if !json.Valid(body) {
+			body, _ = json.Marshal(string(body))
+		}
+		fmt.Fprintf(w, `{"echo": %s, "email": "ops@example.com"}`, body)
+	})
+	var seen []string
+	handler := c.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		seen = append(seen, string(body))
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		server.ServeHTTP(w, r)
+	}))
+
+	send := func(method, path, body string, header map[string]string) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	send("POST", "/records/1?x=1", `{"name": "ada", "contact": {"Email": "ada@example.com"}}`,
+		map[string]string{"X-User-ID": "9", "Authorization": "Bearer secret", "X-Request-ID": "abc"})
+	send("POST", "/records/2", "not json", nil)
+	send("GET", "/missing", "", nil)
+	send("GET", "/metrics", "", nil)
+	send("GET", "/records/secret/1", "", nil)
+	c.Close()
+
+	if seen[0] != `{"name": "ada", "contact": {"Email": "ada@example.com"}}` {
+		t.Errorf("handler read %q, want the body untouched", seen[0])
+	}
+	if strings.Contains(out.String(), "secret") || strings.Contains(out.String(), "@example.com") {
+		t.Errorf("capture leaked a redacted value:\n%s", out.String())
+	}
+
+	entries, err := replay.ReadLog(strings.NewReader(out.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("captured %d entries, want 3 (/metrics and /records/secret are not captured):\n%s", len(entries), out.String())
+	}
+	first := entries[0]
+	if first.Path != "/records/1?x=1" || first.Header["Authorization"] != replay.Redacted || first.Header["X-User-Id"] != "9" {
+		t.Errorf("first entry = %+v", first)
+	}
+	if _, ok := first.Header["X-Request-Id"]; ok {
+		t.Errorf("X-Request-ID was captured: %v", first.Header)
+	}
+	if string(first.Body) != `{"contact":{"Email":"[REDACTED]"},"name":"ada"}` {
+		t.Errorf("first body = %s", first.Body)
+	}
+	if first.CapturedAt == nil || first.Expect.Status != 200 || first.Expect.LatencyMS <= 0 {
+		t.Errorf("first expectation = %+v at %v", first.Expect, first.CapturedAt)
+	}
+	if entries[1].BodyText != "not json" || entries[2].Expect.Status != 404 || entries[2].Expect.Body != nil {
+		t.Errorf("entries = %+v, %+v", entries[1], entries[2])
+	}
+
+	if string(first.Expect.Body) != `{"echo":{"contact":{"Email":"[REDACTED]"},"name":"ada"},"email":"[REDACTED]"}` {
+		t.Errorf("first response = %s", first.Expect.Body)
+	}
+
+	// the log replays cleanly: redacted fields match whatever comes back
+	report := replay.Run(context.Background(), replay.HandlerTarget{Handler: server}, entries, replay.Options{Compare: true})
+	if err := report.Err(); err != nil {
+		t.Errorf("replaying the capture: %v %+v", err, report.Failures)
+	}
+}
+
+func TestCapturer_SamplingAndLimits(t *testing.T) {
+	var out buffer
+	c := replay.NewCapturer(&out, replay.CaptureOptions{SampleRate: 0.5, MaxBodyBytes: 8})
+	handler := c.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, `{"n": %d}`, len(body))
+	}))
+	for i := 0; i < 400; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/x", nil))
+	}
+	c.Close()
+	if n := strings.Count(out.String(), "\n"); n < 120 || n > 280 {
+		t.Errorf("captured %d of 400 requests at sample rate 0.5", n)
+	}
+
+	out.Reset()
+	c = replay.NewCapturer(&out, replay.CaptureOptions{SampleRate: 0})
+	handler = c.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for i := 0; i < 10; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/x", nil))
+	}
+	c.Close()
+	if out.Len() != 0 {
+		t.Errorf("sample rate 0 captured %q, want nothing", out.String())
+	}
+
+	out.Reset()
+	c = replay.NewCapturer(&out, replay.CaptureOptions{SampleRate: 1, MaxBodyBytes: 8})
+	handler = c.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, `{"n": %d, "padding": "longer than eight bytes"}`, len(body))
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/x", strings.NewReader(`"0123456789"`)))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/x", strings.NewReader(`"short"`)))
+	c.Close()
+
+	if !strings.Contains(rec.Body.String(), `"n": 12`) {
+		t.Errorf("handler saw %s, want the whole oversized body", rec.Body.String())
+	}
+	entries, err := replay.ReadLog(strings.NewReader(out.String()))
+	if err != nil || len(entries) != 1 || c.Dropped() != 1 {
+		t.Fatalf("entries = %+v, dropped %d, %v; want only the short request", entries, c.Dropped(), err)
+	}
+	if entries[0].Expect.Body != nil {
+		t.Errorf("oversized response body was captured: %s", entries[0].Expect.Body)
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture", "log.jsonl")
+	rf, err := replay.OpenRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"aaaa\n", "bbbb\n", "cccc\n", "dddd\n", "eeeeeeeeeeee\n", "ffff\n"} {
+		if _, err := io.WriteString(rf, line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rf.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"": "ffff\n", ".1": "eeeeeeeeeeee\n", ".2": "cccc\ndddd\n", ".3": ""}
+	for suffix, content := range want {
+		b, err := os.ReadFile(path + suffix)
+		if content == "" {
+			if !os.IsNotExist(err) {
+				t.Errorf("%s exists, want at most 2 rotated files", path+suffix)
+			}
+			continue
+		}
+		if string(b) != content {
+			t.Errorf("%s = %q, %v; want %q", path+suffix, b, err, content)
+		}
+	}
+
+	// reopening appends
+	rf, err = replay.OpenRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(rf, "gg\n")
+	rf.Close()
+	if b, _ := os.ReadFile(path); string(b) != "ffff\ngg\n" {
+		t.Errorf("after reopen %s = %q", path, b)
+	}
+}
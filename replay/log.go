@@ -0,0 +1,100 @@
+// Package replay re-sends a recorded request log to a server, checks the
+// answers against the recorded expectations and measures latency.
+//
+// A log is JSON Lines, one request per line:
+//
+//	{"id": "create-7", "method": "POST", "path": "/api/v2/records/7",
+//	 "header": {"X-User-ID": "1"}, "body": {"name": "ada"},
+//	 "expect": {"status": 200, "body": {"version": 1, "data": {"name": "ada"}}}}
+//
+// body is sent as JSON; body_text is sent verbatim instead, for payloads that
+// are not JSON. expect is optional, and its body only needs to hold the
+// fields worth checking (see Match). Blank lines and lines starting with #
+// are skipped.
+package replay
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Entry is one recorded request
+type Entry struct {
+	ID       string            `json:"id,omitempty"`
+	Method   string            `json:"method"`
+	Path     string            `json:"path"` // with the query string
+	Header   map[string]string `json:"header,omitempty"`
+	Body     json.RawMessage   `json:"body,omitempty"`
+	BodyText string            `json:"body_text,omitempty"`
+	Expect   *Expectation      `json:"expect,omitempty"`
+
+	// CapturedAt is set on entries written by a Capturer
+	CapturedAt *time.Time `json:"captured_at,omitempty"`
+
+	// Line is the entry's line in its log, for reports
+	Line int `json:"-"`
+}
+
+// Expectation is the recorded answer to an Entry
+type Expectation struct {
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body,omitempty"`
+	// LatencyMS is how long the captured request took; it is not compared
+	LatencyMS float64 `json:"latency_ms,omitempty"`
+}
+
+// Name identifies the entry in reports: its id, else its line and request
+func (e Entry) Name() string {
+	if e.ID != "" {
+		return e.ID
+	}
+	return fmt.Sprintf("line %d: %s %s", e.Line, e.Method, e.Path)
+}
+
+// ReadLog parses a request log; a malformed line fails the whole read so a
+// replay never silently runs part of a log
+func ReadLog(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal([]byte(text), &e); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if e.Method == "" || !strings.HasPrefix(e.Path, "/") {
+			return nil, fmt.Errorf("line %d: method and a path starting with / are required", line)
+		}
+		if len(e.Body) > 0 && e.BodyText != "" {
+			return nil, fmt.Errorf("line %d: body and body_text are exclusive", line)
+		}
+		if string(e.Body) == "null" {
+			e.Body = nil
+		}
+		e.Method = strings.ToUpper(e.Method)
+		e.Line = line
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// WriteEntry appends one entry to a log as a single line
+func WriteEntry(w io.Writer, e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,89 @@
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// DefaultIgnore are fields whose values differ on every run: timestamps and
+// per-request ids
+var DefaultIgnore = []string{"created_at", "updated_at", "changed_at", "timestamp", "request_id", "instance"}
+
+// Match reports how actual differs from the expected JSON, or "" if it does
+// not. Objects match when every expected key is present with a matching value,
+// so extra fields in actual are fine; keys named in ignore are skipped at any
+// depth. Arrays must match element by element, and scalars exactly; an
+// expected Redacted string matches any value, so captured logs still compare.
+func Match(expected, actual []byte, ignore map[string]bool) string {
+	var want, got interface{}
+	if err := json.Unmarshal(expected, &want); err != nil {
+		return fmt.Sprintf("expected body is not JSON: %v", err)
+	}
+	if err := json.Unmarshal(actual, &got); err != nil {
+		return fmt.Sprintf("response body is not JSON: %.80q", actual)
+	}
+	return matchValue("$", want, got, ignore)
+}
+
+func matchValue(path string, want, got interface{}, ignore map[string]bool) string {
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			return fmt.Sprintf("%s: expected an object, got %s", path, describe(got))
+		}
+		keys := make([]string, 0, len(w))
+		for k := range w {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if ignore[k] {
+				continue
+			}
+			gv, ok := g[k]
+			if !ok {
+				return fmt.Sprintf("%s.%s: missing", path, k)
+			}
+			if diff := matchValue(path+"."+k, w[k], gv, ignore); diff != "" {
+				return diff
+			}
+		}
+		return ""
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok {
+			return fmt.Sprintf("%s: expected an array, got %s", path, describe(got))
+		}
+		if len(g) != len(w) {
+			return fmt.Sprintf("%s: expected %d elements, got %d", path, len(w), len(g))
+		}
+		for i := range w {
+			if diff := matchValue(fmt.Sprintf("%s[%d]", path, i), w[i], g[i], ignore); diff != "" {
+				return diff
+			}
+		}
+		return ""
+	default:
+		if want == Redacted {
+			return ""
+		}
+		if !reflect.DeepEqual(want, got) {
+			return fmt.Sprintf("%s: expected %s, got %s", path, describe(want), describe(got))
+		}
+		return ""
+	}
+}
+
+// describe renders a decoded JSON value briefly for a mismatch message
+func describe(v interface{}) string {
+	b, _ := json.Marshal(v)
+	s := string(b)
+	if len(s) > 80 {
+		s = s[:77] + "..."
+	}
+	return strings.TrimSpace(s)
+}
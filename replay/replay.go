@@ -0,0 +1,224 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Target answers replayed requests
+type Target interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// URLTarget sends requests to a running server
+type URLTarget struct {
+	Base   string // e.g. http://localhost:8000
+	Client *http.Client
+}
+
+func (t URLTarget) Do(req *http.Request) (*http.Response, error) {
+	u, err := req.URL.Parse(strings.TrimRight(t.Base, "/") + req.URL.RequestURI())
+	if err != nil {
+		return nil, err
+	}
+	req.URL = u
+	req.Host = u.Host
+	client := t.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
+
+// HandlerTarget serves requests in-process, e.g. with the router of an
+// app.BuildApp, so latency excludes the network
+type HandlerTarget struct {
+	Handler http.Handler
+}
+
+func (t HandlerTarget) Do(req *http.Request) (*http.Response, error) {
+	// make it look like a request a server read off the wire
+	if req.Body == nil {
+		req.Body = http.NoBody
+	}
+	req.RequestURI = req.URL.RequestURI()
+	req.RemoteAddr = "127.0.0.1:0"
+	rec := httptest.NewRecorder()
+	t.Handler.ServeHTTP(rec, req)
+	return rec.Result(), nil
+}
+
+// Options tune a replay; zero values mean one worker, no rate limit, one pass
+type Options struct {
+	Concurrency int
+	// Rate caps requests started per second across all workers; 0 is unlimited
+	Rate float64
+	// Repeat sends the whole log this many times, one pass after another
+	Repeat int
+	// Timeout bounds each request; 0 means none
+	Timeout time.Duration
+	// Header is added to entries that do not set it, e.g. a default X-User-ID
+	Header map[string]string
+	// Compare checks responses against entries' expectations
+	Compare bool
+	// Ignore lists object keys Match skips; nil means DefaultIgnore
+	Ignore []string
+}
+
+// Result is the outcome of one replayed request
+type Result struct {
+	Entry    Entry
+	Pass     int // 1-based
+	Status   int
+	Latency  time.Duration
+	Err      error  // transport failure
+	Mismatch string // how the response differed from the expectation
+}
+
+// Run replays entries against target and summarizes the results. With one
+// worker requests go out in log order, which stateful logs (create, then
+// update) rely on; more workers interleave them.
+func Run(ctx context.Context, target Target, entries []Entry, opts Options) *Report {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+	if opts.Repeat <= 0 {
+		opts.Repeat = 1
+	}
+	ignoreList := opts.Ignore
+	if ignoreList == nil {
+		ignoreList = DefaultIgnore
+	}
+	ignore := make(map[string]bool, len(ignoreList))
+	for _, k := range ignoreList {
+		ignore[k] = true
+	}
+
+	type job struct {
+		entry Entry
+		pass  int
+	}
+	jobs := make(chan job)
+	results := make(chan Result)
+
+	start := time.Now()
+	go func() {
+		defer close(jobs)
+		var tick <-chan time.Time
+		if opts.Rate > 0 {
+			ticker := time.NewTicker(time.Duration(float64(time.Second) / opts.Rate))
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+		for pass := 1; pass <= opts.Repeat; pass++ {
+			for _, e := range entries {
+				if tick != nil {
+					select {
+					case <-tick:
+					case <-ctx.Done():
+						return
+					}
+				}
+				select {
+				case jobs <- job{entry: e, pass: pass}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				r := send(ctx, target, j.entry, opts, ignore)
+				r.Pass = j.pass
+				results <- r
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	report := newReport()
+	for r := range results {
+		report.add(r)
+	}
+	report.finish(time.Since(start))
+	return report
+}
+
+// send replays one entry and checks the answer
+func send(ctx context.Context, target Target, e Entry, opts Options, ignore map[string]bool) Result {
+	res := Result{Entry: e}
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	var body io.Reader
+	switch {
+	case len(e.Body) > 0:
+		body = bytes.NewReader(e.Body)
+	case e.BodyText != "":
+		body = strings.NewReader(e.BodyText)
+	}
+	req, err := http.NewRequestWithContext(ctx, e.Method, e.Path, body)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	for k, v := range opts.Header {
+		req.Header.Set(k, v)
+	}
+	for k, v := range e.Header {
+		req.Header.Set(k, v)
+	}
+	if len(e.Body) > 0 && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	started := time.Now()
+	resp, err := target.Do(req)
+	if err != nil {
+		res.Latency = time.Since(started)
+		res.Err = err
+		return res
+	}
+	got, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	res.Latency = time.Since(started)
+	res.Status = resp.StatusCode
+	if err != nil {
+		res.Err = fmt.Errorf("read response: %w", err)
+		return res
+	}
+
+	if opts.Compare && e.Expect != nil {
+		res.Mismatch = compare(*e.Expect, resp.StatusCode, got, ignore)
+	}
+	return res
+}
+
+func compare(want Expectation, status int, body []byte, ignore map[string]bool) string {
+	if want.Status != 0 && want.Status != status {
+		return fmt.Sprintf("status: expected %d, got %d", want.Status, status)
+	}
+	if len(want.Body) == 0 {
+		return ""
+	}
+	return Match(want.Body, body, ignore)
+}
@@ -0,0 +1,197 @@
+package replay_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rainbowmga/timetravel/replay"
+)
+
+func TestReadLog(t *testing.T) {
+	entries, err := replay.ReadLog(strings.NewReader(`
+# comment
+{"id": "a", "method": "post", "path": "/x", "body": {"k": "v"}, "expect": {"status": 201}}
+
+{"method": "GET", "path": "/y?z=1", "body": null}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	if e := entries[0]; e.Method != "POST" || e.Line != 3 || e.Expect.Status != 201 || string(e.Body) != `{"k": "v"}` {
+		t.Errorf("first entry = %+v", e)
+	}
+	if e := entries[1]; e.Body != nil || e.Name() != "line 5: GET /y?z=1" {
+		t.Errorf("second entry = %+v, name %q", e, e.Name())
+	}
+
+	for _, bad := range []string{
+		`{"method": "GET"}`,
+		`{"method": "GET", "path": "x"}`,
+		`{"method": "POST", "path": "/x", "body": {}, "body_text": "y"}`,
+		`not json`,
+	} {
+		if _, err := replay.ReadLog(strings.NewReader("\n" + bad)); err == nil || !strings.Contains(err.Error(), "line 2") {
+			t.Errorf("ReadLog(%s) error = %v, want a line 2 error", bad, err)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	ignore := map[string]bool{"updated_at": true}
+	tests := []struct {
+		name     string
+		expected string
+		actual   string
+		want     string // substring of the difference; "" for a match
+	}{
+		{"extra fields allowed", `{"a": 1}`, `{"a": 1, "b": 2}`, ""},
+		{"nested", `{"data": {"name": "ada"}}`, `{"data": {"name": "ada", "x": "y"}}`, ""},
+		{"ignored key", `{"updated_at": "then"}`, `{"updated_at": "now"}`, ""},
+		{"ignored at depth", `{"r": {"updated_at": "then"}}`, `{"r": {}}`, ""},
+		{"redacted matches any value", `{"email": "[REDACTED]"}`, `{"email": {"a": 1}}`, ""},
+		{"redacted key must exist", `{"email": "[REDACTED]"}`, `{}`, "$.email: missing"},
+		{"changed value", `{"data": {"name": "ada"}}`, `{"data": {"name": "grace"}}`, `$.data.name: expected "ada", got "grace"`},
+		{"missing key", `{"version": 2}`, `{}`, "$.version: missing"},
+		{"array length", `[1, 2]`, `[1, 2, 3]`, "$: expected 2 elements, got 3"},
+		{"array element", `{"v": [1, {"k": "a"}]}`, `{"v": [1, {"k": "b"}]}`, "$.v[1].k"},
+		{"type", `{"a": {}}`, `{"a": 1}`, "$.a: expected an object, got 1"},
+		{"not json", `{}`, `<html>`, "not JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := replay.Match([]byte(tt.expected), []byte(tt.actual), ignore)
+			if (tt.want == "") != (got == "") || !strings.Contains(got, tt.want) {
+				t.Errorf("Match() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// echo answers 200 with the request as JSON; /missing is 404
+func echo() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"method": r.Method,
+			"user":   r.Header.Get("X-User-ID"),
+			"body":   string(body),
+		})
+	})
+}
+
+func TestRun_ComparesAndReports(t *testing.T) {
+	entries, err := replay.ReadLog(strings.NewReader(`
+{"id": "ok", "method": "POST", "path": "/records/1", "body": {"a": "1"}, "expect": {"status": 200, "body": {"method": "POST", "user": "9", "body": "{\"a\": \"1\"}"}}}
+{"id": "own-user", "method": "GET", "path": "/records/2", "header": {"X-User-ID": "3"}, "expect": {"body": {"user": "3"}}}
+{"id": "wrong-status", "method": "GET", "path": "/missing", "expect": {"status": 200}}
+{"id": "wrong-body", "method": "GET", "path": "/records/3", "expect": {"body": {"method": "PUT"}}}
+{"id": "unchecked", "method": "GET", "path": "/missing"}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := replay.Run(context.Background(), replay.HandlerTarget{Handler: echo()}, entries, replay.Options{
+		Compare: true,
+		Header:  map[string]string{"X-User-ID": "9"},
+	})
+
+	if report.Requests != 5 || report.Mismatches != 2 || report.Errors != 0 {
+		t.Fatalf("report = %+v", report)
+	}
+	if report.Statuses[200] != 3 || report.Statuses[404] != 2 {
+		t.Errorf("statuses = %v", report.Statuses)
+	}
+	if len(report.Failures) != 2 || report.Failures[0].Entry != "wrong-status" || report.Failures[1].Reason != `$.method: expected "PUT", got "GET"` {
+		t.Errorf("failures = %+v", report.Failures)
+	}
+	if !errors.Is(report.Err(), replay.ErrFailed) {
+		t.Errorf("Err() = %v, want ErrFailed", report.Err())
+	}
+
+	var endpoints []string
+	for _, e := range report.Endpoints {
+		endpoints = append(endpoints, e.Endpoint)
+	}
+	if got := strings.Join(endpoints, ","); got != "GET /missing,GET /records/{n},POST /records/{n}" {
+		t.Errorf("endpoints = %s", got)
+	}
+
+	var out strings.Builder
+	if err := report.WriteText(&out); err != nil || !strings.Contains(out.String(), "wrong-body") {
+		t.Errorf("WriteText = %q, %v", out.String(), err)
+	}
+
+	report = replay.Run(context.Background(), replay.HandlerTarget{Handler: echo()}, entries, replay.Options{})
+	if report.Mismatches != 0 || report.Err() != nil {
+		t.Errorf("without Compare: %+v", report)
+	}
+}
+
+func TestRun_ConcurrencyRepeatAndRate(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	})
+	entries := []replay.Entry{{Method: "GET", Path: "/a"}, {Method: "GET", Path: "/b"}}
+
+	report := replay.Run(context.Background(), replay.HandlerTarget{Handler: slow}, entries, replay.Options{Concurrency: 4, Repeat: 4})
+	if report.Requests != 8 {
+		t.Errorf("requests = %d, want 8", report.Requests)
+	}
+	if peak.Load() < 2 {
+		t.Errorf("peak concurrency = %d, want several workers", peak.Load())
+	}
+	if report.Latency.P50 < 10 || report.Latency.Max < report.Latency.P50 {
+		t.Errorf("latency = %+v", report.Latency)
+	}
+
+	start := time.Now()
+	report = replay.Run(context.Background(), replay.HandlerTarget{Handler: echo()}, entries, replay.Options{Repeat: 3, Rate: 100})
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("6 requests at 100/s took %s, want at least 50ms", elapsed)
+	}
+	if report.Requests != 6 {
+		t.Errorf("requests = %d, want 6", report.Requests)
+	}
+}
+
+func TestRun_URLTarget(t *testing.T) {
+	srv := httptest.NewServer(echo())
+	entries := []replay.Entry{{Method: "GET", Path: "/x?y=1", Expect: &replay.Expectation{Status: 200, Body: json.RawMessage(`{"method": "GET"}`)}}}
+
+	report := replay.Run(context.Background(), replay.URLTarget{Base: srv.URL + "/"}, entries, replay.Options{Compare: true})
+	if report.Err() != nil || report.Statuses[200] != 1 {
+		t.Errorf("report = %+v", report)
+	}
+
+	srv.Close()
+	report = replay.Run(context.Background(), replay.URLTarget{Base: srv.URL}, entries, replay.Options{Compare: true})
+	if report.Errors != 1 || len(report.Failures) != 1 {
+		t.Errorf("after close: %+v", report)
+	}
+}
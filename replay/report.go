@@ -0,0 +1,190 @@
+package replay
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// ErrFailed is returned by Report.Err when any request errored or mismatched
+var ErrFailed = errors.New("replay failed")
+
+// maxFailures bounds how many failures a report keeps; counts stay exact
+const maxFailures = 100
+
+// Report summarizes a replay
+type Report struct {
+	Requests   int            `json:"requests"`
+	Errors     int            `json:"errors"`     // transport failures
+	Mismatches int            `json:"mismatches"` // answers that differed from the expectation
+	Statuses   map[int]int    `json:"statuses"`
+	Duration   time.Duration  `json:"duration_ns"`
+	Throughput float64        `json:"throughput_rps"`
+	Latency    Latency        `json:"latency"`
+	Endpoints  []EndpointStat `json:"endpoints"`
+	Failures   []Failure      `json:"failures,omitempty"`
+
+	latencies  []time.Duration
+	byEndpoint map[string][]time.Duration
+}
+
+// Latency percentiles in milliseconds
+type Latency struct {
+	P50  float64 `json:"p50_ms"`
+	P90  float64 `json:"p90_ms"`
+	P99  float64 `json:"p99_ms"`
+	Max  float64 `json:"max_ms"`
+	Mean float64 `json:"mean_ms"`
+}
+
+// EndpointStat is latency per method and path, with numeric path segments
+// folded so /api/v2/records/7 and /api/v2/records/8 count together
+type EndpointStat struct {
+	Endpoint string  `json:"endpoint"`
+	Requests int     `json:"requests"`
+	Latency  Latency `json:"latency"`
+}
+
+// Failure is a request that errored or mismatched
+type Failure struct {
+	Entry  string `json:"entry"`
+	Pass   int    `json:"pass"`
+	Status int    `json:"status,omitempty"`
+	Reason string `json:"reason"`
+
+	line int
+}
+
+func newReport() *Report {
+	return &Report{Statuses: map[int]int{}, byEndpoint: map[string][]time.Duration{}}
+}
+
+func (r *Report) add(res Result) {
+	r.Requests++
+	r.latencies = append(r.latencies, res.Latency)
+	endpoint := endpointOf(res.Entry)
+	r.byEndpoint[endpoint] = append(r.byEndpoint[endpoint], res.Latency)
+
+	reason := res.Mismatch
+	switch {
+	case res.Err != nil:
+		r.Errors++
+		reason = res.Err.Error()
+	case res.Mismatch != "":
+		r.Mismatches++
+	}
+	if res.Err == nil {
+		r.Statuses[res.Status]++
+	}
+	if reason != "" && len(r.Failures) < maxFailures {
+		r.Failures = append(r.Failures, Failure{Entry: res.Entry.Name(), Pass: res.Pass, Status: res.Status, Reason: reason, line: res.Entry.Line})
+	}
+}
+
+func (r *Report) finish(elapsed time.Duration) {
+	r.Duration = elapsed
+	if elapsed > 0 {
+		r.Throughput = float64(r.Requests) / elapsed.Seconds()
+	}
+	r.Latency = latencyOf(r.latencies)
+	for endpoint, l := range r.byEndpoint {
+		r.Endpoints = append(r.Endpoints, EndpointStat{Endpoint: endpoint, Requests: len(l), Latency: latencyOf(l)})
+	}
+	sort.Slice(r.Endpoints, func(i, j int) bool { return r.Endpoints[i].Endpoint < r.Endpoints[j].Endpoint })
+	// failures arrive in completion order; report them in log order
+	sort.Slice(r.Failures, func(i, j int) bool {
+		a, b := r.Failures[i], r.Failures[j]
+		return a.Pass < b.Pass || (a.Pass == b.Pass && a.line < b.line)
+	})
+}
+
+// Err is ErrFailed when any request errored or mismatched
+func (r *Report) Err() error {
+	if r.Errors == 0 && r.Mismatches == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %d mismatches, %d errors in %d requests", ErrFailed, r.Mismatches, r.Errors, r.Requests)
+}
+
+// WriteText prints the report as tables
+func (r *Report) WriteText(out io.Writer) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "requests\t%d in %s (%.1f/s)\n", r.Requests, r.Duration.Round(time.Millisecond), r.Throughput)
+	fmt.Fprintf(w, "mismatches\t%d\n", r.Mismatches)
+	fmt.Fprintf(w, "errors\t%d\n", r.Errors)
+	statuses := make([]int, 0, len(r.Statuses))
+	for s := range r.Statuses {
+		statuses = append(statuses, s)
+	}
+	sort.Ints(statuses)
+	parts := make([]string, 0, len(statuses))
+	for _, s := range statuses {
+		parts = append(parts, fmt.Sprintf("%d×%d", s, r.Statuses[s]))
+	}
+	fmt.Fprintf(w, "statuses\t%s\n\n", strings.Join(parts, " "))
+
+	fmt.Fprintln(w, "ENDPOINT\tREQUESTS\tP50 MS\tP90 MS\tP99 MS\tMAX MS")
+	for _, e := range append(r.Endpoints, EndpointStat{Endpoint: "all", Requests: r.Requests, Latency: r.Latency}) {
+		fmt.Fprintf(w, "%s\t%d\t%.2f\t%.2f\t%.2f\t%.2f\n", e.Endpoint, e.Requests, e.Latency.P50, e.Latency.P90, e.Latency.P99, e.Latency.Max)
+	}
+
+	if len(r.Failures) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "FAILED\tPASS\tSTATUS\tREASON")
+		for _, f := range r.Failures {
+			fmt.Fprintf(w, "%s\t%d\t%d\t%s\n", f.Entry, f.Pass, f.Status, f.Reason)
+		}
+		if shown := len(r.Failures); shown < r.Errors+r.Mismatches {
+			fmt.Fprintf(w, "... %d more\n", r.Errors+r.Mismatches-shown)
+		}
+	}
+	return w.Flush()
+}
+
+// latencyOf computes nearest-rank percentiles
+func latencyOf(l []time.Duration) Latency {
+	if len(l) == 0 {
+		return Latency{}
+	}
+	sorted := append([]time.Duration(nil), l...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := func(p float64) float64 {
+		i := int(math.Ceil(p*float64(len(sorted)))) - 1
+		if i < 0 {
+			i = 0
+		}
+		return ms(sorted[i])
+	}
+	var sum time.Duration
+	for _, d := range sorted {
+		sum += d
+	}
+	return Latency{
+		P50:  rank(0.50),
+		P90:  rank(0.90),
+		P99:  rank(0.99),
+		Max:  ms(sorted[len(sorted)-1]),
+		Mean: ms(sum / time.Duration(len(sorted))),
+	}
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// endpointOf folds ids out of the path and drops the query string
+func endpointOf(e Entry) string {
+	path, _, _ := strings.Cut(e.Path, "?")
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if s != "" && strings.Trim(s, "0123456789") == "" {
+			segments[i] = "{n}"
+		}
+	}
+	return e.Method + " " + strings.Join(segments, "/")
+}
@@ -0,0 +1,21 @@
+// Package script embeds the SQL assets so the binary does not depend on the
+// working directory it is started from.
+package script
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed migrations/*.sql
+var files embed.FS
+
+// Migrations returns the contents of script/migrations
+func Migrations() fs.FS {
+	sub, err := fs.Sub(files, "migrations")
+	if err != nil {
+		// the pattern above is checked at compile time
+		panic(err)
+	}
+	return sub
+}
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS event_log;
+DROP TABLE IF EXISTS audit_history;
+DROP TABLE IF EXISTS policyholder_records;
+DROP TABLE IF EXISTS policyholders;
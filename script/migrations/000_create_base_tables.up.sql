@@ -1,3 +1,5 @@
+-- Base tables from before the versioned migrations; 001 upgrades them to the v2 layout
+
 -- Policyholder table
 CREATE TABLE IF NOT EXISTS policyholders (
     policyholder_id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -0,0 +1,50 @@
+--------------------------------------------------
+-- POLICYHOLDER RECORDS
+--------------------------------------------------
+-- Back to the layout of script/create_v2_tables.sql (replaces the old
+-- rollback_create_v2_tables.sql script); the latest data of every record is kept.
+ALTER TABLE policyholder_records RENAME TO policyholder_records_v2_backup;
+
+CREATE TABLE policyholder_records (
+    record_id INTEGER PRIMARY KEY,
+    policyholder_id INTEGER,
+    data TEXT NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO policyholder_records (record_id, policyholder_id, data, version, created_at, updated_at)
+SELECT record_id, policyholder_id, data, version, created_at, updated_at
+FROM policyholder_records_v2_backup;
+
+DROP TABLE IF EXISTS policyholder_records_v2_backup;
+DROP INDEX IF EXISTS idx_records_version;
+
+--------------------------------------------------
+-- AUDIT HISTORY
+--------------------------------------------------
+ALTER TABLE audit_history RENAME TO audit_history_v2_backup;
+
+CREATE TABLE audit_history (
+    audit_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    record_id INTEGER NOT NULL,
+    data TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    changed_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (record_id) REFERENCES policyholder_records(record_id)
+);
+
+INSERT INTO audit_history (audit_id, record_id, data, event_type, changed_at)
+SELECT audit_id, record_id, data, event_type, changed_at
+FROM audit_history_v2_backup;
+
+DROP TABLE IF EXISTS audit_history_v2_backup;
+
+--------------------------------------------------
+-- V2-ONLY TABLES
+--------------------------------------------------
+DROP TABLE IF EXISTS event_logs;
+DROP TABLE IF EXISTS observability_metrics;
+DROP TABLE IF EXISTS feature_flags;
+DROP TABLE IF EXISTS users;
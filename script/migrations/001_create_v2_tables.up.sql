@@ -1,5 +1,3 @@
-PRAGMA foreign_keys = OFF;
-
 --------------------------------------------------
 -- FEATURE FLAGS
 --------------------------------------------------
@@ -113,19 +111,4 @@ CREATE TABLE IF NOT EXISTS observability_metrics (
     recorded_at DATETIME DEFAULT CURRENT_TIMESTAMP
 );
 
---------------------------------------------------
--- SCHEMA MIGRATION TRACKING
---------------------------------------------------
-CREATE TABLE IF NOT EXISTS schema_migrations (
-    version TEXT PRIMARY KEY,
-    applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
-);
-
 CREATE TABLE IF NOT EXISTS users(id INTEGER PRIMARY KEY);
-
-INSERT INTO schema_migrations(version)
-VALUES('001_create_users.sql')
-ON CONFLICT(version) DO NOTHING;
-
-
-PRAGMA foreign_keys = ON;
@@ -0,0 +1,17 @@
+--------------------------------------------------
+-- API VERSION ROLLOUT
+--------------------------------------------------
+-- Drives routing of unversioned /api/records/... requests.
+-- Newest active version whose rollout_percentage admits the caller wins.
+CREATE TABLE IF NOT EXISTS api_versions (
+    version TEXT PRIMARY KEY,
+    is_active BOOLEAN NOT NULL DEFAULT 1,
+    rollout_percentage INTEGER NOT NULL DEFAULT 0,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO api_versions(version, is_active, rollout_percentage, updated_at)
+VALUES
+('v1', 1, 100, CURRENT_TIMESTAMP),
+('v2', 1, 0, CURRENT_TIMESTAMP)
+ON CONFLICT(version) DO NOTHING;
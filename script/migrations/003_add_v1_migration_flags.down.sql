@@ -0,0 +1,2 @@
+DELETE FROM feature_flags
+WHERE flag_key IN ('enable_v1_dual_write', 'enable_v1_shadow_read');
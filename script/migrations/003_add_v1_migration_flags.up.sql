@@ -0,0 +1,11 @@
+--------------------------------------------------
+-- V1 -> V2 MIGRATION FLAGS
+--------------------------------------------------
+-- enable_v1_dual_write:  v1 POST also writes the record to the v2 SQLite store
+-- enable_v1_shadow_read: v1 GET also reads v2 and reports mismatches
+-- Both start disabled; existing values are preserved on re-run.
+INSERT INTO feature_flags(flag_key, enabled, description, updated_at, rollout_percentage)
+VALUES
+('enable_v1_dual_write', 0, 'Dual-write v1 records to the v2 store', CURRENT_TIMESTAMP, 100),
+('enable_v1_shadow_read', 0, 'Shadow-read v1 records from the v2 store and compare', CURRENT_TIMESTAMP, 100)
+ON CONFLICT(flag_key) DO NOTHING;
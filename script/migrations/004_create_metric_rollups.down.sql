@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_observability_metrics_recorded_at;
+DROP TABLE IF EXISTS observability_metric_rollups;
@@ -0,0 +1,24 @@
+--------------------------------------------------
+-- OBSERVABILITY METRIC ROLLUPS
+--------------------------------------------------
+-- Per-minute aggregates are built from observability_metrics, per-hour
+-- aggregates from the minute rollups. Raw rows and each resolution are
+-- pruned independently (see metrics.rollup in conf/config.yaml).
+CREATE TABLE IF NOT EXISTS observability_metric_rollups (
+    metric_name TEXT NOT NULL,
+    metric_type TEXT NOT NULL,
+    region TEXT NOT NULL DEFAULT '',
+    resolution TEXT NOT NULL CHECK (resolution IN ('minute', 'hour')),
+    bucket_start DATETIME NOT NULL,
+    count INTEGER NOT NULL,
+    sum REAL NOT NULL,
+    min REAL,
+    max REAL,
+    PRIMARY KEY (metric_name, metric_type, region, resolution, bucket_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_metric_rollups_query
+    ON observability_metric_rollups(metric_name, resolution, bucket_start);
+
+CREATE INDEX IF NOT EXISTS idx_observability_metrics_recorded_at
+    ON observability_metrics(recorded_at);
@@ -0,0 +1,25 @@
+--------------------------------------------------
+-- POLICYHOLDER CONTACT DETAILS REQUIRED AGAIN
+--------------------------------------------------
+-- 005 became necessary when every connection started enforcing foreign
+-- keys (_foreign_keys=1 in the DSN). Before that, "PRAGMA foreign_keys = ON"
+-- ran once per pool and most connections accepted records for missing
+-- policyholders. Rolling back brings back NOT NULL email/country_code, so the
+-- v2 service's first-write policyholder insert is ignored again and the
+-- record insert fails its foreign key. (005.up.sql is checksummed once
+-- applied, so this note lives here.)
+CREATE TABLE policyholders_old (
+    policyholder_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    email TEXT NOT NULL,
+    country_code TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO policyholders_old (policyholder_id, name, email, country_code, created_at, updated_at)
+SELECT policyholder_id, name, COALESCE(email, ''), COALESCE(country_code, ''), created_at, updated_at
+FROM policyholders;
+
+DROP TABLE policyholders;
+ALTER TABLE policyholders_old RENAME TO policyholders;
@@ -0,0 +1,21 @@
+--------------------------------------------------
+-- POLICYHOLDER CONTACT DETAILS OPTIONAL
+--------------------------------------------------
+-- The v2 record service creates a policyholder on first write with only an
+-- id and name; with email/country_code NOT NULL that insert was ignored and
+-- the record insert then failed its foreign key.
+CREATE TABLE policyholders_new (
+    policyholder_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    email TEXT,
+    country_code TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO policyholders_new (policyholder_id, name, email, country_code, created_at, updated_at)
+SELECT policyholder_id, name, email, country_code, created_at, updated_at
+FROM policyholders;
+
+DROP TABLE policyholders;
+ALTER TABLE policyholders_new RENAME TO policyholders;
@@ -0,0 +1,20 @@
+--------------------------------------------------
+-- API KEYS
+--------------------------------------------------
+-- Callers send tt_<prefix>_<secret> in X-API-Key; only the SHA-256 of the
+-- whole key is kept. Revoked and rotated keys stay for audits: revoked_at
+-- ends a key at once, expires_at (set on rotation) after a grace period.
+CREATE TABLE IF NOT EXISTS api_keys (
+    api_key_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    prefix TEXT NOT NULL UNIQUE,
+    key_hash TEXT NOT NULL,
+    name TEXT NOT NULL,
+    user_id INTEGER NOT NULL CHECK (user_id > 0),
+    tenant TEXT NOT NULL DEFAULT '',
+    roles TEXT NOT NULL DEFAULT '[]', -- JSON array of role names
+    created_at DATETIME NOT NULL,
+    expires_at DATETIME,
+    revoked_at DATETIME,
+    last_used_at DATETIME,
+    rotated_from INTEGER REFERENCES api_keys(api_key_id)
+);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS policyholder_assignments;
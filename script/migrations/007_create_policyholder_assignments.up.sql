@@ -0,0 +1,11 @@
+--------------------------------------------------
+-- POLICYHOLDER ASSIGNMENTS
+--------------------------------------------------
+-- Scoped roles (broker) act only on the policyholders assigned to them.
+-- An assignment may name a policyholder whose record does not exist yet.
+CREATE TABLE IF NOT EXISTS policyholder_assignments (
+    user_id INTEGER NOT NULL CHECK (user_id > 0),
+    policyholder_id INTEGER NOT NULL CHECK (policyholder_id > 0),
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY (user_id, policyholder_id)
+);
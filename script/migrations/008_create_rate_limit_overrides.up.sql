@@ -0,0 +1,13 @@
+--------------------------------------------------
+-- RATE LIMIT OVERRIDES
+--------------------------------------------------
+-- Per-user limits replacing the configured default of one route class.
+-- per_minute = 0 lifts the limit for that user; burst = 0 means one minute's worth.
+CREATE TABLE IF NOT EXISTS rate_limit_overrides (
+    user_id INTEGER NOT NULL CHECK (user_id > 0),
+    route_class TEXT NOT NULL CHECK (route_class IN ('read', 'write', 'admin')),
+    per_minute INTEGER NOT NULL CHECK (per_minute >= 0),
+    burst INTEGER NOT NULL DEFAULT 0 CHECK (burst >= 0),
+    updated_at DATETIME NOT NULL,
+    PRIMARY KEY (user_id, route_class)
+);
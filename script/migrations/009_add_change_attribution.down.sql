@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_audit_changed_by;
+
+ALTER TABLE event_logs DROP COLUMN changed_by;
+
+ALTER TABLE audit_history DROP COLUMN reason;
+ALTER TABLE audit_history DROP COLUMN source;
+ALTER TABLE audit_history DROP COLUMN request_id;
+ALTER TABLE audit_history DROP COLUMN changed_by;
@@ -0,0 +1,16 @@
+--------------------------------------------------
+-- CHANGE ATTRIBUTION
+--------------------------------------------------
+-- Who wrote each version and event, from which call and through which path.
+-- changed_by is the authenticated user (NULL for v1 writes, which carry no
+-- user); source is api_v1, api_v2, import or revert; reason is the optional
+-- X-Change-Reason text. Rows written before this migration stay NULL.
+ALTER TABLE audit_history ADD COLUMN changed_by INTEGER;
+ALTER TABLE audit_history ADD COLUMN request_id TEXT;
+ALTER TABLE audit_history ADD COLUMN source TEXT;
+ALTER TABLE audit_history ADD COLUMN reason TEXT;
+
+ALTER TABLE event_logs ADD COLUMN changed_by INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_audit_changed_by
+ON audit_history(record_id, changed_by);
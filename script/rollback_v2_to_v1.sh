@@ -1 +0,0 @@
-sqlite3 db/timetravel.db < rollback_create_v2_tables.sql
\ No newline at end of file
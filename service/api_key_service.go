@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/auth"
+	"github.com/rainbowmga/timetravel/entity"
+	"github.com/rainbowmga/timetravel/gateways"
+)
+
+var (
+	ErrAPIKeyDoesNotExist = apperr.New(apperr.ErrNotFound, "api key does not exist")
+	ErrAPIKeyRevoked      = apperr.Conflict("api key has been revoked")
+)
+
+// APIKeyInput describes a key to create
+type APIKeyInput struct {
+	Name      string
+	UserID    int64
+	Tenant    string
+	Roles     []string
+	ExpiresAt *time.Time
+}
+
+// APIKeyUpdate changes the set fields of a key
+type APIKeyUpdate struct {
+	Name   *string
+	Tenant *string
+	Roles  []string // nil leaves roles unchanged
+}
+
+// APIKeyServiceInterface stores hashed API keys; Create and Rotate return the
+// only copy of the secret key
+type APIKeyServiceInterface interface {
+	auth.APIKeyStore
+	Create(ctx context.Context, in APIKeyInput) (entity.APIKey, string, error)
+	List(ctx context.Context) ([]entity.APIKey, error)
+	Get(ctx context.Context, id int64) (entity.APIKey, error)
+	Update(ctx context.Context, id int64, u APIKeyUpdate) (entity.APIKey, error)
+	Revoke(ctx context.Context, id int64) (entity.APIKey, error)
+	Rotate(ctx context.Context, id int64, grace time.Duration) (entity.APIKey, string, error)
+}
+
+// Ensure APIKeyService implements the interface
+var _ APIKeyServiceInterface = (*APIKeyService)(nil)
+
+// APIKeyService keeps API keys in the api_keys table
+type APIKeyService struct {
+	db     *sql.DB // writer
+	reader *sql.DB
+	now    func() time.Time
+}
+
+// NewAPIKeyServiceWithDB stores keys through the shared database
+func NewAPIKeyServiceWithDB(db *gateways.Database) *APIKeyService {
+	return &APIKeyService{db: db.Writer(), reader: db.Reader(), now: time.Now}
+}
+
+const apiKeyColumns = `
+	SELECT api_key_id, prefix, key_hash, name, user_id, tenant, roles,
+	       created_at, expires_at, revoked_at, last_used_at, rotated_from
+	FROM api_keys`
+
+// Create stores a new key and returns it with its secret
+func (s *APIKeyService) Create(ctx context.Context, in APIKeyInput) (entity.APIKey, string, error) {
+	var key entity.APIKey
+	var secret string
+	err := gateways.RunInTx(ctx, s.db, gateways.TxOptions{Operation: "create_api_key"}, func(tx *sql.Tx) error {
+		var err error
+		key, secret, err = s.insert(ctx, tx, in, nil)
+		return err
+	})
+	return key, secret, err
+}
+
+func (s *APIKeyService) insert(ctx context.Context, tx *sql.Tx, in APIKeyInput, rotatedFrom *int64) (entity.APIKey, string, error) {
+	secret, prefix, hash, err := auth.NewAPIKey()
+	if err != nil {
+		return entity.APIKey{}, "", err
+	}
+	if in.Roles == nil {
+		in.Roles = []string{}
+	}
+	roles, err := json.Marshal(in.Roles)
+	if err != nil {
+		return entity.APIKey{}, "", err
+	}
+
+	key := entity.APIKey{
+		Prefix:      prefix,
+		Hash:        hash,
+		Name:        in.Name,
+		UserID:      in.UserID,
+		Tenant:      in.Tenant,
+		Roles:       in.Roles,
+		CreatedAt:   s.now().UTC().Truncate(time.Second),
+		ExpiresAt:   in.ExpiresAt,
+		RotatedFrom: rotatedFrom,
+	}
+	res, err := execTraced(ctx, tx, "INSERT", `
+		INSERT INTO api_keys (prefix, key_hash, name, user_id, tenant, roles, created_at, expires_at, rotated_from)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		key.Prefix, key.Hash, key.Name, key.UserID, key.Tenant, string(roles), key.CreatedAt, key.ExpiresAt, key.RotatedFrom,
+	)
+	if err != nil {
+		return entity.APIKey{}, "", err
+	}
+	if key.ID, err = res.LastInsertId(); err != nil {
+		return entity.APIKey{}, "", err
+	}
+	return key, secret, nil
+}
+
+// List returns every key, newest first, revoked and expired ones included
+func (s *APIKeyService) List(ctx context.Context) ([]entity.APIKey, error) {
+	rows, err := queryTraced(ctx, s.reader, "SELECT", apiKeyColumns+`
+		ORDER BY api_key_id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	return scanAPIKeys(rows)
+}
+
+// Get returns one key
+func (s *APIKeyService) Get(ctx context.Context, id int64) (entity.APIKey, error) {
+	return s.getOne(ctx, s.reader, `WHERE api_key_id = ?`, id)
+}
+
+// LookupAPIKey finds a key by its public prefix
+func (s *APIKeyService) LookupAPIKey(ctx context.Context, prefix string) (entity.APIKey, error) {
+	return s.getOne(ctx, s.reader, `WHERE prefix = ?`, prefix)
+}
+
+func (s *APIKeyService) getOne(ctx context.Context, db sqlQuerier, where string, arg interface{}) (entity.APIKey, error) {
+	rows, err := queryTraced(ctx, db, "SELECT", apiKeyColumns+"\n\t"+where, arg)
+	if err != nil {
+		return entity.APIKey{}, err
+	}
+	keys, err := scanAPIKeys(rows)
+	if err != nil {
+		return entity.APIKey{}, err
+	}
+	if len(keys) == 0 {
+		return entity.APIKey{}, ErrAPIKeyDoesNotExist
+	}
+	return keys[0], nil
+}
+
+// TouchAPIKey records when a key was last used
+func (s *APIKeyService) TouchAPIKey(ctx context.Context, id int64, at time.Time) error {
+	_, err := execTraced(ctx, s.db, "UPDATE", `
+		UPDATE api_keys SET last_used_at = ? WHERE api_key_id = ?`, at.UTC(), id)
+	return err
+}
+
+// Update renames a key or changes its tenant or roles; revoked keys are final
+func (s *APIKeyService) Update(ctx context.Context, id int64, u APIKeyUpdate) (entity.APIKey, error) {
+	var key entity.APIKey
+	err := gateways.RunInTx(ctx, s.db, gateways.TxOptions{Operation: "update_api_key"}, func(tx *sql.Tx) error {
+		var err error
+		if key, err = s.getOne(ctx, tx, `WHERE api_key_id = ?`, id); err != nil {
+			return err
+		}
+		if key.RevokedAt != nil {
+			return ErrAPIKeyRevoked
+		}
+		if u.Name != nil {
+			key.Name = *u.Name
+		}
+		if u.Tenant != nil {
+			key.Tenant = *u.Tenant
+		}
+		if u.Roles != nil {
+			key.Roles = u.Roles
+		}
+		roles, err := json.Marshal(key.Roles)
+		if err != nil {
+			return err
+		}
+		_, err = execTraced(ctx, tx, "UPDATE", `
+			UPDATE api_keys SET name = ?, tenant = ?, roles = ? WHERE api_key_id = ?`,
+			key.Name, key.Tenant, string(roles), id)
+		return err
+	})
+	return key, err
+}
+
+// Revoke ends a key at once; revoking twice is a no-op
+func (s *APIKeyService) Revoke(ctx context.Context, id int64) (entity.APIKey, error) {
+	var key entity.APIKey
+	err := gateways.RunInTx(ctx, s.db, gateways.TxOptions{Operation: "revoke_api_key"}, func(tx *sql.Tx) error {
+		var err error
+		if key, err = s.getOne(ctx, tx, `WHERE api_key_id = ?`, id); err != nil {
+			return err
+		}
+		if key.RevokedAt != nil {
+			return nil
+		}
+		now := s.now().UTC()
+		key.RevokedAt = &now
+		_, err = execTraced(ctx, tx, "UPDATE", `
+			UPDATE api_keys SET revoked_at = ? WHERE api_key_id = ?`, now, id)
+		return err
+	})
+	return key, err
+}
+
+// Rotate issues a replacement with the same name, owner and roles, and lets
+// the old key work for grace more (0 ends it now). The replacement records
+// the key it rotated from.
+func (s *APIKeyService) Rotate(ctx context.Context, id int64, grace time.Duration) (entity.APIKey, string, error) {
+	var key entity.APIKey
+	var secret string
+	err := gateways.RunInTx(ctx, s.db, gateways.TxOptions{Operation: "rotate_api_key"}, func(tx *sql.Tx) error {
+		old, err := s.getOne(ctx, tx, `WHERE api_key_id = ?`, id)
+		if err != nil {
+			return err
+		}
+		if old.RevokedAt != nil {
+			return ErrAPIKeyRevoked
+		}
+
+		key, secret, err = s.insert(ctx, tx, APIKeyInput{
+			Name:      old.Name,
+			UserID:    old.UserID,
+			Tenant:    old.Tenant,
+			Roles:     old.Roles,
+			ExpiresAt: old.ExpiresAt,
+		}, &old.ID)
+		if err != nil {
+			return err
+		}
+
+		// an earlier expiry stands
+		ends := s.now().UTC().Add(grace)
+		if old.ExpiresAt != nil && old.ExpiresAt.Before(ends) {
+			return nil
+		}
+		_, err = execTraced(ctx, tx, "UPDATE", `
+			UPDATE api_keys SET expires_at = ? WHERE api_key_id = ?`, ends, id)
+		return err
+	})
+	return key, secret, err
+}
+
+func scanAPIKeys(rows *sql.Rows) ([]entity.APIKey, error) {
+	defer rows.Close()
+	var keys []entity.APIKey
+	for rows.Next() {
+		var k entity.APIKey
+		var roles string
+		var expiresAt, revokedAt, lastUsedAt sql.NullTime
+		var rotatedFrom sql.NullInt64
+		if err := rows.Scan(&k.ID, &k.Prefix, &k.Hash, &k.Name, &k.UserID, &k.Tenant, &roles,
+			&k.CreatedAt, &expiresAt, &revokedAt, &lastUsedAt, &rotatedFrom); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(roles), &k.Roles); err != nil {
+			return nil, errors.Join(errors.New("api_keys.roles is not a JSON array"), err)
+		}
+		k.ExpiresAt = nullTimePtr(expiresAt)
+		k.RevokedAt = nullTimePtr(revokedAt)
+		k.LastUsedAt = nullTimePtr(lastUsedAt)
+		if rotatedFrom.Valid {
+			k.RotatedFrom = &rotatedFrom.Int64
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	utc := t.Time.UTC()
+	return &utc
+}
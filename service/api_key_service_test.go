@@ -0,0 +1,143 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rainbowmga/timetravel/auth"
+	"github.com/rainbowmga/timetravel/service"
+)
+
+func TestAPIKeyService_CreateLookupAndAuthenticate(t *testing.T) {
+	keys := service.NewAPIKeyServiceWithDB(openMigratedDB(t))
+	ctx := context.Background()
+
+	created, secret, err := keys.Create(ctx, service.APIKeyInput{Name: "billing", UserID: 7, Tenant: "acme", Roles: []string{"writer"}})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if secret == "" || created.Hash != auth.HashAPIKey(secret) {
+		t.Fatalf("Create() = %+v, %q; want the hash of the secret stored", created, secret)
+	}
+
+	got, err := keys.LookupAPIKey(ctx, created.Prefix)
+	if err != nil || got.ID != created.ID || got.Hash != created.Hash || len(got.Roles) != 1 {
+		t.Fatalf("LookupAPIKey() = %+v, %v", got, err)
+	}
+	if _, err := keys.LookupAPIKey(ctx, "000000000000"); !errors.Is(err, service.ErrAPIKeyDoesNotExist) {
+		t.Errorf("LookupAPIKey(unknown) error = %v", err)
+	}
+
+	p, err := auth.NewAPIKeyAuthenticator(keys).Authenticate(ctx, auth.Credentials{APIKey: secret})
+	if err != nil || p.UserID != 7 || p.Tenant != "acme" || !p.HasRole("writer") {
+		t.Fatalf("Authenticate() = %+v, %v", p, err)
+	}
+	if got, _ := keys.Get(ctx, created.ID); got.LastUsedAt == nil {
+		t.Error("last_used_at was not recorded")
+	}
+}
+
+func TestAPIKeyService_UpdateRevoke(t *testing.T) {
+	keys := service.NewAPIKeyServiceWithDB(openMigratedDB(t))
+	ctx := context.Background()
+	created, secret, err := keys.Create(ctx, service.APIKeyInput{Name: "ci", UserID: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name := "deploys"
+	updated, err := keys.Update(ctx, created.ID, service.APIKeyUpdate{Name: &name, Roles: []string{"admin"}})
+	if err != nil || updated.Name != "deploys" || len(updated.Roles) != 1 {
+		t.Fatalf("Update() = %+v, %v", updated, err)
+	}
+
+	revoked, err := keys.Revoke(ctx, created.ID)
+	if err != nil || revoked.RevokedAt == nil {
+		t.Fatalf("Revoke() = %+v, %v", revoked, err)
+	}
+	if _, err := keys.Revoke(ctx, created.ID); err != nil {
+		t.Errorf("second Revoke() error = %v", err)
+	}
+	if _, err := keys.Update(ctx, created.ID, service.APIKeyUpdate{Name: &name}); !errors.Is(err, service.ErrAPIKeyRevoked) {
+		t.Errorf("Update(revoked) error = %v", err)
+	}
+	if _, err := auth.NewAPIKeyAuthenticator(keys).Authenticate(ctx, auth.Credentials{APIKey: secret}); err == nil {
+		t.Error("a revoked key authenticated")
+	}
+	if _, err := keys.Get(ctx, created.ID+1); !errors.Is(err, service.ErrAPIKeyDoesNotExist) {
+		t.Errorf("Get(unknown) error = %v", err)
+	}
+
+	list, err := keys.List(ctx)
+	if err != nil || len(list) != 1 {
+		t.Errorf("List() = %+v, %v; revoked keys stay listed", list, err)
+	}
+}
+
+func TestAPIKeyService_Rotate(t *testing.T) {
+	keys := service.NewAPIKeyServiceWithDB(openMigratedDB(t))
+	ctx := context.Background()
+	later := time.Now().Add(time.Minute).UTC().Truncate(time.Second)
+	soon, _, err := keys.Create(ctx, service.APIKeyInput{Name: "soon", UserID: 1, ExpiresAt: &later})
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, oldSecret, err := keys.Create(ctx, service.APIKeyInput{Name: "billing", UserID: 7, Roles: []string{"writer"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		id        int64
+		grace     time.Duration
+		wantUntil func(time.Time) bool // the old key's expiry
+	}{
+		{"grace period", old.ID, time.Hour, func(exp time.Time) bool {
+			return exp.After(time.Now().Add(59*time.Minute)) && exp.Before(time.Now().Add(61*time.Minute))
+		}},
+		{"earlier expiry stands", soon.ID, time.Hour, func(exp time.Time) bool { return exp.Equal(later) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replacement, secret, err := keys.Rotate(ctx, tt.id, tt.grace)
+			if err != nil {
+				t.Fatalf("Rotate() error = %v", err)
+			}
+			if secret == "" || replacement.RotatedFrom == nil || *replacement.RotatedFrom != tt.id {
+				t.Errorf("replacement = %+v", replacement)
+			}
+			prev, err := keys.Get(ctx, tt.id)
+			if err != nil || prev.ExpiresAt == nil || !tt.wantUntil(*prev.ExpiresAt) {
+				t.Errorf("old key = %+v, %v", prev, err)
+			}
+		})
+	}
+
+	// the old key works during the grace period, and both keys act as the same user
+	authn := auth.NewAPIKeyAuthenticator(keys)
+	if p, err := authn.Authenticate(ctx, auth.Credentials{APIKey: oldSecret}); err != nil || p.UserID != 7 {
+		t.Errorf("old key during grace = %+v, %v", p, err)
+	}
+
+	// without grace the old key ends at once
+	replacement, secret, err := keys.Rotate(ctx, old.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := authn.Authenticate(ctx, auth.Credentials{APIKey: oldSecret}); err == nil {
+		t.Error("old key still works after rotation without grace")
+	}
+	if p, err := authn.Authenticate(ctx, auth.Credentials{APIKey: secret}); err != nil || p.UserID != 7 || !p.HasRole("writer") {
+		t.Errorf("replacement %d = %+v, %v", replacement.ID, p, err)
+	}
+
+	if _, err := keys.Revoke(ctx, soon.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := keys.Rotate(ctx, soon.ID, 0); !errors.Is(err, service.ErrAPIKeyRevoked) {
+		t.Errorf("Rotate(revoked) error = %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/entity"
+	"github.com/rainbowmga/timetravel/gateways"
+)
+
+var (
+	ErrAPIVersionDoesNotExist = apperr.New(apperr.ErrNotFound, "api version does not exist")
+)
+
+// DefaultAPIVersion is used when no api_versions row is active
+const DefaultAPIVersion = "v1"
+
+// APIVersionServiceInterface resolves which API version serves unversioned requests
+type APIVersionServiceInterface interface {
+	Resolve(userID int64, hasUser bool) string
+	List() []entity.APIVersionConfig
+	Update(ctx context.Context, version string, isActive bool, rolloutPercentage int) (entity.APIVersionConfig, error)
+	Refresh(ctx context.Context) error
+}
+
+// Ensure APIVersionService implements the interface
+var _ APIVersionServiceInterface = (*APIVersionService)(nil)
+
+// APIVersionService caches the api_versions table in memory
+type APIVersionService struct {
+	db     *sql.DB // writer
+	reader *sql.DB
+	mu     sync.RWMutex
+	cache  []entity.APIVersionConfig // newest version first
+}
+
+// NewAPIVersionService opens the DB and loads the rollout config
+func NewAPIVersionService(dbPath string) (*APIVersionService, error) {
+	db, err := gateways.OpenDatabase(dbPath, gateways.DatabaseOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return NewAPIVersionServiceWithDB(db)
+}
+
+// NewAPIVersionServiceWithDB loads the rollout config through the shared database
+func NewAPIVersionServiceWithDB(db *gateways.Database) (*APIVersionService, error) {
+	s := &APIVersionService{db: db.Writer(), reader: db.Reader()}
+	if err := s.Refresh(context.Background()); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Refresh reloads api_versions into memory
+func (s *APIVersionService) Refresh(ctx context.Context) error {
+	if s == nil || s.db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	rows, err := s.reader.QueryContext(ctx, `
+		SELECT version, is_active, rollout_percentage, updated_at
+		FROM api_versions`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var tmp []entity.APIVersionConfig
+	for rows.Next() {
+		var c entity.APIVersionConfig
+		var updatedAt sql.NullTime
+		if err := rows.Scan(&c.Version, &c.IsActive, &c.RolloutPercentage, &updatedAt); err != nil {
+			return err
+		}
+		c.UpdatedAt = updatedAt.Time
+		tmp = append(tmp, c)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// "v10" must sort after "v9", so compare by length first
+	sort.Slice(tmp, func(i, j int) bool {
+		if len(tmp[i].Version) != len(tmp[j].Version) {
+			return len(tmp[i].Version) > len(tmp[j].Version)
+		}
+		return tmp[i].Version > tmp[j].Version
+	})
+
+	s.mu.Lock()
+	s.cache = tmp
+	s.mu.Unlock()
+	return nil
+}
+
+// Resolve picks the newest active version whose rollout admits the user.
+// Callers without a user id (and users outside every rollout) get the oldest active version.
+func (s *APIVersionService) Resolve(userID int64, hasUser bool) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	fallback := DefaultAPIVersion
+	for _, c := range s.cache {
+		if c.IsActive {
+			fallback = c.Version
+		}
+	}
+	if !hasUser {
+		return fallback
+	}
+
+	bucket := rolloutBucket(userID)
+	for _, c := range s.cache {
+		if c.IsActive && bucket < c.RolloutPercentage {
+			return c.Version
+		}
+	}
+	return fallback
+}
+
+// List returns a copy of the cached configs, newest version first
+func (s *APIVersionService) List() []entity.APIVersionConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]entity.APIVersionConfig, len(s.cache))
+	copy(out, s.cache)
+	return out
+}
+
+// Update changes a version's activation and rollout, then reloads the cache
+func (s *APIVersionService) Update(ctx context.Context, version string, isActive bool, rolloutPercentage int) (entity.APIVersionConfig, error) {
+	now := time.Now().UTC()
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE api_versions
+		SET is_active = ?, rollout_percentage = ?, updated_at = ?
+		WHERE version = ?`,
+		isActive, rolloutPercentage, now, version,
+	)
+	if err != nil {
+		return entity.APIVersionConfig{}, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return entity.APIVersionConfig{}, ErrAPIVersionDoesNotExist
+	}
+
+	if err := s.Refresh(ctx); err != nil {
+		return entity.APIVersionConfig{}, err
+	}
+
+	return entity.APIVersionConfig{
+		Version:           version,
+		IsActive:          isActive,
+		RolloutPercentage: rolloutPercentage,
+		UpdatedAt:         now,
+	}, nil
+}
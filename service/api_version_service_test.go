@@ -0,0 +1,132 @@
+package service_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/rainbowmga/timetravel/service"
+)
+
+func createAPIVersionTestDB(t *testing.T, v2Active bool, v2Rollout int) (string, func()) {
+	t.Helper()
+
+	file, err := os.CreateTemp("", "apiversions-*.db")
+	if err != nil {
+		t.Fatalf("failed to create temp db: %v", err)
+	}
+	path := file.Name()
+	file.Close()
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`
+	CREATE TABLE api_versions (
+		version TEXT PRIMARY KEY,
+		is_active BOOLEAN NOT NULL DEFAULT 1,
+		rollout_percentage INTEGER NOT NULL DEFAULT 0,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	INSERT INTO api_versions (version, is_active, rollout_percentage) VALUES ('v1', 1, 100);
+	`)
+	if err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO api_versions (version, is_active, rollout_percentage) VALUES ('v2', ?, ?)`, v2Active, v2Rollout); err != nil {
+		t.Fatalf("failed to seed v2: %v", err)
+	}
+
+	return path, func() { os.Remove(path) }
+}
+
+func TestAPIVersionService_Resolve(t *testing.T) {
+	tests := []struct {
+		name      string
+		v2Active  bool
+		v2Rollout int
+		userID    int64
+		hasUser   bool
+		want      string
+	}{
+		{"v2 at 0 percent", true, 0, 1, true, "v1"},
+		{"v2 at 100 percent", true, 100, 1, true, "v2"},
+		{"v2 inactive", false, 100, 1, true, "v1"},
+		{"no user stays on oldest", true, 100, 0, false, "v1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, cleanup := createAPIVersionTestDB(t, tt.v2Active, tt.v2Rollout)
+			defer cleanup()
+
+			svc, err := service.NewAPIVersionService(path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := svc.Resolve(tt.userID, tt.hasUser); got != tt.want {
+				t.Errorf("Resolve() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIVersionService_PartialRolloutIsSticky(t *testing.T) {
+	path, cleanup := createAPIVersionTestDB(t, true, 50)
+	defer cleanup()
+
+	svc, err := service.NewAPIVersionService(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	seen := map[string]int{}
+	for id := int64(1); id <= 200; id++ {
+		first := svc.Resolve(id, true)
+		if again := svc.Resolve(id, true); again != first {
+			t.Fatalf("user %d flipped between %s and %s", id, first, again)
+		}
+		seen[first]++
+	}
+	if seen["v1"] == 0 || seen["v2"] == 0 {
+		t.Errorf("expected a split between v1 and v2, got %v", seen)
+	}
+}
+
+func TestAPIVersionService_Update(t *testing.T) {
+	path, cleanup := createAPIVersionTestDB(t, true, 0)
+	defer cleanup()
+
+	svc, err := service.NewAPIVersionService(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := svc.Update(context.Background(), "v2", true, 100); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if got := svc.Resolve(1, true); got != "v2" {
+		t.Errorf("expected v2 after full rollout, got %s", got)
+	}
+
+	versions := svc.List()
+	if len(versions) != 2 || versions[0].Version != "v2" {
+		t.Errorf("expected v2 listed first, got %+v", versions)
+	}
+
+	if _, err := svc.Update(context.Background(), "v9", true, 10); err != service.ErrAPIVersionDoesNotExist {
+		t.Errorf("expected ErrAPIVersionDoesNotExist, got %v", err)
+	}
+}
+
+func TestNewAPIVersionService_MissingTable(t *testing.T) {
+	if _, err := service.NewAPIVersionService(":memory:"); err == nil {
+		t.Errorf("expected error when api_versions table is missing")
+	}
+}
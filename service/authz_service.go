@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/authz"
+	"github.com/rainbowmga/timetravel/common"
+	"github.com/rainbowmga/timetravel/entity"
+	"github.com/rainbowmga/timetravel/gateways"
+)
+
+// ActionAccessDenied is the event_logs action of refused requests
+const ActionAccessDenied = "access_denied"
+
+var ErrAssignmentDoesNotExist = apperr.New(apperr.ErrNotFound, "policyholder is not assigned to this user")
+
+// AuthzServiceInterface stores policyholder assignments and denied requests
+type AuthzServiceInterface interface {
+	authz.Assignments
+	authz.DenialLog
+	Assign(ctx context.Context, userID, policyholderID int64) (entity.PolicyholderAssignment, error)
+	Unassign(ctx context.Context, userID, policyholderID int64) error
+	ListAssignments(ctx context.Context, userID int64) ([]entity.PolicyholderAssignment, error)
+}
+
+// Ensure AuthzService implements the interface
+var _ AuthzServiceInterface = (*AuthzService)(nil)
+
+// AuthzService keeps assignments in policyholder_assignments and denials in event_logs
+type AuthzService struct {
+	db     *sql.DB // writer
+	reader *sql.DB
+	now    func() time.Time
+}
+
+// NewAuthzServiceWithDB stores through the shared database
+func NewAuthzServiceWithDB(db *gateways.Database) *AuthzService {
+	return &AuthzService{db: db.Writer(), reader: db.Reader(), now: time.Now}
+}
+
+// IsAssigned reports whether policyholderID is assigned to userID
+func (s *AuthzService) IsAssigned(ctx context.Context, userID, policyholderID int64) (bool, error) {
+	var n int
+	err := queryRowTraced(ctx, s.reader, "SELECT", `
+		SELECT COUNT(*) FROM policyholder_assignments
+		WHERE user_id = ? AND policyholder_id = ?`, []interface{}{userID, policyholderID}, &n)
+	return n > 0, err
+}
+
+// Assign gives userID access to policyholderID; assigning twice keeps the first
+func (s *AuthzService) Assign(ctx context.Context, userID, policyholderID int64) (entity.PolicyholderAssignment, error) {
+	a := entity.PolicyholderAssignment{UserID: userID, PolicyholderID: policyholderID, CreatedAt: s.now().UTC().Truncate(time.Second)}
+	err := gateways.RunInTx(ctx, s.db, gateways.TxOptions{Operation: "assign_policyholder"}, func(tx *sql.Tx) error {
+		if _, err := execTraced(ctx, tx, "INSERT", `
+			INSERT INTO policyholder_assignments (user_id, policyholder_id, created_at)
+			VALUES (?, ?, ?)
+			ON CONFLICT (user_id, policyholder_id) DO NOTHING`,
+			userID, policyholderID, a.CreatedAt,
+		); err != nil {
+			return err
+		}
+		return queryRowTraced(ctx, tx, "SELECT", `
+			SELECT created_at FROM policyholder_assignments
+			WHERE user_id = ? AND policyholder_id = ?`, []interface{}{userID, policyholderID}, &a.CreatedAt)
+	})
+	return a, err
+}
+
+// Unassign removes an assignment
+func (s *AuthzService) Unassign(ctx context.Context, userID, policyholderID int64) error {
+	res, err := execTraced(ctx, s.db, "DELETE", `
+		DELETE FROM policyholder_assignments
+		WHERE user_id = ? AND policyholder_id = ?`, userID, policyholderID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrAssignmentDoesNotExist
+	}
+	return nil
+}
+
+// ListAssignments returns the policyholders assigned to userID, by id
+func (s *AuthzService) ListAssignments(ctx context.Context, userID int64) ([]entity.PolicyholderAssignment, error) {
+	rows, err := queryTraced(ctx, s.reader, "SELECT", `
+		SELECT user_id, policyholder_id, created_at
+		FROM policyholder_assignments
+		WHERE user_id = ?
+		ORDER BY policyholder_id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assignments := []entity.PolicyholderAssignment{}
+	for rows.Next() {
+		var a entity.PolicyholderAssignment
+		if err := rows.Scan(&a.UserID, &a.PolicyholderID, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		a.CreatedAt = a.CreatedAt.UTC()
+		assignments = append(assignments, a)
+	}
+	return assignments, rows.Err()
+}
+
+// RecordDenial writes a refused request to event_logs, attributed to the
+// refused user, on the record it targeted when that record exists
+func (s *AuthzService) RecordDenial(ctx context.Context, d authz.Denial) error {
+	details := struct {
+		RequestID string `json:"request_id,omitempty"`
+		authz.Denial
+	}{Denial: d}
+	if id, ok := common.GetRequestID(ctx); ok {
+		details.RequestID = id
+	}
+	b, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+
+	_, err = execTraced(ctx, s.db, "INSERT", `
+		INSERT INTO event_logs (record_id, action, timestamp, details, changed_by)
+		VALUES ((SELECT record_id FROM policyholder_records WHERE policyholder_id = ? LIMIT 1), ?, ?, ?, ?)`,
+		d.PolicyholderID, ActionAccessDenied, s.now().UTC(), string(b), nullInt64(d.UserID),
+	)
+	return err
+}
@@ -0,0 +1,96 @@
+package service_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/rainbowmga/timetravel/authz"
+	"github.com/rainbowmga/timetravel/service"
+)
+
+func TestAuthzService_Assignments(t *testing.T) {
+	svc := service.NewAuthzServiceWithDB(openMigratedDB(t))
+	ctx := context.Background()
+
+	first, err := svc.Assign(ctx, 7, 3)
+	if err != nil || first.UserID != 7 || first.PolicyholderID != 3 || first.CreatedAt.IsZero() {
+		t.Fatalf("Assign() = %+v, %v", first, err)
+	}
+	again, err := svc.Assign(ctx, 7, 3)
+	if err != nil || !again.CreatedAt.Equal(first.CreatedAt) {
+		t.Fatalf("Assign() twice = %+v, %v; want the first assignment kept", again, err)
+	}
+	if _, err := svc.Assign(ctx, 7, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := svc.ListAssignments(ctx, 7)
+	if err != nil || len(list) != 2 || list[0].PolicyholderID != 1 || list[1].PolicyholderID != 3 {
+		t.Fatalf("ListAssignments() = %+v, %v", list, err)
+	}
+	if ok, err := svc.IsAssigned(ctx, 7, 3); err != nil || !ok {
+		t.Errorf("IsAssigned(7, 3) = %v, %v", ok, err)
+	}
+	if ok, err := svc.IsAssigned(ctx, 8, 3); err != nil || ok {
+		t.Errorf("IsAssigned(8, 3) = %v, %v", ok, err)
+	}
+
+	if err := svc.Unassign(ctx, 7, 3); err != nil {
+		t.Fatalf("Unassign() error = %v", err)
+	}
+	if err := svc.Unassign(ctx, 7, 3); !errors.Is(err, service.ErrAssignmentDoesNotExist) {
+		t.Errorf("Unassign() twice error = %v", err)
+	}
+	if list, _ := svc.ListAssignments(ctx, 8); list == nil || len(list) != 0 {
+		t.Errorf("ListAssignments(none) = %#v, want an empty slice", list)
+	}
+}
+
+func TestAuthzService_RecordDenial(t *testing.T) {
+	db := openMigratedDB(t)
+	svc := service.NewAuthzServiceWithDB(db)
+	ctx := context.Background()
+	rec, err := service.NewSQLiteRecordServiceWithDB(db, nil, "").CreateOrUpdate(ctx, 3, map[string]string{"name": "ann"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	denials := []authz.Denial{
+		{UserID: 8, Roles: []string{"broker"}, Permission: authz.RecordsWrite, Resource: "PATCH /api/v2/records/{policyholder_id}", PolicyholderID: 3, Reason: "policyholder 3 is not assigned to user 8"},
+		{UserID: 8, Roles: []string{"reader"}, Permission: authz.MetricsRead, Resource: "GET /api/v2/admin/metrics", Reason: "roles [reader] do not grant metrics:read"},
+	}
+	for _, d := range denials {
+		if err := svc.RecordDenial(ctx, d); err != nil {
+			t.Fatalf("RecordDenial() error = %v", err)
+		}
+	}
+
+	rows, err := db.Reader().Query(`SELECT record_id, details, changed_by FROM event_logs WHERE action = ? ORDER BY event_id`, service.ActionAccessDenied)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []sql.NullInt64
+	for rows.Next() {
+		var recordID sql.NullInt64
+		var details string
+		var changedBy int64
+		if err := rows.Scan(&recordID, &details, &changedBy); err != nil {
+			t.Fatal(err)
+		}
+		if changedBy != 8 {
+			t.Errorf("changed_by = %d, want the refused user 8", changedBy)
+		}
+		var d authz.Denial
+		if err := json.Unmarshal([]byte(details), &d); err != nil || d.Reason != denials[len(got)].Reason {
+			t.Errorf("details = %s, %v", details, err)
+		}
+		got = append(got, recordID)
+	}
+	if len(got) != 2 || got[0].Int64 != rec.ID || got[1].Valid {
+		t.Errorf("record ids = %v, want [%d, NULL]", got, rec.ID)
+	}
+}
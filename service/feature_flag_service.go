@@ -1,11 +1,9 @@
 package service
 
 import (
-	"database/sql"
-	"sync"
 	"hash/fnv"
-	"fmt"
 	"strconv"
+
 	"github.com/rainbowmga/timetravel/observability"
 )
 
@@ -15,13 +13,22 @@ type FeatureFlagServiceInterface interface {
 	Refresh() error
 }
 
-// Ensure FeatureFlagService implements the interface
-var _ FeatureFlagServiceInterface = (*FeatureFlagService)(nil)
+// FlagEvaluator is the narrow view used by layers that gate behaviour on a flag.
+// defaultValue applies when the flag is not defined at all, so kill-switch flags
+// (audit, metrics) stay on unless someone explicitly turns them off.
+type FlagEvaluator interface {
+	IsEnabledWithDefault(flagKey string, userID int64, defaultValue bool) bool
+}
+
+// Ensure FeatureFlagService implements the interfaces
+var (
+	_ FeatureFlagServiceInterface = (*FeatureFlagService)(nil)
+	_ FlagEvaluator               = (*FeatureFlagService)(nil)
+)
 
+// FeatureFlagService evaluates flags supplied by a FlagProvider
 type FeatureFlagService struct {
-	db   *sql.DB
-	mu   sync.RWMutex
-	cache map[string]FeatureFlag
+	provider FlagProvider
 }
 
 type FeatureFlag struct {
@@ -30,63 +37,38 @@ type FeatureFlag struct {
 	RolloutPercentage int
 }
 
-// NewFeatureFlagService initializes and loads flags into memory
+// NewFeatureFlagService initializes a SQLite-backed service and loads flags into memory
 func NewFeatureFlagService(dbPath string) (*FeatureFlagService, error) {
-	db, err := sql.Open("sqlite3", dbPath)
+	provider, err := NewSQLiteFlagProvider(dbPath)
 	if err != nil {
 		return nil, err
 	}
+	return NewFeatureFlagServiceWithProvider(provider), nil
+}
 
-	// Enforce foreign keys
-	_, _ = db.Exec("PRAGMA foreign_keys = ON;")
+// NewFeatureFlagServiceWithProvider evaluates flags from any provider (file, static, layered)
+func NewFeatureFlagServiceWithProvider(provider FlagProvider) *FeatureFlagService {
+	return &FeatureFlagService{provider: provider}
+}
 
-	s := &FeatureFlagService{
-		db:    db,
-		cache: make(map[string]FeatureFlag),
-	}
-	if err := s.Refresh(); err != nil {
-		return nil, err
-	}
-	return s, nil
+// Provider returns the underlying flag source
+func (s *FeatureFlagService) Provider() FlagProvider {
+	return s.provider
 }
 
-// reload loads flags from DB into memory
+// Refresh reloads flags from the provider
 func (s *FeatureFlagService) Refresh() error {
-	if s == nil || s.db == nil {
-        return fmt.Errorf("database not initialized")
-    }
-
-	rows, err := s.db.Query(`
-		SELECT flag_key, enabled, rollout_percentage
-		FROM feature_flags`)
-	if err != nil {
-		return err
-	}
-	defer rows.Close()
-
-	tmp := make(map[string]FeatureFlag)
-
-	for rows.Next() {
-		var f FeatureFlag
-		if err := rows.Scan(&f.Key, &f.Enabled, &f.RolloutPercentage); err != nil {
-			return err
-		}
-		tmp[f.Key] = f
-	}
-
-	s.mu.Lock()
-	s.cache = tmp
-	s.mu.Unlock()
+	return s.provider.Refresh()
+}
 
-	observability.DefaultLogger.Info("Refresh ", s.cache["enable_v2_api"])
-	return nil
+// Ready reports whether the provider has loaded its flags
+func (s *FeatureFlagService) Ready() error {
+	return s.provider.Ready()
 }
 
 // IsEnabled checks if a flag is enabled
 func (s *FeatureFlagService) IsEnabled(flagKey string, userID int64) bool {
-	s.mu.RLock()
-	flag, ok := s.cache[flagKey]
-	s.mu.RUnlock()
+	flag, ok := s.provider.Lookup(flagKey)
 
 	if !ok || !flag.Enabled {
 		observability.FlagEvaluated(flagKey, false)
@@ -99,17 +81,24 @@ func (s *FeatureFlagService) IsEnabled(flagKey string, userID int64) bool {
 		return true
 	}
 
-	// deterministic hash
-	h := fnv.New32a()
-	h.Write([]byte(strconv.FormatInt(userID, 10)))
-	value := h.Sum32() % 100
-
-	enabled := int(value) < flag.RolloutPercentage
+	enabled := rolloutBucket(userID) < flag.RolloutPercentage
 
 	observability.FlagEvaluated(flagKey, enabled)
-	observability.DefaultLogger.Info("IsEnabled ", flagKey, s.cache[flagKey])
+	observability.DefaultLogger.Debug("flag_evaluated", "flag", flagKey, "enabled", enabled, "rollout_percentage", flag.RolloutPercentage)
 	return enabled
 }
 
+// IsEnabledWithDefault evaluates the flag, returning defaultValue if it is not defined
+func (s *FeatureFlagService) IsEnabledWithDefault(flagKey string, userID int64, defaultValue bool) bool {
+	if _, ok := s.provider.Lookup(flagKey); !ok {
+		return defaultValue
+	}
+	return s.IsEnabled(flagKey, userID)
+}
 
-
+// rolloutBucket deterministically maps a user to 0..99 so percentage rollouts are sticky
+func rolloutBucket(userID int64) int {
+	h := fnv.New32a()
+	h.Write([]byte(strconv.FormatInt(userID, 10)))
+	return int(h.Sum32() % 100)
+}
@@ -184,3 +184,26 @@ func TestNewFeatureFlagService_InvalidDB(t *testing.T) {
 		t.Errorf("expected error for invalid DB path")
 	}
 }
+
+func TestIsEnabledWithDefault(t *testing.T) {
+	svc := service.NewFeatureFlagServiceWithProvider(service.NewStaticFlagProvider(map[string]service.FeatureFlag{
+		"off": {Enabled: false, RolloutPercentage: 100},
+		"on":  {Enabled: true, RolloutPercentage: 100},
+	}))
+
+	tests := []struct {
+		flag string
+		def  bool
+		want bool
+	}{
+		{"off", true, false},
+		{"on", false, true},
+		{"missing", true, true},
+		{"missing", false, false},
+	}
+	for _, tt := range tests {
+		if got := svc.IsEnabledWithDefault(tt.flag, 1, tt.def); got != tt.want {
+			t.Errorf("IsEnabledWithDefault(%s, %v) = %v, want %v", tt.flag, tt.def, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/rainbowmga/timetravel/observability"
+)
+
+// flagFile is the on-disk format shared by the YAML and JSON providers:
+//
+//	flags:
+//	  enable_v2_api:
+//	    enabled: true
+//	    rollout_percentage: 50
+type flagFile struct {
+	Flags map[string]flagFileEntry `yaml:"flags" json:"flags"`
+}
+
+type flagFileEntry struct {
+	Enabled           bool `yaml:"enabled" json:"enabled"`
+	RolloutPercentage *int `yaml:"rollout_percentage" json:"rollout_percentage"`
+}
+
+// FileFlagProvider loads flags from a YAML or JSON file and can watch it for changes
+type FileFlagProvider struct {
+	path    string
+	mu      sync.RWMutex
+	flags   map[string]FeatureFlag
+	modTime time.Time
+}
+
+// NewFileFlagProvider reads the file once; the format is chosen by extension (.json or YAML)
+func NewFileFlagProvider(path string) (*FileFlagProvider, error) {
+	p := &FileFlagProvider{path: path, flags: make(map[string]FeatureFlag)}
+	if err := p.Refresh(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Refresh re-reads the file and swaps the flag set atomically
+func (p *FileFlagProvider) Refresh() error {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return fmt.Errorf("failed to stat flag file: %w", err)
+	}
+
+	content, err := os.ReadFile(p.path)
+	if err != nil {
+		return fmt.Errorf("failed to read flag file: %w", err)
+	}
+
+	var parsed flagFile
+	if strings.EqualFold(filepath.Ext(p.path), ".json") {
+		err = json.Unmarshal(content, &parsed)
+	} else {
+		err = yaml.Unmarshal(content, &parsed)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse flag file %s: %w", p.path, err)
+	}
+
+	tmp := make(map[string]FeatureFlag, len(parsed.Flags))
+	for key, entry := range parsed.Flags {
+		// match the feature_flags column default
+		rollout := 100
+		if entry.RolloutPercentage != nil {
+			rollout = *entry.RolloutPercentage
+		}
+		tmp[key] = FeatureFlag{Key: key, Enabled: entry.Enabled, RolloutPercentage: rollout}
+	}
+
+	p.mu.Lock()
+	p.flags = tmp
+	p.modTime = info.ModTime()
+	p.mu.Unlock()
+
+	return nil
+}
+
+// Lookup returns the flag as last read from disk
+func (p *FileFlagProvider) Lookup(flagKey string) (FeatureFlag, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	f, ok := p.flags[flagKey]
+	return f, ok
+}
+
+// Ready fails until the file has been parsed successfully
+func (p *FileFlagProvider) Ready() error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.modTime.IsZero() {
+		return ErrFlagsNotLoaded
+	}
+	return nil
+}
+
+// Watch polls the file's modification time and reloads it when it changes.
+// Polling (rather than inotify) survives editors that replace the file on save.
+// The returned function stops the watcher.
+func (p *FileFlagProvider) Watch(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	var once sync.Once
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		var failedModTime time.Time
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				info, err := os.Stat(p.path)
+				if err != nil {
+					observability.DefaultLogger.Warn("flag file stat failed", "path", p.path, "error", err)
+					continue
+				}
+
+				p.mu.RLock()
+				changed := !info.ModTime().Equal(p.modTime)
+				p.mu.RUnlock()
+				if !changed || info.ModTime().Equal(failedModTime) {
+					continue
+				}
+
+				// keep the last good flag set if the new file is invalid
+				if err := p.Refresh(); err != nil {
+					failedModTime = info.ModTime()
+					observability.DefaultLogger.Error("flag file reload failed", "path", p.path, "error", err)
+					continue
+				}
+				observability.DefaultLogger.Info("flag file reloaded", "path", p.path)
+			}
+		}
+	}()
+
+	return func() { once.Do(func() { close(done) }) }
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/rainbowmga/timetravel/gateways"
+	"github.com/rainbowmga/timetravel/observability"
+)
+
+// FlagProvider supplies flag definitions to FeatureFlagService.
+// Rollout evaluation stays in the service so every provider buckets users the same way.
+type FlagProvider interface {
+	Lookup(flagKey string) (FeatureFlag, bool)
+	Refresh() error
+	// Ready reports whether a flag set has been loaded at least once
+	Ready() error
+}
+
+// ErrFlagsNotLoaded is returned by Ready before the first successful load
+var ErrFlagsNotLoaded = errors.New("feature flags not loaded")
+
+// Ensure providers implement the interface
+var (
+	_ FlagProvider = (*SQLiteFlagProvider)(nil)
+	_ FlagProvider = (*StaticFlagProvider)(nil)
+	_ FlagProvider = (*FileFlagProvider)(nil)
+	_ FlagProvider = (*LayeredFlagProvider)(nil)
+)
+
+// ------------------------------
+// SQLITE PROVIDER
+// ------------------------------
+
+// SQLiteFlagProvider caches the feature_flags table in memory
+type SQLiteFlagProvider struct {
+	db     *sql.DB
+	mu     sync.RWMutex
+	cache  map[string]FeatureFlag
+	loaded bool
+}
+
+// NewSQLiteFlagProvider opens the DB and loads flags into memory
+func NewSQLiteFlagProvider(dbPath string) (*SQLiteFlagProvider, error) {
+	db, err := gateways.OpenDatabase(dbPath, gateways.DatabaseOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return NewSQLiteFlagProviderWithDB(db)
+}
+
+// NewSQLiteFlagProviderWithDB loads flags through the shared database's reader pool
+func NewSQLiteFlagProviderWithDB(db *gateways.Database) (*SQLiteFlagProvider, error) {
+	p := &SQLiteFlagProvider{
+		db:    db.Reader(),
+		cache: make(map[string]FeatureFlag),
+	}
+	if err := p.Refresh(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Refresh loads flags from DB into memory
+func (p *SQLiteFlagProvider) Refresh() error {
+	if p == nil || p.db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	rows, err := p.db.Query(`
+		SELECT flag_key, enabled, rollout_percentage
+		FROM feature_flags`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	tmp := make(map[string]FeatureFlag)
+
+	for rows.Next() {
+		var f FeatureFlag
+		if err := rows.Scan(&f.Key, &f.Enabled, &f.RolloutPercentage); err != nil {
+			return err
+		}
+		tmp[f.Key] = f
+	}
+
+	p.mu.Lock()
+	p.cache = tmp
+	p.loaded = true
+	p.mu.Unlock()
+
+	observability.DefaultLogger.Info("feature_flags_refreshed", "count", len(tmp))
+	return nil
+}
+
+// Ready fails until the feature_flags table has been read successfully
+func (p *SQLiteFlagProvider) Ready() error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if !p.loaded {
+		return ErrFlagsNotLoaded
+	}
+	return nil
+}
+
+// Lookup returns the cached flag definition
+func (p *SQLiteFlagProvider) Lookup(flagKey string) (FeatureFlag, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	f, ok := p.cache[flagKey]
+	return f, ok
+}
+
+// ------------------------------
+// STATIC PROVIDER
+// ------------------------------
+
+// StaticFlagProvider holds flags in memory; intended for tests and local overrides
+type StaticFlagProvider struct {
+	mu    sync.RWMutex
+	flags map[string]FeatureFlag
+}
+
+// NewStaticFlagProvider copies the given flags into a new provider
+func NewStaticFlagProvider(flags map[string]FeatureFlag) *StaticFlagProvider {
+	p := &StaticFlagProvider{flags: make(map[string]FeatureFlag, len(flags))}
+	for k, f := range flags {
+		f.Key = k
+		p.flags[k] = f
+	}
+	return p
+}
+
+// Set adds or replaces a flag at runtime
+func (p *StaticFlagProvider) Set(flagKey string, enabled bool, rolloutPercentage int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.flags[flagKey] = FeatureFlag{Key: flagKey, Enabled: enabled, RolloutPercentage: rolloutPercentage}
+}
+
+// Delete removes a flag so lower layers (or the default) apply again
+func (p *StaticFlagProvider) Delete(flagKey string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.flags, flagKey)
+}
+
+// Lookup returns the stored flag definition
+func (p *StaticFlagProvider) Lookup(flagKey string) (FeatureFlag, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	f, ok := p.flags[flagKey]
+	return f, ok
+}
+
+// Refresh is a no-op; static flags only change through Set/Delete
+func (p *StaticFlagProvider) Refresh() error {
+	return nil
+}
+
+// Ready always succeeds; static flags exist from construction
+func (p *StaticFlagProvider) Ready() error {
+	return nil
+}
+
+// ------------------------------
+// LAYERED PROVIDER
+// ------------------------------
+
+// LayeredFlagProvider overlays providers: the first layer that defines a flag wins.
+// Typical order is local overrides first, SQLite last.
+type LayeredFlagProvider struct {
+	layers []FlagProvider
+}
+
+// NewLayeredFlagProvider builds a provider from highest to lowest priority
+func NewLayeredFlagProvider(layers ...FlagProvider) *LayeredFlagProvider {
+	return &LayeredFlagProvider{layers: layers}
+}
+
+// Lookup walks the layers in priority order
+func (p *LayeredFlagProvider) Lookup(flagKey string) (FeatureFlag, bool) {
+	for _, layer := range p.layers {
+		if f, ok := layer.Lookup(flagKey); ok {
+			return f, true
+		}
+	}
+	return FeatureFlag{}, false
+}
+
+// Refresh reloads every layer and returns the first error
+func (p *LayeredFlagProvider) Refresh() error {
+	var firstErr error
+	for _, layer := range p.layers {
+		if err := layer.Refresh(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Ready requires every layer to be ready
+func (p *LayeredFlagProvider) Ready() error {
+	for _, layer := range p.layers {
+		if err := layer.Ready(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package service_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rainbowmga/timetravel/service"
+)
+
+func writeFlagFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write flag file: %v", err)
+	}
+}
+
+func TestStaticFlagProvider(t *testing.T) {
+	p := service.NewStaticFlagProvider(map[string]service.FeatureFlag{
+		"on":  {Enabled: true, RolloutPercentage: 100},
+		"off": {Enabled: false, RolloutPercentage: 100},
+	})
+	svc := service.NewFeatureFlagServiceWithProvider(p)
+
+	if !svc.IsEnabled("on", 1) {
+		t.Errorf("expected 'on' enabled")
+	}
+	if svc.IsEnabled("off", 1) {
+		t.Errorf("expected 'off' disabled")
+	}
+
+	p.Set("off", true, 100)
+	if !svc.IsEnabled("off", 1) {
+		t.Errorf("expected 'off' enabled after Set")
+	}
+
+	p.Delete("on")
+	if svc.IsEnabled("on", 1) {
+		t.Errorf("expected 'on' missing after Delete")
+	}
+}
+
+func TestFileFlagProvider_Formats(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{"yaml", "flags.yaml", "flags:\n  feature_a:\n    enabled: true\n  feature_b:\n    enabled: true\n    rollout_percentage: 0\n"},
+		{"json", "flags.json", `{"flags":{"feature_a":{"enabled":true},"feature_b":{"enabled":true,"rollout_percentage":0}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.file)
+			writeFlagFile(t, path, tt.content)
+
+			p, err := service.NewFileFlagProvider(path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			a, ok := p.Lookup("feature_a")
+			if !ok || !a.Enabled || a.RolloutPercentage != 100 {
+				t.Errorf("feature_a = %+v, want enabled with default rollout 100", a)
+			}
+			b, _ := p.Lookup("feature_b")
+			if b.RolloutPercentage != 0 {
+				t.Errorf("feature_b rollout = %d, want 0", b.RolloutPercentage)
+			}
+		})
+	}
+}
+
+func TestFileFlagProvider_InvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.yaml")
+	writeFlagFile(t, path, "flags: [not a map")
+
+	if _, err := service.NewFileFlagProvider(path); err == nil {
+		t.Errorf("expected parse error")
+	}
+	if _, err := service.NewFileFlagProvider(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Errorf("expected error for missing file")
+	}
+}
+
+func TestFileFlagProvider_Watch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.yaml")
+	writeFlagFile(t, path, "flags:\n  feature_a:\n    enabled: false\n")
+
+	p, err := service.NewFileFlagProvider(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stop := p.Watch(10 * time.Millisecond)
+	defer stop()
+
+	writeFlagFile(t, path, "flags:\n  feature_a:\n    enabled: true\n")
+	// make sure the mtime moves even on coarse-grained filesystems
+	future := time.Now().Add(2 * time.Second)
+	_ = os.Chtimes(path, future, future)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if f, _ := p.Lookup("feature_a"); f.Enabled {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("expected watcher to reload feature_a as enabled")
+}
+
+func TestLayeredFlagProvider(t *testing.T) {
+	base := service.NewStaticFlagProvider(map[string]service.FeatureFlag{
+		"shared":    {Enabled: false, RolloutPercentage: 100},
+		"base_only": {Enabled: true, RolloutPercentage: 100},
+	})
+	overrides := service.NewStaticFlagProvider(map[string]service.FeatureFlag{
+		"shared": {Enabled: true, RolloutPercentage: 100},
+	})
+
+	svc := service.NewFeatureFlagServiceWithProvider(service.NewLayeredFlagProvider(overrides, base))
+
+	tests := []struct {
+		flag string
+		want bool
+	}{
+		{"shared", true},
+		{"base_only", true},
+		{"missing", false},
+	}
+	for _, tt := range tests {
+		if got := svc.IsEnabled(tt.flag, 7); got != tt.want {
+			t.Errorf("IsEnabled(%s) = %v, want %v", tt.flag, got, tt.want)
+		}
+	}
+
+	if err := svc.Refresh(); err != nil {
+		t.Errorf("unexpected refresh error: %v", err)
+	}
+}
+
+func TestFlagProviders_Ready(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.yaml")
+	writeFlagFile(t, path, "flags:\n  a:\n    enabled: true\n")
+	loaded, err := service.NewFileFlagProvider(path)
+	if err != nil {
+		t.Fatalf("failed to load flag file: %v", err)
+	}
+	static := service.NewStaticFlagProvider(nil)
+
+	tests := []struct {
+		name     string
+		provider service.FlagProvider
+		want     error
+	}{
+		{"static", static, nil},
+		{"loaded file", loaded, nil},
+		{"never loaded sqlite", &service.SQLiteFlagProvider{}, service.ErrFlagsNotLoaded},
+		{"never loaded file", &service.FileFlagProvider{}, service.ErrFlagsNotLoaded},
+		{"layered all ready", service.NewLayeredFlagProvider(static, loaded), nil},
+		{"layered with unloaded layer", service.NewLayeredFlagProvider(static, &service.SQLiteFlagProvider{}), service.ErrFlagsNotLoaded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := service.NewFeatureFlagServiceWithProvider(tt.provider).Ready(); err != tt.want {
+				t.Errorf("Ready() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/entity"
+	"github.com/rainbowmga/timetravel/gateways"
+	"github.com/rainbowmga/timetravel/observability"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	ErrPolicyholderDoesNotExist = apperr.NotFound("policyholder does not exist")
+)
+
+// HistoryService reads policyholders, records, their audit history and event
+// logs for exploration (the GraphQL API). It never writes. A zero asOf means
+// "now"; listings are keyset-paginated: rows after the given key, up to limit.
+type HistoryService struct {
+	reader *sql.DB
+}
+
+// NewHistoryServiceWithDB reads through the shared database's reader pool
+func NewHistoryServiceWithDB(db *gateways.Database) *HistoryService {
+	return &HistoryService{reader: db.Reader()}
+}
+
+// asOfParam is the SQL parameter for an optional upper time bound: NULL when unset
+func asOfParam(asOf time.Time) interface{} {
+	if asOf.IsZero() {
+		return nil
+	}
+	return asOf.UTC()
+}
+
+const policyholderColumns = `
+	SELECT policyholder_id, name, COALESCE(email, ''), COALESCE(country_code, ''), created_at, updated_at
+	FROM policyholders`
+
+// GetPolicyholder returns one policyholder
+func (s *HistoryService) GetPolicyholder(ctx context.Context, id int64) (_ *entity.Policyholder, err error) {
+	ctx, span := observability.StartSpan(ctx, "HistoryService.GetPolicyholder", attribute.Int64("policyholder.id", id))
+	defer func() { observability.EndSpan(span, err) }()
+
+	rows, err := queryTraced(ctx, s.reader, "SELECT", policyholderColumns+`
+		WHERE policyholder_id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	holders, err := scanPolicyholders(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(holders) == 0 {
+		return nil, ErrPolicyholderDoesNotExist
+	}
+	return &holders[0], nil
+}
+
+// ListPolicyholders returns policyholders with ids after afterID, in id order
+func (s *HistoryService) ListPolicyholders(ctx context.Context, afterID int64, limit int) (_ []entity.Policyholder, err error) {
+	ctx, span := observability.StartSpan(ctx, "HistoryService.ListPolicyholders")
+	defer func() { observability.EndSpan(span, err) }()
+
+	rows, err := queryTraced(ctx, s.reader, "SELECT", policyholderColumns+`
+		WHERE policyholder_id > ?
+		ORDER BY policyholder_id
+		LIMIT ?`, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanPolicyholders(rows)
+}
+
+// GetRecordAsOf returns a policyholder's record as it was at asOf: the latest
+// version written by then, with UpdatedAt set to when that version was written.
+// ErrRecordDoesNotExist if there was no record yet.
+func (s *HistoryService) GetRecordAsOf(ctx context.Context, policyholderID int64, asOf time.Time) (_ *entity.PolicyholderRecord, err error) {
+	ctx, span := observability.StartSpan(ctx, "HistoryService.GetRecordAsOf", attribute.Int64("policyholder.id", policyholderID))
+	defer func() { observability.EndSpan(span, err) }()
+
+	query := `
+		SELECT record_id, data, version, created_at, updated_at
+		FROM policyholder_records
+		WHERE policyholder_id = ?`
+	args := []interface{}{policyholderID}
+	if !asOf.IsZero() {
+		query = `
+		SELECT pr.record_id, ah.data, ah.version, pr.created_at, ah.changed_at
+		FROM audit_history ah
+		JOIN policyholder_records pr ON pr.record_id = ah.record_id
+		WHERE pr.policyholder_id = ?
+		AND ah.changed_at <= ?
+		ORDER BY ah.version DESC
+		LIMIT 1`
+		args = append(args, asOf.UTC())
+	}
+
+	var rec entity.PolicyholderRecord
+	var dataJSON, createdAt, updatedAt string
+	err = queryRowTraced(ctx, s.reader, "SELECT", query, args, &rec.ID, &dataJSON, &rec.Version, &createdAt, &updatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrRecordDoesNotExist
+	} else if err != nil {
+		return nil, err
+	}
+	_ = json.Unmarshal([]byte(dataJSON), &rec.Data)
+	rec.CreatedAt = parseDBTime(createdAt)
+	rec.UpdatedAt = parseDBTime(updatedAt)
+	return &rec, nil
+}
+
+// ListAuditEntries returns a record's versions after afterVersion written by
+// asOf, oldest first, each with the data of the version before it
+func (s *HistoryService) ListAuditEntries(ctx context.Context, recordID int64, afterVersion int, asOf time.Time, limit int) (entries []entity.AuditEntry, err error) {
+	ctx, span := observability.StartSpan(ctx, "HistoryService.ListAuditEntries", attribute.Int64("record.id", recordID))
+	defer func() { observability.EndSpan(span, err) }()
+
+	// LAG runs over the whole history so the first row of a page still gets
+	// its predecessor
+	rows, err := queryTraced(ctx, s.reader, "SELECT", `
+		SELECT audit_id, record_id, version, data, event_type, changed_at, previous,
+			changed_by, request_id, source, reason
+		FROM (
+			SELECT rowid AS audit_id, record_id, version, data,
+				COALESCE(event_type, '') AS event_type, changed_at,
+				LAG(data) OVER (ORDER BY version) AS previous,
+				COALESCE(changed_by, 0) AS changed_by, COALESCE(request_id, '') AS request_id,
+				COALESCE(source, '') AS source, COALESCE(reason, '') AS reason
+			FROM audit_history
+			WHERE record_id = ?
+		)
+		WHERE version > ?
+		AND (? IS NULL OR changed_at <= ?)
+		ORDER BY version
+		LIMIT ?`,
+		recordID, afterVersion, asOfParam(asOf), asOfParam(asOf), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries = []entity.AuditEntry{}
+	for rows.Next() {
+		var e entity.AuditEntry
+		var dataJSON, changedAt string
+		var previous sql.NullString
+		if err := rows.Scan(&e.ID, &e.RecordID, &e.Version, &dataJSON, &e.EventType, &changedAt, &previous,
+			&e.ChangedBy, &e.RequestID, &e.Source, &e.Reason); err != nil {
+			return nil, err
+		}
+		_ = json.Unmarshal([]byte(dataJSON), &e.Data)
+		if previous.Valid {
+			_ = json.Unmarshal([]byte(previous.String), &e.Previous)
+		}
+		e.ChangedAt = parseDBTime(changedAt)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// ListEvents returns a record's event log entries after afterID logged by
+// asOf, oldest first
+func (s *HistoryService) ListEvents(ctx context.Context, recordID, afterID int64, asOf time.Time, limit int) (_ []entity.EventLog, err error) {
+	ctx, span := observability.StartSpan(ctx, "HistoryService.ListEvents", attribute.Int64("record.id", recordID))
+	defer func() { observability.EndSpan(span, err) }()
+
+	rows, err := queryTraced(ctx, s.reader, "SELECT", `
+		SELECT event_id, record_id, action, COALESCE(details, ''), timestamp, COALESCE(changed_by, 0)
+		FROM event_logs
+		WHERE record_id = ?
+		AND event_id > ?
+		AND (? IS NULL OR timestamp <= ?)
+		ORDER BY event_id
+		LIMIT ?`,
+		recordID, afterID, asOfParam(asOf), asOfParam(asOf), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanEvents(rows)
+}
+
+// EventsAt returns the event log entries written with a version: same record,
+// same timestamp (writeAudit stamps both rows with one clock reading)
+func (s *HistoryService) EventsAt(ctx context.Context, recordID int64, at time.Time) (_ []entity.EventLog, err error) {
+	ctx, span := observability.StartSpan(ctx, "HistoryService.EventsAt", attribute.Int64("record.id", recordID))
+	defer func() { observability.EndSpan(span, err) }()
+
+	rows, err := queryTraced(ctx, s.reader, "SELECT", `
+		SELECT event_id, record_id, action, COALESCE(details, ''), timestamp, COALESCE(changed_by, 0)
+		FROM event_logs
+		WHERE record_id = ?
+		AND timestamp = ?
+		ORDER BY event_id`,
+		recordID, at.UTC(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanEvents(rows)
+}
+
+func scanPolicyholders(rows *sql.Rows) ([]entity.Policyholder, error) {
+	defer rows.Close()
+
+	holders := []entity.Policyholder{}
+	for rows.Next() {
+		var p entity.Policyholder
+		var createdAt, updatedAt sql.NullString
+		if err := rows.Scan(&p.ID, &p.Name, &p.Email, &p.Country, &createdAt, &updatedAt); err != nil {
+			return nil, err
+		}
+		p.CreatedAt = parseDBTime(createdAt.String)
+		p.UpdatedAt = parseDBTime(updatedAt.String)
+		holders = append(holders, p)
+	}
+	return holders, rows.Err()
+}
+
+func scanEvents(rows *sql.Rows) ([]entity.EventLog, error) {
+	defer rows.Close()
+
+	events := []entity.EventLog{}
+	for rows.Next() {
+		var e entity.EventLog
+		var timestamp string
+		if err := rows.Scan(&e.ID, &e.RecordID, &e.Action, &e.Details, &timestamp, &e.ChangedBy); err != nil {
+			return nil, err
+		}
+		e.Timestamp = parseDBTime(timestamp)
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
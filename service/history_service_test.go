@@ -0,0 +1,118 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rainbowmga/timetravel/gateways"
+	"github.com/rainbowmga/timetravel/script"
+	"github.com/rainbowmga/timetravel/service"
+)
+
+// openMigratedDB opens a file database with the embedded migrations applied
+func openMigratedDB(t *testing.T) *gateways.Database {
+	t.Helper()
+
+	db, err := gateways.OpenDatabase(filepath.Join(t.TempDir(), "history.db"), gateways.DatabaseOptions{})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	m, err := gateways.NewMigrator(db.Writer(), script.Migrations())
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+func TestHistoryService_PoliciesAndRecordAsOf(t *testing.T) {
+	db := openMigratedDB(t)
+	records := service.NewSQLiteRecordServiceWithDB(db, nil, "")
+	history := service.NewHistoryServiceWithDB(db)
+	ctx := context.Background()
+
+	for _, id := range []int64{3, 1, 2} {
+		if _, err := records.CreateOrUpdate(ctx, id, map[string]string{"name": "p"}); err != nil {
+			t.Fatalf("CreateOrUpdate: %v", err)
+		}
+	}
+	between := time.Now().UTC()
+	_, _ = records.CreateOrUpdate(ctx, 1, map[string]string{"name": "q"})
+
+	page, err := history.ListPolicyholders(ctx, 1, 10)
+	if err != nil || len(page) != 2 || page[0].ID != 2 || page[1].ID != 3 {
+		t.Fatalf("ListPolicyholders after 1 = %+v, %v", page, err)
+	}
+	if page[0].Name != "p" || page[0].CreatedAt.IsZero() {
+		t.Errorf("unexpected policyholder %+v", page[0])
+	}
+
+	if _, err := history.GetPolicyholder(ctx, 99); !errors.Is(err, service.ErrPolicyholderDoesNotExist) {
+		t.Errorf("GetPolicyholder(99) err = %v", err)
+	}
+
+	current, err := history.GetRecordAsOf(ctx, 1, time.Time{})
+	if err != nil || current.Version != 2 || current.Data["name"] != "q" {
+		t.Fatalf("current record = %+v, %v", current, err)
+	}
+	past, err := history.GetRecordAsOf(ctx, 1, between)
+	if err != nil || past.Version != 1 || past.Data["name"] != "p" || past.ID != current.ID {
+		t.Fatalf("record as of %v = %+v, %v", between, past, err)
+	}
+	if _, err := history.GetRecordAsOf(ctx, 1, between.Add(-time.Hour)); !errors.Is(err, service.ErrRecordDoesNotExist) {
+		t.Errorf("record before it existed: err = %v", err)
+	}
+}
+
+func TestHistoryService_AuditEntriesAndEvents(t *testing.T) {
+	db := openMigratedDB(t)
+	records := service.NewSQLiteRecordServiceWithDB(db, nil, "")
+	history := service.NewHistoryServiceWithDB(db)
+	ctx := context.Background()
+
+	rec, _ := records.CreateOrUpdate(ctx, 1, map[string]string{"name": "a"})
+	_, _ = records.CreateOrUpdate(ctx, 1, map[string]string{"name": "b"})
+	between := time.Now().UTC()
+	_, _ = records.CreateOrUpdate(ctx, 1, map[string]string{"name": "c"})
+
+	all, err := history.ListAuditEntries(ctx, rec.ID, 0, time.Time{}, 10)
+	if err != nil || len(all) != 3 {
+		t.Fatalf("ListAuditEntries = %+v, %v", all, err)
+	}
+	if all[0].Previous != nil || all[2].Previous["name"] != "b" || all[2].Data["name"] != "c" {
+		t.Errorf("unexpected previous data: %+v", all)
+	}
+
+	// a page starting mid-history still carries its predecessor
+	page, _ := history.ListAuditEntries(ctx, rec.ID, 1, time.Time{}, 1)
+	if len(page) != 1 || page[0].Version != 2 || page[0].Previous["name"] != "a" {
+		t.Errorf("page after version 1 = %+v", page)
+	}
+
+	asOf, _ := history.ListAuditEntries(ctx, rec.ID, 0, between, 10)
+	if len(asOf) != 2 {
+		t.Errorf("entries as of %v: %d, want 2", between, len(asOf))
+	}
+
+	events, err := history.ListEvents(ctx, rec.ID, 0, time.Time{}, 10)
+	if err != nil || len(events) != 3 || events[0].Action != "create" {
+		t.Fatalf("ListEvents = %+v, %v", events, err)
+	}
+	if later, _ := history.ListEvents(ctx, rec.ID, events[1].ID, time.Time{}, 10); len(later) != 1 {
+		t.Errorf("events after %d: %d, want 1", events[1].ID, len(later))
+	}
+
+	// the event written with each version is found by its timestamp
+	for _, e := range all {
+		at, err := history.EventsAt(ctx, rec.ID, e.ChangedAt)
+		if err != nil || len(at) != 1 || at[0].Action != e.EventType {
+			t.Errorf("EventsAt(version %d) = %+v, %v", e.Version, at, err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/entity"
+	"github.com/rainbowmga/timetravel/gateways"
+)
+
+var ErrRateLimitOverrideDoesNotExist = apperr.New(apperr.ErrNotFound, "rate limit override does not exist")
+
+// RateLimitServiceInterface stores per-user rate limit overrides
+type RateLimitServiceInterface interface {
+	ListOverrides(ctx context.Context) ([]entity.RateLimitOverride, error)
+	PutOverride(ctx context.Context, o entity.RateLimitOverride) (entity.RateLimitOverride, error)
+	DeleteOverride(ctx context.Context, userID int64, class string) error
+}
+
+// Ensure RateLimitService implements the interface
+var _ RateLimitServiceInterface = (*RateLimitService)(nil)
+
+// RateLimitService keeps overrides in rate_limit_overrides
+type RateLimitService struct {
+	db     *sql.DB // writer
+	reader *sql.DB
+	now    func() time.Time
+}
+
+// NewRateLimitServiceWithDB stores through the shared database
+func NewRateLimitServiceWithDB(db *gateways.Database) *RateLimitService {
+	return &RateLimitService{db: db.Writer(), reader: db.Reader(), now: time.Now}
+}
+
+// ListOverrides returns every override by user, then class
+func (s *RateLimitService) ListOverrides(ctx context.Context) ([]entity.RateLimitOverride, error) {
+	rows, err := queryTraced(ctx, s.reader, "SELECT", `
+		SELECT user_id, route_class, per_minute, burst, updated_at
+		FROM rate_limit_overrides
+		ORDER BY user_id, route_class`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	overrides := []entity.RateLimitOverride{}
+	for rows.Next() {
+		var o entity.RateLimitOverride
+		if err := rows.Scan(&o.UserID, &o.Class, &o.PerMinute, &o.Burst, &o.UpdatedAt); err != nil {
+			return nil, err
+		}
+		o.UpdatedAt = o.UpdatedAt.UTC()
+		overrides = append(overrides, o)
+	}
+	return overrides, rows.Err()
+}
+
+// PutOverride creates or replaces the override of one user and class
+func (s *RateLimitService) PutOverride(ctx context.Context, o entity.RateLimitOverride) (entity.RateLimitOverride, error) {
+	o.UpdatedAt = s.now().UTC().Truncate(time.Second)
+	_, err := execTraced(ctx, s.db, "INSERT", `
+		INSERT INTO rate_limit_overrides (user_id, route_class, per_minute, burst, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, route_class) DO UPDATE SET
+			per_minute = excluded.per_minute,
+			burst = excluded.burst,
+			updated_at = excluded.updated_at`,
+		o.UserID, o.Class, o.PerMinute, o.Burst, o.UpdatedAt,
+	)
+	return o, err
+}
+
+// DeleteOverride puts a user back on the class default
+func (s *RateLimitService) DeleteOverride(ctx context.Context, userID int64, class string) error {
+	res, err := execTraced(ctx, s.db, "DELETE", `
+		DELETE FROM rate_limit_overrides
+		WHERE user_id = ? AND route_class = ?`, userID, class)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrRateLimitOverrideDoesNotExist
+	}
+	return nil
+}
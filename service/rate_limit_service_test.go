@@ -0,0 +1,49 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rainbowmga/timetravel/entity"
+	"github.com/rainbowmga/timetravel/service"
+)
+
+func TestRateLimitService_Overrides(t *testing.T) {
+	svc := service.NewRateLimitServiceWithDB(openMigratedDB(t))
+	ctx := context.Background()
+
+	if list, err := svc.ListOverrides(ctx); err != nil || list == nil || len(list) != 0 {
+		t.Fatalf("ListOverrides(none) = %#v, %v; want an empty slice", list, err)
+	}
+
+	put := []entity.RateLimitOverride{
+		{UserID: 7, Class: "write", PerMinute: 10, Burst: 2},
+		{UserID: 3, Class: "read", PerMinute: 0},
+		{UserID: 7, Class: "write", PerMinute: 20, Burst: 5}, // replaces the first
+	}
+	for _, o := range put {
+		got, err := svc.PutOverride(ctx, o)
+		if err != nil || got.UpdatedAt.IsZero() {
+			t.Fatalf("PutOverride(%+v) = %+v, %v", o, got, err)
+		}
+	}
+	if _, err := svc.PutOverride(ctx, entity.RateLimitOverride{UserID: 7, Class: "delete", PerMinute: 1}); err == nil {
+		t.Error("PutOverride(unknown class) succeeded")
+	}
+
+	list, err := svc.ListOverrides(ctx)
+	if err != nil || len(list) != 2 {
+		t.Fatalf("ListOverrides() = %+v, %v", list, err)
+	}
+	if list[0].UserID != 3 || list[1].UserID != 7 || list[1].PerMinute != 20 || list[1].Burst != 5 {
+		t.Errorf("ListOverrides() = %+v, want user 3 then user 7 at 20/5", list)
+	}
+
+	if err := svc.DeleteOverride(ctx, 7, "write"); err != nil {
+		t.Fatalf("DeleteOverride() error = %v", err)
+	}
+	if err := svc.DeleteOverride(ctx, 7, "write"); !errors.Is(err, service.ErrRateLimitOverrideDoesNotExist) {
+		t.Errorf("DeleteOverride() twice error = %v", err)
+	}
+}
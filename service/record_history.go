@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/rainbowmga/timetravel/entity"
+	"github.com/rainbowmga/timetravel/observability"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// changeColumns selects an audit_history row as an entity.RecordChange. The
+// change id is the rowid, which audit_id (INTEGER PRIMARY KEY) aliases.
+const changeColumns = `
+	SELECT ah.rowid, pr.policyholder_id, ah.version, COALESCE(ah.event_type, ''), ah.data, ah.changed_at
+	FROM audit_history ah
+	JOIN policyholder_records pr ON pr.record_id = ah.record_id`
+
+// GetAsOf returns the version that was current at asOf: the latest snapshot
+// written at or before it. ErrRecordDoesNotExist if the record did not exist yet.
+func (s *SQLiteRecordService) GetAsOf(ctx context.Context, policyholderID int64, asOf time.Time) (change *entity.RecordChange, err error) {
+	ctx, span := observability.StartSpan(ctx, "SQLiteRecordService.GetAsOf",
+		attribute.Int64("policyholder.id", policyholderID),
+		attribute.String("record.as_of", asOf.UTC().Format(time.RFC3339Nano)),
+	)
+	defer func() { observability.EndSpan(span, err) }()
+
+	rows, err := queryTraced(ctx, s.reader, "SELECT", changeColumns+`
+		WHERE pr.policyholder_id = ?
+		AND ah.changed_at <= ?
+		ORDER BY ah.version DESC
+		LIMIT 1`,
+		policyholderID, asOf.UTC(),
+	)
+	if err != nil {
+		s.metrics.AsOfQuery(AsOfError)
+		return nil, err
+	}
+	changes, err := scanChanges(rows)
+	if err != nil {
+		s.metrics.AsOfQuery(AsOfError)
+		return nil, err
+	}
+	if len(changes) == 0 {
+		s.metrics.AsOfQuery(AsOfNotFound)
+		return nil, ErrRecordDoesNotExist
+	}
+	s.metrics.AsOfQuery(AsOfFound)
+	return &changes[0], nil
+}
+
+// ListChanges returns up to limit snapshots written after the change id afterID,
+// oldest first; policyholderID 0 includes every record
+func (s *SQLiteRecordService) ListChanges(ctx context.Context, afterID, policyholderID int64, limit int) (changes []entity.RecordChange, err error) {
+	ctx, span := observability.StartSpan(ctx, "SQLiteRecordService.ListChanges",
+		attribute.Int64("policyholder.id", policyholderID),
+		attribute.Int64("change.after", afterID),
+	)
+	defer func() { observability.EndSpan(span, err) }()
+
+	rows, err := queryTraced(ctx, s.reader, "SELECT", changeColumns+`
+		WHERE ah.rowid > ?
+		AND (? = 0 OR pr.policyholder_id = ?)
+		ORDER BY ah.rowid
+		LIMIT ?`,
+		afterID, policyholderID, policyholderID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanChanges(rows)
+}
+
+// VersionFilter narrows ListVersionEntries; zero fields match every version
+type VersionFilter struct {
+	Version   int
+	ChangedBy int64
+}
+
+// ListVersionEntries returns a record's versions with their attribution,
+// oldest first; an unknown policyholder has none
+func (s *SQLiteRecordService) ListVersionEntries(ctx context.Context, policyholderID int64, filter VersionFilter) (entries []entity.AuditHistory, err error) {
+	ctx, span := observability.StartSpan(ctx, "SQLiteRecordService.ListVersionEntries", attribute.Int64("policyholder.id", policyholderID))
+	defer func() { observability.EndSpan(span, err) }()
+
+	rows, err := queryTraced(ctx, s.reader, "SELECT", `
+		SELECT ah.rowid, ah.record_id, ah.version, ah.data, COALESCE(ah.event_type, ''), ah.changed_at,
+			COALESCE(ah.changed_by, 0), COALESCE(ah.request_id, ''), COALESCE(ah.source, ''), COALESCE(ah.reason, '')
+		FROM audit_history ah
+		JOIN policyholder_records pr ON pr.record_id = ah.record_id
+		WHERE pr.policyholder_id = ?
+		AND (? = 0 OR ah.version = ?)
+		AND (? = 0 OR ah.changed_by = ?)
+		ORDER BY ah.version`,
+		policyholderID, filter.Version, filter.Version, filter.ChangedBy, filter.ChangedBy,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries = []entity.AuditHistory{}
+	for rows.Next() {
+		var e entity.AuditHistory
+		var dataJSON, changedAt string
+		if err := rows.Scan(&e.ID, &e.RecordID, &e.Version, &dataJSON, &e.EventType, &changedAt,
+			&e.ChangedBy, &e.RequestID, &e.Source, &e.Reason); err != nil {
+			return nil, err
+		}
+		_ = json.Unmarshal([]byte(dataJSON), &e.Data)
+		e.ChangedAt = parseDBTime(changedAt)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// LatestChangeID is the id of the newest snapshot, 0 when there is none; change
+// feeds start after it
+func (s *SQLiteRecordService) LatestChangeID(ctx context.Context) (id int64, err error) {
+	ctx, span := observability.StartSpan(ctx, "SQLiteRecordService.LatestChangeID")
+	defer func() { observability.EndSpan(span, err) }()
+
+	err = queryRowTraced(ctx, s.reader, "SELECT", `SELECT COALESCE(MAX(rowid), 0) FROM audit_history`, nil, &id)
+	return id, err
+}
+
+func scanChanges(rows *sql.Rows) ([]entity.RecordChange, error) {
+	defer rows.Close()
+
+	changes := []entity.RecordChange{}
+	for rows.Next() {
+		var c entity.RecordChange
+		var dataJSON, changedAt string
+		if err := rows.Scan(&c.ID, &c.PolicyholderID, &c.Version, &c.Action, &dataJSON, &changedAt); err != nil {
+			return nil, err
+		}
+		_ = json.Unmarshal([]byte(dataJSON), &c.Data)
+		c.ChangedAt = parseDBTime(changedAt)
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+// dbTimeFormats are the layouts a timestamp column may come back in: how
+// go-sqlite3 writes time.Time parameters, the RFC3339 rendering of DATETIME
+// values, and SQLite's CURRENT_TIMESTAMP
+var dbTimeFormats = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+}
+
+// parseDBTime reads a timestamp column scanned as text; unparseable values are zero
+func parseDBTime(s string) time.Time {
+	for _, layout := range dbTimeFormats {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
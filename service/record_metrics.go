@@ -0,0 +1,58 @@
+package service
+
+import "time"
+
+// Record lifecycle actions reported to RecordMetrics. Create and update match
+// audit_history.event_type; a revert is stored as an update whose source is
+// revert, and only the metric tells them apart.
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionRevert = "revert"
+)
+
+// Transaction outcomes reported to RecordMetrics
+const (
+	TxCommitted  = "commit"
+	TxRolledBack = "rollback"
+)
+
+// Historical read results reported to RecordMetrics.AsOfQuery
+const (
+	AsOfFound    = "found"
+	AsOfNotFound = "not_found"
+	AsOfError    = "error"
+)
+
+// RecordMetrics receives domain metrics from the record service. It is injected
+// (see SQLiteRecordService.SetMetrics) so tests and alternative backends don't
+// depend on the process-wide Prometheus registry.
+type RecordMetrics interface {
+	// RecordChanged counts a committed create/update/revert
+	RecordChanged(action string)
+	// VersionsPerRecord observes a record's version count after a write
+	VersionsPerRecord(versions int)
+	// PayloadSize observes the serialized record data in bytes
+	PayloadSize(bytes int)
+	// AsOfQuery counts historical reads by result (found, not_found, error)
+	AsOfQuery(result string)
+	// TxDuration observes a SQLite transaction from BEGIN to commit or rollback
+	TxDuration(operation, outcome string, d time.Duration)
+	// TxRetry counts a transaction retried after a transient SQLite error
+	TxRetry(operation string)
+	// AuditRowsWritten counts rows appended to audit_history
+	AuditRowsWritten(n int)
+}
+
+// NoopRecordMetrics discards everything; it is the service default
+type NoopRecordMetrics struct{}
+
+var _ RecordMetrics = NoopRecordMetrics{}
+
+func (NoopRecordMetrics) RecordChanged(string)                     {}
+func (NoopRecordMetrics) VersionsPerRecord(int)                    {}
+func (NoopRecordMetrics) PayloadSize(int)                          {}
+func (NoopRecordMetrics) AsOfQuery(string)                         {}
+func (NoopRecordMetrics) TxDuration(string, string, time.Duration) {}
+func (NoopRecordMetrics) TxRetry(string)                           {}
+func (NoopRecordMetrics) AuditRowsWritten(int)                     {}
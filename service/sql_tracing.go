@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/rainbowmga/timetravel/observability"
+)
+
+// sqlExecer and sqlQuerier are satisfied by both *sql.DB and *sql.Tx
+type sqlExecer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+type sqlQuerier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// execTraced runs one statement inside its own SQL span
+func execTraced(ctx context.Context, db sqlExecer, operation, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := observability.StartSQLSpan(ctx, operation, query)
+	res, err := db.ExecContext(ctx, query, args...)
+	observability.EndSpan(span, err)
+	return res, err
+}
+
+// queryRowTraced runs a single-row query and scans it inside one SQL span.
+// sql.ErrNoRows is an expected outcome and is not recorded as a span error.
+func queryRowTraced(ctx context.Context, db sqlQuerier, operation, query string, args []interface{}, dest ...interface{}) error {
+	ctx, span := observability.StartSQLSpan(ctx, operation, query)
+	err := db.QueryRowContext(ctx, query, args...).Scan(dest...)
+	if err == sql.ErrNoRows {
+		observability.EndSpan(span, nil)
+	} else {
+		observability.EndSpan(span, err)
+	}
+	return err
+}
+
+// queryTraced opens a result set inside a SQL span; the span covers query execution only
+func queryTraced(ctx context.Context, db sqlQuerier, operation, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := observability.StartSQLSpan(ctx, operation, query)
+	rows, err := db.QueryContext(ctx, query, args...)
+	observability.EndSpan(span, err)
+	return rows, err
+}
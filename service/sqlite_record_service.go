@@ -1,151 +1,208 @@
 package service
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/rainbowmga/timetravel/apperr"
+	"github.com/rainbowmga/timetravel/common"
 	"github.com/rainbowmga/timetravel/entity"
+	"github.com/rainbowmga/timetravel/gateways"
+	"github.com/rainbowmga/timetravel/observability"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Flag keys consulted by the record service
+const (
+	FlagEnableAuditLogging = "enable_audit_logging"
 )
 
 var (
-	ErrRecordDoesNotExist = errors.New("record does not exist")
+	ErrRecordDoesNotExist = apperr.New(apperr.ErrNotFound, "record does not exist")
 )
 
 // SQLiteRecordService implements v2 persistent storage with versioning
 type SQLiteRecordService struct {
-	db *sql.DB
+	db          *sql.DB // writer; transactions
+	reader      *sql.DB
+	flags       FlagEvaluator
+	environment string
+	metrics     RecordMetrics
 }
 
 // define interface for the controller to allow testing with mocks
 type SQLiteRecordServiceInterface interface {
-	Get(int64) (*entity.PolicyholderRecord, error)
-	CreateOrUpdate(int64, map[string]string) (*entity.PolicyholderRecord, error)
-	GetVersion(int64, int) (map[string]string, error)
-	ListVersions(int64) ([]int, error)
+	Get(context.Context, int64) (*entity.PolicyholderRecord, error)
+	CreateOrUpdate(context.Context, int64, map[string]string) (*entity.PolicyholderRecord, error)
+	GetVersion(context.Context, int64, int) (map[string]string, error)
+	ListVersions(context.Context, int64) ([]int, error)
+	ListVersionEntries(context.Context, int64, VersionFilter) ([]entity.AuditHistory, error)
+	GetAsOf(context.Context, int64, time.Time) (*entity.RecordChange, error)
+	ListChanges(ctx context.Context, afterID, policyholderID int64, limit int) ([]entity.RecordChange, error)
+	LatestChangeID(context.Context) (int64, error)
 }
 
-// NewSQLiteRecordService initializes the service with DB connection
+// NewSQLiteRecordService initializes the service with DB connection; audit logging is always on
 func NewSQLiteRecordService(dbPath string) (*SQLiteRecordService, error) {
-	db, err := sql.Open("sqlite3", dbPath)
+	return NewSQLiteRecordServiceWithFlags(dbPath, nil, "")
+}
+
+// NewSQLiteRecordServiceWithFlags lets enable_audit_logging switch audit/event writes at runtime.
+// The flag is ignored in production: audit history can only be disabled in non-production environments.
+func NewSQLiteRecordServiceWithFlags(dbPath string, flags FlagEvaluator, environment string) (*SQLiteRecordService, error) {
+	db, err := gateways.OpenDatabase(dbPath, gateways.DatabaseOptions{})
 	if err != nil {
 		return nil, err
 	}
+	return NewSQLiteRecordServiceWithDB(db, flags, environment), nil
+}
 
-	// Enforce foreign keys
-	_, _ = db.Exec("PRAGMA foreign_keys = ON;")
-	return &SQLiteRecordService{db: db}, nil
+// NewSQLiteRecordServiceWithDB shares the process-wide database: writes go through
+// its writer pool, Get/GetVersion/ListVersions through its reader pool
+func NewSQLiteRecordServiceWithDB(db *gateways.Database, flags FlagEvaluator, environment string) *SQLiteRecordService {
+	if flags != nil && IsProduction(environment) {
+		observability.DefaultLogger.Info("enable_audit_logging is ignored in production", "environment", environment)
+	}
+
+	return &SQLiteRecordService{db: db.Writer(), reader: db.Reader(), flags: flags, environment: environment, metrics: NoopRecordMetrics{}}
 }
 
-// CreateOrUpdate inserts or updates a policyholder record, increments version, writes audit + event log
-func (s *SQLiteRecordService) CreateOrUpdate(policyholderID int64, data map[string]string) (*entity.PolicyholderRecord, error) {
-	tx, err := s.db.Begin()
-	if err != nil {
-		return nil, err
+// SetMetrics installs the sink for record lifecycle metrics; nil restores the no-op default
+func (s *SQLiteRecordService) SetMetrics(m RecordMetrics) {
+	if m == nil {
+		m = NoopRecordMetrics{}
 	}
-	defer tx.Rollback()
+	s.metrics = m
+}
 
-	dataJSON, _ := json.Marshal(data)
-	now := time.Now().UTC()
+// IsProduction treats an unset environment as production so safety checks fail closed
+func IsProduction(environment string) bool {
+	switch environment {
+	case "", "production", "prod":
+		return true
+	}
+	return false
+}
 
-	// --- Step 0: Ensure policyholder exists ---
-	_, err = tx.Exec(`
-		INSERT OR IGNORE INTO policyholders (policyholder_id, name)
-		VALUES (?, ?)`, policyholderID, data["name"])
-	if err != nil {
-		return nil, fmt.Errorf("failed to ensure policyholder exists: %w", err)
+// IsDevelopment is true only for an environment explicitly named development,
+// for switches that must never be on by accident
+func IsDevelopment(environment string) bool {
+	switch environment {
+	case "development", "dev":
+		return true
 	}
+	return false
+}
 
-	// --- Step 1: Check if record exists ---
-	var recordID int64
-	var currentVersion int
-	row := tx.QueryRow(`
-		SELECT record_id, version
-		FROM policyholder_records
-		WHERE policyholder_id = ?`, policyholderID)
-	err = row.Scan(&recordID, &currentVersion)
+// auditEnabled reports whether audit_history/event_logs should be written for this change
+func (s *SQLiteRecordService) auditEnabled(policyholderID int64) bool {
+	if s.flags == nil || IsProduction(s.environment) {
+		return true
+	}
+	if s.flags.IsEnabledWithDefault(FlagEnableAuditLogging, 0, true) {
+		return true
+	}
 
-	if err == sql.ErrNoRows {
-		// Insert new record
-		res, err := tx.Exec(`
-			INSERT INTO policyholder_records 
-			(policyholder_id, data, version, created_at, updated_at)
-			VALUES (?, ?, ?, ?, ?)`,
-			policyholderID, string(dataJSON), 1, now, now,
-		)
-		if err != nil {
-			return nil, err
-		}
+	observability.DefaultLogger.Warn("AUDIT LOGGING DISABLED: change will not be recorded in audit_history or event_logs",
+		"policyholder_id", policyholderID,
+		"environment", s.environment,
+	)
+	observability.AuditWriteSkipped()
+	return false
+}
 
-		recordID, _ = res.LastInsertId()
-		currentVersion = 1
+// CreateOrUpdate inserts or updates a policyholder record, increments version, writes audit + event log
+func (s *SQLiteRecordService) CreateOrUpdate(ctx context.Context, policyholderID int64, data map[string]string) (rec *entity.PolicyholderRecord, err error) {
+	ctx, span := observability.StartSpan(ctx, "SQLiteRecordService.CreateOrUpdate", attribute.Int64("policyholder.id", policyholderID))
+	defer func() { observability.EndSpan(span, err) }()
 
-		// Insert audit history
-		_, err = tx.Exec(`
-			INSERT INTO audit_history 
-			(record_id, version, data, changed_at, event_type)
-			VALUES (?, ?, ?, ?, ?)`,
-			recordID, currentVersion, string(dataJSON), now, "create",
-		)
-		if err != nil {
-			return nil, err
-		}
+	dataJSON, _ := json.Marshal(data)
+	now := time.Now().UTC()
+	audit := s.auditEnabled(policyholderID)
 
-		// Insert event log
-		_, err = tx.Exec(`
-			INSERT INTO event_logs 
-			(record_id, action, timestamp, details)
-			VALUES (?, ?, ?, ?)`,
-			recordID, "create", now, string(dataJSON),
-		)
+	var (
+		recordID       int64
+		currentVersion int
+		action         string
+	)
+	txStart := time.Now()
+	err = gateways.RunInTx(ctx, s.db, gateways.TxOptions{
+		Operation: "create_or_update",
+		OnRetry: func(attempt int, err error) {
+			s.metrics.TxRetry("create_or_update")
+			observability.DefaultLogger.WarnContext(ctx, "sqlite_busy_retry", "operation", "create_or_update", "attempt", attempt, "error", err)
+		},
+	}, func(tx *sql.Tx) error {
+		// runs again from scratch when SQLite reports the database busy
+		action = ActionUpdate
+
+		// --- Step 0: Ensure policyholder exists ---
+		_, err := execTraced(ctx, tx, "INSERT", `
+			INSERT OR IGNORE INTO policyholders (policyholder_id, name)
+			VALUES (?, ?)`, policyholderID, data["name"])
 		if err != nil {
-			return nil, err
+			return fmt.Errorf("failed to ensure policyholder exists: %w", err)
 		}
 
-	} else if err == nil {
-		// Update existing record
-		currentVersion++
-		_, err = tx.Exec(`
-			UPDATE policyholder_records
-			SET data = ?, version = ?, updated_at = ?
-			WHERE record_id = ?`,
-			string(dataJSON), currentVersion, now, recordID,
-		)
-		if err != nil {
-			return nil, err
-		}
+		// --- Step 1: Check if record exists ---
+		err = queryRowTraced(ctx, tx, "SELECT", `
+			SELECT record_id, version
+			FROM policyholder_records
+			WHERE policyholder_id = ?`, []interface{}{policyholderID}, &recordID, &currentVersion)
 
-		// Insert audit history
-		_, err = tx.Exec(`
-			INSERT INTO audit_history 
-			(record_id, version, data, changed_at, event_type)
-			VALUES (?, ?, ?, ?, ?)`,
-			recordID, currentVersion, string(dataJSON), now, "update",
-		)
-		if err != nil {
-			return nil, err
+		if err == sql.ErrNoRows {
+			// Insert new record
+			res, err := execTraced(ctx, tx, "INSERT", `
+				INSERT INTO policyholder_records 
+				(policyholder_id, data, version, created_at, updated_at)
+				VALUES (?, ?, ?, ?, ?)`,
+				policyholderID, string(dataJSON), 1, now, now,
+			)
+			if err != nil {
+				return err
+			}
+
+			recordID, _ = res.LastInsertId()
+			currentVersion = 1
+			action = ActionCreate
+
+		} else if err == nil {
+			// Update existing record
+			currentVersion++
+			_, err = execTraced(ctx, tx, "UPDATE", `
+				UPDATE policyholder_records
+				SET data = ?, version = ?, updated_at = ?
+				WHERE record_id = ?`,
+				string(dataJSON), currentVersion, now, recordID,
+			)
+			if err != nil {
+				return err
+			}
+
+		} else {
+			return err
 		}
 
-		// Insert event log
-		_, err = tx.Exec(`
-			INSERT INTO event_logs 
-			(record_id, action, timestamp, details)
-			VALUES (?, ?, ?, ?)`,
-			recordID, "update", now, string(dataJSON),
-		)
-		if err != nil {
-			return nil, err
+		if audit {
+			return writeAudit(ctx, tx, recordID, currentVersion, string(dataJSON), now, action)
 		}
-
-	} else {
+		return nil
+	})
+	if err != nil {
+		s.metrics.TxDuration("create_or_update", TxRolledBack, time.Since(txStart))
 		return nil, err
 	}
+	s.metrics.TxDuration("create_or_update", TxCommitted, time.Since(txStart))
 
-	if err := tx.Commit(); err != nil {
-		return nil, err
+	s.metrics.RecordChanged(metricAction(ctx, action))
+	s.metrics.VersionsPerRecord(currentVersion)
+	s.metrics.PayloadSize(len(dataJSON))
+	if audit {
+		s.metrics.AuditRowsWritten(1)
 	}
 
 	return &entity.PolicyholderRecord{
@@ -157,19 +214,89 @@ func (s *SQLiteRecordService) CreateOrUpdate(policyholderID int64, data map[stri
 	}, nil
 }
 
+// writeAudit inserts the immutable audit snapshot and its event log entry,
+// both attributed to the user, request and source in ctx
+func writeAudit(ctx context.Context, tx *sql.Tx, recordID int64, version int, dataJSON string, now time.Time, action string) error {
+	by := changeAttribution(ctx)
+
+	// Insert audit history
+	_, err := execTraced(ctx, tx, "INSERT", `
+		INSERT INTO audit_history 
+		(record_id, version, data, changed_at, event_type, changed_by, request_id, source, reason)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		recordID, version, dataJSON, now, action,
+		nullInt64(by.ChangedBy), nullString(by.RequestID), nullString(by.Source), nullString(by.Reason),
+	)
+	if err != nil {
+		return err
+	}
+
+	// Insert event log; the request id ties the row back to the originating call
+	_, err = execTraced(ctx, tx, "INSERT", `
+		INSERT INTO event_logs 
+		(record_id, action, timestamp, details, changed_by)
+		VALUES (?, ?, ?, ?, ?)`,
+		recordID, action, now, eventDetails(by, dataJSON), nullInt64(by.ChangedBy),
+	)
+	return err
+}
+
+// changeAttribution reads who is writing, and how, from the request context
+func changeAttribution(ctx context.Context) entity.ChangeAttribution {
+	var by entity.ChangeAttribution
+	by.ChangedBy, _ = common.GetUserID(ctx)
+	by.RequestID, _ = common.GetRequestID(ctx)
+	by.Source, _ = common.GetChangeSource(ctx)
+	by.Reason, _ = common.GetChangeReason(ctx)
+	return by
+}
+
+// metricAction reports an update the caller declared a revert as ActionRevert
+func metricAction(ctx context.Context, action string) string {
+	if source, _ := common.GetChangeSource(ctx); action == ActionUpdate && source == common.SourceRevert {
+		return ActionRevert
+	}
+	return action
+}
+
+// eventDetails wraps the snapshot with its attribution:
+// {"request_id": "...", "source": "...", "reason": "...", "data": {...}}
+func eventDetails(by entity.ChangeAttribution, dataJSON string) string {
+	details := struct {
+		RequestID string          `json:"request_id,omitempty"`
+		Source    string          `json:"source,omitempty"`
+		Reason    string          `json:"reason,omitempty"`
+		Data      json.RawMessage `json:"data"`
+	}{RequestID: by.RequestID, Source: by.Source, Reason: by.Reason, Data: json.RawMessage(dataJSON)}
+	b, _ := json.Marshal(details)
+	return string(b)
+}
+
+// nullInt64 stores 0 (unknown) as NULL
+func nullInt64(v int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: v, Valid: v != 0}
+}
+
+// nullString stores "" (unset) as NULL
+func nullString(v string) sql.NullString {
+	return sql.NullString{String: v, Valid: v != ""}
+}
+
 // Get retrieves a record by policyholder ID
-func (s *SQLiteRecordService) Get(policyholderID int64) (*entity.PolicyholderRecord, error) {
-	row := s.db.QueryRow(`
-		SELECT record_id, data, version, created_at, updated_at
-		FROM policyholder_records
-		WHERE policyholder_id = ?`, policyholderID)
+func (s *SQLiteRecordService) Get(ctx context.Context, policyholderID int64) (rec *entity.PolicyholderRecord, err error) {
+	ctx, span := observability.StartSpan(ctx, "SQLiteRecordService.Get", attribute.Int64("policyholder.id", policyholderID))
+	defer func() { observability.EndSpan(span, err) }()
 
 	var recordID int64
 	var dataJSON string
 	var version int
 	var createdAt, updatedAt string
 
-	err := row.Scan(&recordID, &dataJSON, &version, &createdAt, &updatedAt)
+	err = queryRowTraced(ctx, s.reader, "SELECT", `
+		SELECT record_id, data, version, created_at, updated_at
+		FROM policyholder_records
+		WHERE policyholder_id = ?`, []interface{}{policyholderID},
+		&recordID, &dataJSON, &version, &createdAt, &updatedAt)
 	if err == sql.ErrNoRows {
 		return nil, ErrRecordDoesNotExist
 	} else if err != nil {
@@ -192,33 +319,42 @@ func (s *SQLiteRecordService) Get(policyholderID int64) (*entity.PolicyholderRec
 }
 
 // GetVersion returns a specific historical version
-func (s *SQLiteRecordService) GetVersion(policyholderID int64, version int) (map[string]string, error) {
-	row := s.db.QueryRow(`
+func (s *SQLiteRecordService) GetVersion(ctx context.Context, policyholderID int64, version int) (data map[string]string, err error) {
+	ctx, span := observability.StartSpan(ctx, "SQLiteRecordService.GetVersion",
+		attribute.Int64("policyholder.id", policyholderID),
+		attribute.Int("record.version", version),
+	)
+	defer func() { observability.EndSpan(span, err) }()
+
+	var jsonData string
+	if err := queryRowTraced(ctx, s.reader, "SELECT", `
 		SELECT ah.data
 		FROM audit_history ah
 		JOIN policyholder_records pr ON pr.record_id = ah.record_id
 		WHERE pr.policyholder_id = ?
 		AND ah.version = ?`,
-		policyholderID, version,
-	)
-
-	var jsonData string
-	if err := row.Scan(&jsonData); err != nil {
+		[]interface{}{policyholderID, version}, &jsonData,
+	); err != nil {
 		if err == sql.ErrNoRows {
+			s.metrics.AsOfQuery(AsOfNotFound)
 			return nil, ErrRecordDoesNotExist
 		}
+		s.metrics.AsOfQuery(AsOfError)
 		return nil, err
 	}
+	s.metrics.AsOfQuery(AsOfFound)
 
-	var data map[string]string
 	_ = json.Unmarshal([]byte(jsonData), &data)
 
 	return data, nil
 }
 
 // ListVersions returns all versions for a record
-func (s *SQLiteRecordService) ListVersions(policyholderID int64) ([]int, error) {
-	rows, err := s.db.Query(`
+func (s *SQLiteRecordService) ListVersions(ctx context.Context, policyholderID int64) (versions []int, err error) {
+	ctx, span := observability.StartSpan(ctx, "SQLiteRecordService.ListVersions", attribute.Int64("policyholder.id", policyholderID))
+	defer func() { observability.EndSpan(span, err) }()
+
+	rows, err := queryTraced(ctx, s.reader, "SELECT", `
 		SELECT ah.version
 		FROM audit_history ah
 		JOIN policyholder_records pr ON pr.record_id = ah.record_id
@@ -231,7 +367,6 @@ func (s *SQLiteRecordService) ListVersions(policyholderID int64) ([]int, error)
 	}
 	defer rows.Close()
 
-	var versions []int
 	for rows.Next() {
 		var v int
 		rows.Scan(&v)
@@ -1,13 +1,22 @@
 package service_test
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/rainbowmga/timetravel/common"
+	"github.com/rainbowmga/timetravel/observability"
 	"github.com/rainbowmga/timetravel/service"
 	_ "github.com/mattn/go-sqlite3"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func createRecordTestDB(t *testing.T) (string, func()) {
@@ -46,7 +55,11 @@ func createRecordTestDB(t *testing.T) (string, func()) {
 		version INTEGER,
 		data TEXT,
 		changed_at TEXT,
-		event_type TEXT
+		event_type TEXT,
+		changed_by INTEGER,
+		request_id TEXT,
+		source TEXT,
+		reason TEXT
 	);
 
 	CREATE TABLE event_logs (
@@ -54,7 +67,8 @@ func createRecordTestDB(t *testing.T) (string, func()) {
 		record_id INTEGER,
 		action TEXT,
 		timestamp TEXT,
-		details TEXT
+		details TEXT,
+		changed_by INTEGER
 	);
 	`
 
@@ -79,7 +93,7 @@ func TestCreateOrUpdate_CreateThenUpdate(t *testing.T) {
 
 	// ---- CREATE ----
 	data := map[string]string{"name": "John"}
-	record, err := svc.CreateOrUpdate(1, data)
+	record, err := svc.CreateOrUpdate(context.Background(), 1, data)
 	if err != nil {
 		t.Fatalf("create failed: %v", err)
 	}
@@ -90,7 +104,7 @@ func TestCreateOrUpdate_CreateThenUpdate(t *testing.T) {
 
 	// ---- UPDATE ----
 	data2 := map[string]string{"name": "John Updated"}
-	record2, err := svc.CreateOrUpdate(1, data2)
+	record2, err := svc.CreateOrUpdate(context.Background(), 1, data2)
 	if err != nil {
 		t.Fatalf("update failed: %v", err)
 	}
@@ -106,9 +120,9 @@ func TestGet_RecordExists(t *testing.T) {
 
 	svc, _ := service.NewSQLiteRecordService(path)
 
-	_, _ = svc.CreateOrUpdate(1, map[string]string{"name": "Alice"})
+	_, _ = svc.CreateOrUpdate(context.Background(), 1, map[string]string{"name": "Alice"})
 
-	record, err := svc.Get(1)
+	record, err := svc.Get(context.Background(), 1)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -128,7 +142,7 @@ func TestGet_RecordDoesNotExist(t *testing.T) {
 
 	svc, _ := service.NewSQLiteRecordService(path)
 
-	_, err := svc.Get(999)
+	_, err := svc.Get(context.Background(), 999)
 	if err != service.ErrRecordDoesNotExist {
 		t.Errorf("expected ErrRecordDoesNotExist, got %v", err)
 	}
@@ -140,10 +154,10 @@ func TestGetVersion(t *testing.T) {
 
 	svc, _ := service.NewSQLiteRecordService(path)
 
-	_, _ = svc.CreateOrUpdate(1, map[string]string{"name": "V1"})
-	_, _ = svc.CreateOrUpdate(1, map[string]string{"name": "V2"})
+	_, _ = svc.CreateOrUpdate(context.Background(), 1, map[string]string{"name": "V1"})
+	_, _ = svc.CreateOrUpdate(context.Background(), 1, map[string]string{"name": "V2"})
 
-	v1, err := svc.GetVersion(1, 1)
+	v1, err := svc.GetVersion(context.Background(), 1, 1)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -152,7 +166,7 @@ func TestGetVersion(t *testing.T) {
 		t.Errorf("expected V1")
 	}
 
-	v2, err := svc.GetVersion(1, 2)
+	v2, err := svc.GetVersion(context.Background(), 1, 2)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -168,7 +182,7 @@ func TestGetVersion_NotFound(t *testing.T) {
 
 	svc, _ := service.NewSQLiteRecordService(path)
 
-	_, err := svc.GetVersion(1, 1)
+	_, err := svc.GetVersion(context.Background(), 1, 1)
 	if err != service.ErrRecordDoesNotExist {
 		t.Errorf("expected ErrRecordDoesNotExist")
 	}
@@ -180,11 +194,11 @@ func TestListVersions(t *testing.T) {
 
 	svc, _ := service.NewSQLiteRecordService(path)
 
-	_, _ = svc.CreateOrUpdate(1, map[string]string{"name": "V1"})
-	_, _ = svc.CreateOrUpdate(1, map[string]string{"name": "V2"})
-	_, _ = svc.CreateOrUpdate(1, map[string]string{"name": "V3"})
+	_, _ = svc.CreateOrUpdate(context.Background(), 1, map[string]string{"name": "V1"})
+	_, _ = svc.CreateOrUpdate(context.Background(), 1, map[string]string{"name": "V2"})
+	_, _ = svc.CreateOrUpdate(context.Background(), 1, map[string]string{"name": "V3"})
 
-	versions, err := svc.ListVersions(1)
+	versions, err := svc.ListVersions(context.Background(), 1)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -204,7 +218,7 @@ func TestTimestampsAreSet(t *testing.T) {
 
 	svc, _ := service.NewSQLiteRecordService(path)
 
-	record, err := svc.CreateOrUpdate(1, map[string]string{"name": "TimeTest"})
+	record, err := svc.CreateOrUpdate(context.Background(), 1, map[string]string{"name": "TimeTest"})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -218,3 +232,373 @@ func TestTimestampsAreSet(t *testing.T) {
 		t.Errorf("created_at too old")
 	}
 }
+
+func countAuditRows(t *testing.T, path string) int {
+	t.Helper()
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM audit_history`).Scan(&n); err != nil {
+		t.Fatalf("failed to count audit rows: %v", err)
+	}
+	return n
+}
+
+func TestCreateOrUpdate_AuditLoggingFlag(t *testing.T) {
+	auditOff := service.NewFeatureFlagServiceWithProvider(service.NewStaticFlagProvider(map[string]service.FeatureFlag{
+		service.FlagEnableAuditLogging: {Enabled: false, RolloutPercentage: 100},
+	}))
+	noFlags := service.NewFeatureFlagServiceWithProvider(service.NewStaticFlagProvider(nil))
+
+	tests := []struct {
+		name        string
+		flags       service.FlagEvaluator
+		environment string
+		wantAudit   int
+	}{
+		{"no evaluator always audits", nil, "development", 1},
+		{"flag off in development skips audit", auditOff, "development", 0},
+		{"flag off ignored in production", auditOff, "production", 1},
+		{"flag off ignored when environment unset", auditOff, "", 1},
+		{"undefined flag defaults to audit", noFlags, "development", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, cleanup := createRecordTestDB(t)
+			defer cleanup()
+
+			svc, err := service.NewSQLiteRecordServiceWithFlags(path, tt.flags, tt.environment)
+			if err != nil {
+				t.Fatalf("failed to create service: %v", err)
+			}
+
+			if _, err := svc.CreateOrUpdate(context.Background(), 1, map[string]string{"name": "Audit"}); err != nil {
+				t.Fatalf("create failed: %v", err)
+			}
+
+			if got := countAuditRows(t, path); got != tt.wantAudit {
+				t.Errorf("audit rows = %d, want %d", got, tt.wantAudit)
+			}
+		})
+	}
+}
+
+func TestCreateOrUpdate_RequestIDAndSQLSpans(t *testing.T) {
+	exp := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp))
+	orig := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	defer otel.SetTracerProvider(orig)
+
+	path, cleanup := createRecordTestDB(t)
+	defer cleanup()
+
+	svc, err := service.NewSQLiteRecordService(path)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+
+	ctx := context.WithValue(context.Background(), common.RequestIDKey, "req-abc")
+	if _, err := svc.CreateOrUpdate(ctx, 1, map[string]string{"name": "Traced"}); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+
+	var raw string
+	if err := db.QueryRow(`SELECT details FROM event_logs`).Scan(&raw); err != nil {
+		t.Fatalf("failed to read event log: %v", err)
+	}
+	var details struct {
+		RequestID string            `json:"request_id"`
+		Data      map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(raw), &details); err != nil {
+		t.Fatalf("event_logs.details is not JSON: %q", raw)
+	}
+	if details.RequestID != "req-abc" || details.Data["name"] != "Traced" {
+		t.Errorf("unexpected event details: %s", raw)
+	}
+
+	spans := exp.GetSpans()
+	var root string
+	var sqlSpans int
+	for _, s := range spans {
+		switch {
+		case s.Name == "SQLiteRecordService.CreateOrUpdate":
+			root = s.SpanContext.SpanID().String()
+		case strings.HasPrefix(s.Name, "sql "):
+			sqlSpans++
+		}
+	}
+	if root == "" {
+		t.Fatal("expected a service span")
+	}
+	// ensure policyholder, select, insert record, audit, event log
+	if sqlSpans != 5 {
+		t.Errorf("expected 5 SQL spans, got %d", sqlSpans)
+	}
+	for _, s := range spans {
+		if strings.HasPrefix(s.Name, "sql ") && s.Parent.SpanID().String() != root {
+			t.Errorf("SQL span %q is not a child of the service span", s.Name)
+		}
+	}
+}
+
+var _ service.RecordMetrics = (*observability.PrometheusRecordMetrics)(nil)
+
+type fakeRecordMetrics struct {
+	service.NoopRecordMetrics
+	changes  []string
+	versions []int
+	asOf     []string
+	txs      []string
+	audit    int
+}
+
+func (f *fakeRecordMetrics) RecordChanged(action string) { f.changes = append(f.changes, action) }
+func (f *fakeRecordMetrics) VersionsPerRecord(n int)     { f.versions = append(f.versions, n) }
+func (f *fakeRecordMetrics) AsOfQuery(result string)     { f.asOf = append(f.asOf, result) }
+func (f *fakeRecordMetrics) AuditRowsWritten(n int)      { f.audit += n }
+func (f *fakeRecordMetrics) TxDuration(op, outcome string, d time.Duration) {
+	f.txs = append(f.txs, op+":"+outcome)
+}
+
+func TestRecordService_EmitsLifecycleMetrics(t *testing.T) {
+	path, cleanup := createRecordTestDB(t)
+	defer cleanup()
+
+	svc, err := service.NewSQLiteRecordService(path)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	m := &fakeRecordMetrics{}
+	svc.SetMetrics(m)
+
+	ctx := context.Background()
+	_, _ = svc.CreateOrUpdate(ctx, 1, map[string]string{"name": "V1"})
+	_, _ = svc.CreateOrUpdate(ctx, 1, map[string]string{"name": "V2"})
+	_, _ = svc.GetVersion(ctx, 1, 2)
+	_, _ = svc.GetVersion(ctx, 1, 9)
+
+	if strings.Join(m.changes, ",") != "create,update" {
+		t.Errorf("changes = %v", m.changes)
+	}
+	if len(m.versions) != 2 || m.versions[1] != 2 {
+		t.Errorf("versions = %v", m.versions)
+	}
+	if strings.Join(m.asOf, ",") != "found,not_found" {
+		t.Errorf("as-of results = %v", m.asOf)
+	}
+	if strings.Join(m.txs, ",") != "create_or_update:commit,create_or_update:commit" {
+		t.Errorf("transactions = %v", m.txs)
+	}
+	if m.audit != 2 {
+		t.Errorf("audit rows = %d, want 2", m.audit)
+	}
+}
+
+func TestRecordService_CountsReverts(t *testing.T) {
+	path, cleanup := createRecordTestDB(t)
+	defer cleanup()
+
+	svc, err := service.NewSQLiteRecordService(path)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	m := &fakeRecordMetrics{}
+	svc.SetMetrics(m)
+
+	ctx := context.Background()
+	revert := context.WithValue(ctx, common.ChangeSourceKey, common.SourceRevert)
+	_, _ = svc.CreateOrUpdate(ctx, 1, map[string]string{"name": "V1"})
+	_, _ = svc.CreateOrUpdate(ctx, 1, map[string]string{"name": "V2"})
+	_, _ = svc.CreateOrUpdate(revert, 1, map[string]string{"name": "V1"})
+
+	if strings.Join(m.changes, ",") != "create,update,revert" {
+		t.Errorf("changes = %v, want the revert counted as one", m.changes)
+	}
+}
+
+func TestRecordService_CanceledContextStopsSQL(t *testing.T) {
+	path, cleanup := createRecordTestDB(t)
+	defer cleanup()
+
+	svc, _ := service.NewSQLiteRecordService(path)
+	if _, err := svc.CreateOrUpdate(context.Background(), 1, map[string]string{"name": "V1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := svc.Get(ctx, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("Get() error = %v, want context.Canceled", err)
+	}
+	if _, err := svc.ListVersions(ctx, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("ListVersions() error = %v, want context.Canceled", err)
+	}
+	if _, err := svc.CreateOrUpdate(ctx, 1, map[string]string{"name": "V2"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("CreateOrUpdate() error = %v, want context.Canceled", err)
+	}
+
+	rec, err := svc.Get(context.Background(), 1)
+	if err != nil || rec.Version != 1 {
+		t.Errorf("canceled write must not commit: got %+v, %v", rec, err)
+	}
+}
+
+func TestGetAsOf(t *testing.T) {
+	path, cleanup := createRecordTestDB(t)
+	defer cleanup()
+
+	svc, _ := service.NewSQLiteRecordService(path)
+	ctx := context.Background()
+
+	before := time.Now().UTC()
+	_, _ = svc.CreateOrUpdate(ctx, 1, map[string]string{"name": "V1"})
+	between := time.Now().UTC()
+	_, _ = svc.CreateOrUpdate(ctx, 1, map[string]string{"name": "V2"})
+	after := time.Now().UTC()
+
+	tests := []struct {
+		name    string
+		asOf    time.Time
+		version int
+		value   string
+		wantErr error
+	}{
+		{"before the record existed", before.Add(-time.Second), 0, "", service.ErrRecordDoesNotExist},
+		{"between the versions", between, 1, "V1", nil},
+		{"after the last version", after, 2, "V2", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			change, err := svc.GetAsOf(ctx, 1, tt.asOf)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if change.Version != tt.version || change.Data["name"] != tt.value {
+				t.Errorf("got version %d %v, want %d %s", change.Version, change.Data, tt.version, tt.value)
+			}
+			if change.PolicyholderID != 1 || change.ChangedAt.IsZero() || change.ChangedAt.After(tt.asOf) {
+				t.Errorf("unexpected change %+v", change)
+			}
+		})
+	}
+}
+
+func TestListChanges(t *testing.T) {
+	path, cleanup := createRecordTestDB(t)
+	defer cleanup()
+
+	svc, _ := service.NewSQLiteRecordService(path)
+	ctx := context.Background()
+
+	if latest, err := svc.LatestChangeID(ctx); err != nil || latest != 0 {
+		t.Fatalf("LatestChangeID on empty db = %d, %v", latest, err)
+	}
+
+	_, _ = svc.CreateOrUpdate(ctx, 1, map[string]string{"name": "A1"})
+	_, _ = svc.CreateOrUpdate(ctx, 2, map[string]string{"name": "B1"})
+	_, _ = svc.CreateOrUpdate(ctx, 1, map[string]string{"name": "A2"})
+
+	all, err := svc.ListChanges(ctx, 0, 0, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(all) != 3 || all[0].Data["name"] != "A1" || all[2].Data["name"] != "A2" {
+		t.Fatalf("unexpected changes: %+v", all)
+	}
+	if all[0].Action != "create" || all[2].Action != "update" {
+		t.Errorf("unexpected actions: %s, %s", all[0].Action, all[2].Action)
+	}
+
+	onlyOne, _ := svc.ListChanges(ctx, all[0].ID, 1, 10)
+	if len(onlyOne) != 1 || onlyOne[0].Version != 2 {
+		t.Errorf("policyholder filter: %+v", onlyOne)
+	}
+
+	limited, _ := svc.ListChanges(ctx, 0, 0, 2)
+	if len(limited) != 2 {
+		t.Errorf("limit: got %d changes", len(limited))
+	}
+
+	latest, err := svc.LatestChangeID(ctx)
+	if err != nil || latest != all[2].ID {
+		t.Errorf("LatestChangeID = %d, %v; want %d", latest, err, all[2].ID)
+	}
+}
+
+func TestCreateOrUpdate_ChangeAttribution(t *testing.T) {
+	db := openMigratedDB(t)
+	svc := service.NewSQLiteRecordServiceWithDB(db, nil, "development")
+
+	write := func(userID int64, source, reason string, data map[string]string) {
+		t.Helper()
+		ctx := context.WithValue(context.Background(), common.RequestIDKey, "req-"+source)
+		if userID > 0 {
+			ctx = context.WithValue(ctx, common.UserIDKey, userID)
+		}
+		ctx = context.WithValue(ctx, common.ChangeSourceKey, source)
+		ctx = context.WithValue(ctx, common.ChangeReasonKey, reason)
+		if _, err := svc.CreateOrUpdate(ctx, 1, data); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	write(7, common.SourceAPIV2, "", map[string]string{"name": "ann"})
+	write(0, common.SourceAPIV1, "", map[string]string{"name": "bea"})
+	write(7, common.SourceRevert, "undo rename", map[string]string{"name": "ann"})
+
+	all, err := svc.ListVersionEntries(context.Background(), 1, service.VersionFilter{})
+	if err != nil || len(all) != 3 {
+		t.Fatalf("ListVersionEntries() = %+v, %v", all, err)
+	}
+	if all[0].ChangedBy != 7 || all[0].Source != common.SourceAPIV2 || all[0].RequestID != "req-api_v2" || all[0].Reason != "" {
+		t.Errorf("version 1 attribution = %+v", all[0].ChangeAttribution)
+	}
+	if all[1].ChangedBy != 0 || all[1].Source != common.SourceAPIV1 {
+		t.Errorf("version 2 attribution = %+v, want an anonymous v1 write", all[1].ChangeAttribution)
+	}
+	if all[2].Reason != "undo rename" || all[2].Data["name"] != "ann" || all[2].EventType != service.ActionUpdate {
+		t.Errorf("version 3 = %+v", all[2])
+	}
+
+	byUser, err := svc.ListVersionEntries(context.Background(), 1, service.VersionFilter{ChangedBy: 7})
+	if err != nil || len(byUser) != 2 || byUser[0].Version != 1 || byUser[1].Version != 3 {
+		t.Errorf("ListVersionEntries(changed_by 7) = %+v, %v", byUser, err)
+	}
+	one, err := svc.ListVersionEntries(context.Background(), 1, service.VersionFilter{Version: 2})
+	if err != nil || len(one) != 1 || one[0].Version != 2 {
+		t.Errorf("ListVersionEntries(version 2) = %+v, %v", one, err)
+	}
+	if none, err := svc.ListVersionEntries(context.Background(), 2, service.VersionFilter{}); err != nil || none == nil || len(none) != 0 {
+		t.Errorf("ListVersionEntries(unknown) = %#v, %v; want an empty slice", none, err)
+	}
+
+	var changedBy sql.NullInt64
+	var raw string
+	if err := db.Reader().QueryRow(`SELECT changed_by, details FROM event_logs ORDER BY event_id DESC LIMIT 1`).Scan(&changedBy, &raw); err != nil {
+		t.Fatal(err)
+	}
+	var details struct {
+		Source string `json:"source"`
+		Reason string `json:"reason"`
+	}
+	if err := json.Unmarshal([]byte(raw), &details); err != nil || changedBy.Int64 != 7 ||
+		details.Source != common.SourceRevert || details.Reason != "undo rename" {
+		t.Errorf("event log = %d %s, want user 7 with the revert source and reason", changedBy.Int64, raw)
+	}
+}